@@ -23,53 +23,1581 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/audio/stream/{segment}": {
+        "/admin/duplicates": {
+            "get": {
+                "description": "Lists groups of tracks whose audio payloads have the same SHA-256, or that have the same artist,\nalbum and title, ignoring case, and durations within two seconds of each other. Hash groups come\nfirst. The tracks of a group are listed in the order they were ingested. The tracks are only\nlisted, nothing is merged or deleted.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin-controller"
+                ],
+                "summary": "List the tracks suspected to be duplicates.",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of groups per page, at most 100",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.DuplicateGroup"
+                            }
+                        },
+                        "headers": {
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of groups"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or page_size parameters",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/albums/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the album with its tracks ordered by disc and track number.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "library-controller"
+                ],
+                "summary": "Album with its tracks.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.AlbumDetail"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Album not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/artists": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the artists of the tracks and the album artists of the albums, ordered by name.\nNames that only differ in case or white space are one artist.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "library-controller"
+                ],
+                "summary": "List the artists.",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Artist"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or page_size parameters",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/artists/{id}/albums": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the albums the artist is the album artist of, oldest first.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "library-controller"
+                ],
+                "summary": "List the albums of an artist.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Artist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Album"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or page_size parameters",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Artist not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/audio/hls/{track_id}/master.m3u8": {
             "get": {
-                "description": "Streams audio files in the specified directory as MP3 or FLAC.",
+                "description": "Returns the HLS master playlist with the MP3 rendition of the track.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/vnd.apple.mpegurl"
+                ],
+                "tags": [
+                    "audio-controller"
+                ],
+                "summary": "HLS master playlist of a track.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "track_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signed stream token, required without the session cookie",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Master playlist",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Segment not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "HLS is only available for MP3 tracks",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/audio/hls/{track_id}/media.m3u8": {
+            "get": {
+                "description": "Returns the VOD media playlist of the track split into frame-aligned MP3 segments of 6-10 seconds.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/vnd.apple.mpegurl"
+                ],
+                "tags": [
+                    "audio-controller"
+                ],
+                "summary": "HLS media playlist of a track.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "track_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signed stream token, required without the session cookie",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Media playlist",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Segment not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "HLS is only available for MP3 tracks",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/audio/hls/{track_id}/segment/{segment}": {
+            "get": {
+                "description": "Streams one MP3 segment of the track media playlist.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "audio/mpeg"
+                ],
+                "tags": [
+                    "audio-controller"
+                ],
+                "summary": "HLS segment of a track.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "track_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Segment index, e.g. 3.mp3",
+                        "name": "segment",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signed stream token, required without the session cookie",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Segment",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid segment index",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Segment not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "HLS is only available for MP3 tracks",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/audio/stream/{segment}": {
+            "get": {
+                "description": "Streams the audio file of the track with the MIME type detected when it was scanned.\nSupports RFC 7233 byte ranges (single and multipart) and If-Range with the S3 ETag.\nWith delivery=redirect the request is answered with a 302 to a short-lived presigned S3 URL of the track version.\nTracks cut out of a single-file album by a CUE sheet are served as streams of their own, cut on FLAC or MP3\nframe boundaries, and always through the app.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "audio/mpeg",
+                    "audio/flac",
+                    "audio/ogg",
+                    "audio/opus",
+                    "audio/mp4",
+                    "audio/wav",
+                    "audio/aiff",
+                    "application/octet-stream",
+                    "multipart/byteranges"
+                ],
+                "tags": [
+                    "audio-controller"
+                ],
+                "summary": "Stream audio files.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "segment",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signed stream token, required without the session cookie",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery mode ('proxy' or 'redirect'), defaults to the configured one",
+                        "name": "delivery",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "S3 version of the track to stream, from /tracks/{code}/versions, defaults to the current one",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Byte ranges, e.g. bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag or Last-Modified date the range is conditional on",
+                        "name": "If-Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Whole file",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "302": {
+                        "description": "Redirect to the presigned S3 URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "delivery must be 'proxy' or 'redirect'",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Segment not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "406": {
+                        "description": "Segment not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "CUE tracks can only be cut out of FLAC and MP3",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "416": {
+                        "description": "Requested Range Not Satisfiable",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Error reading audio frames",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/audio/{playlist_id}": {
+            "get": {
+                "description": "Streams audio files in the specified directory as MP3 or FLAC.\nWith format=hls an HLS media playlist of the MP3 tracks is returned instead of a plain M3U list.\nTrack URIs carry a signed, expiring token so that players without the session cookie can fetch them.\nM3U entries carry the ReplayGain track gain, with replaygain=album the album gain as well.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/x-mpegURL",
+                    "application/vnd.apple.mpegurl"
+                ],
+                "tags": [
+                    "audio-controller"
+                ],
+                "summary": "Stream audio files.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "playlist_id",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Control operation playlist play",
+                        "name": "control",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Playlist format ('m3u' or 'hls')",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set to 'album' to add the album gain to M3U entries",
+                        "name": "replaygain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Track"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/genres": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the genres of the tracks, ordered by name.\nNames that only differ in case or white space are one genre.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "library-controller"
+                ],
+                "summary": "List the genres.",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Genre"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page or page_size parameters",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/hls/keys/{track_id}": {
+            "get": {
+                "description": "Returns the raw 16 byte key of the track version referenced by the EXT-X-KEY tags.\nRequires a signed in user or the stream token of the track, keys of replaced track versions are not served.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "audio-controller"
+                ],
+                "summary": "AES-128 key of an encrypted HLS track.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "track_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Track S3 version",
+                        "name": "v",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signed stream token, required without the session cookie",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Key",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Key not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/integrity": {
+            "get": {
+                "description": "Lists the S3 object versions the integrity job found corrupted or truncated, newest check first.\nThe objects are only reported, nothing is deleted.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "integrity-controller"
+                ],
+                "summary": "List the audio objects that failed the integrity check.",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses ('ok', 'corrupt', 'truncated' or 'unsupported'), defaults to 'corrupt,truncated'",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.IntegrityCheck"
+                            }
+                        },
+                        "headers": {
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "Number of matching object versions"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid page, page_size or status parameters",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/job/status": {
+            "get": {
+                "description": "Check if the application server is running jobs",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "job-controller"
+                ],
+                "summary": "All Job status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/otp/disable": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Disable OTP for a user_handler by setting 'otp_enabled' to 'false' in the database.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OTP"
+                ],
+                "summary": "Disable OTP for a user_handler.",
+                "parameters": [
+                    {
+                        "description": "OTP input data",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OTPInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OTP disabled successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.OkLoginResponce"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - User unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update OTP status",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/otp/generate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generate an OTP token for a user and store it in the database.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OTP"
+                ],
+                "summary": "Generate OTP for a user.",
+                "parameters": [
+                    {
+                        "description": "OTP input data",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OTPInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OTP generated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.OkGenerateOTP"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid refresh payload",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Failed to find user_handler or invalid email/password",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update OTP secret or URL",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/otp/validate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Validates a One-Time Password (OTP) for a user.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OTP"
+                ],
+                "summary": "Validates a One-Time Password (OTP).",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "OTP Input",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OTPInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OTP Valid",
+                        "schema": {
+                            "$ref": "#/definitions/model.OkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request - Invalid OTP",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - User unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found - User not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/otp/verify": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Verify the OTP token for a user_handler and update 'otp_enabled' and 'otp_verified' fields in the database.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "OTP"
+                ],
+                "summary": "Verify OTP for a user.",
+                "parameters": [
+                    {
+                        "description": "OTP input data",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.OTPInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OTP verified successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.OkLoginResponce"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - User unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update OTP status",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/create": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates a new playlist with the provided information.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist-controller"
+                ],
+                "summary": "Create a new playlist.",
+                "parameters": [
+                    {
+                        "description": "PLayList details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.PLayList"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Playlist created successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.PLayList"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - User unauthenticated",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/get": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves all playlists available in the storage, newest first.\nWith the cursor parameter it returns one page of page_size playlists and the Link header has the next and the first page.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist-controller"
+                ],
+                "summary": "Get all playlists",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the next link, empty for the first page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of playlists per page with cursor",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Playlists retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.PlaylistsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid cursor or page_size",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Playlists not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/{playlist_id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get tracks from a playlist by providing the playlist ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist-controller"
+                ],
+                "summary": "Get tracks from a playlist.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "playlist_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tracks retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.PlaylistTracksResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete a playlist based on its unique ID.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist-controller"
+                ],
+                "summary": "Delete a playlist by ID.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/{playlist_id}/clear": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes all tracks from a playlist, effectively clearing it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist-controller"
+                ],
+                "summary": "Clear a playlist by removing all tracks from it.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "playlist_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Playlist cleared successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Playlist not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/{playlist_id}/tracks": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Set tracks in a playlist by providing a list of track IDs.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist-controller"
+                ],
+                "summary": "Set tracks in a playlist.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "playlist_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "List of track IDs to set in the playlist",
+                        "name": "track_ids",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tracks set in the playlist successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/{playlist_id}/{track_id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Add a track to an existing playlist.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist-controller"
+                ],
+                "summary": "Add a track to a playlist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "playlist_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "track_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Track added to the playlist successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Playlist or track not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove a track from the specified playlist.",
                 "consumes": [
-                    "*/*"
+                    "application/json"
                 ],
                 "produces": [
-                    "audio/mpeg",
-                    "audio/flac",
-                    "application/octet-stream"
+                    "application/json"
                 ],
                 "tags": [
-                    "audio-controller"
+                    "playlist-controller"
                 ],
-                "summary": "Stream audio files.",
+                "summary": "Remove a track from the playlist.",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Playlist ID",
                         "name": "playlist_id",
-                        "in": "path"
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Control operation playlist play",
-                        "name": "control",
-                        "in": "path"
+                        "description": "Track ID",
+                        "name": "track_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Track removed from playlist successfully",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Track"
-                            }
+                            "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Segment not found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "406": {
-                        "description": "Segment not found",
+                    "404": {
+                        "description": "Playlist or track not found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -83,41 +1611,69 @@ const docTemplate = `{
                 }
             }
         },
-        "/audio/{playlist_id}": {
+        "/radio/{playlist_id}": {
             "get": {
-                "description": "Streams audio files in the specified directory as MP3 or FLAC.",
+                "description": "Streams the MP3 tracks of the playlist as one endless Icecast/SHOUTcast compatible stream.\nListeners share the current position, ICY metadata is interleaved when the client sends Icy-MetaData: 1.",
                 "consumes": [
                     "*/*"
                 ],
                 "produces": [
-                    "application/x-mpegURL"
+                    "audio/mpeg"
                 ],
                 "tags": [
-                    "audio-controller"
+                    "radio-controller"
                 ],
-                "summary": "Stream audio files.",
+                "summary": "Listen to a playlist as internet radio.",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Playlist ID",
                         "name": "playlist_id",
-                        "in": "path"
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Restart the playlist when it ends, defaults to the configured value",
+                        "name": "loop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Shuffle the playlist on every pass, defaults to the configured value",
+                        "name": "shuffle",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Control operation playlist play",
-                        "name": "control",
-                        "in": "path"
+                        "description": "1 to receive ICY metadata every icy-metaint bytes",
+                        "name": "Icy-MetaData",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Radio stream",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Track"
-                            }
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "loop must be a boolean",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Unauthorized access",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No tracks to play",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "500": {
@@ -129,95 +1685,98 @@ const docTemplate = `{
                 }
             }
         },
-        "/job/status": {
+        "/radio/{playlist_id}/listeners": {
             "get": {
-                "description": "Check if the application server is running jobs",
+                "description": "Returns the number of clients currently listening to the radio stream of the playlist.",
                 "consumes": [
-                    "application/json"
+                    "*/*"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "job-controller"
+                    "radio-controller"
+                ],
+                "summary": "Listener count of a playlist radio.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "playlist_id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "All Job status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.RadioListeners"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/otp/disable": {
-            "post": {
+        "/search": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Disable OTP for a user_handler by setting 'otp_enabled' to 'false' in the database.",
+                "description": "Searches the tracks, albums, artists and playlists, best match first in each list.\nWords are matched in the title, artist, album, composer and lyrics of the tracks, the title weighing most,\nand titles and names close to the query are matched too, so that typos still find something.\nThe query takes quoted phrases, \"or\" and a leading \"-\" to exclude a word.\nSnippets are HTML escaped with the matched words in \u003cb\u003e tags. Members only find their own playlists.",
                 "consumes": [
-                    "application/json"
+                    "*/*"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "OTP"
+                    "search-controller"
                 ],
-                "summary": "Disable OTP for a user_handler.",
+                "summary": "Search the library.",
                 "parameters": [
                     {
-                        "description": "OTP input data",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.OTPInput"
-                        }
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results of each kind, 1 to 50, defaults to 10",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OTP disabled successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OkLoginResponce"
+                            "$ref": "#/definitions/model.SearchResult"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Missing q or invalid limit",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized - User unauthenticated",
-                        "schema": {
-                            "$ref": "#/definitions/model.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "User not found",
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update OTP status",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -225,56 +1784,116 @@ const docTemplate = `{
                 }
             }
         },
-        "/otp/generate": {
-            "post": {
+        "/tracks": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Generate an OTP token for a user and store it in the database.",
+                "description": "responds with the list of all tracks as JSON.\nWith the cursor parameter the list is paged by the sort keys of the last track, which keeps deep pages fast,\nand the Link header has the next and the first page.",
                 "consumes": [
-                    "application/json"
+                    "*/*"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "OTP"
+                    "track-controller"
                 ],
-                "summary": "Generate OTP for a user.",
+                "summary": "Show the list of all tracks.",
                 "parameters": [
                     {
-                        "description": "OTP input data",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.OTPInput"
-                        }
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated fields to sort by, '-' or '+' in front sorts descending or ascending (e.g., 'year,-title', 'bpm' or 'musical_key')",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order of the fields without '-' or '+' ('asc' or 'desc')",
+                        "name": "sort_order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter criteria ('I0001' or '=I0001')",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter query of field:value terms, quoted values and '-' negations, e.g. 'genre:rock year:1990..1999 duration:\u003e300 -live'",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Lowest estimated tempo",
+                        "name": "bpm_min",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Highest estimated tempo",
+                        "name": "bpm_max",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Estimated key ('A minor', 'Am' or 'C')",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the next link, pages by the sort keys instead of page when given, empty for the first page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Total count: exact (default with page), estimate (default with cursor) or none (cursor only)",
+                        "name": "count",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OTP generated successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OkGenerateOTP"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Track"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Invalid refresh payload",
+                        "description": "Invalid page, page_size, sort_by, query, cursor or count parameters",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Failed to find user_handler or invalid email/password",
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update OTP secret or URL",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -282,67 +1901,64 @@ const docTemplate = `{
                 }
             }
         },
-        "/otp/validate": {
+        "/tracks/upload": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Validates a One-Time Password (OTP) for a user.",
+                "description": "Uploads audio files to S3 and ingests them at once. Admins only.\nThe format and the tags of every file are checked first, files that are not audio, have no title or artist,\nare larger than the configured limit or would duplicate the title of an existing track are rejected with the reason.\nA file named like the object of existing tracks becomes their current version.",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "OTP"
+                    "track-controller"
                 ],
-                "summary": "Validates a One-Time Password (OTP).",
+                "summary": "Upload tracks.",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "user_id",
-                        "in": "path",
+                        "type": "file",
+                        "description": "Audio files, the field may be repeated",
+                        "name": "files",
+                        "in": "formData",
                         "required": true
-                    },
-                    {
-                        "description": "OTP Input",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.OTPInput"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OTP Valid",
+                        "description": "Track IDs or the reason of every file, some were ingested",
                         "schema": {
-                            "$ref": "#/definitions/model.OkResponse"
+                            "$ref": "#/definitions/model.UploadResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad Request - Invalid OTP",
+                        "description": "Not a multipart request, or no files",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized - User unauthenticated",
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found - User not found",
+                    "413": {
+                        "description": "The request is too large",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "422": {
+                        "description": "The reason of every file, none was ingested",
+                        "schema": {
+                            "$ref": "#/definitions/model.UploadResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -352,71 +1968,67 @@ const docTemplate = `{
                 }
             }
         },
-        "/otp/verify": {
-            "post": {
+        "/tracks/{code}": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Verify the OTP token for a user_handler and update 'otp_enabled' and 'otp_verified' fields in the database.",
+                "description": "locates the track whose ID value matches the id parameter sent by the client,\nthen returns that track as a response.",
                 "consumes": [
-                    "application/json"
+                    "*/*"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "OTP"
+                    "track-controller"
                 ],
-                "summary": "Verify OTP for a user.",
+                "summary": "Track whose ID value matches the id.",
                 "parameters": [
                     {
-                        "description": "OTP input data",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.OTPInput"
-                        }
+                        "type": "string",
+                        "description": "Code track",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OTP verified successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.OkLoginResponce"
+                            "$ref": "#/definitions/model.Track"
                         }
                     },
-                    "400": {
-                        "description": "Bad request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized - User unauthenticated",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update OTP status",
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/playlist/create": {
-            "post": {
+            },
+            "patch": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Creates a new playlist with the provided information.",
+                "description": "Changes the given tags of the track, the ones left out keep their value. Admins only.\nWith write_tags the tags are also written into the audio file, as ID3v2 for MP3 and as Vorbis comments for FLAC,\nwhich is uploaded as a new version of its S3 object. The track is pointed at the new version,\nso a rescan of the bucket keeps the edit.",
                 "consumes": [
                     "application/json"
                 ],
@@ -424,41 +2036,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "playlist-controller"
+                    "track-controller"
                 ],
-                "summary": "Create a new playlist.",
+                "summary": "Edit the tags of a track.",
                 "parameters": [
                     {
-                        "description": "PLayList details",
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags to change",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.PLayList"
-                            }
+                            "$ref": "#/definitions/model.TrackPatch"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Playlist created successfully",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.PLayList"
-                            }
+                            "$ref": "#/definitions/model.Track"
                         }
                     },
                     "400": {
-                        "description": "Invalid input",
+                        "description": "Invalid tags, or tags that cannot be written into the file",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "401": {
-                        "description": "Unauthorized - User unauthenticated",
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Track not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "The tags could not be written into the file",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -468,37 +2093,70 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
+                    },
+                    "502": {
+                        "description": "The track was saved but its file could not be uploaded",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/playlist/get": {
+        "/tracks/{code}/cover": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves all playlists available in the storage.",
+                "description": "Returns the cover art embedded in the track, or the folder.jpg/cover.png next to its audio file,\nscaled to fit into a size by size square. Thumbnails are made on the first request and cached in S3.",
                 "consumes": [
-                    "application/json"
+                    "*/*"
                 ],
                 "produces": [
-                    "application/json"
+                    "image/jpeg"
                 ],
                 "tags": [
-                    "playlist-controller"
+                    "track-controller"
+                ],
+                "summary": "Cover art of the track.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Edge in pixels: 64, 300 (default) or 1200",
+                        "name": "size",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all playlists",
                 "responses": {
                     "200": {
-                        "description": "Playlists retrieved successfully",
+                        "description": "JPEG image",
                         "schema": {
-                            "$ref": "#/definitions/model.PlaylistsResponse"
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "size must be 64, 300 or 1200",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Playlists not found",
+                        "description": "Cover not found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -512,48 +2170,51 @@ const docTemplate = `{
                 }
             }
         },
-        "/playlist/{playlist_id}": {
+        "/tracks/{code}/versions": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get tracks from a playlist by providing the playlist ID.",
+                "description": "Returns every S3 object version the track has had with its ETag, size and upload time, newest first.\nThe current one is the version the track is streamed from, the others can be streamed with the version\nparameter of /audio/stream/{segment}.",
                 "consumes": [
-                    "application/json"
+                    "*/*"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "playlist-controller"
+                    "track-controller"
                 ],
-                "summary": "Get tracks from a playlist.",
+                "summary": "S3 versions of the track.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Playlist ID",
-                        "name": "playlist_id",
+                        "description": "Track ID",
+                        "name": "code",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Tracks retrieved successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.PlaylistTracksResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.TrackVersion"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Track not found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -565,45 +2226,60 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/tracks/{code}/versions/{version}/restore": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete a playlist based on its unique ID.",
+                "description": "Makes an earlier S3 object version of the track its current version again, the track is streamed from it.\nThe tags, format, duration and hash of the track are read again from the version. Admins only.",
                 "consumes": [
-                    "application/json"
+                    "*/*"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "playlist-controller"
+                    "track-controller"
                 ],
-                "summary": "Delete a playlist by ID.",
+                "summary": "Restore an S3 version of the track.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Playlist ID",
-                        "name": "id",
+                        "description": "Track ID",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "S3 version ID",
+                        "name": "version",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.TrackVersion"
+                            }
+                        }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not Found",
+                        "description": "Track or version not found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -617,48 +2293,73 @@ const docTemplate = `{
                 }
             }
         },
-        "/playlist/{playlist_id}/clear": {
-            "delete": {
+        "/tracks/{code}/waveform": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Removes all tracks from a playlist, effectively clearing it.",
+                "description": "Returns min/max peak pairs of the current S3 version of the track for drawing a seek bar,\nas JSON or, with format=binary, as a format version byte, a big endian uint32 pair count and int8 pairs.\nFLAC and MP3 tracks only. The peaks are recomputed when the S3 version changes.",
                 "consumes": [
-                    "application/json"
+                    "*/*"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/octet-stream"
                 ],
                 "tags": [
-                    "playlist-controller"
+                    "track-controller"
                 ],
-                "summary": "Clear a playlist by removing all tracks from it.",
+                "summary": "Waveform peaks of the track.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Playlist ID",
-                        "name": "playlist_id",
+                        "description": "Track ID",
+                        "name": "code",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of min/max pairs, 1 to 2048 (default)",
+                        "name": "points",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response format ('json' or 'binary')",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Playlist cleared successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.Waveform"
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "points must be between 1 and 2048",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Playlist not found",
+                        "description": "Waveform not found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "waveform needs FLAC or MP3 audio",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -672,54 +2373,57 @@ const docTemplate = `{
                 }
             }
         },
-        "/playlist/{playlist_id}/tracks": {
+        "/tus": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Set tracks in a playlist by providing a list of track IDs.",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Creates a tus upload of an audio file as an S3 multipart upload. Admins only.\nThe filename in Upload-Metadata names the object, the upload completes to a new version of it and is\ningested like a file dropped into the bucket.",
                 "tags": [
-                    "playlist-controller"
+                    "tus-controller"
                 ],
-                "summary": "Set tracks in a playlist.",
+                "summary": "Create a resumable upload.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Playlist ID",
-                        "name": "playlist_id",
-                        "in": "path",
+                        "description": "1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
                         "required": true
                     },
                     {
-                        "description": "List of track IDs to set in the playlist",
-                        "name": "track_ids",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "string"
-                            }
-                        }
+                        "type": "integer",
+                        "description": "Size of the file in bytes",
+                        "name": "Upload-Length",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated keys and base64 values, filename is required",
+                        "name": "Upload-Metadata",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Tracks set in the playlist successfully",
-                        "schema": {
-                            "type": "string"
+                    "201": {
+                        "description": "Created",
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the upload"
+                            },
+                            "Upload-Expires": {
+                                "type": "string",
+                                "description": "When the unfinished upload is removed"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Bad Request",
+                        "description": "Missing Upload-Length, or invalid Upload-Metadata or filename",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -730,6 +2434,15 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "412": {
+                        "description": "Unsupported tus version"
+                    },
+                    "413": {
+                        "description": "The file is larger than Tus-Max-Size",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -737,57 +2450,83 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "options": {
+                "description": "Returns the tus version, the extensions and the largest file a resumable upload takes.",
+                "tags": [
+                    "tus-controller"
+                ],
+                "summary": "Describe the tus server.",
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "headers": {
+                            "Tus-Extension": {
+                                "type": "string",
+                                "description": "creation,termination,expiration"
+                            },
+                            "Tus-Max-Size": {
+                                "type": "integer",
+                                "description": "Largest upload in bytes"
+                            },
+                            "Tus-Version": {
+                                "type": "string",
+                                "description": "1.0.0"
+                            }
+                        }
+                    }
+                }
             }
         },
-        "/playlist/{playlist_id}/{track_id}": {
-            "post": {
+        "/tus/{id}": {
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Add a track to an existing playlist.",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Removes the upload and the parts received. The object and the tracks of a completed upload are kept.",
                 "tags": [
-                    "playlist-controller"
+                    "tus-controller"
                 ],
-                "summary": "Add a track to a playlist",
+                "summary": "Terminate a resumable upload.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Playlist ID",
-                        "name": "playlist_id",
+                        "description": "Upload ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Track ID",
-                        "name": "track_id",
-                        "in": "path",
+                        "description": "1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Track added to the playlist successfully",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Upload not found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Playlist or track not found",
+                    "412": {
+                        "description": "Unsupported tus version"
+                    },
+                    "423": {
+                        "description": "Another request is writing to the upload",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -800,129 +2539,126 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
+            "head": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Remove a track from the specified playlist.",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Returns how many bytes of the upload were received. A completed upload reports the tracks it became,\nor why it did not become one.",
                 "tags": [
-                    "playlist-controller"
+                    "tus-controller"
                 ],
-                "summary": "Remove a track from the playlist.",
+                "summary": "Get the offset of a resumable upload.",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Playlist ID",
-                        "name": "playlist_id",
+                        "description": "Upload ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Track ID",
-                        "name": "track_id",
-                        "in": "path",
+                        "description": "1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Track removed from playlist successfully",
-                        "schema": {
-                            "type": "string"
+                        "description": "OK",
+                        "headers": {
+                            "Upload-Length": {
+                                "type": "integer",
+                                "description": "Size of the file in bytes"
+                            },
+                            "Upload-Offset": {
+                                "type": "integer",
+                                "description": "Bytes received"
+                            },
+                            "X-Ingest-Error": {
+                                "type": "string",
+                                "description": "Why a completed upload did not become a track"
+                            },
+                            "X-Track-Ids": {
+                                "type": "string",
+                                "description": "Comma separated IDs of the tracks of a completed upload"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/model.ErrorResponse"
-                        }
+                    "401": {
+                        "description": "Unauthorized"
                     },
                     "404": {
-                        "description": "Playlist or track not found",
-                        "schema": {
-                            "$ref": "#/definitions/model.ErrorResponse"
-                        }
+                        "description": "Upload not found"
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/model.ErrorResponse"
-                        }
+                    "410": {
+                        "description": "The upload expired"
+                    },
+                    "412": {
+                        "description": "Unsupported tus version"
                     }
                 }
-            }
-        },
-        "/tracks": {
-            "get": {
+            },
+            "patch": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "responds with the list of all tracks as JSON.",
+                "description": "Appends the body to the upload at Upload-Offset. The bytes received are kept when the request breaks off,\nHEAD tells where to resume. With the last byte the object is completed and ingested.",
                 "consumes": [
-                    "*/*"
-                ],
-                "produces": [
-                    "application/json"
+                    "application/offset+octet-stream"
                 ],
                 "tags": [
-                    "track-controller"
+                    "tus-controller"
                 ],
-                "summary": "Show the list of all tracks.",
+                "summary": "Append to a resumable upload.",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Number of items per page",
-                        "name": "page_size",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "Field to sort by (e.g., 'created_at')",
-                        "name": "sort_by",
-                        "in": "query"
+                        "description": "Upload ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Sort order ('asc' or 'desc')",
-                        "name": "sort_order",
-                        "in": "query"
+                        "description": "1.0.0",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Filter criteria ('I0001' or '=I0001')",
-                        "name": "filter",
-                        "in": "query"
+                        "type": "integer",
+                        "description": "Offset of the upload the body starts at",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Track"
+                    "204": {
+                        "description": "No Content",
+                        "headers": {
+                            "Upload-Offset": {
+                                "type": "integer",
+                                "description": "Bytes received"
+                            },
+                            "X-Ingest-Error": {
+                                "type": "string",
+                                "description": "Why a completed upload did not become a track"
+                            },
+                            "X-Track-Ids": {
+                                "type": "string",
+                                "description": "Comma separated IDs of the tracks of a completed upload"
                             }
                         }
                     },
                     "400": {
-                        "description": "Invalid page or page_size parameters",
+                        "description": "Invalid Upload-Offset",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -933,57 +2669,41 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Upload not found",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/tracks/{code}": {
-            "get": {
-                "security": [
-                    {
-                        "ApiKeyAuth": []
-                    }
-                ],
-                "description": "locates the track whose ID value matches the id parameter sent by the client,\nthen returns that track as a response.",
-                "consumes": [
-                    "*/*"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "track-controller"
-                ],
-                "summary": "Track whose ID value matches the id.",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Code track",
-                        "name": "code",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "409": {
+                        "description": "Upload-Offset is not the offset of the upload",
                         "schema": {
-                            "$ref": "#/definitions/model.Track"
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "410": {
+                        "description": "The upload expired",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "412": {
+                        "description": "Unsupported tus version"
+                    },
+                    "413": {
+                        "description": "The body runs past Upload-Length",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type is not application/offset+octet-stream",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "423": {
+                        "description": "Another request is writing to the upload",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -1282,9 +3002,156 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        }
+    },
+    "definitions": {
+        "model.Album": {
+            "type": "object",
+            "properties": {
+                "_id": {
+                    "type": "string",
+                    "example": "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+                },
+                "artist": {
+                    "type": "string",
+                    "example": "Album artist name"
+                },
+                "artist_id": {
+                    "type": "string",
+                    "example": "3d1f6c4e-8a0b-4f5e-9c2d-7b6a5e4d3c21"
+                },
+                "artwork": {
+                    "type": "string",
+                    "example": "artwork/9f86d081884c7d65.jpg"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Album name"
+                },
+                "track_count": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "year": {
+                    "type": "integer",
+                    "example": 2022
+                }
+            }
+        },
+        "model.AlbumDetail": {
+            "type": "object",
+            "properties": {
+                "_id": {
+                    "type": "string",
+                    "example": "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+                },
+                "artist": {
+                    "type": "string",
+                    "example": "Album artist name"
+                },
+                "artist_id": {
+                    "type": "string",
+                    "example": "3d1f6c4e-8a0b-4f5e-9c2d-7b6a5e4d3c21"
+                },
+                "artwork": {
+                    "type": "string",
+                    "example": "artwork/9f86d081884c7d65.jpg"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Album name"
+                },
+                "track_count": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "tracks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Track"
+                    }
+                },
+                "year": {
+                    "type": "integer",
+                    "example": 2022
+                }
+            }
+        },
+        "model.AlbumHit": {
+            "type": "object",
+            "properties": {
+                "album": {
+                    "$ref": "#/definitions/model.Album"
+                },
+                "rank": {
+                    "type": "number",
+                    "example": 0.83
+                },
+                "snippet": {
+                    "type": "string",
+                    "example": "\u003cb\u003eYesterday\u003c/b\u003e — The Beatles — Help!"
+                }
+            }
+        },
+        "model.Artist": {
+            "type": "object",
+            "properties": {
+                "_id": {
+                    "type": "string",
+                    "example": "3d1f6c4e-8a0b-4f5e-9c2d-7b6a5e4d3c21"
+                },
+                "album_count": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Artist name"
+                },
+                "track_count": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "model.ArtistHit": {
+            "type": "object",
+            "properties": {
+                "artist": {
+                    "$ref": "#/definitions/model.Artist"
+                },
+                "rank": {
+                    "type": "number",
+                    "example": 0.83
+                },
+                "snippet": {
+                    "type": "string",
+                    "example": "\u003cb\u003eYesterday\u003c/b\u003e — The Beatles — Help!"
+                }
+            }
+        },
+        "model.DuplicateGroup": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "description": "Key is the shared hash, or the shared artist, album and title in lower\ncase.",
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "reason": {
+                    "description": "Reason is content_hash or tags.",
+                    "type": "string",
+                    "example": "content_hash"
+                },
+                "tracks": {
+                    "description": "Tracks are listed in the order they were ingested.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Track"
+                    }
+                }
+            }
+        },
         "model.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -1293,6 +3160,59 @@ const docTemplate = `{
                 }
             }
         },
+        "model.Genre": {
+            "type": "object",
+            "properties": {
+                "_id": {
+                    "type": "string",
+                    "example": "5c4b3a29-1807-4f6e-8d5c-4b3a29180716"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Genre name"
+                },
+                "track_count": {
+                    "type": "integer",
+                    "example": 128
+                }
+            }
+        },
+        "model.IntegrityCheck": {
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "frame 812: CRC-16 checksum mismatch"
+                },
+                "etag": {
+                    "type": "string",
+                    "example": "9b2cf535f27731c974343645a3985328"
+                },
+                "object_key": {
+                    "type": "string",
+                    "example": "Artist/Album/01 Intro.flac"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 31457280
+                },
+                "status": {
+                    "type": "string",
+                    "example": "corrupt"
+                },
+                "track_id": {
+                    "type": "string",
+                    "example": "0b7e2a57-4c1e-4b8a-a2a4-3f3c7b1c9d12"
+                },
+                "version_id": {
+                    "type": "string",
+                    "example": "6f1c3c5e-2b0e-4d8f-9a51-0c2d7e4b9f10"
+                }
+            }
+        },
         "model.LoginInput": {
             "type": "object",
             "properties": {
@@ -1409,6 +3329,22 @@ const docTemplate = `{
                 }
             }
         },
+        "model.PlaylistHit": {
+            "type": "object",
+            "properties": {
+                "playlist": {
+                    "$ref": "#/definitions/model.PLayList"
+                },
+                "rank": {
+                    "type": "number",
+                    "example": 0.83
+                },
+                "snippet": {
+                    "type": "string",
+                    "example": "\u003cb\u003eYesterday\u003c/b\u003e — The Beatles — Help!"
+                }
+            }
+        },
         "model.PlaylistTracksResponse": {
             "type": "object",
             "properties": {
@@ -1440,6 +3376,19 @@ const docTemplate = `{
                 }
             }
         },
+        "model.RadioListeners": {
+            "type": "object",
+            "properties": {
+                "listeners": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "playlist_id": {
+                    "type": "string",
+                    "example": "c42a5a0b-6c1b-4d3b-8a56-9d2c5d0f3f1e"
+                }
+            }
+        },
         "model.ResponceRefreshTocken": {
             "type": "object",
             "properties": {
@@ -1453,6 +3402,35 @@ const docTemplate = `{
                 }
             }
         },
+        "model.SearchResult": {
+            "type": "object",
+            "properties": {
+                "albums": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AlbumHit"
+                    }
+                },
+                "artists": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ArtistHit"
+                    }
+                },
+                "playlists": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PlaylistHit"
+                    }
+                },
+                "tracks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.TrackHit"
+                    }
+                }
+            }
+        },
         "model.Track": {
             "type": "object",
             "properties": {
@@ -1464,14 +3442,35 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Album artist name"
                 },
+                "album_id": {
+                    "type": "string",
+                    "example": "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+                },
                 "artist": {
                     "type": "string",
                     "example": "Artist name"
                 },
+                "artist_id": {
+                    "description": "ArtistID, AlbumID and GenreID link the track to the artist, album and\ngenre entities its tags are matched to at ingestion.",
+                    "type": "string",
+                    "example": "3d1f6c4e-8a0b-4f5e-9c2d-7b6a5e4d3c21"
+                },
+                "artwork": {
+                    "type": "string",
+                    "example": "artwork/9f86d081884c7d65.jpg"
+                },
                 "bitrate": {
                     "type": "integer",
                     "example": 320
                 },
+                "bpm": {
+                    "type": "number",
+                    "example": 124.5
+                },
+                "bpm_confidence": {
+                    "type": "number",
+                    "example": 0.62
+                },
                 "comment": {
                     "type": "string",
                     "example": "Additional comments"
@@ -1480,6 +3479,20 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Composer name"
                 },
+                "content_hash": {
+                    "description": "ContentHash is the hex SHA-256 of the audio payload of the file without\nits tags, empty for tracks cut out by a CUE sheet and tracks not hashed\nyet.",
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "cue_end": {
+                    "type": "integer",
+                    "example": 10306260
+                },
+                "cue_start": {
+                    "description": "CueStart and CueEnd are the sample offsets of a track cut out of a\nsingle-file album by a CUE sheet, nil for tracks that are whole objects.\nA nil CueEnd runs to the end of the object.",
+                    "type": "integer",
+                    "example": 2977500
+                },
                 "disc": {
                     "type": "integer",
                     "example": 1
@@ -1492,10 +3505,35 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Genre name"
                 },
+                "genre_id": {
+                    "type": "string",
+                    "example": "5c4b3a29-1807-4f6e-8d5c-4b3a29180716"
+                },
+                "ingested_at": {
+                    "description": "IngestedAt is when the track was ingested. CreatedAt is the release\ndate of its tags.",
+                    "type": "string",
+                    "example": "2024-05-01T12:00:00Z"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "A minor"
+                },
+                "key_confidence": {
+                    "type": "number",
+                    "example": 0.81
+                },
+                "loudness": {
+                    "type": "number",
+                    "example": -9.4
+                },
                 "lyrics": {
                     "type": "string",
                     "example": "Lyrics of the track"
                 },
+                "mime_type": {
+                    "type": "string",
+                    "example": "audio/mpeg"
+                },
                 "sample_rate": {
                     "type": "integer",
                     "example": 44100
@@ -1508,16 +3546,170 @@ const docTemplate = `{
                     "type": "integer",
                     "example": 3
                 },
+                "track_gain": {
+                    "type": "number",
+                    "example": -8.6
+                },
+                "track_total": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "true_peak": {
+                    "type": "number",
+                    "example": -0.3
+                },
+                "year": {
+                    "type": "integer",
+                    "example": 2022
+                }
+            }
+        },
+        "model.TrackHit": {
+            "type": "object",
+            "properties": {
+                "lyrics_snippet": {
+                    "type": "string",
+                    "example": "… all my \u003cb\u003etroubles\u003c/b\u003e seemed so far away …"
+                },
+                "rank": {
+                    "type": "number",
+                    "example": 0.83
+                },
+                "snippet": {
+                    "type": "string",
+                    "example": "\u003cb\u003eYesterday\u003c/b\u003e — The Beatles — Help!"
+                },
+                "track": {
+                    "$ref": "#/definitions/model.Track"
+                }
+            }
+        },
+        "model.TrackPatch": {
+            "type": "object",
+            "properties": {
+                "album": {
+                    "type": "string",
+                    "example": "Album name"
+                },
+                "album_artist": {
+                    "type": "string",
+                    "example": "Album artist name"
+                },
+                "artist": {
+                    "type": "string",
+                    "example": "Artist name"
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "Additional comments"
+                },
+                "composer": {
+                    "type": "string",
+                    "example": "Composer name"
+                },
+                "disc": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "disc_total": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "genre": {
+                    "type": "string",
+                    "example": "Genre name"
+                },
+                "lyrics": {
+                    "type": "string",
+                    "example": "Lyrics of the track"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Title name"
+                },
+                "track": {
+                    "type": "integer",
+                    "example": 3
+                },
                 "track_total": {
                     "type": "integer",
                     "example": 10
                 },
+                "write_tags": {
+                    "type": "boolean",
+                    "example": true
+                },
                 "year": {
                     "type": "integer",
                     "example": 2022
                 }
             }
         },
+        "model.TrackVersion": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "etag": {
+                    "type": "string",
+                    "example": "9b2cf535f27731c974343645a3985328"
+                },
+                "object_key": {
+                    "type": "string",
+                    "example": "01 Intro.flac"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 31457280
+                },
+                "track_id": {
+                    "type": "string",
+                    "example": "0b7e2a57-4c1e-4b8a-a2a4-3f3c7b1c9d12"
+                },
+                "uploaded_at": {
+                    "type": "string"
+                },
+                "version_id": {
+                    "type": "string",
+                    "example": "6f1c3c5e-2b0e-4d8f-9a51-0c2d7e4b9f10"
+                }
+            }
+        },
+        "model.UploadResponse": {
+            "type": "object",
+            "properties": {
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.UploadResult"
+                    }
+                }
+            }
+        },
+        "model.UploadResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "failed to read tags: empty title or artist"
+                },
+                "file": {
+                    "type": "string",
+                    "example": "01 Intro.flac"
+                },
+                "track_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "0b7e2a57-4c1e-4b8a-a2a4-3f3c7b1c9d12"
+                    ]
+                }
+            }
+        },
         "model.User": {
             "description": "User account information with: user _id, name, email, password",
             "type": "object",
@@ -1557,6 +3749,32 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "model.Waveform": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "Data alternates the minimum and maximum of every point, scaled to -127..127.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        -12,
+                        15,
+                        -40,
+                        38
+                    ]
+                },
+                "points": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "track_id": {
+                    "type": "string",
+                    "example": "5c2c1e44-6f3b-4b1e-9a7e-3a4c1d7f2b10"
+                }
+            }
         }
     },
     "securityDefinitions": {
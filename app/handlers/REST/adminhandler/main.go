@@ -0,0 +1,56 @@
+package adminhandler
+
+import (
+	"net/http"
+	"s3MediaStreamer/app/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+type DuplicateServiceInterface interface {
+	DuplicatesService(c *gin.Context, page, pageSize string) ([]model.DuplicateGroup, int, *model.RestError)
+}
+
+type Handler struct {
+	duplicate DuplicateServiceInterface
+}
+
+func NewAdminHandler(duplicate DuplicateServiceInterface) *Handler {
+	return &Handler{duplicate}
+}
+
+// GetDuplicates godoc
+// @Summary List the tracks suspected to be duplicates.
+// @Description Lists groups of tracks whose audio payloads have the same SHA-256, or that have the same artist,
+// @Description album and title, ignoring case, and durations within two seconds of each other. Hash groups come
+// @Description first. The tracks of a group are listed in the order they were ingested. The tracks are only
+// @Description listed, nothing is merged or deleted.
+// @Tags admin-controller
+// @Accept */*
+// @Produce json
+// @Param page query int false "Page number"
+// @Param page_size query int false "Number of groups per page, at most 100"
+// @Success 200 {array} model.DuplicateGroup "OK"
+// @Header 200 {integer} X-Total-Count "Number of groups"
+// @Failure 400 {object} model.ErrorResponse "Invalid page or page_size parameters"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /admin/duplicates [get]
+func (h *Handler) GetDuplicates(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "GetDuplicates")
+	defer span.End()
+
+	groups, total, err := h.duplicate.DuplicatesService(c, c.DefaultQuery("page", "1"), c.DefaultQuery("page_size", "50"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	if groups == nil {
+		groups = []model.DuplicateGroup{}
+	}
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.Header("Access-Control-Expose-Headers", "X-Total-Count")
+	c.JSON(http.StatusOK, groups)
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"s3MediaStreamer/app/internal/logs"
 	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/audio"
+	"s3MediaStreamer/app/services/monitoring"
 
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
@@ -14,30 +15,51 @@ import (
 )
 
 type AudioServiceInterface interface {
-	GenerateM3U8Playlist(filePaths *[]model.TrackRequest) []*model.PlaylistM3U
+	GenerateM3U8Playlist(filePaths *[]model.TrackRequest, userID, clientIP string) []*model.PlaylistM3U
 	PlayM3UPlaylist(playlist []*model.PlaylistM3U, c *gin.Context)
+	AlbumGainService(ctx context.Context, tracks *[]model.TrackRequest, playlist []*model.PlaylistM3U)
 	PlayPlaylist(ctx context.Context, playlistID string) (*[]model.TrackRequest, error)
-	StreamM3UReadFileService(ctx context.Context, segmentPath string) (*minio.ObjectInfo, string, *os.File, *model.Track, *model.RestError)
-	StreamFileService(c *gin.Context, fileName string, f *os.File)
+	FindSegmentObject(ctx context.Context, segmentPath string) (*minio.ObjectInfo, *model.Track, *model.RestError)
+	FindVersionObject(ctx context.Context, segmentPath, version string) (*minio.ObjectInfo, *model.Track, *model.RestError)
+	StreamObjectService(c *gin.Context, object *minio.ObjectInfo, contentType string) *model.RestError
+	ParseRangeService(rangeHeader, ifRange string, object *minio.ObjectInfo) ([]model.HTTPRange, *model.RestError)
+	StreamRangeService(c *gin.Context, object *minio.ObjectInfo, ranges []model.HTTPRange, contentType string) error
+	CueSliceService(ctx context.Context, object *minio.ObjectInfo, track *model.Track) (*model.CueSlice, *model.RestError)
+	StreamCueService(c *gin.Context, object *minio.ObjectInfo, slice *model.CueSlice, contentType string) *model.RestError
+	PlayHLSPlaylist(c *gin.Context, tracks *[]model.TrackRequest, userID string)
+	AuthorizeStreamService(c *gin.Context, trackID string) *model.RestError
+	DeliveryModeService(requested string) (string, *model.RestError)
+	RedirectObjectService(c *gin.Context, object *minio.ObjectInfo, contentType string) *model.RestError
+	HLSMasterPlaylistService(c *gin.Context, trackID string) *model.RestError
+	HLSMediaPlaylistService(c *gin.Context, trackID string) *model.RestError
+	HLSSegmentService(c *gin.Context, trackID, segment string) *model.RestError
+	HLSKeyService(c *gin.Context, trackID, version string) *model.RestError
 }
 
 type Handler struct {
-	audio  AudioServiceInterface
-	logger *logs.Logger
+	audio   AudioServiceInterface
+	metrics *monitoring.CombinedMetrics
+	logger  *logs.Logger
 }
 
-func NewAudioHandler(audio AudioServiceInterface, logger *logs.Logger) *Handler {
-	return &Handler{audio, logger}
+func NewAudioHandler(audio AudioServiceInterface, metrics *monitoring.CombinedMetrics, logger *logs.Logger) *Handler {
+	return &Handler{audio, metrics, logger}
 }
 
 // Audio godoc
 // @Summary Stream audio files.
 // @Description Streams audio files in the specified directory as MP3 or FLAC.
+// @Description With format=hls an HLS media playlist of the MP3 tracks is returned instead of a plain M3U list.
+// @Description Track URIs carry a signed, expiring token so that players without the session cookie can fetch them.
+// @Description M3U entries carry the ReplayGain track gain, with replaygain=album the album gain as well.
 // @Tags audio-controller
 // @Accept */*
 // @Produce application/x-mpegURL
+// @Produce application/vnd.apple.mpegurl
 // @Param playlist_id path string false "Playlist ID"
 // @Param control path string false "Control operation playlist play"
+// @Param format query string false "Playlist format ('m3u' or 'hls')"
+// @Param replaygain query string false "Set to 'album' to add the album gain to M3U entries"
 // @Success 200 {array} model.Track "OK"
 // @Failure 500 {object} model.ErrorResponse "Internal Server Error"
 // @Router /audio/{playlist_id} [get]
@@ -58,47 +80,237 @@ func (h *Handler) Audio(c *gin.Context) {
 		return
 	}
 
-	playlist := h.audio.GenerateM3U8Playlist(tracks)
+	if c.DefaultQuery("format", "m3u") == "hls" {
+		h.audio.PlayHLSPlaylist(c, tracks, c.GetString("user_id"))
+		return
+	}
+
+	playlist := h.audio.GenerateM3U8Playlist(tracks, c.GetString("user_id"), c.ClientIP())
+	if c.Query("replaygain") == "album" {
+		h.audio.AlbumGainService(c.Request.Context(), tracks, playlist)
+	}
 	h.audio.PlayM3UPlaylist(playlist, c)
 }
 
 // StreamM3U godoc
 // @Summary Stream audio files.
-// @Description Streams audio files in the specified directory as MP3 or FLAC.
+// @Description Streams the audio file of the track with the MIME type detected when it was scanned.
+// @Description Supports RFC 7233 byte ranges (single and multipart) and If-Range with the S3 ETag.
+// @Description With delivery=redirect the request is answered with a 302 to a short-lived presigned S3 URL of the track version.
+// @Description Tracks cut out of a single-file album by a CUE sheet are served as streams of their own, cut on FLAC or MP3
+// @Description frame boundaries, and always through the app.
 // @Tags audio-controller
 // @Accept */*
 // @Produce audio/mpeg
 // @Produce audio/flac
+// @Produce audio/ogg
+// @Produce audio/opus
+// @Produce audio/mp4
+// @Produce audio/wav
+// @Produce audio/aiff
 // @Produce application/octet-stream
-// @Param playlist_id path string false "Playlist ID"
-// @Param control path string false "Control operation playlist play"
-// @Success 200 {array} model.Track "OK"
+// @Produce multipart/byteranges
+// @Param segment path string true "Track ID"
+// @Param token query string false "Signed stream token, required without the session cookie"
+// @Param delivery query string false "Delivery mode ('proxy' or 'redirect'), defaults to the configured one"
+// @Param version query string false "S3 version of the track to stream, from /tracks/{code}/versions, defaults to the current one"
+// @Param Range header string false "Byte ranges, e.g. bytes=0-1023"
+// @Param If-Range header string false "ETag or Last-Modified date the range is conditional on"
+// @Success 200 {file} file "Whole file"
+// @Success 206 {file} file "Partial Content"
+// @Success 302 {string} string "Redirect to the presigned S3 URL"
+// @Failure 400 {object} model.ErrorResponse "delivery must be 'proxy' or 'redirect'"
+// @Failure 401 {object} model.ErrorResponse "unauthenticated"
 // @Failure 404 {object} model.ErrorResponse "Segment not found"
 // @Failure 406 {object} model.ErrorResponse "Segment not found"
+// @Failure 415 {object} model.ErrorResponse "CUE tracks can only be cut out of FLAC and MP3"
+// @Failure 416 {object} model.ErrorResponse "Requested Range Not Satisfiable"
+// @Failure 422 {object} model.ErrorResponse "Error reading audio frames"
 // @Failure 500 {object} model.ErrorResponse "Internal Server Error"
 // @Router /audio/stream/{segment} [get]
 func (h *Handler) StreamM3U(c *gin.Context) {
 	_, span := otel.Tracer("").Start(c.Request.Context(), "StreamM3U")
 	defer span.End()
 	segmentPath := c.Param("segment")
-	var track *model.Track
+	if errAuth := h.audio.AuthorizeStreamService(c, segmentPath); errAuth != nil {
+		c.JSON(errAuth.Code, errAuth.Err)
+		return
+	}
+
+	mode, errMode := h.audio.DeliveryModeService(c.Query("delivery"))
+	if errMode != nil {
+		c.JSON(errMode.Code, errMode.Err)
+		return
+	}
+
+	findObject, track, errFind := h.findStreamObject(c, segmentPath)
+	if errFind != nil {
+		c.JSON(errFind.Code, errFind.Err)
+		return
+	}
+	// Tracks scanned before format detection have no MIME type yet.
+	contentType := track.MimeType
+	if contentType == "" {
+		contentType = findObject.Metadata.Get("Content-Type")
+	}
+	slice, errSlice := h.audio.CueSliceService(c.Request.Context(), findObject, track)
+	if errSlice != nil {
+		c.JSON(errSlice.Code, errSlice.Err)
+		return
+	}
+	if slice != nil {
+		// A presigned URL cannot cut the track out of the album.
+		mode = audio.DeliveryProxy
+	}
+	h.metrics.StreamMetrics.DeliveryCounter.WithLabelValues(mode).Inc()
 
-	findObject, fileName, f, track, errValidateOTP := h.audio.StreamM3UReadFileService(c, segmentPath)
-	if errValidateOTP != nil {
-		c.JSON(errValidateOTP.Code, errValidateOTP.Err)
+	if mode == audio.DeliveryRedirect {
+		if errRedirect := h.audio.RedirectObjectService(c, findObject, contentType); errRedirect != nil {
+			c.JSON(errRedirect.Code, errRedirect.Err)
+		}
 		return
 	}
-	defer f.Close()
 
-	c.Header("Content-Type", findObject.Metadata.Get("Content-Type"))
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("ETag", audio.QuoteETag(findObject.ETag))
+	c.Header("Last-Modified", findObject.LastModified.UTC().Format(http.TimeFormat))
 	c.Header("Content-Disposition", "inline; filename="+findObject.Key)
-	c.Header("Content-Length", fmt.Sprintf("%d", findObject.Size))
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Content-Duration", fmt.Sprintf("%d", track.Duration)) // second
 
-	h.audio.StreamFileService(c, fileName, f)
-	// Wait for client disconnect notification
-	<-c.Writer.CloseNotify()
-	// Client disconnected, clean up and return
-	h.logger.Info("Client disconnected, stopping streaming.")
+	if slice != nil {
+		if errCue := h.audio.StreamCueService(c, findObject, slice, contentType); errCue != nil {
+			c.JSON(errCue.Code, errCue.Err)
+		}
+		return
+	}
+
+	ranges, errRange := h.audio.ParseRangeService(c.GetHeader("Range"), c.GetHeader("If-Range"), findObject)
+	if errRange != nil {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", findObject.Size))
+		c.JSON(errRange.Code, errRange.Err)
+		return
+	}
+	if ranges != nil {
+		if err := h.audio.StreamRangeService(c, findObject, ranges, contentType); err != nil {
+			h.logger.Errorf("Error streaming ranges of %s: %v", findObject.Key, err)
+		}
+		return
+	}
+
+	if errStream := h.audio.StreamObjectService(c, findObject, contentType); errStream != nil {
+		c.JSON(errStream.Code, errStream.Err)
+	}
+}
+
+// findStreamObject resolves the version of the track asked for by the version
+// parameter, the current one without it.
+func (h *Handler) findStreamObject(c *gin.Context, segmentPath string) (*minio.ObjectInfo, *model.Track, *model.RestError) {
+	if version := c.Query("version"); version != "" {
+		return h.audio.FindVersionObject(c, segmentPath, version)
+	}
+	return h.audio.FindSegmentObject(c, segmentPath)
+}
+
+// HLSMaster godoc
+// @Summary HLS master playlist of a track.
+// @Description Returns the HLS master playlist with the MP3 rendition of the track.
+// @Tags audio-controller
+// @Accept */*
+// @Produce application/vnd.apple.mpegurl
+// @Param track_id path string true "Track ID"
+// @Param token query string false "Signed stream token, required without the session cookie"
+// @Success 200 {string} string "Master playlist"
+// @Failure 401 {object} model.ErrorResponse "unauthenticated"
+// @Failure 404 {object} model.ErrorResponse "Segment not found"
+// @Failure 415 {object} model.ErrorResponse "HLS is only available for MP3 tracks"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /audio/hls/{track_id}/master.m3u8 [get]
+func (h *Handler) HLSMaster(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HLSMaster")
+	defer span.End()
+	if errAuth := h.audio.AuthorizeStreamService(c, c.Param("track_id")); errAuth != nil {
+		c.JSON(errAuth.Code, errAuth.Err)
+		return
+	}
+	if errHLS := h.audio.HLSMasterPlaylistService(c, c.Param("track_id")); errHLS != nil {
+		c.JSON(errHLS.Code, errHLS.Err)
+	}
+}
+
+// HLSMedia godoc
+// @Summary HLS media playlist of a track.
+// @Description Returns the VOD media playlist of the track split into frame-aligned MP3 segments of 6-10 seconds.
+// @Tags audio-controller
+// @Accept */*
+// @Produce application/vnd.apple.mpegurl
+// @Param track_id path string true "Track ID"
+// @Param token query string false "Signed stream token, required without the session cookie"
+// @Success 200 {string} string "Media playlist"
+// @Failure 401 {object} model.ErrorResponse "unauthenticated"
+// @Failure 404 {object} model.ErrorResponse "Segment not found"
+// @Failure 415 {object} model.ErrorResponse "HLS is only available for MP3 tracks"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /audio/hls/{track_id}/media.m3u8 [get]
+func (h *Handler) HLSMedia(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HLSMedia")
+	defer span.End()
+	if errAuth := h.audio.AuthorizeStreamService(c, c.Param("track_id")); errAuth != nil {
+		c.JSON(errAuth.Code, errAuth.Err)
+		return
+	}
+	if errHLS := h.audio.HLSMediaPlaylistService(c, c.Param("track_id")); errHLS != nil {
+		c.JSON(errHLS.Code, errHLS.Err)
+	}
+}
+
+// HLSSegment godoc
+// @Summary HLS segment of a track.
+// @Description Streams one MP3 segment of the track media playlist.
+// @Tags audio-controller
+// @Accept */*
+// @Produce audio/mpeg
+// @Param track_id path string true "Track ID"
+// @Param segment path string true "Segment index, e.g. 3.mp3"
+// @Param token query string false "Signed stream token, required without the session cookie"
+// @Success 200 {file} file "Segment"
+// @Failure 400 {object} model.ErrorResponse "invalid segment index"
+// @Failure 401 {object} model.ErrorResponse "unauthenticated"
+// @Failure 404 {object} model.ErrorResponse "Segment not found"
+// @Failure 415 {object} model.ErrorResponse "HLS is only available for MP3 tracks"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /audio/hls/{track_id}/segment/{segment} [get]
+func (h *Handler) HLSSegment(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HLSSegment")
+	defer span.End()
+	if errAuth := h.audio.AuthorizeStreamService(c, c.Param("track_id")); errAuth != nil {
+		c.JSON(errAuth.Code, errAuth.Err)
+		return
+	}
+	if errHLS := h.audio.HLSSegmentService(c, c.Param("track_id"), c.Param("segment")); errHLS != nil {
+		c.JSON(errHLS.Code, errHLS.Err)
+	}
+}
+
+// HLSKey godoc
+// @Summary AES-128 key of an encrypted HLS track.
+// @Description Returns the raw 16 byte key of the track version referenced by the EXT-X-KEY tags.
+// @Description Requires a signed in user or the stream token of the track, keys of replaced track versions are not served.
+// @Tags audio-controller
+// @Accept */*
+// @Produce application/octet-stream
+// @Param track_id path string true "Track ID"
+// @Param v query string true "Track S3 version"
+// @Param token query string false "Signed stream token, required without the session cookie"
+// @Success 200 {file} file "Key"
+// @Failure 401 {object} model.ErrorResponse "unauthenticated"
+// @Failure 404 {object} model.ErrorResponse "Key not found"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /hls/keys/{track_id} [get]
+func (h *Handler) HLSKey(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HLSKey")
+	defer span.End()
+	if errKey := h.audio.HLSKeyService(c, c.Param("track_id"), c.Query("v")); errKey != nil {
+		c.JSON(errKey.Code, errKey.Err)
+	}
 }
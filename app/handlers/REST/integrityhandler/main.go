@@ -0,0 +1,54 @@
+package integrityhandler
+
+import (
+	"net/http"
+	"s3MediaStreamer/app/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+type IntegrityServiceInterface interface {
+	ReportService(c *gin.Context, status, page, pageSize string) ([]model.IntegrityCheck, int, *model.RestError)
+}
+
+type Handler struct {
+	integrity IntegrityServiceInterface
+}
+
+func NewIntegrityHandler(integrity IntegrityServiceInterface) *Handler {
+	return &Handler{integrity}
+}
+
+// Report godoc
+// @Summary List the audio objects that failed the integrity check.
+// @Description Lists the S3 object versions the integrity job found corrupted or truncated, newest check first.
+// @Description The objects are only reported, nothing is deleted.
+// @Tags integrity-controller
+// @Accept */*
+// @Produce json
+// @Param page query int false "Page number"
+// @Param page_size query int false "Number of items per page"
+// @Param status query string false "Comma separated statuses ('ok', 'corrupt', 'truncated' or 'unsupported'), defaults to 'corrupt,truncated'"
+// @Success 200 {array} model.IntegrityCheck "OK"
+// @Header 200 {integer} X-Total-Count "Number of matching object versions"
+// @Failure 400 {object} model.ErrorResponse "Invalid page, page_size or status parameters"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /integrity [get]
+func (h *Handler) Report(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "Report")
+	defer span.End()
+
+	checks, total, err := h.integrity.ReportService(c, c.Query("status"), c.DefaultQuery("page", "1"), c.DefaultQuery("page_size", "50"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	if checks == nil {
+		checks = []model.IntegrityCheck{}
+	}
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.Header("Access-Control-Expose-Headers", "X-Total-Count")
+	c.JSON(http.StatusOK, checks)
+}
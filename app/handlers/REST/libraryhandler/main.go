@@ -0,0 +1,150 @@
+package libraryhandler
+
+import (
+	"net/http"
+	"s3MediaStreamer/app/handlers/REST/pagination"
+	"s3MediaStreamer/app/model"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+type LibraryServiceInterface interface {
+	ArtistsService(c *gin.Context, page, pageSize string) ([]model.Artist, int, int, int, *model.RestError)
+	ArtistAlbumsService(c *gin.Context, id, page, pageSize string) ([]model.Album, int, int, int, *model.RestError)
+	AlbumService(c *gin.Context, id string) (*model.AlbumDetail, *model.RestError)
+	GenresService(c *gin.Context, page, pageSize string) ([]model.Genre, int, int, int, *model.RestError)
+}
+
+type Handler struct {
+	library LibraryServiceInterface
+}
+
+func NewLibraryHandler(library LibraryServiceInterface) *Handler {
+	return &Handler{library}
+}
+
+// GetArtists godoc
+// @Summary List the artists.
+// @Description Lists the artists of the tracks and the album artists of the albums, ordered by name.
+// @Description Names that only differ in case or white space are one artist.
+// @Tags library-controller
+// @Accept */*
+// @Produce json
+// @Param page query int false "Page number"
+// @Param page_size query int false "Number of items per page"
+// @Success 200 {array} model.Artist "OK"
+// @Failure 400 {object} model.ErrorResponse "Invalid page or page_size parameters"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /artists [get]
+func (h *Handler) GetArtists(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "GetArtists")
+	defer span.End()
+
+	pageSize := c.DefaultQuery("page_size", "10")
+	artists, countTotal, pageInt, totalPages, err := h.library.ArtistsService(c, c.DefaultQuery("page", "1"), pageSize)
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	if len(artists) == 0 {
+		c.JSON(http.StatusNoContent, nil)
+		return
+	}
+	pagination.SetHeaders(c, countTotal, pageInt, totalPages, pageSize)
+	c.IndentedJSON(http.StatusOK, artists)
+}
+
+// GetArtistAlbums godoc
+// @Summary List the albums of an artist.
+// @Description Lists the albums the artist is the album artist of, oldest first.
+// @Tags library-controller
+// @Accept */*
+// @Produce json
+// @Param id path string true "Artist ID"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Number of items per page"
+// @Success 200 {array} model.Album "OK"
+// @Failure 400 {object} model.ErrorResponse "Invalid page or page_size parameters"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 404 {object} model.ErrorResponse "Artist not found"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /artists/{id}/albums [get]
+func (h *Handler) GetArtistAlbums(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "GetArtistAlbums")
+	defer span.End()
+
+	pageSize := c.DefaultQuery("page_size", "10")
+	albums, countTotal, pageInt, totalPages, err := h.library.ArtistAlbumsService(c, c.Param("id"), c.DefaultQuery("page", "1"), pageSize)
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	if len(albums) == 0 {
+		c.JSON(http.StatusNoContent, nil)
+		return
+	}
+	pagination.SetHeaders(c, countTotal, pageInt, totalPages, pageSize)
+	c.IndentedJSON(http.StatusOK, albums)
+}
+
+// GetAlbum godoc
+// @Summary Album with its tracks.
+// @Description Returns the album with its tracks ordered by disc and track number.
+// @Tags library-controller
+// @Accept */*
+// @Produce json
+// @Param id path string true "Album ID"
+// @Success 200 {object} model.AlbumDetail "OK"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 404 {object} model.ErrorResponse "Album not found"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /albums/{id} [get]
+func (h *Handler) GetAlbum(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "GetAlbum")
+	defer span.End()
+
+	album, err := h.library.AlbumService(c, c.Param("id"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, album)
+}
+
+// GetGenres godoc
+// @Summary List the genres.
+// @Description Lists the genres of the tracks, ordered by name.
+// @Description Names that only differ in case or white space are one genre.
+// @Tags library-controller
+// @Accept */*
+// @Produce json
+// @Param page query int false "Page number"
+// @Param page_size query int false "Number of items per page"
+// @Success 200 {array} model.Genre "OK"
+// @Failure 400 {object} model.ErrorResponse "Invalid page or page_size parameters"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /genres [get]
+func (h *Handler) GetGenres(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "GetGenres")
+	defer span.End()
+
+	pageSize := c.DefaultQuery("page_size", "10")
+	genres, countTotal, pageInt, totalPages, err := h.library.GenresService(c, c.DefaultQuery("page", "1"), pageSize)
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	if len(genres) == 0 {
+		c.JSON(http.StatusNoContent, nil)
+		return
+	}
+	pagination.SetHeaders(c, countTotal, pageInt, totalPages, pageSize)
+	c.IndentedJSON(http.StatusOK, genres)
+}
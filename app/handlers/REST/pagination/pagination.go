@@ -0,0 +1,86 @@
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetHeaders sets the total count, the number of pages and the links to the
+// neighbouring pages of a paginated list response. The links keep the other
+// query parameters of the request.
+func SetHeaders(c *gin.Context, countTotal, currentPage, totalPages int, pageSize string) {
+	query := c.Request.URL.Query()
+	query.Set("page_size", pageSize)
+	c.Header("X-Total-Count", strconv.Itoa(countTotal))
+	c.Header("X-Total-Pages", strconv.Itoa(totalPages))
+	c.Header("Link", Links(baseURL(c), c.Request.URL.Path, query, currentPage, totalPages))
+	c.Header("Access-Control-Expose-Headers", "X-Total-Count,X-Total-Pages,Link")
+}
+
+// SetCursorHeaders sets the total count, left out when it is negative, and
+// the links to the next and the first page of a list paginated by cursor.
+// The links keep the other query parameters of the request.
+func SetCursorHeaders(c *gin.Context, countTotal int, next, pageSize string) {
+	query := c.Request.URL.Query()
+	query.Set("page_size", pageSize)
+	query.Del("page")
+	if countTotal >= 0 {
+		c.Header("X-Total-Count", strconv.Itoa(countTotal))
+	}
+	c.Header("Link", CursorLinks(baseURL(c), c.Request.URL.Path, query, next))
+	c.Header("Access-Control-Expose-Headers", "X-Total-Count,Link")
+}
+
+func baseURL(c *gin.Context) string {
+	scheme := "http" // По умолчанию HTTP
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+}
+
+// Links returns the prev, next, first and last links of the page, the query
+// with the page number of each.
+func Links(baseURL, basePath string, query url.Values, currentPage, totalPages int) string {
+	var links []string
+	link := func(page int, rel string) {
+		query.Set("page", strconv.Itoa(page))
+		links = append(links, fmt.Sprintf("<%s%s?%s>; rel=\"%s\"", baseURL, basePath, query.Encode(), rel))
+	}
+
+	if currentPage > 1 {
+		link(currentPage-1, "prev")
+	}
+
+	if currentPage < totalPages {
+		link(currentPage+1, "next")
+	}
+
+	if totalPages > 0 {
+		link(1, "first")
+		link(totalPages, "last")
+	}
+
+	return strings.Join(links, ", ")
+}
+
+// CursorLinks returns the next link, when there is a next cursor, and the
+// first link of a page, the query with the cursor of each.
+func CursorLinks(baseURL, basePath string, query url.Values, next string) string {
+	var links []string
+	link := func(cursor, rel string) {
+		query.Set("cursor", cursor)
+		links = append(links, fmt.Sprintf("<%s%s?%s>; rel=\"%s\"", baseURL, basePath, query.Encode(), rel))
+	}
+
+	if next != "" {
+		link(next, "next")
+	}
+	link("", "first")
+
+	return strings.Join(links, ", ")
+}
@@ -2,6 +2,7 @@ package playlisthandler
 
 import (
 	"net/http"
+	"s3MediaStreamer/app/handlers/REST/pagination"
 	"s3MediaStreamer/app/handlers/REST/userhandler"
 	"s3MediaStreamer/app/model"
 	"s3MediaStreamer/app/services/playlist"
@@ -155,11 +156,15 @@ func (h *Handler) ListTracksFromPlaylist(c *gin.Context, userContext *model.User
 
 // ListPlaylists godoc
 // @Summary Get all playlists
-// @Description Retrieves all playlists available in the storage.
+// @Description Retrieves all playlists available in the storage, newest first.
+// @Description With the cursor parameter it returns one page of page_size playlists and the Link header has the next and the first page.
 // @Tags playlist-controller
 // @Accept json
 // @Produce json
+// @Param cursor query string false "Opaque cursor from the next link, empty for the first page"
+// @Param page_size query int false "Number of playlists per page with cursor"
 // @Success 200 {object} model.PlaylistsResponse "Playlists retrieved successfully"
+// @Failure 400 {object} model.ErrorResponse "Invalid cursor or page_size"
 // @Failure 404 {object} model.ErrorResponse "Playlists not found"
 // @Failure 500 {object} model.ErrorResponse "Internal Server Error"
 // @Security ApiKeyAuth
@@ -168,6 +173,18 @@ func (h *Handler) ListPlaylists(c *gin.Context, userContext *model.UserContext)
 	_, span := otel.Tracer("").Start(c.Request.Context(), "ListAllPlaylist")
 	defer span.End()
 
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		pageSize := c.DefaultQuery("page_size", "10")
+		response, next, err := h.playlistService.GetUserPlaylistsPage(c, userContext, cursor, pageSize)
+		if err != nil {
+			c.JSON(err.Code, err.Err)
+			return
+		}
+		pagination.SetCursorHeaders(c, -1, next, pageSize)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
 	response, errListTracksFromPlaylist := h.playlistService.GetUserPlaylists(
 		c,
 		userContext,
@@ -0,0 +1,63 @@
+package radiohandler
+
+import (
+	"net/http"
+	"s3MediaStreamer/app/model"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+type RadioServiceInterface interface {
+	ListenService(c *gin.Context, playlistID string) *model.RestError
+	ListenersService(playlistID string) model.RadioListeners
+}
+
+type Handler struct {
+	radio RadioServiceInterface
+}
+
+func NewRadioHandler(radio RadioServiceInterface) *Handler {
+	return &Handler{radio}
+}
+
+// Radio godoc
+// @Summary Listen to a playlist as internet radio.
+// @Description Streams the MP3 tracks of the playlist as one endless Icecast/SHOUTcast compatible stream.
+// @Description Listeners share the current position, ICY metadata is interleaved when the client sends Icy-MetaData: 1.
+// @Tags radio-controller
+// @Accept */*
+// @Produce audio/mpeg
+// @Param playlist_id path string true "Playlist ID"
+// @Param loop query bool false "Restart the playlist when it ends, defaults to the configured value"
+// @Param shuffle query bool false "Shuffle the playlist on every pass, defaults to the configured value"
+// @Param Icy-MetaData header string false "1 to receive ICY metadata every icy-metaint bytes"
+// @Success 200 {file} file "Radio stream"
+// @Failure 400 {object} model.ErrorResponse "loop must be a boolean"
+// @Failure 403 {object} model.ErrorResponse "Unauthorized access"
+// @Failure 404 {object} model.ErrorResponse "No tracks to play"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /radio/{playlist_id} [get]
+func (h *Handler) Radio(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "Radio")
+	defer span.End()
+	if errListen := h.radio.ListenService(c, c.Param("playlist_id")); errListen != nil {
+		c.JSON(errListen.Code, errListen.Err)
+	}
+}
+
+// Listeners godoc
+// @Summary Listener count of a playlist radio.
+// @Description Returns the number of clients currently listening to the radio stream of the playlist.
+// @Tags radio-controller
+// @Accept */*
+// @Produce json
+// @Param playlist_id path string true "Playlist ID"
+// @Success 200 {object} model.RadioListeners "OK"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Router /radio/{playlist_id}/listeners [get]
+func (h *Handler) Listeners(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "Listeners")
+	defer span.End()
+	c.JSON(http.StatusOK, h.radio.ListenersService(c.Param("playlist_id")))
+}
@@ -0,0 +1,51 @@
+package searchhandler
+
+import (
+	"net/http"
+	"s3MediaStreamer/app/model"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+type SearchServiceInterface interface {
+	SearchService(c *gin.Context, userContext *model.UserContext, term, limit string) (*model.SearchResult, *model.RestError)
+}
+
+type Handler struct {
+	search SearchServiceInterface
+}
+
+func NewSearchHandler(search SearchServiceInterface) *Handler {
+	return &Handler{search}
+}
+
+// Search godoc
+// @Summary Search the library.
+// @Description Searches the tracks, albums, artists and playlists, best match first in each list.
+// @Description Words are matched in the title, artist, album, composer and lyrics of the tracks, the title weighing most,
+// @Description and titles and names close to the query are matched too, so that typos still find something.
+// @Description The query takes quoted phrases, "or" and a leading "-" to exclude a word.
+// @Description Snippets are HTML escaped with the matched words in <b> tags. Members only find their own playlists.
+// @Tags search-controller
+// @Accept */*
+// @Produce json
+// @Param q query string true "Search query"
+// @Param limit query int false "Maximum number of results of each kind, 1 to 50, defaults to 10"
+// @Success 200 {object} model.SearchResult "OK"
+// @Failure 400 {object} model.ErrorResponse "Missing q or invalid limit"
+// @Failure 401 {object} model.ErrorResponse "Unauthorized"
+// @Failure 500 {object} model.ErrorResponse "Internal Server Error"
+// @Security ApiKeyAuth
+// @Router /search [get]
+func (h *Handler) Search(c *gin.Context, userContext *model.UserContext) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "Search")
+	defer span.End()
+
+	result, err := h.search.SearchService(c, userContext, c.Query("q"), c.Query("limit"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, result)
+}
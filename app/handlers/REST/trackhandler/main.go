@@ -1,10 +1,15 @@
 package trackhandler
 
 import (
-	"fmt"
 	"net/http"
+	"s3MediaStreamer/app/handlers/REST/pagination"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/artwork"
 	"s3MediaStreamer/app/services/track"
-	"strconv"
+	"s3MediaStreamer/app/services/trackedit"
+	"s3MediaStreamer/app/services/trackversion"
+	"s3MediaStreamer/app/services/upload"
+	"s3MediaStreamer/app/services/waveform"
 
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel"
@@ -14,25 +19,39 @@ type TrackServiceInterface interface {
 }
 type Handler struct {
 	trackService track.Service
+	artwork      *artwork.Service
+	waveform     *waveform.Service
+	trackEdit    *trackedit.Service
+	trackVersion *trackversion.Service
+	upload       *upload.Service
 }
 
-func NewTrackHandler(trackService track.Service) *Handler {
-	return &Handler{trackService}
+func NewTrackHandler(trackService track.Service, artwork *artwork.Service, waveform *waveform.Service,
+	trackEdit *trackedit.Service, trackVersion *trackversion.Service, upload *upload.Service) *Handler {
+	return &Handler{trackService, artwork, waveform, trackEdit, trackVersion, upload}
 }
 
 // GetAllTracks	godoc
 // @Summary		Show the list of all tracks.
 // @Description responds with the list of all tracks as JSON.
+// @Description	With the cursor parameter the list is paged by the sort keys of the last track, which keeps deep pages fast,
+// @Description	and the Link header has the next and the first page.
 // @Tags		track-controller
 // @Accept		*/*
 // @Produce		json
 // @Param       page query   int           false "Page number"
 // @Param       page_size    query         int false "Number of items per page"
-// @Param       sort_by      query         string false "Field to sort by (e.g., 'created_at')"
-// @Param       sort_order   query         string false "Sort order ('asc' or 'desc')"
+// @Param       sort_by      query         string false "Comma separated fields to sort by, '-' or '+' in front sorts descending or ascending (e.g., 'year,-title', 'bpm' or 'musical_key')"
+// @Param       sort_order   query         string false "Sort order of the fields without '-' or '+' ('asc' or 'desc')"
 // @Param       filter       query         string false "Filter criteria ('I0001' or '=I0001')"
+// @Param       query        query         string false "Filter query of field:value terms, quoted values and '-' negations, e.g. 'genre:rock year:1990..1999 duration:>300 -live'"
+// @Param       bpm_min      query         number false "Lowest estimated tempo"
+// @Param       bpm_max      query         number false "Highest estimated tempo"
+// @Param       key          query         string false "Estimated key ('A minor', 'Am' or 'C')"
+// @Param       cursor       query         string false "Opaque cursor from the next link, pages by the sort keys instead of page when given, empty for the first page"
+// @Param       count        query         string false "Total count: exact (default with page), estimate (default with cursor) or none (cursor only)"
 // @Success		200 {array}  model.Track  "OK"
-// @Failure		400 {object} model.ErrorResponse "Invalid page or page_size parameters"
+// @Failure		400 {object} model.ErrorResponse "Invalid page, page_size, sort_by, query, cursor or count parameters"
 // @Failure		401 {object} model.ErrorResponse "Unauthorized"
 // @Failure		500 {object} model.ErrorResponse "Internal Server Error"
 // @Security    ApiKeyAuth
@@ -48,12 +67,15 @@ func (h *Handler) GetAllTracks(c *gin.Context) {
 	sortBy := c.DefaultQuery("sort_by", "created_at")
 	sortOrder := c.DefaultQuery("sort_order", "desc")
 	filter := c.DefaultQuery("filter", "")
+	query := c.DefaultQuery("query", "")
 
-	baseURL := "http" // По умолчанию HTTP
-	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
-		baseURL = proto
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		h.getTracksByCursor(c, cursor, pageSize, filter, query, sortBy, sortOrder)
+		return
 	}
-	tracks, pageInt, countTotal, totalPages, err := h.trackService.GetTracksService(c, page, pageSize, filter, sortBy, sortOrder)
+
+	tracks, countTotal, pageInt, totalPages, err := h.trackService.GetTracksService(c, page, pageSize, filter, query, sortBy, sortOrder,
+		c.Query("bpm_min"), c.Query("bpm_max"), c.Query("key"), c.Query("count"))
 	if err != nil {
 		c.JSON(err.Code, err.Err)
 		return
@@ -65,11 +87,25 @@ func (h *Handler) GetAllTracks(c *gin.Context) {
 		return
 	}
 
-	baseURL = fmt.Sprintf("%s://%s", baseURL, c.Request.Host)
-	c.Header("X-Total-Count", strconv.Itoa(countTotal))
-	c.Header("X-Total-Pages", strconv.Itoa(totalPages))
-	c.Header("Link", generatePaginationLinks(baseURL, c.FullPath(), pageInt, totalPages, pageSize))
-	c.Header("Access-Control-Expose-Headers", "X-Total-Count,X-Total-Pages,Link")
+	pagination.SetHeaders(c, countTotal, pageInt, totalPages, pageSize)
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.IndentedJSON(http.StatusOK, tracks)
+}
+
+// getTracksByCursor responds with the page of the track list after the cursor.
+func (h *Handler) getTracksByCursor(c *gin.Context, cursor, pageSize, filter, query, sortBy, sortOrder string) {
+	tracks, next, countTotal, err := h.trackService.GetTracksCursorService(c, cursor, pageSize, filter, query, sortBy, sortOrder,
+		c.Query("bpm_min"), c.Query("bpm_max"), c.Query("key"), c.Query("count"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	if len(tracks) == 0 {
+		c.JSON(http.StatusNoContent, nil)
+		return
+	}
+
+	pagination.SetCursorHeaders(c, countTotal, next, pageSize)
 	c.Header("Content-Type", "application/json; charset=utf-8")
 	c.IndentedJSON(http.StatusOK, tracks)
 }
@@ -101,3 +137,193 @@ func (h *Handler) GetTrackByID(c *gin.Context) {
 	}
 	c.IndentedJSON(http.StatusOK, result)
 }
+
+// PatchTrack godoc
+// @Summary		Edit the tags of a track.
+// @Description Changes the given tags of the track, the ones left out keep their value. Admins only.
+// @Description	With write_tags the tags are also written into the audio file, as ID3v2 for MP3 and as Vorbis comments for FLAC,
+// @Description	which is uploaded as a new version of its S3 object. The track is pointed at the new version,
+// @Description	so a rescan of the bucket keeps the edit.
+// @Tags		track-controller
+// @Accept		json
+// @Produce		json
+// @Param		code    path      string            true  "Track ID"
+// @Param		request body      model.TrackPatch  true  "Tags to change"
+// @Success     200 {object} model.Track  "OK"
+// @Failure     400 {object} model.ErrorResponse  "Invalid tags, or tags that cannot be written into the file"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     404 {object} model.ErrorResponse  "Track not found"
+// @Failure     422 {object} model.ErrorResponse  "The tags could not be written into the file"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Failure     502 {object} model.ErrorResponse  "The track was saved but its file could not be uploaded"
+// @Security    ApiKeyAuth
+// @Router		/tracks/{code} [patch]
+func (h *Handler) PatchTrack(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "PatchTrack")
+	defer span.End()
+
+	var patch model.TrackPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := h.trackEdit.EditService(c, c.Param("code"), &patch)
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// UploadTracks godoc
+// @Summary		Upload tracks.
+// @Description Uploads audio files to S3 and ingests them at once. Admins only.
+// @Description	The format and the tags of every file are checked first, files that are not audio, have no title or artist,
+// @Description	are larger than the configured limit or would duplicate the title of an existing track are rejected with the reason.
+// @Description	A file named like the object of existing tracks becomes their current version.
+// @Tags		track-controller
+// @Accept		multipart/form-data
+// @Produce		json
+// @Param		files   formData  file       true  "Audio files, the field may be repeated"
+// @Success     200 {object} model.UploadResponse  "Track IDs or the reason of every file, some were ingested"
+// @Failure     400 {object} model.ErrorResponse  "Not a multipart request, or no files"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     413 {object} model.ErrorResponse  "The request is too large"
+// @Failure     422 {object} model.UploadResponse  "The reason of every file, none was ingested"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Security    ApiKeyAuth
+// @Router		/tracks/upload [post]
+func (h *Handler) UploadTracks(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "UploadTracks")
+	defer span.End()
+	result, ingested, err := h.upload.UploadService(c)
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	if !ingested {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetVersions godoc
+// @Summary		S3 versions of the track.
+// @Description Returns every S3 object version the track has had with its ETag, size and upload time, newest first.
+// @Description	The current one is the version the track is streamed from, the others can be streamed with the version
+// @Description	parameter of /audio/stream/{segment}.
+// @Tags		track-controller
+// @Accept		*/*
+// @Produce		json
+// @Param		code    path      string     true  "Track ID"
+// @Success     200 {array}  model.TrackVersion  "OK"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     404 {object} model.ErrorResponse  "Track not found"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Security    ApiKeyAuth
+// @Router		/tracks/{code}/versions [get]
+func (h *Handler) GetVersions(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "GetVersions")
+	defer span.End()
+	result, err := h.trackVersion.VersionsService(c, c.Param("code"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// RestoreVersion godoc
+// @Summary		Restore an S3 version of the track.
+// @Description Makes an earlier S3 object version of the track its current version again, the track is streamed from it.
+// @Description	The tags, format, duration and hash of the track are read again from the version. Admins only.
+// @Tags		track-controller
+// @Accept		*/*
+// @Produce		json
+// @Param		code    path      string     true  "Track ID"
+// @Param		version path      string     true  "S3 version ID"
+// @Success     200 {array}  model.TrackVersion  "OK"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     404 {object} model.ErrorResponse  "Track or version not found"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Security    ApiKeyAuth
+// @Router		/tracks/{code}/versions/{version}/restore [post]
+func (h *Handler) RestoreVersion(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "RestoreVersion")
+	defer span.End()
+	result, err := h.trackVersion.RestoreService(c, c.Param("code"), c.Param("version"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetCover godoc
+// @Summary		Cover art of the track.
+// @Description Returns the cover art embedded in the track, or the folder.jpg/cover.png next to its audio file,
+// @Description	scaled to fit into a size by size square. Thumbnails are made on the first request and cached in S3.
+// @Tags		track-controller
+// @Accept		*/*
+// @Produce		image/jpeg
+// @Param		code    path      string     true  "Track ID"
+// @Param		size    query     string     false "Edge in pixels: 64, 300 (default) or 1200"
+// @Success     200 {file} file "JPEG image"
+// @Failure     400 {object} model.ErrorResponse  "size must be 64, 300 or 1200"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     404 {object} model.ErrorResponse  "Cover not found"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Security    ApiKeyAuth
+// @Router		/tracks/{code}/cover [get]
+func (h *Handler) GetCover(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "GetCover")
+	defer span.End()
+	cover, err := h.artwork.CoverService(c, c.Param("code"), c.Query("size"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.Data(http.StatusOK, "image/jpeg", cover)
+}
+
+// GetWaveform godoc
+// @Summary		Waveform peaks of the track.
+// @Description Returns min/max peak pairs of the current S3 version of the track for drawing a seek bar,
+// @Description	as JSON or, with format=binary, as a format version byte, a big endian uint32 pair count and int8 pairs.
+// @Description	FLAC and MP3 tracks only. The peaks are recomputed when the S3 version changes.
+// @Tags		track-controller
+// @Accept		*/*
+// @Produce		json
+// @Produce		application/octet-stream
+// @Param		code    path      string     true  "Track ID"
+// @Param		points  query     int        false "Number of min/max pairs, 1 to 2048 (default)"
+// @Param		format  query     string     false "Response format ('json' or 'binary')"
+// @Success     200 {object} model.Waveform  "OK"
+// @Failure     400 {object} model.ErrorResponse  "points must be between 1 and 2048"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     404 {object} model.ErrorResponse  "Waveform not found"
+// @Failure     422 {object} model.ErrorResponse  "waveform needs FLAC or MP3 audio"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Security    ApiKeyAuth
+// @Router		/tracks/{code}/waveform [get]
+func (h *Handler) GetWaveform(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "GetWaveform")
+	defer span.End()
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "binary" {
+		c.JSON(http.StatusBadRequest, "format must be 'json' or 'binary'")
+		return
+	}
+	result, err := h.waveform.WaveformService(c, c.Param("code"), c.Query("points"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	if format == "binary" {
+		c.Data(http.StatusOK, "application/octet-stream", waveform.Encode(result.Data))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,223 @@
+package tushandler
+
+import (
+	"net/http"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/tus"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+// exposedHeaders are the response headers a tus client in a browser reads.
+const exposedHeaders = "Location, Upload-Offset, Upload-Length, Upload-Metadata, Upload-Expires, " +
+	"Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, X-Track-Ids, X-Ingest-Error"
+
+type TusServiceInterface interface {
+	MaxSize() int64
+	CreateService(c *gin.Context, length, metadata string) (*model.TusUpload, *model.RestError)
+	HeadService(c *gin.Context, id string) (*model.TusUpload, *model.RestError)
+	PatchService(c *gin.Context, id, offset string) (*model.TusUpload, *model.RestError)
+	DeleteService(c *gin.Context, id string) *model.RestError
+}
+
+type Handler struct {
+	tus TusServiceInterface
+}
+
+func NewTusHandler(tus TusServiceInterface) *Handler {
+	return &Handler{tus}
+}
+
+// Options godoc
+// @Summary		Describe the tus server.
+// @Description Returns the tus version, the extensions and the largest file a resumable upload takes.
+// @Tags		tus-controller
+// @Success     204 "No Content"
+// @Header      204 {string} Tus-Version "1.0.0"
+// @Header      204 {string} Tus-Extension "creation,termination,expiration"
+// @Header      204 {integer} Tus-Max-Size "Largest upload in bytes"
+// @Router		/tus [options]
+func (h *Handler) Options(c *gin.Context) {
+	c.Header("Tus-Resumable", tus.Version)
+	c.Header("Tus-Version", tus.Version)
+	c.Header("Tus-Extension", tus.Extensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.tus.MaxSize(), 10))
+	c.Header("Access-Control-Expose-Headers", exposedHeaders)
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+// @Summary		Create a resumable upload.
+// @Description Creates a tus upload of an audio file as an S3 multipart upload. Admins only.
+// @Description	The filename in Upload-Metadata names the object, the upload completes to a new version of it and is
+// @Description	ingested like a file dropped into the bucket.
+// @Tags		tus-controller
+// @Param		Tus-Resumable   header  string  true   "1.0.0"
+// @Param		Upload-Length   header  integer true   "Size of the file in bytes"
+// @Param		Upload-Metadata header  string  true   "Comma separated keys and base64 values, filename is required"
+// @Success     201 "Created"
+// @Header      201 {string} Location "URL of the upload"
+// @Header      201 {string} Upload-Expires "When the unfinished upload is removed"
+// @Failure     400 {object} model.ErrorResponse  "Missing Upload-Length, or invalid Upload-Metadata or filename"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     412 "Unsupported tus version"
+// @Failure     413 {object} model.ErrorResponse  "The file is larger than Tus-Max-Size"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Security    ApiKeyAuth
+// @Router		/tus [post]
+func (h *Handler) CreateUpload(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "CreateUpload")
+	defer span.End()
+	if !resumable(c) {
+		return
+	}
+	created, err := h.tus.CreateService(c, c.GetHeader("Upload-Length"), c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+created.ID.String())
+	setUploadHeaders(c, created)
+	c.Status(http.StatusCreated)
+}
+
+// HeadUpload godoc
+// @Summary		Get the offset of a resumable upload.
+// @Description Returns how many bytes of the upload were received. A completed upload reports the tracks it became,
+// @Description	or why it did not become one.
+// @Tags		tus-controller
+// @Param		id              path    string  true   "Upload ID"
+// @Param		Tus-Resumable   header  string  true   "1.0.0"
+// @Success     200 "OK"
+// @Header      200 {integer} Upload-Offset "Bytes received"
+// @Header      200 {integer} Upload-Length "Size of the file in bytes"
+// @Header      200 {string} X-Track-Ids "Comma separated IDs of the tracks of a completed upload"
+// @Header      200 {string} X-Ingest-Error "Why a completed upload did not become a track"
+// @Failure     401 "Unauthorized"
+// @Failure     404 "Upload not found"
+// @Failure     410 "The upload expired"
+// @Failure     412 "Unsupported tus version"
+// @Security    ApiKeyAuth
+// @Router		/tus/{id} [head]
+func (h *Handler) HeadUpload(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HeadUpload")
+	defer span.End()
+	if !resumable(c) {
+		return
+	}
+	found, err := h.tus.HeadService(c, c.Param("id"))
+	if err != nil {
+		c.Status(err.Code)
+		return
+	}
+	c.Header("Upload-Length", strconv.FormatInt(found.Length, 10))
+	if found.Metadata != "" {
+		c.Header("Upload-Metadata", found.Metadata)
+	}
+	c.Header("Cache-Control", "no-store")
+	setUploadHeaders(c, found)
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload godoc
+// @Summary		Append to a resumable upload.
+// @Description Appends the body to the upload at Upload-Offset. The bytes received are kept when the request breaks off,
+// @Description	HEAD tells where to resume. With the last byte the object is completed and ingested.
+// @Tags		tus-controller
+// @Accept		application/offset+octet-stream
+// @Param		id              path    string  true   "Upload ID"
+// @Param		Tus-Resumable   header  string  true   "1.0.0"
+// @Param		Upload-Offset   header  integer true   "Offset of the upload the body starts at"
+// @Success     204 "No Content"
+// @Header      204 {integer} Upload-Offset "Bytes received"
+// @Header      204 {string} X-Track-Ids "Comma separated IDs of the tracks of a completed upload"
+// @Header      204 {string} X-Ingest-Error "Why a completed upload did not become a track"
+// @Failure     400 {object} model.ErrorResponse  "Invalid Upload-Offset"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     404 {object} model.ErrorResponse  "Upload not found"
+// @Failure     409 {object} model.ErrorResponse  "Upload-Offset is not the offset of the upload"
+// @Failure     410 {object} model.ErrorResponse  "The upload expired"
+// @Failure     412 "Unsupported tus version"
+// @Failure     413 {object} model.ErrorResponse  "The body runs past Upload-Length"
+// @Failure     415 {object} model.ErrorResponse  "Content-Type is not application/offset+octet-stream"
+// @Failure     423 {object} model.ErrorResponse  "Another request is writing to the upload"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Security    ApiKeyAuth
+// @Router		/tus/{id} [patch]
+func (h *Handler) PatchUpload(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "PatchUpload")
+	defer span.End()
+	if !resumable(c) {
+		return
+	}
+	if c.ContentType() != tus.ContentType {
+		c.JSON(http.StatusUnsupportedMediaType, "Content-Type must be "+tus.ContentType)
+		return
+	}
+	patched, err := h.tus.PatchService(c, c.Param("id"), c.GetHeader("Upload-Offset"))
+	if err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	setUploadHeaders(c, patched)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload godoc
+// @Summary		Terminate a resumable upload.
+// @Description Removes the upload and the parts received. The object and the tracks of a completed upload are kept.
+// @Tags		tus-controller
+// @Param		id              path    string  true   "Upload ID"
+// @Param		Tus-Resumable   header  string  true   "1.0.0"
+// @Success     204 "No Content"
+// @Failure     401 {object} model.ErrorResponse  "Unauthorized"
+// @Failure     404 {object} model.ErrorResponse  "Upload not found"
+// @Failure     412 "Unsupported tus version"
+// @Failure     423 {object} model.ErrorResponse  "Another request is writing to the upload"
+// @Failure     500 {object} model.ErrorResponse  "Internal Server Error"
+// @Security    ApiKeyAuth
+// @Router		/tus/{id} [delete]
+func (h *Handler) DeleteUpload(c *gin.Context) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "DeleteUpload")
+	defer span.End()
+	if !resumable(c) {
+		return
+	}
+	if err := h.tus.DeleteService(c, c.Param("id")); err != nil {
+		c.JSON(err.Code, err.Err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// resumable sets the tus version of the response and checks the one of the
+// request, which fails with 412 when the server does not speak it.
+func resumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tus.Version)
+	c.Header("Access-Control-Expose-Headers", exposedHeaders)
+	if c.GetHeader("Tus-Resumable") != tus.Version {
+		c.Header("Tus-Version", tus.Version)
+		c.AbortWithStatus(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// setUploadHeaders sets the offset of the upload, and when it expires or
+// what it became once completed.
+func setUploadHeaders(c *gin.Context, upload *model.TusUpload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.CompletedAt == nil {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		return
+	}
+	if len(upload.TrackIDs) > 0 {
+		c.Header("X-Track-Ids", strings.Join(upload.TrackIDs, ","))
+	}
+	if upload.IngestError != "" {
+		c.Header("X-Ingest-Error", upload.IngestError)
+	}
+}
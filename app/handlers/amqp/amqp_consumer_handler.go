@@ -43,8 +43,9 @@ func (c *Handler) processMessage(ctx context.Context, queueName string, message
 		return
 	}
 
-	// Process and acknowledge the message
-	go c.handleAndAcknowledge(ctx, queueName, message, messageBody)
+	// Process and acknowledge the message, one at a time per consumer so that
+	// the workers bound the messages handled at once
+	c.handleAndAcknowledge(ctx, queueName, message, messageBody)
 }
 
 // handleAndAcknowledge processes the message and acknowledges it.
@@ -126,6 +127,11 @@ func (c *Handler) consumeMessagesFromQueue(
 	numWorkers int,
 	workerDone chan struct{},
 ) error {
+	// The broker delivers no more unacknowledged messages than the workers
+	// handle at once
+	if err := c.channels[queueName].Qos(numWorkers, 0, false); err != nil {
+		return err
+	}
 	messages, err := c.channels[queueName].Consume(
 		queue.Name,        // queue
 		"",                // consumer
@@ -2,7 +2,6 @@ package amqp
 
 import (
 	"context"
-	"encoding/json"
 	"sync"
 
 	"github.com/rabbitmq/amqp091-go"
@@ -37,7 +36,6 @@ func (w *Worker) worker(ctx context.Context, queueName string, messages <-chan a
 		wg.Done()
 	}()
 
-	// Worker function body remains unchanged
 	for {
 		select {
 		case <-ctx.Done():
@@ -46,14 +44,7 @@ func (w *Worker) worker(ctx context.Context, queueName string, messages <-chan a
 			if !ok {
 				return
 			}
-			var messageBody map[string]interface{}
-			err := json.Unmarshal(message.Body, &messageBody)
-			if err != nil {
-				// error unmarshal
-				continue
-			}
-
-			w.MessageClient.HandleMessage(ctx, queueName, messageBody)
+			w.MessageClient.processMessage(ctx, queueName, message)
 		}
 	}
 }
@@ -13,13 +13,14 @@ const (
 func ConfigCORS(allowOrigins string) cors.Config {
 	return cors.Config{
 		AllowOrigins:  []string{allowOrigins},
-		AllowMethods:  []string{"POST", "OPTIONS", "GET", "PATCH", "DELETE"},
+		AllowMethods:  []string{"POST", "OPTIONS", "GET", "HEAD", "PATCH", "DELETE"},
 		ExposeHeaders: []string{"Origin"},
 		AllowHeaders: []string{
 			"Content-Type", "Content-Length", "Accept-Encoding",
 			"X-CSRF-Token", "Authorization", "accept", "origin",
 			"Cache-Control", "X-Requested-With", "Connection",
-			"Transfer-Encoding",
+			"Transfer-Encoding", "Tus-Resumable", "Upload-Length",
+			"Upload-Offset", "Upload-Metadata",
 		},
 		AllowCredentials: true,
 		MaxAge:           MaxAgeDuration,
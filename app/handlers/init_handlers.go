@@ -2,49 +2,73 @@ package handlers
 
 import (
 	"context"
+	"s3MediaStreamer/app/handlers/REST/adminhandler"
 	"s3MediaStreamer/app/handlers/REST/audiohandler"
 	"s3MediaStreamer/app/handlers/REST/healthhandler"
+	"s3MediaStreamer/app/handlers/REST/integrityhandler"
 	"s3MediaStreamer/app/handlers/REST/jobshandler"
+	"s3MediaStreamer/app/handlers/REST/libraryhandler"
 	"s3MediaStreamer/app/handlers/REST/otphandler"
 	"s3MediaStreamer/app/handlers/REST/playlisthandler"
+	"s3MediaStreamer/app/handlers/REST/radiohandler"
+	"s3MediaStreamer/app/handlers/REST/searchhandler"
 	"s3MediaStreamer/app/handlers/REST/trackhandler"
+	"s3MediaStreamer/app/handlers/REST/tushandler"
 	"s3MediaStreamer/app/handlers/REST/userhandler"
 	amqp2 "s3MediaStreamer/app/handlers/amqp"
 	"s3MediaStreamer/app/internal/app"
 )
 
 type Handlers struct {
-	Audio    *audiohandler.Handler
-	Health   *healthhandler.Handler
-	Job      *jobshandler.Handler
-	Otp      *otphandler.Handler
-	Playlist *playlisthandler.Handler
-	Track    *trackhandler.Handler
-	User     *userhandler.Handler
-	Messages *amqp2.Handler
-	Wrapper  *WrapperHandler
+	Admin     *adminhandler.Handler
+	Audio     *audiohandler.Handler
+	Health    *healthhandler.Handler
+	Integrity *integrityhandler.Handler
+	Job       *jobshandler.Handler
+	Library   *libraryhandler.Handler
+	Otp       *otphandler.Handler
+	Playlist  *playlisthandler.Handler
+	Radio     *radiohandler.Handler
+	Search    *searchhandler.Handler
+	Track     *trackhandler.Handler
+	Tus       *tushandler.Handler
+	User      *userhandler.Handler
+	Messages  *amqp2.Handler
+	Wrapper   *WrapperHandler
 }
 
 func NewHandlers(ctx context.Context, app *app.App) *Handlers {
+	adminHandler := adminhandler.NewAdminHandler(app.Service.Duplicate)
 	healthHandler := healthhandler.NewMonitoringHandler(*app.Service.Health)
+	integrityHandler := integrityhandler.NewIntegrityHandler(app.Service.Integrity)
 	jobHandler := jobshandler.NewJobHandler()
-	trackHandler := trackhandler.NewTrackHandler(*app.Service.Track)
+	libraryHandler := libraryhandler.NewLibraryHandler(app.Service.Library)
+	trackHandler := trackhandler.NewTrackHandler(*app.Service.Track, app.Service.Artwork, app.Service.Waveform, app.Service.TrackEdit, app.Service.TrackVersion, app.Service.Upload)
+	tusHandler := tushandler.NewTusHandler(app.Service.Tus)
 	userHandler := userhandler.NewUserHandler(*app.Service.ACL, *app.Service.User, *app.Service.AccessControl, app.Service.MetricsMonitor, app.Service.TracingProvider)
 	playlistHandler := playlisthandler.NewPlaylistHandler(*app.Service.Playlist, *userHandler)
 	otpHandler := otphandler.NewOtpHandler(*app.Service.OTP)
 	messageRepo, err := amqp2.NewRabbitMQHandlerWrapper(ctx, app.Cfg, app.Logger, app.Service.InitRepo.InitConnect.RabbitCon, *app.Service.Message)
-	audioHandler := audiohandler.NewAudioHandler(app.Service.Audio, app.Logger)
+	audioHandler := audiohandler.NewAudioHandler(app.Service.Audio, app.Service.MetricsMonitor, app.Logger)
+	radioHandler := radiohandler.NewRadioHandler(app.Service.Radio)
+	searchHandler := searchhandler.NewSearchHandler(app.Service.Search)
 	wrapper := NewTrackHandler(*app.Service.User, app.Service.Session, app.Logger)
 	if err != nil {
 		return nil
 	}
 	return &Handlers{
+		adminHandler,
 		audioHandler,
 		healthHandler,
+		integrityHandler,
 		jobHandler,
+		libraryHandler,
 		otpHandler,
 		playlistHandler,
+		radioHandler,
+		searchHandler,
 		trackHandler,
+		tusHandler,
 		userHandler,
 		messageRepo,
 		wrapper,
@@ -2,26 +2,41 @@ package inits
 
 import (
 	"context"
+	"io"
 	"s3MediaStreamer/app/internal/logs"
 	"s3MediaStreamer/app/model"
 	"s3MediaStreamer/app/services/acl"
+	"s3MediaStreamer/app/services/artwork"
 	"s3MediaStreamer/app/services/audio"
 	"s3MediaStreamer/app/services/auth"
 	"s3MediaStreamer/app/services/cashing"
 	"s3MediaStreamer/app/services/consul"
 	"s3MediaStreamer/app/services/db"
+	"s3MediaStreamer/app/services/diskcache"
+	"s3MediaStreamer/app/services/duplicate"
 	"s3MediaStreamer/app/services/health"
+	"s3MediaStreamer/app/services/integrity"
+	"s3MediaStreamer/app/services/library"
 	"s3MediaStreamer/app/services/monitoring"
 	"s3MediaStreamer/app/services/otel"
 	"s3MediaStreamer/app/services/otp"
 	"s3MediaStreamer/app/services/playlist"
 	"s3MediaStreamer/app/services/rabbitmq"
+	"s3MediaStreamer/app/services/radio"
 	"s3MediaStreamer/app/services/s3"
+	"s3MediaStreamer/app/services/search"
 	session "s3MediaStreamer/app/services/session"
 	"s3MediaStreamer/app/services/tags"
 	"s3MediaStreamer/app/services/track"
+	"s3MediaStreamer/app/services/trackedit"
+	"s3MediaStreamer/app/services/trackversion"
 	"s3MediaStreamer/app/services/tree"
+	"s3MediaStreamer/app/services/tus"
+	"s3MediaStreamer/app/services/upload"
 	"s3MediaStreamer/app/services/user"
+	"s3MediaStreamer/app/services/waveform"
+
+	"github.com/minio/minio-go/v7"
 )
 
 func initServices(ctx context.Context,
@@ -45,6 +60,12 @@ func initServices(ctx context.Context,
 		return nil, err
 	}
 	metricsMonitorService := monitoring.NewMonitoringService(repo.InitConnect.metrics)
+	cacheService, err := diskcache.NewDiskCacheService(cfg, func(ctx context.Context, object *minio.ObjectInfo) (io.ReadCloser, error) {
+		return s3Service.GetObjectS3(ctx, object)
+	}, metricsMonitorService.CacheMetrics)
+	if err != nil {
+		return nil, err
+	}
 
 	accessControlService := auth.NewAuthService(repo.PgRepo)
 	treeService := tree.NewTreeService()
@@ -63,10 +84,24 @@ func initServices(ctx context.Context,
 
 	userService := user.NewUserService(repo.PgRepo, *sessionService, *cashingService, logger, *accessControlService, cfg)
 	playlistService := playlist.NewPlaylistService(repo.PgRepo, repo.PgRepo, *sessionService, *accessControlService, *userService, logger, treeService)
-	audioService := audio.NewAudioService(*trackService, *s3Service, *playlistService, logger)
+	if err = audio.CheckStreamTokenKeys(cfg.AppConfig.Stream.Token.Keys, cfg.AppConfig.Stream.Token.ActiveKey); err != nil {
+		return nil, err
+	}
+	audioService := audio.NewAudioService(cfg, *trackService, *s3Service, *playlistService, repo.PgRepo, cacheService, logger)
+	radioService := radio.NewRadioService(cfg, *audioService, *playlistService, logger)
+	artworkService := artwork.NewArtworkService(*s3Service, *trackService, *tagsService, logger)
+	waveformService := waveform.NewWaveformService(repo.PgRepo, *s3Service, cacheService, logger)
+	trackEditService := trackedit.NewTrackEditService(repo.PgRepo, *s3Service, *tagsService, cacheService, logger)
+	integrityService := integrity.NewIntegrityService(repo.PgRepo, *s3Service, cacheService, logger)
+	duplicateService := duplicate.NewDuplicateService(repo.PgRepo, logger)
+	libraryService := library.NewLibraryService(repo.PgRepo, logger)
+	searchService := search.NewSearchService(repo.PgRepo, logger)
 	otpService := otp.NewOTPService(*userService, cfg)
 
-	messageService := rabbitmq.NewMessageService(cfg, logger, repo.PgRepo, *s3Service, *trackService, *tagsService)
+	messageService := rabbitmq.NewMessageService(cfg, logger, repo.PgRepo, *s3Service, *trackService, *tagsService, cacheService, artworkService, waveformService)
+	uploadService := upload.NewUploadService(cfg, repo.PgRepo, *s3Service, *tagsService, messageService, logger)
+	tusService := tus.NewTusService(cfg, repo.PgRepo, *s3Service, messageService, logger)
+	trackVersionService := trackversion.NewTrackVersionService(repo.PgRepo, *s3Service, messageService, logger)
 
 	logger.Info("Complete service initialize.")
 	return &Service{
@@ -76,6 +111,7 @@ func initServices(ctx context.Context,
 		ConsulKV:        consulKV,
 		AuthCache:       cashingService,
 		S3Storage:       s3Service,
+		DiskCache:       cacheService,
 		TracingProvider: tracingService,
 		MetricsMonitor:  metricsMonitorService,
 		AccessControl:   accessControlService,
@@ -88,6 +124,17 @@ func initServices(ctx context.Context,
 		Tags:            tagsService,
 		User:            userService,
 		Playlist:        playlistService,
+		Radio:           radioService,
+		Artwork:         artworkService,
+		Waveform:        waveformService,
+		TrackEdit:       trackEditService,
+		TrackVersion:    trackVersionService,
+		Upload:          uploadService,
+		Tus:             tusService,
+		Integrity:       integrityService,
+		Duplicate:       duplicateService,
+		Library:         libraryService,
+		Search:          searchService,
 		Session:         sessionService,
 		OTP:             otpService,
 		Tree:            treeService,
@@ -8,23 +8,35 @@ import (
 	repoDB "s3MediaStreamer/app/repository/postgres"
 	repoS3 "s3MediaStreamer/app/repository/s3"
 	"s3MediaStreamer/app/services/acl"
+	"s3MediaStreamer/app/services/artwork"
 	"s3MediaStreamer/app/services/audio"
 	"s3MediaStreamer/app/services/auth"
 	"s3MediaStreamer/app/services/cashing"
 	"s3MediaStreamer/app/services/consul"
 	"s3MediaStreamer/app/services/db"
+	"s3MediaStreamer/app/services/diskcache"
+	"s3MediaStreamer/app/services/duplicate"
 	"s3MediaStreamer/app/services/health"
+	"s3MediaStreamer/app/services/integrity"
+	"s3MediaStreamer/app/services/library"
 	"s3MediaStreamer/app/services/monitoring"
 	"s3MediaStreamer/app/services/otel"
 	"s3MediaStreamer/app/services/otp"
 	"s3MediaStreamer/app/services/playlist"
 	"s3MediaStreamer/app/services/rabbitmq"
+	"s3MediaStreamer/app/services/radio"
 	"s3MediaStreamer/app/services/s3"
+	"s3MediaStreamer/app/services/search"
 	session "s3MediaStreamer/app/services/session"
 	"s3MediaStreamer/app/services/tags"
 	"s3MediaStreamer/app/services/track"
+	"s3MediaStreamer/app/services/trackedit"
+	"s3MediaStreamer/app/services/trackversion"
 	"s3MediaStreamer/app/services/tree"
+	"s3MediaStreamer/app/services/tus"
+	"s3MediaStreamer/app/services/upload"
 	"s3MediaStreamer/app/services/user"
+	"s3MediaStreamer/app/services/waveform"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/go-redis/redis/v8"
@@ -55,6 +67,7 @@ type Service struct {
 	ConsulKV        *consul.KVService
 	AuthCache       *cashing.CachingService
 	S3Storage       *s3.Service
+	DiskCache       *diskcache.Service
 	TracingProvider *otel.Provider
 	MetricsMonitor  *monitoring.CombinedMetrics
 	AccessControl   *auth.Service
@@ -67,6 +80,17 @@ type Service struct {
 	Tags            *tags.Service
 	User            *user.Service
 	Playlist        *playlist.Service
+	Radio           *radio.Service
+	Artwork         *artwork.Service
+	Waveform        *waveform.Service
+	TrackEdit       *trackedit.Service
+	TrackVersion    *trackversion.Service
+	Upload          *upload.Service
+	Tus             *tus.Service
+	Integrity       *integrity.Service
+	Duplicate       *duplicate.Service
+	Library         *library.Service
+	Search          *search.Service
 	Session         *session.Service
 	OTP             *otp.Service
 	Tree            *tree.Service
@@ -21,4 +21,8 @@ const (
 	minSubmatchesCount       = 4
 	lengthRandomGenerateCode = 8
 	maxConcurrentOperations  = 2
+	analyzeTracksPerRun      = 100
+	hashTracksPerRun         = 200
+	hashTrackAttempts        = 3
+	waveformTracksPerRun     = 100
 )
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/analysis"
+	"s3MediaStreamer/app/services/loudness"
+	"s3MediaStreamer/app/services/pcm"
+)
+
+// Run estimates the tempo and key of the tracks ingested before the analysis
+// existed, a bounded number per run. Missing loudness is filled in on the way.
+func (j *AnalyzeTracksJob) Run() {
+	ctx := context.Background()
+	if !j.app.Service.ConsulElection.IsLeader() {
+		j.app.Logger.Info("I'm not the leader.")
+		return
+	}
+
+	j.app.Logger.Info("Start Job Analyze tempo and key of tracks...")
+
+	tracks, err := j.app.Service.Track.GetTracksWithoutAnalysis(ctx, analyzeTracksPerRun)
+	if err != nil {
+		j.app.Logger.Errorf("Error fetching tracks: %s", err)
+		return
+	}
+	for i := range tracks {
+		j.analyzeTrack(ctx, &tracks[i])
+	}
+
+	j.app.Logger.Infof("complete Job Analyze tempo and key of %d tracks", len(tracks))
+}
+
+func (j *AnalyzeTracksJob) analyzeTrack(ctx context.Context, track *model.Track) {
+	version, err := j.app.Service.S3Storage.GetS3VersionByTrackID(ctx, track.ID.String())
+	if err != nil {
+		j.app.Logger.Warnf("No S3 version of track %s: %v", track.ID, err)
+		return
+	}
+	object, err := j.app.Service.S3Storage.FindObjectFromVersion(ctx, version)
+	if err != nil {
+		j.app.Logger.Warnf("Error finding object of track %s: %v", track.ID, err)
+		return
+	}
+
+	meter, analyzer := loudness.NewMeter(), analysis.NewAnalyzer()
+	handlers := []pcm.Handler{meter, analyzer}
+	if track.CueStart != nil {
+		// A track cut out of a single-file album by a CUE sheet.
+		end := int64(0)
+		if track.CueEnd != nil {
+			end = *track.CueEnd
+		}
+		handlers = []pcm.Handler{pcm.Range(*track.CueStart, end, meter, analyzer)}
+	}
+	var errDecode error
+	err = j.app.Service.DiskCache.FetchUncached(ctx, &object, func(fileName string) error {
+		errDecode = pcm.DecodeFile(fileName, handlers...)
+		return nil
+	})
+	if err != nil {
+		j.app.Logger.Errorf("Error downloading file %s from S3: %v\n", object.Key, err)
+		return
+	}
+
+	// Tracks that cannot be decoded are stored with zero confidence, so that
+	// the next run does not try them again.
+	result := &analysis.Result{}
+	if errDecode == nil {
+		result = analyzer.Result()
+		if track.Loudness == nil {
+			if level, errLevel := meter.Result(); errLevel == nil {
+				level.Apply(track)
+			}
+		}
+	} else {
+		j.app.Logger.Debugf("No audio analysis for %s: %v", object.Key, errDecode)
+	}
+	result.Apply(track)
+
+	if err = j.app.Service.Track.UpdateTracks(ctx, track); err != nil {
+		j.app.Logger.Errorf("Error saving analysis of track %s: %v", track.ID, err)
+	}
+}
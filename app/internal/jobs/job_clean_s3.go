@@ -2,6 +2,8 @@ package jobs
 
 import (
 	"context"
+	"s3MediaStreamer/app/services/artwork"
+	"s3MediaStreamer/app/services/cue"
 	"sync"
 
 	"github.com/minio/minio-go/v7"
@@ -63,16 +65,22 @@ func (j *CleanS3Job) processS3Object(ctx context.Context, wg *sync.WaitGroup, ob
 }
 
 func (j *CleanS3Job) processS3ObjectContent(ctx context.Context, obj minio.ObjectInfo) {
-	fileName, errDownS3 := j.app.Service.S3Storage.DownloadFilesS3(ctx, obj.Key)
+	if artwork.IsArtworkObject(obj.Key) || cue.IsSheet(obj.Key) {
+		return
+	}
+
+	// Create a Track from the file data, downloaded past the disk cache
+	var errReadTags error
+	errDownS3 := j.app.Service.DiskCache.FetchUncached(ctx, &obj, func(fileName string) error {
+		j.app.Logger.Debugf("Read file: %s\n", fileName)
+		_, errReadTags = j.app.Service.Tags.ReadTags(fileName)
+		return nil
+	})
 	if errDownS3 != nil {
 		j.app.Logger.Errorf("Error downloading file %s from S3: %v\n", obj.Key, errDownS3)
 		return
 	}
 
-	j.app.Logger.Debugf("Create file: %s\n", fileName)
-
-	// Create a Track from the file data
-	_, errReadTags := j.app.Service.Tags.ReadTags(fileName)
 	if errReadTags != nil {
 		j.app.Logger.Errorf("Find empty tags in file: %s\n", obj.Key)
 		err := j.app.Service.S3Storage.DeleteObjectS3(ctx, &obj)
@@ -80,9 +88,4 @@ func (j *CleanS3Job) processS3ObjectContent(ctx context.Context, obj minio.Objec
 			j.app.Logger.Errorf("Error delete file %s from S3: %v\n", obj.Key, err)
 		}
 	}
-
-	err := j.app.Service.S3Storage.CleanTemplateFile(fileName)
-	if err != nil {
-		return
-	}
 }
@@ -23,12 +23,13 @@ func (j *CreateNewMusicChartJob) Run() {
 	j.app.Logger.Info("Start Job Create New Music chart...")
 
 	page, pageSize := 0, 100
-	sortBy, sortOrder := "updated_at", "DESC"
+	orderBy := []string{"updated_at DESC NULLS LAST"}
 
 	startTime := time.Now().Add(-24 * time.Hour).Format(timeFormat)
 	endTime := time.Now().Format(timeFormat)
 
-	tracks, _, err := j.app.Service.Track.GetTracks(ctx, page, pageSize, sortBy, sortOrder, "", startTime, endTime)
+	tracks, _, err := j.app.Service.Track.GetTracks(ctx, page, pageSize, orderBy, nil, nil, "", startTime, endTime,
+		model.TrackAnalysisFilter{}, model.CountNone)
 
 	if err != nil {
 		j.app.Logger.Errorf("Error fetching tracks: %s", err)
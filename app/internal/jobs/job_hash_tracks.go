@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"s3MediaStreamer/app/model"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Run hashes the audio payload of the tracks ingested before tracks were
+// identified by it, a bounded number per run, so that they are found by the
+// duplicate check of the ingestion and listed with their duplicates. The
+// objects of the tracks are looked up in one listing of the bucket. A track
+// that fails to hash hashTrackAttempts times is given up on.
+func (j *HashTracksJob) Run() {
+	ctx := context.Background()
+	if !j.app.Service.ConsulElection.IsLeader() {
+		j.app.Logger.Info("I'm not the leader.")
+		return
+	}
+
+	tracks, err := j.app.Service.Track.GetTracksWithoutContentHash(ctx, hashTrackAttempts, hashTracksPerRun)
+	if err != nil {
+		j.app.Logger.Errorf("Error fetching tracks: %s", err)
+		return
+	}
+	if len(tracks) == 0 {
+		return
+	}
+
+	j.app.Logger.Info("Start Job Hash audio of tracks...")
+
+	listObject, err := j.app.Service.S3Storage.ListObjectS3(ctx)
+	if err != nil {
+		j.app.Logger.Errorf("Error listing objects in S3: %v", err)
+		return
+	}
+	objects := make(map[string]*minio.ObjectInfo, len(listObject))
+	for i := range listObject {
+		objects[listObject[i].VersionID] = &listObject[i]
+	}
+
+	hashed := 0
+	for i := range tracks {
+		if j.hashTrack(ctx, &tracks[i], objects) {
+			hashed++
+		} else if err = j.app.Service.Track.AddTrackContentHashAttempt(ctx, tracks[i].ID.String()); err != nil {
+			j.app.Logger.Errorf("Error counting the hash attempt of track %s: %v", tracks[i].ID, err)
+		}
+	}
+
+	j.app.Logger.Infof("complete Job Hash audio of %d of %d tracks", hashed, len(tracks))
+}
+
+// hashTrack stores the hash of the audio payload of the current version of
+// the track, downloaded past the disk cache, and reports whether it did.
+func (j *HashTracksJob) hashTrack(ctx context.Context, track *model.Track, objects map[string]*minio.ObjectInfo) bool {
+	id := track.ID.String()
+	version, err := j.app.Service.S3Storage.GetS3VersionByTrackID(ctx, id)
+	if err != nil {
+		j.app.Logger.Warnf("No S3 version of track %s: %v", id, err)
+		return false
+	}
+	object, ok := objects[version]
+	if !ok {
+		j.app.Logger.Warnf("Version %s of track %s is not in the bucket", version, id)
+		return false
+	}
+
+	var hash string
+	err = j.app.Service.DiskCache.FetchUncached(ctx, object, func(fileName string) error {
+		var errHash error
+		hash, errHash = j.app.Service.Tags.HashAudio(fileName)
+		return errHash
+	})
+	if err != nil {
+		j.app.Logger.Errorf("Error hashing file %s of track %s: %v", object.Key, id, err)
+		return false
+	}
+	if err = j.app.Service.Track.SetTrackContentHash(ctx, []string{id}, hash); err != nil {
+		j.app.Logger.Errorf("Error saving content hash of track %s: %v", id, err)
+		return false
+	}
+	return true
+}
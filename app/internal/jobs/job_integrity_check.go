@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+)
+
+// Run decodes every audio object version in S3 that changed since the last
+// run and records whether it is intact. Broken objects are only reported.
+func (j *IntegrityCheckJob) Run() {
+	ctx := context.Background()
+	if !j.app.Service.ConsulElection.IsLeader() {
+		j.app.Logger.Info("I'm not the leader.")
+		return
+	}
+
+	j.app.Logger.Info("Start Job Check integrity of s3 files...")
+
+	checked, err := j.app.Service.Integrity.RunService(ctx)
+	if err != nil {
+		j.app.Logger.Errorf("Error checking integrity of s3 files: %v", err)
+		return
+	}
+
+	j.app.Logger.Infof("complete Job Check integrity of %d s3 files", checked)
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"context"
+	"s3MediaStreamer/app/services/s3"
+
+	"github.com/google/uuid"
+)
+
+// Run fills in the object of the versions recorded before the versions kept
+// their object key, from one listing of the bucket. Without the key a new
+// version of the object is not matched to its tracks.
+func (j *S3VersionKeysJob) Run() {
+	ctx := context.Background()
+	if !j.app.Service.ConsulElection.IsLeader() {
+		j.app.Logger.Info("I'm not the leader.")
+		return
+	}
+
+	versions, err := j.app.Service.S3Storage.GetS3VersionsWithoutObjectKey(ctx)
+	if err != nil {
+		j.app.Logger.Errorf("Error getting the S3 versions without object key: %v", err)
+		return
+	}
+	if len(versions) == 0 {
+		return
+	}
+
+	j.app.Logger.Info("Start Job Fill the object keys of S3 versions...")
+
+	missing := make(map[string]struct{}, len(versions))
+	for _, version := range versions {
+		missing[version] = struct{}{}
+	}
+
+	listObject, err := j.app.Service.S3Storage.ListObjectS3(ctx)
+	if err != nil {
+		j.app.Logger.Errorf("Error listing objects in S3: %v", err)
+		return
+	}
+
+	filled := 0
+	for i := range listObject {
+		object := &listObject[i]
+		if _, ok := missing[object.VersionID]; !ok || object.IsDeleteMarker {
+			continue
+		}
+		// The track ID is not part of the update
+		if err = j.app.Service.S3Storage.SetS3VersionObject(ctx, s3.NewTrackVersion(uuid.Nil, object)); err != nil {
+			j.app.Logger.Errorf("Error filling the object of S3 version %s: %v", object.VersionID, err)
+			continue
+		}
+		delete(missing, object.VersionID)
+		filled++
+	}
+
+	if len(missing) > 0 {
+		j.app.Logger.Warnf("%d S3 versions are no longer in the bucket", len(missing))
+	}
+	j.app.Logger.Infof("complete Job Fill the object keys of %d S3 versions", filled)
+}
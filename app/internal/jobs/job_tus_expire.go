@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+)
+
+// Run removes the resumable uploads that expired, the unfinished ones with
+// the parts they left in S3.
+func (j *ExpireTusUploadsJob) Run() {
+	ctx := context.Background()
+	if !j.app.Service.ConsulElection.IsLeader() {
+		j.app.Logger.Info("I'm not the leader.")
+		return
+	}
+
+	j.app.Logger.Info("Start Job Expire resumable uploads...")
+
+	removed, err := j.app.Service.Tus.ExpireService(ctx)
+	if err != nil {
+		j.app.Logger.Errorf("Error expiring resumable uploads: %v", err)
+		return
+	}
+
+	j.app.Logger.Infof("complete Job Expire %d resumable uploads", removed)
+}
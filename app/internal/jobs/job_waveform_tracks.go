@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"s3MediaStreamer/app/services/pcm"
+)
+
+// Run computes the waveforms of the tracks that have none, the ones ingested
+// before waveforms existed and the ones whose computation failed, a bounded
+// number per run. Every run goes on after the last track of the previous one,
+// so tracks that keep failing do not hold up the others.
+func (j *WaveformTracksJob) Run() {
+	ctx := context.Background()
+	if !j.app.Service.ConsulElection.IsLeader() {
+		j.app.Logger.Info("I'm not the leader.")
+		return
+	}
+	if !j.mu.TryLock() {
+		j.app.Logger.Info("Job Compute waveforms of tracks is still running.")
+		return
+	}
+	defer j.mu.Unlock()
+
+	j.app.Logger.Info("Start Job Compute waveforms of tracks...")
+
+	tracks, err := j.app.Service.Waveform.TracksWithoutWaveformService(ctx, j.after, waveformTracksPerRun)
+	if err != nil {
+		j.app.Logger.Errorf("Error fetching tracks: %s", err)
+		return
+	}
+	for i := range tracks {
+		id := tracks[i].ID.String()
+		if err = j.app.Service.Waveform.BackfillService(ctx, id); err != nil && !errors.Is(err, pcm.ErrUnsupported) {
+			j.app.Logger.Warnf("Error computing waveform of track %s: %v", id, err)
+		}
+	}
+	// Start over once the end of the tracks was reached.
+	j.after = ""
+	if len(tracks) == waveformTracksPerRun {
+		j.after = tracks[len(tracks)-1].ID.String()
+	}
+
+	j.app.Logger.Infof("complete Job Compute waveforms of %d tracks", len(tracks))
+}
@@ -3,6 +3,7 @@ package jobs
 import (
 	"fmt"
 	"s3MediaStreamer/app/internal/app"
+	"sync"
 	"time"
 
 	"github.com/bamzi/jobrunner"
@@ -86,6 +87,24 @@ func (js *JobScheduler) scheduleJobsFromConsul(app *app.App) error {
 		case "createNewMusicChart":
 			job := NewCreateNewMusicChartJob(app)
 			err = jobrunner.Schedule(interval, job)
+		case "analyzeTracks":
+			job := NewAnalyzeTracksJob(app)
+			err = jobrunner.Schedule(interval, job)
+		case "integrityCheck":
+			job := NewIntegrityCheckJob(app)
+			err = jobrunner.Schedule(interval, job)
+		case "tusExpire":
+			job := NewExpireTusUploadsJob(app)
+			err = jobrunner.Schedule(interval, job)
+		case "hashTracks":
+			job := NewHashTracksJob(app)
+			err = jobrunner.Schedule(interval, job)
+		case "waveformTracks":
+			job := NewWaveformTracksJob(app)
+			err = jobrunner.Schedule(interval, job)
+		case "s3VersionKeys":
+			job := NewS3VersionKeysJob(app)
+			err = jobrunner.Schedule(interval, job)
 		default:
 			app.Logger.Warnf("Unknown job function: %s", jobConfig.Name)
 			continue
@@ -150,3 +169,72 @@ func NewCreateNewMusicChartJob(app *app.App) *CreateNewMusicChartJob {
 type CreateNewMusicChartJob struct {
 	app *app.App
 }
+
+// NewAnalyzeTracksJob creates a new AnalyzeTracksJob instance.
+func NewAnalyzeTracksJob(app *app.App) *AnalyzeTracksJob {
+	return &AnalyzeTracksJob{
+		app: app,
+	}
+}
+
+type AnalyzeTracksJob struct {
+	app *app.App
+}
+
+// NewIntegrityCheckJob creates a new IntegrityCheckJob instance.
+func NewIntegrityCheckJob(app *app.App) *IntegrityCheckJob {
+	return &IntegrityCheckJob{
+		app: app,
+	}
+}
+
+type IntegrityCheckJob struct {
+	app *app.App
+}
+
+// NewExpireTusUploadsJob creates a new ExpireTusUploadsJob instance.
+func NewExpireTusUploadsJob(app *app.App) *ExpireTusUploadsJob {
+	return &ExpireTusUploadsJob{
+		app: app,
+	}
+}
+
+type ExpireTusUploadsJob struct {
+	app *app.App
+}
+
+// NewHashTracksJob creates a new HashTracksJob instance.
+func NewHashTracksJob(app *app.App) *HashTracksJob {
+	return &HashTracksJob{
+		app: app,
+	}
+}
+
+type HashTracksJob struct {
+	app *app.App
+}
+
+// NewWaveformTracksJob creates a new WaveformTracksJob instance.
+func NewWaveformTracksJob(app *app.App) *WaveformTracksJob {
+	return &WaveformTracksJob{
+		app: app,
+	}
+}
+
+type WaveformTracksJob struct {
+	app *app.App
+
+	mu    sync.Mutex
+	after string // ID of the last track of the previous run
+}
+
+// NewS3VersionKeysJob creates a new S3VersionKeysJob instance.
+func NewS3VersionKeysJob(app *app.App) *S3VersionKeysJob {
+	return &S3VersionKeysJob{
+		app: app,
+	}
+}
+
+type S3VersionKeysJob struct {
+	app *app.App
+}
@@ -77,7 +77,46 @@ type Config struct {
 			UseSSL          bool   `yaml:"use_ssl" env:"S3_USE_SSL"`
 			BucketName      string `yaml:"bucket_name" env:"S3_BUCKET_NAME"`
 			Location        string `yaml:"location" env:"S3_LOCATION"`
+			Cache           struct {
+				Enabled bool   `yaml:"enabled" env:"S3_CACHE_ENABLED"`
+				Dir     string `yaml:"dir" env:"S3_CACHE_DIR"`
+				MaxSize int    `yaml:"max_size" env:"S3_CACHE_MAX_SIZE"`
+				MaxAge  int    `yaml:"max_age" env:"S3_CACHE_MAX_AGE"`
+			} `yaml:"cache"`
 		} `yaml:"s3"`
+
+		Stream struct {
+			HLS struct {
+				SegmentDuration    int  `yaml:"segment_duration" env:"STREAM_HLS_SEGMENT_DURATION"`
+				SeekTableCacheSize int  `yaml:"seek_table_cache_size" env:"STREAM_HLS_SEEK_TABLE_CACHE_SIZE"`
+				Encryption         bool `yaml:"encryption" env:"STREAM_HLS_ENCRYPTION"`
+			} `yaml:"hls"`
+			Token struct {
+				Keys      map[string]string `yaml:"keys" env:"STREAM_TOKEN_KEYS"` // key id -> secret, e.g. k1:secret1,k2:secret2
+				ActiveKey string            `yaml:"active_key" env:"STREAM_TOKEN_ACTIVE_KEY"`
+				TTL       int               `yaml:"ttl" env:"STREAM_TOKEN_TTL"`
+				BindIP    bool              `yaml:"bind_ip" env:"STREAM_TOKEN_BIND_IP"`
+			} `yaml:"token"`
+			Delivery struct {
+				Mode          string `yaml:"mode" env:"STREAM_DELIVERY_MODE"`
+				PresignExpiry int    `yaml:"presign_expiry" env:"STREAM_DELIVERY_PRESIGN_EXPIRY"`
+			} `yaml:"delivery"`
+			Radio struct {
+				MetaInt int  `yaml:"meta_int" env:"STREAM_RADIO_META_INT"`
+				Loop    bool `yaml:"loop" env:"STREAM_RADIO_LOOP"`
+				Shuffle bool `yaml:"shuffle" env:"STREAM_RADIO_SHUFFLE"`
+			} `yaml:"radio"`
+		} `yaml:"stream"`
+
+		Upload struct {
+			MaxFileSize int `yaml:"max_file_size" env:"UPLOAD_MAX_FILE_SIZE"`
+			MaxFiles    int `yaml:"max_files" env:"UPLOAD_MAX_FILES"`
+		} `yaml:"upload"`
+
+		Tus struct {
+			MaxSize    int `yaml:"max_size" env:"TUS_MAX_SIZE"`
+			Expiration int `yaml:"expiration" env:"TUS_EXPIRATION"`
+		} `yaml:"tus"`
 	} `yaml:"app_config"`
 
 	Storage struct {
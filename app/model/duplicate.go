@@ -0,0 +1,16 @@
+package model
+
+// DuplicateGroup is a set of tracks that are suspected to be the same track,
+// either because their audio payloads have the same hash or because they
+// have the same artist, album and title and about the same duration.
+type DuplicateGroup struct {
+	// Reason is content_hash or tags.
+	Reason string `json:"reason" example:"content_hash"`
+	// Key is the shared hash, or the shared artist, album and title in lower
+	// case.
+	Key string `json:"key" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// TrackIDs are the IDs of the tracks in the order they were ingested.
+	TrackIDs []string `json:"-"`
+	// Tracks are listed in the order they were ingested.
+	Tracks []Track `json:"tracks"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// IntegrityCheck is the outcome of decoding one S3 object version in full.
+type IntegrityCheck struct {
+	ObjectKey string    `json:"object_key" example:"Artist/Album/01 Intro.flac"`
+	VersionID string    `json:"version_id" example:"6f1c3c5e-2b0e-4d8f-9a51-0c2d7e4b9f10"`
+	ETag      string    `json:"etag" example:"9b2cf535f27731c974343645a3985328"`
+	Size      int64     `json:"size" example:"31457280"`
+	TrackID   string    `json:"track_id,omitempty" example:"0b7e2a57-4c1e-4b8a-a2a4-3f3c7b1c9d12"`
+	Status    string    `json:"status" example:"corrupt"`
+	Detail    string    `json:"detail" example:"frame 812: CRC-16 checksum mismatch"`
+	CheckedAt time.Time `json:"checked_at"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "github.com/google/uuid"
+
+// Artist is an artist of tracks or album artist of albums.
+type Artist struct {
+	ID         uuid.UUID `json:"_id" example:"3d1f6c4e-8a0b-4f5e-9c2d-7b6a5e4d3c21"`
+	Name       string    `json:"name" example:"Artist name"`
+	AlbumCount int       `json:"album_count" example:"4"`
+	TrackCount int       `json:"track_count" example:"42"`
+}
+
+// Album is an album of an album artist.
+type Album struct {
+	ID         uuid.UUID `json:"_id" example:"9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"`
+	Title      string    `json:"title" example:"Album name"`
+	ArtistID   uuid.UUID `json:"artist_id" example:"3d1f6c4e-8a0b-4f5e-9c2d-7b6a5e4d3c21"`
+	Artist     string    `json:"artist" example:"Album artist name"`
+	Year       int       `json:"year" example:"2022"`
+	Artwork    string    `json:"artwork" example:"artwork/9f86d081884c7d65.jpg"`
+	TrackCount int       `json:"track_count" example:"10"`
+}
+
+// AlbumDetail is an album with its tracks ordered by disc and track number.
+type AlbumDetail struct {
+	Album
+	Tracks []Track `json:"tracks"`
+}
+
+// Genre is a genre of tracks.
+type Genre struct {
+	ID         uuid.UUID `json:"_id" example:"5c4b3a29-1807-4f6e-8d5c-4b3a29180716"`
+	Name       string    `json:"name" example:"Genre name"`
+	TrackCount int       `json:"track_count" example:"128"`
+}
@@ -1,7 +1,9 @@
 package model
 
 type PlaylistM3U struct {
-	Title    string
-	URI      string
-	Duration float64
+	Title     string
+	URI       string
+	Duration  float64
+	TrackGain *float64
+	AlbumGain *float64
 }
@@ -0,0 +1,6 @@
+package model
+
+type RadioListeners struct {
+	PlaylistID string `json:"playlist_id" example:"c42a5a0b-6c1b-4d3b-8a56-9d2c5d0f3f1e"`
+	Listeners  int    `json:"listeners" example:"3"`
+}
@@ -1,6 +1,10 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type S3 struct {
 	ID        uuid.UUID `json:"track_id" bson:"track_id" pg:"type:uuid" swaggerignore:"true"`
@@ -12,3 +16,15 @@ type UploadS3 struct {
 	FilePath    string `json:"file_path" example:"File path"`
 	ContentType string `json:"content_type" example:"Content Type"`
 }
+
+// TrackVersion is an S3 object version a track has had. The current one is
+// the version the track is streamed from.
+type TrackVersion struct {
+	TrackID    uuid.UUID `json:"track_id" swaggertype:"string" example:"0b7e2a57-4c1e-4b8a-a2a4-3f3c7b1c9d12"`
+	VersionID  string    `json:"version_id" example:"6f1c3c5e-2b0e-4d8f-9a51-0c2d7e4b9f10"`
+	ObjectKey  string    `json:"object_key" example:"01 Intro.flac"`
+	ETag       string    `json:"etag" example:"9b2cf535f27731c974343645a3985328"`
+	Size       int64     `json:"size" example:"31457280"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	Current    bool      `json:"current" example:"true"`
+}
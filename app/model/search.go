@@ -0,0 +1,53 @@
+package model
+
+// SearchResult is what a search matched, best match first in each list.
+type SearchResult struct {
+	Tracks    []TrackHit    `json:"tracks"`
+	Albums    []AlbumHit    `json:"albums"`
+	Artists   []ArtistHit   `json:"artists"`
+	Playlists []PlaylistHit `json:"playlists"`
+}
+
+// SearchHit is the rank of a match and its text with the matched words in
+// <b> tags. The rest of the snippet is HTML escaped.
+type SearchHit struct {
+	Rank    float64 `json:"rank" example:"0.83"`
+	Snippet string  `json:"snippet" example:"<b>Yesterday</b> — The Beatles — Help!"`
+}
+
+// TrackHit is a matched track. LyricsSnippet is the matched part of the
+// lyrics when the words were found in them.
+type TrackHit struct {
+	Track Track `json:"track"`
+	SearchHit
+	LyricsSnippet string `json:"lyrics_snippet,omitempty" example:"… all my <b>troubles</b> seemed so far away …"`
+}
+
+// AlbumHit is a matched album.
+type AlbumHit struct {
+	Album Album `json:"album"`
+	SearchHit
+}
+
+// ArtistHit is a matched artist.
+type ArtistHit struct {
+	Artist Artist `json:"artist"`
+	SearchHit
+}
+
+// PlaylistHit is a matched playlist.
+type PlaylistHit struct {
+	Playlist PLayList `json:"playlist"`
+	SearchHit
+}
+
+// SearchQuery is a search over the library. Owner leaves the playlists of
+// other users out unless it is empty. The matched words of the snippets are
+// put between StartSel and StopSel.
+type SearchQuery struct {
+	Term     string
+	Owner    string
+	Limit    int
+	StartSel string
+	StopSel  string
+}
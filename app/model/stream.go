@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// HTTPRange specifies the byte range of an object requested by the client.
+type HTTPRange struct {
+	Start  int64
+	Length int64
+}
+
+// HLSSegment is a frame-aligned byte range of an MP3 object served as one HLS
+// segment. Start is the time of its first frame in the track.
+type HLSSegment struct {
+	Index    int
+	Offset   int64
+	Length   int64
+	Start    time.Duration
+	Duration time.Duration
+}
+
+// CueSlice is a CUE track cut out of an S3 object version on frame
+// boundaries. Header is sent before the Length bytes at Offset of the
+// object, Samples is the number of samples per channel in them.
+type CueSlice struct {
+	Header  []byte
+	Offset  int64
+	Length  int64
+	Samples int64
+}
+
+// SeekTable holds the HLS segmentation of one S3 object version.
+type SeekTable struct {
+	Version  string
+	Bitrate  int64 // average bits per second
+	Duration time.Duration
+	Segments []HLSSegment
+}
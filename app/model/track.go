@@ -8,30 +8,97 @@ import (
 
 // Track represents data about a record track.
 type Track struct {
-	ID          uuid.UUID     `json:"_id" bson:"_id" pg:"type:uuid" swaggerignore:"true"`
-	CreatedAt   time.Time     `json:"created_at" bson:"created_at" pg:"default:now()" swaggerignore:"true"`
-	UpdatedAt   time.Time     `json:"updated_at" bson:"updated_at" pg:"default:now()" swaggerignore:"true"`
-	Album       string        `json:"album" bson:"album" example:"Album name"`
-	AlbumArtist string        `json:"album_artist" bson:"album_artist" example:"Album artist name"`
-	Composer    string        `json:"composer" bson:"composer" example:"Composer name"`
-	Genre       string        `json:"genre" bson:"genre" example:"Genre name"`
-	Lyrics      string        `json:"lyrics" bson:"lyrics" example:"Lyrics of the track"`
-	Title       string        `json:"title" bson:"title" example:"Title name"`
-	Artist      string        `json:"artist" bson:"artist" example:"Artist name"`
-	Year        int           `json:"year" bson:"year" example:"2022"`
-	Comment     string        `json:"comment" bson:"comment" example:"Additional comments"`
-	Disc        int           `json:"disc" bson:"disc" example:"1"`
-	DiscTotal   int           `json:"disc_total" bson:"disc_total" example:"2"`
-	Track       int           `json:"track" bson:"track" example:"3"`
-	TrackTotal  int           `json:"track_total" bson:"track_total" example:"10"`
-	Duration    time.Duration `json:"duration" bson:"duration" swaggerignore:"true"`
-	SampleRate  uint32        `json:"sample_rate" bson:"sample_rate" example:"44100"`
-	Bitrate     uint32        `json:"bitrate" bson:"bitrate" example:"320"`
+	ID            uuid.UUID     `json:"_id" bson:"_id" pg:"type:uuid" swaggerignore:"true"`
+	CreatedAt     time.Time     `json:"created_at" bson:"created_at" pg:"default:now()" swaggerignore:"true"`
+	UpdatedAt     time.Time     `json:"updated_at" bson:"updated_at" pg:"default:now()" swaggerignore:"true"`
+	Album         string        `json:"album" bson:"album" example:"Album name"`
+	AlbumArtist   string        `json:"album_artist" bson:"album_artist" example:"Album artist name"`
+	Composer      string        `json:"composer" bson:"composer" example:"Composer name"`
+	Genre         string        `json:"genre" bson:"genre" example:"Genre name"`
+	Lyrics        string        `json:"lyrics" bson:"lyrics" example:"Lyrics of the track"`
+	Title         string        `json:"title" bson:"title" example:"Title name"`
+	Artist        string        `json:"artist" bson:"artist" example:"Artist name"`
+	Year          int           `json:"year" bson:"year" example:"2022"`
+	Comment       string        `json:"comment" bson:"comment" example:"Additional comments"`
+	Disc          int           `json:"disc" bson:"disc" example:"1"`
+	DiscTotal     int           `json:"disc_total" bson:"disc_total" example:"2"`
+	Track         int           `json:"track" bson:"track" example:"3"`
+	TrackTotal    int           `json:"track_total" bson:"track_total" example:"10"`
+	Duration      time.Duration `json:"duration" bson:"duration" swaggerignore:"true"`
+	SampleRate    uint32        `json:"sample_rate" bson:"sample_rate" example:"44100"`
+	Bitrate       uint32        `json:"bitrate" bson:"bitrate" example:"320"`
+	MimeType      string        `json:"mime_type" bson:"mime_type" example:"audio/mpeg"`
+	Artwork       string        `json:"artwork" bson:"artwork" example:"artwork/9f86d081884c7d65.jpg"`
+	Loudness      *float64      `json:"loudness" bson:"loudness" example:"-9.4"`
+	TruePeak      *float64      `json:"true_peak" bson:"true_peak" example:"-0.3"`
+	TrackGain     *float64      `json:"track_gain" bson:"track_gain" example:"-8.6"`
+	BPM           *float64      `json:"bpm" bson:"bpm" example:"124.5"`
+	BPMConfidence *float64      `json:"bpm_confidence" bson:"bpm_confidence" example:"0.62"`
+	Key           string        `json:"key" bson:"key" example:"A minor"`
+	KeyConfidence *float64      `json:"key_confidence" bson:"key_confidence" example:"0.81"`
+	// CueStart and CueEnd are the sample offsets of a track cut out of a
+	// single-file album by a CUE sheet, nil for tracks that are whole objects.
+	// A nil CueEnd runs to the end of the object.
+	CueStart *int64 `json:"cue_start,omitempty" bson:"cue_start" example:"2977500"`
+	CueEnd   *int64 `json:"cue_end,omitempty" bson:"cue_end" example:"10306260"`
+	// ArtistID, AlbumID and GenreID link the track to the artist, album and
+	// genre entities its tags are matched to at ingestion.
+	ArtistID *uuid.UUID `json:"artist_id,omitempty" bson:"artist_id" swaggertype:"string" example:"3d1f6c4e-8a0b-4f5e-9c2d-7b6a5e4d3c21"`
+	AlbumID  *uuid.UUID `json:"album_id,omitempty" bson:"album_id" swaggertype:"string" example:"9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"`
+	GenreID  *uuid.UUID `json:"genre_id,omitempty" bson:"genre_id" swaggertype:"string" example:"5c4b3a29-1807-4f6e-8d5c-4b3a29180716"`
+	// ContentHash is the hex SHA-256 of the audio payload of the file without
+	// its tags, empty for tracks cut out by a CUE sheet and tracks not hashed
+	// yet.
+	ContentHash string `json:"content_hash,omitempty" bson:"content_hash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// ContentHashAttempts counts the failed attempts at hashing the audio
+	// payload of a track ingested before tracks were hashed.
+	ContentHashAttempts int `json:"-" bson:"content_hash_attempts"`
+	// IngestedAt is when the track was ingested. CreatedAt is the release
+	// date of its tags.
+	IngestedAt time.Time `json:"ingested_at" bson:"ingested_at" example:"2024-05-01T12:00:00Z"`
 }
 
+// TrackAnalysisFilter narrows a track list down by the analysed tempo and
+// key. Zero values leave the bound out.
+type TrackAnalysisFilter struct {
+	BPMMin float64
+	BPMMax float64
+	Key    string
+}
+
+// TrackCount is how the total of a track list is counted: exactly, as the
+// planner estimates it, or not at all.
+type TrackCount string
+
+const (
+	CountExact    TrackCount = "exact"
+	CountEstimate TrackCount = "estimate"
+	CountNone     TrackCount = "none"
+)
+
 // Track represents data about a record track.
 type TrackRequest struct {
 	Position   string `json:"position" bson:"position" example:"0"`
 	PlaylistID string `json:"playlist_id" bson:"playlist_id" pg:"type:uuid" swaggerignore:"true"`
 	Track
 }
+
+// TrackPatch is an edit of the tags of a track. Fields left out keep their
+// value. With WriteTags the tags are also written into the audio file, which
+// is uploaded as a new version of its S3 object.
+type TrackPatch struct {
+	Title       *string `json:"title" example:"Title name"`
+	Artist      *string `json:"artist" example:"Artist name"`
+	Album       *string `json:"album" example:"Album name"`
+	AlbumArtist *string `json:"album_artist" example:"Album artist name"`
+	Composer    *string `json:"composer" example:"Composer name"`
+	Genre       *string `json:"genre" example:"Genre name"`
+	Year        *int    `json:"year" example:"2022"`
+	Disc        *int    `json:"disc" example:"1"`
+	DiscTotal   *int    `json:"disc_total" example:"2"`
+	Track       *int    `json:"track" example:"3"`
+	TrackTotal  *int    `json:"track_total" example:"10"`
+	Comment     *string `json:"comment" example:"Additional comments"`
+	Lyrics      *string `json:"lyrics" example:"Lyrics of the track"`
+	WriteTags   bool    `json:"write_tags" example:"true"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TusUpload is a resumable upload of the tus protocol, stored in S3 as a
+// multipart upload. The bytes that do not fill a part yet are kept pending,
+// so that any instance can resume the upload.
+type TusUpload struct {
+	ID          uuid.UUID  `json:"_id"`
+	ObjectKey   string     `json:"object_key"`
+	UploadID    string     `json:"upload_id"`
+	Length      int64      `json:"length"`
+	Offset      int64      `json:"offset"`
+	Metadata    string     `json:"metadata"`
+	Parts       []TusPart  `json:"parts"`
+	Pending     []byte     `json:"-"`
+	TrackIDs    []string   `json:"track_ids,omitempty"`
+	IngestError string     `json:"ingest_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// TusPart is an uploaded part of the S3 multipart upload of a TusUpload.
+type TusPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
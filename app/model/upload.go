@@ -0,0 +1,14 @@
+package model
+
+// UploadResult is the outcome of one file of a track upload: the tracks it
+// was ingested as, or why it was rejected.
+type UploadResult struct {
+	File     string   `json:"file" example:"01 Intro.flac"`
+	TrackIDs []string `json:"track_ids,omitempty" example:"0b7e2a57-4c1e-4b8a-a2a4-3f3c7b1c9d12"`
+	Error    string   `json:"error,omitempty" example:"failed to read tags: empty title or artist"`
+}
+
+// UploadResponse lists the outcome of every file of a track upload.
+type UploadResponse struct {
+	Files []UploadResult `json:"files"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// Waveform holds min/max peak pairs of a track for drawing a seek bar.
+type Waveform struct {
+	TrackID string `json:"track_id" example:"5c2c1e44-6f3b-4b1e-9a7e-3a4c1d7f2b10"`
+	Points  int    `json:"points" example:"1024"`
+	// Data alternates the minimum and maximum of every point, scaled to -127..127.
+	Data []int8 `json:"data" swaggertype:"array,integer" example:"-12,15,-40,38"`
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"s3MediaStreamer/app/model"
+
+	"github.com/Masterminds/squirrel"
+)
+
+type DuplicateRepositoryInterface interface {
+	GetDuplicateGroups(ctx context.Context, offset, limit int) ([]model.DuplicateGroup, int, error)
+	GetTracksByIDs(ctx context.Context, ids []string) ([]model.Track, error)
+}
+
+// duplicateGroupsQuery groups the tracks with the same content hash, and the
+// tracks with the same artist, album and title whose durations are within
+// the identity tolerance of each other. A tags group whose tracks all have
+// the same hash is already a hash group and is left out.
+var duplicateGroupsQuery = fmt.Sprintf(`
+	SELECT 'content_hash' AS reason, content_hash AS key, array_agg(_id::text ORDER BY ingested_at) AS track_ids
+	FROM tracks
+	WHERE content_hash <> ''
+	GROUP BY content_hash
+	HAVING COUNT(*) > 1
+	UNION ALL
+	SELECT 'tags', concat_ws(' / ', lower(artist), lower(album), lower(title)), array_agg(_id::text ORDER BY ingested_at)
+	FROM tracks
+	GROUP BY lower(artist), lower(album), lower(title)
+	HAVING COUNT(*) > 1
+	   AND max(duration) - min(duration) <= interval '%d milliseconds'
+	   AND NOT (COUNT(DISTINCT content_hash) = 1 AND min(content_hash) <> '')`,
+	identityDurationTolerance.Milliseconds())
+
+// GetDuplicateGroups returns a page of the groups of suspected duplicate
+// tracks, with the IDs of their tracks first ingested first, and the total
+// count of groups.
+func (c *Client) GetDuplicateGroups(ctx context.Context, offset, limit int) ([]model.DuplicateGroup, int, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetDuplicateGroups")
+	defer span.End()
+
+	selectQuery := squirrel.Select("reason", "key", "track_ids").
+		From("("+duplicateGroupsQuery+") g").
+		OrderBy("reason", "key")
+	sql, args, err := applyPagination(selectQuery, offset, limit).PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var groups []model.DuplicateGroup
+	for rows.Next() {
+		var group model.DuplicateGroup
+		if err = rows.Scan(&group.Reason, &group.Key, &group.TrackIDs); err != nil {
+			return nil, 0, err
+		}
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	var total int
+	if err = c.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM ("+duplicateGroupsQuery+") g").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+// GetTracksByIDs returns the tracks of the IDs, in no particular order.
+// IDs without a track are left out.
+func (c *Client) GetTracksByIDs(ctx context.Context, ids []string) ([]model.Track, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTracksByIDs")
+	defer span.End()
+
+	return c.ExecuteSelectQuery(ctx, squirrel.Select("*").
+		From("tracks").
+		Where(squirrel.Eq{"_id": ids}).
+		PlaceholderFormat(squirrel.Dollar))
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+)
+
+type HLSKeyRepositoryInterface interface {
+	GetHLSKey(ctx context.Context, trackID, version string) ([]byte, error)
+	AddHLSKey(ctx context.Context, trackID, version string, key []byte) error
+	DeleteHLSKeys(ctx context.Context, trackID, exceptVersion string) error
+}
+
+// GetHLSKey returns the AES-128 key of a track version, pgx.ErrNoRows if none was generated yet.
+func (c *Client) GetHLSKey(ctx context.Context, trackID, version string) ([]byte, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetHLSKey")
+	defer span.End()
+
+	selectQuery := squirrel.Select("key").
+		From("hls_keys").
+		Where(squirrel.Eq{"track_id": trackID, "version": version}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := selectQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	if err = c.Pool.QueryRow(ctx, sql, args...).Scan(&key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// AddHLSKey stores the key of a track version. An existing key is kept, so
+// concurrent requests for the same version end up with one key.
+func (c *Client) AddHLSKey(ctx context.Context, trackID, version string, key []byte) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "AddHLSKey")
+	defer span.End()
+
+	insertQuery := squirrel.Insert("hls_keys").
+		Columns("track_id", "version", "key").
+		Values(trackID, version, key).
+		Suffix("ON CONFLICT (track_id, version) DO NOTHING").
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := insertQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// DeleteHLSKeys removes the keys of every other version of the track.
+func (c *Client) DeleteHLSKeys(ctx context.Context, trackID, exceptVersion string) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "DeleteHLSKeys")
+	defer span.End()
+
+	deleteQuery := squirrel.Delete("hls_keys").
+		Where(squirrel.Eq{"track_id": trackID}).
+		Where(squirrel.NotEq{"version": exceptVersion}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := deleteQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"s3MediaStreamer/app/model"
+
+	"github.com/Masterminds/squirrel"
+)
+
+type IntegrityRepositoryInterface interface {
+	ListIntegrityChecks(ctx context.Context) ([]model.IntegrityCheck, error)
+	SaveIntegrityCheck(ctx context.Context, check *model.IntegrityCheck) error
+	DeleteIntegrityCheck(ctx context.Context, objectKey, versionID string) error
+	GetIntegrityReport(ctx context.Context, statuses []string, offset, limit int) ([]model.IntegrityCheck, int, error)
+}
+
+// ListIntegrityChecks returns the stored check of every object version.
+func (c *Client) ListIntegrityChecks(ctx context.Context) ([]model.IntegrityCheck, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "ListIntegrityChecks")
+	defer span.End()
+
+	selectQuery := squirrel.Select("object_key", "version_id", "etag", "size", "status", "detail", "checked_at").
+		From("track_integrity")
+	return c.queryIntegrityChecks(ctx, selectQuery, false)
+}
+
+// SaveIntegrityCheck stores the check of an object version, replacing the previous one.
+func (c *Client) SaveIntegrityCheck(ctx context.Context, check *model.IntegrityCheck) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "SaveIntegrityCheck")
+	defer span.End()
+
+	insertQuery := squirrel.Insert("track_integrity").
+		Columns("object_key", "version_id", "etag", "size", "status", "detail", "checked_at").
+		Values(check.ObjectKey, check.VersionID, check.ETag, check.Size, check.Status, check.Detail, check.CheckedAt).
+		Suffix("ON CONFLICT (object_key, version_id) DO UPDATE SET etag = EXCLUDED.etag, size = EXCLUDED.size, " +
+			"status = EXCLUDED.status, detail = EXCLUDED.detail, checked_at = EXCLUDED.checked_at").
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := insertQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// DeleteIntegrityCheck removes the check of an object version that is gone from S3.
+func (c *Client) DeleteIntegrityCheck(ctx context.Context, objectKey, versionID string) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "DeleteIntegrityCheck")
+	defer span.End()
+
+	deleteQuery := squirrel.Delete("track_integrity").
+		Where(squirrel.Eq{"object_key": objectKey, "version_id": versionID}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := deleteQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// GetIntegrityReport returns a page of the checks with one of the statuses,
+// newest first, with the track the version belongs to, and their total count.
+func (c *Client) GetIntegrityReport(ctx context.Context, statuses []string, offset, limit int) ([]model.IntegrityCheck, int, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetIntegrityReport")
+	defer span.End()
+
+	selectQuery := squirrel.Select("i.object_key", "i.version_id", "i.etag", "i.size", "i.status", "i.detail", "i.checked_at",
+		"COALESCE(s.track_id::text, '')").
+		From("track_integrity i").
+		LeftJoin("s3version s ON s.version::text = i.version_id").
+		Where(squirrel.Eq{"i.status": statuses}).
+		OrderBy("i.checked_at DESC", "i.object_key")
+
+	checks, err := c.queryIntegrityChecks(ctx, applyPagination(selectQuery, offset, limit), true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	countQuery := squirrel.Select("COUNT(*)").
+		From("track_integrity").
+		Where(squirrel.Eq{"status": statuses}).
+		PlaceholderFormat(squirrel.Dollar)
+	sql, args, err := countQuery.ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	if err = c.Pool.QueryRow(ctx, sql, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	return checks, total, nil
+}
+
+func (c *Client) queryIntegrityChecks(ctx context.Context, selectQuery squirrel.SelectBuilder, withTrack bool) ([]model.IntegrityCheck, error) {
+	sql, args, err := selectQuery.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []model.IntegrityCheck
+	for rows.Next() {
+		var check model.IntegrityCheck
+		dest := []interface{}{
+			&check.ObjectKey, &check.VersionID, &check.ETag, &check.Size,
+			&check.Status, &check.Detail, &check.CheckedAt,
+		}
+		if withTrack {
+			dest = append(dest, &check.TrackID)
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
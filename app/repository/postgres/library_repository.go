@@ -0,0 +1,311 @@
+package postgres
+
+import (
+	"context"
+	"s3MediaStreamer/app/model"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type LibraryRepositoryInterface interface {
+	GetArtists(ctx context.Context, offset, limit int) ([]model.Artist, int, error)
+	GetArtist(ctx context.Context, id uuid.UUID) (*model.Artist, error)
+	GetArtistAlbums(ctx context.Context, artistID uuid.UUID, offset, limit int) ([]model.Album, int, error)
+	GetAlbum(ctx context.Context, id uuid.UUID) (*model.Album, error)
+	GetAlbumTracks(ctx context.Context, albumID uuid.UUID) ([]model.Track, error)
+	GetGenres(ctx context.Context, offset, limit int) ([]model.Genre, int, error)
+}
+
+// rowQuerier runs the upserts that link a track, a pool or a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// NormalizeName is the form artist, album and genre names are matched in:
+// lower case, trimmed, with runs of white space collapsed to one space.
+func NormalizeName(name string) string {
+	return strings.ToLower(displayName(name))
+}
+
+// displayName trims the name and collapses runs of white space in it.
+func displayName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// linkLibrary sets the artist, album and genre of the track to the entities
+// its tags match, creating the ones that do not exist yet. The album belongs
+// to the album artist, the artist of the track when the tag is empty.
+func linkLibrary(ctx context.Context, q rowQuerier, track *model.Track) error {
+	var err error
+	if track.ArtistID, err = upsertNamed(ctx, q, "artists", track.Artist); err != nil {
+		return err
+	}
+	if track.GenreID, err = upsertNamed(ctx, q, "genres", track.Genre); err != nil {
+		return err
+	}
+
+	albumArtistID, albumArtist := track.ArtistID, track.Artist
+	if NormalizeName(track.AlbumArtist) != "" {
+		if albumArtistID, err = upsertNamed(ctx, q, "artists", track.AlbumArtist); err != nil {
+			return err
+		}
+		albumArtist = track.AlbumArtist
+	}
+	track.AlbumID = nil
+	if albumArtistID == nil || NormalizeName(track.Album) == "" {
+		return nil
+	}
+
+	insertQuery := squirrel.Insert("albums").
+		Columns("_id", "title", "normalized_title", "artist_id", "artist_name", "year", "artwork").
+		Values(uuid.New(), displayName(track.Album), NormalizeName(track.Album), *albumArtistID, displayName(albumArtist), track.Year, track.Artwork).
+		Suffix("ON CONFLICT (artist_id, normalized_title) DO UPDATE SET " +
+			"year = CASE WHEN albums.year = 0 THEN EXCLUDED.year ELSE albums.year END, " +
+			"artwork = CASE WHEN albums.artwork = '' THEN EXCLUDED.artwork ELSE albums.artwork END " +
+			"RETURNING _id").
+		PlaceholderFormat(squirrel.Dollar)
+	track.AlbumID, err = queryID(ctx, q, insertQuery)
+	return err
+}
+
+// upsertNamed returns the ID of the artist or genre with the name, creating
+// it when there is none, and nil for an empty name.
+func upsertNamed(ctx context.Context, q rowQuerier, table, name string) (*uuid.UUID, error) {
+	normalized := NormalizeName(name)
+	if normalized == "" {
+		return nil, nil
+	}
+	insertQuery := squirrel.Insert(table).
+		Columns("_id", "name", "normalized_name").
+		Values(uuid.New(), displayName(name), normalized).
+		// The no-op update makes RETURNING report the existing row.
+		Suffix("ON CONFLICT (normalized_name) DO UPDATE SET name = " + table + ".name RETURNING _id").
+		PlaceholderFormat(squirrel.Dollar)
+	return queryID(ctx, q, insertQuery)
+}
+
+func queryID(ctx context.Context, q rowQuerier, query squirrel.Sqlizer) (*uuid.UUID, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	var id uuid.UUID
+	if err = q.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// cleanLibrary deletes the albums, genres and artists no track is linked to anymore.
+func cleanLibrary(ctx context.Context, tx pgx.Tx) error {
+	for _, query := range []squirrel.Sqlizer{
+		squirrel.Delete("albums").Where("NOT EXISTS (SELECT 1 FROM tracks WHERE tracks.album_id = albums._id)"),
+		squirrel.Delete("genres").Where("NOT EXISTS (SELECT 1 FROM tracks WHERE tracks.genre_id = genres._id)"),
+		squirrel.Delete("artists").Where("NOT EXISTS (SELECT 1 FROM tracks WHERE tracks.artist_id = artists._id)").
+			Where("NOT EXISTS (SELECT 1 FROM albums WHERE albums.artist_id = artists._id)"),
+	} {
+		if err := ExecuteSQL(ctx, tx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// artistSelect selects artists with the number of their albums and tracks.
+func artistSelect() squirrel.SelectBuilder {
+	return squirrel.Select("a._id", "a.name",
+		"(SELECT COUNT(*) FROM albums al WHERE al.artist_id = a._id)",
+		"(SELECT COUNT(*) FROM tracks t WHERE t.artist_id = a._id)").
+		From("artists a")
+}
+
+// albumSelect selects albums with the name of their artist and the number of their tracks.
+func albumSelect() squirrel.SelectBuilder {
+	return squirrel.Select("al._id", "al.title", "al.artist_id", "a.name", "al.year", "al.artwork",
+		"(SELECT COUNT(*) FROM tracks t WHERE t.album_id = al._id)").
+		From("albums al").
+		Join("artists a ON a._id = al.artist_id")
+}
+
+// GetArtists returns a page of the artists ordered by name and their total count.
+func (c *Client) GetArtists(ctx context.Context, offset, limit int) ([]model.Artist, int, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetArtists")
+	defer span.End()
+
+	selectQuery := applyPagination(artistSelect().OrderBy("a.normalized_name"), offset, limit)
+	artists, err := c.queryArtists(ctx, selectQuery)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := c.countRows(ctx, squirrel.Select("COUNT(*)").From("artists"))
+	if err != nil {
+		return nil, 0, err
+	}
+	return artists, total, nil
+}
+
+// GetArtist returns the artist with the ID, pgx.ErrNoRows when there is none.
+func (c *Client) GetArtist(ctx context.Context, id uuid.UUID) (*model.Artist, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetArtist")
+	defer span.End()
+
+	artists, err := c.queryArtists(ctx, artistSelect().Where(squirrel.Eq{"a._id": id}))
+	if err != nil {
+		return nil, err
+	}
+	if len(artists) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &artists[0], nil
+}
+
+// GetArtistAlbums returns a page of the albums of the album artist, oldest
+// first, and their total count.
+func (c *Client) GetArtistAlbums(ctx context.Context, artistID uuid.UUID, offset, limit int) ([]model.Album, int, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetArtistAlbums")
+	defer span.End()
+
+	selectQuery := albumSelect().
+		Where(squirrel.Eq{"al.artist_id": artistID}).
+		OrderBy("al.year", "al.normalized_title")
+	albums, err := c.queryAlbums(ctx, applyPagination(selectQuery, offset, limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := c.countRows(ctx, squirrel.Select("COUNT(*)").From("albums").Where(squirrel.Eq{"artist_id": artistID}))
+	if err != nil {
+		return nil, 0, err
+	}
+	return albums, total, nil
+}
+
+// GetAlbum returns the album with the ID, pgx.ErrNoRows when there is none.
+func (c *Client) GetAlbum(ctx context.Context, id uuid.UUID) (*model.Album, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetAlbum")
+	defer span.End()
+
+	albums, err := c.queryAlbums(ctx, albumSelect().Where(squirrel.Eq{"al._id": id}))
+	if err != nil {
+		return nil, err
+	}
+	if len(albums) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &albums[0], nil
+}
+
+// GetAlbumTracks returns the tracks of the album ordered by disc and track number.
+func (c *Client) GetAlbumTracks(ctx context.Context, albumID uuid.UUID) ([]model.Track, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetAlbumTracks")
+	defer span.End()
+
+	selectQuery := squirrel.Select("*").
+		From("tracks").
+		Where(squirrel.Eq{"album_id": albumID}).
+		OrderBy("disc", "track", "title").
+		PlaceholderFormat(squirrel.Dollar)
+	return c.ExecuteSelectQuery(ctx, selectQuery)
+}
+
+// GetGenres returns a page of the genres ordered by name and their total count.
+func (c *Client) GetGenres(ctx context.Context, offset, limit int) ([]model.Genre, int, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetGenres")
+	defer span.End()
+
+	selectQuery := squirrel.Select("g._id", "g.name",
+		"(SELECT COUNT(*) FROM tracks t WHERE t.genre_id = g._id)").
+		From("genres g").
+		OrderBy("g.normalized_name")
+	sql, args, err := applyPagination(selectQuery, offset, limit).PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var genres []model.Genre
+	for rows.Next() {
+		var genre model.Genre
+		if err = rows.Scan(&genre.ID, &genre.Name, &genre.TrackCount); err != nil {
+			return nil, 0, err
+		}
+		genres = append(genres, genre)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := c.countRows(ctx, squirrel.Select("COUNT(*)").From("genres"))
+	if err != nil {
+		return nil, 0, err
+	}
+	return genres, total, nil
+}
+
+func (c *Client) queryArtists(ctx context.Context, selectQuery squirrel.SelectBuilder) ([]model.Artist, error) {
+	sql, args, err := selectQuery.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artists []model.Artist
+	for rows.Next() {
+		var artist model.Artist
+		if err = rows.Scan(&artist.ID, &artist.Name, &artist.AlbumCount, &artist.TrackCount); err != nil {
+			return nil, err
+		}
+		artists = append(artists, artist)
+	}
+	return artists, rows.Err()
+}
+
+func (c *Client) queryAlbums(ctx context.Context, selectQuery squirrel.SelectBuilder) ([]model.Album, error) {
+	sql, args, err := selectQuery.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var albums []model.Album
+	for rows.Next() {
+		var album model.Album
+		err = rows.Scan(&album.ID, &album.Title, &album.ArtistID, &album.Artist,
+			&album.Year, &album.Artwork, &album.TrackCount)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, album)
+	}
+	return albums, rows.Err()
+}
+
+func (c *Client) countRows(ctx context.Context, countQuery squirrel.SelectBuilder) (int, error) {
+	sql, args, err := countQuery.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	var total int
+	err = c.Pool.QueryRow(ctx, sql, args...).Scan(&total)
+	return total, err
+}
@@ -0,0 +1,14 @@
+package postgres_test
+
+import (
+	"s3MediaStreamer/app/repository/postgres"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "the beatles", postgres.NormalizeName("  The\tBeatles "))
+	assert.Equal(t, postgres.NormalizeName("Daft Punk"), postgres.NormalizeName("DAFT  PUNK"))
+	assert.Equal(t, "", postgres.NormalizeName(" \n "))
+}
@@ -19,7 +19,7 @@ type PlaylistRepositoryInterface interface {
 	DeletePlaylist(ctx context.Context, playlistID string) error
 	UpdatePlaylistDetails(ctx context.Context, playlistID, title, description string) error
 	GetPlaylistOwner(ctx context.Context, playlistID string) (uuid.UUID, error)
-	GetPlaylists(ctx context.Context, userID string) ([]model.PLayList, error)
+	GetPlaylists(ctx context.Context, userID string, orderBy []string, after squirrel.Sqlizer, limit int) ([]model.PLayList, error)
 	GetPlaylistAllTracks(ctx context.Context, playlistID string) ([]model.TrackRequest, error)
 	GetPlaylistItems(ctx context.Context, playlistID string) ([]model.PlaylistStruct, error)
 	GetPlaylistPath(ctx context.Context, playlistID string) (string, error)
@@ -183,6 +183,23 @@ func (c *Client) GetTracksByPlaylist(ctx context.Context, playlistID string) ([]
 			&track.Duration,
 			&track.SampleRate,
 			&track.Bitrate,
+			&track.MimeType,
+			&track.Artwork,
+			&track.Loudness,
+			&track.TruePeak,
+			&track.TrackGain,
+			&track.BPM,
+			&track.BPMConfidence,
+			&track.Key,
+			&track.KeyConfidence,
+			&track.CueStart,
+			&track.CueEnd,
+			&track.ArtistID,
+			&track.AlbumID,
+			&track.GenreID,
+			&track.ContentHash,
+			&track.ContentHashAttempts,
+			&track.IngestedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -397,11 +414,17 @@ func (c *Client) GetRootPlaylistOwner(ctx context.Context) (uuid.UUID, error) {
 //     A context that handles the request lifecycle and deadlines.
 //   - userID: uuid.UUID or string
 //     The unique identifier of the user whose playlists we want to fetch. Pass an empty string to fetch all playlists.
+//   - orderBy: []string
+//     The ORDER BY terms of the playlists.
+//   - after: squirrel.Sqlizer
+//     The condition of the playlists after a cursor, nil to start at the first one.
+//   - limit: int
+//     The maximum number of playlists, 0 for all of them.
 //
 // Return Values:
 //   - []model.PlayList: A slice of playlists. Each playlist contains metadata such as ID, title, description, etc.
 //   - error: Returns an error if the SQL query execution or row scanning fails.
-func (c *Client) GetPlaylists(ctx context.Context, userID string) ([]model.PLayList, error) {
+func (c *Client) GetPlaylists(ctx context.Context, userID string, orderBy []string, after squirrel.Sqlizer, limit int) ([]model.PLayList, error) {
 	// Get the tracer for the current context
 	tracer := GetTracer(ctx)
 	_, span := tracer.Start(ctx, "GetPlaylists")
@@ -415,6 +438,15 @@ func (c *Client) GetPlaylists(ctx context.Context, userID string) ([]model.PLayL
 		queryBuilder = queryBuilder.Where(squirrel.Eq{"_creator_user": userID})
 	}
 
+	// Continue after the cursor, in the order of its keys
+	if after != nil {
+		queryBuilder = queryBuilder.Where(after)
+	}
+	queryBuilder = queryBuilder.OrderBy(orderBy...)
+	if limit > 0 {
+		queryBuilder = queryBuilder.Limit(uint64(limit))
+	}
+
 	// Convert query to SQL
 	sql, args, err := queryBuilder.ToSql()
 	if err != nil {
@@ -506,6 +538,23 @@ func (c *Client) GetPlaylistAllTracks(ctx context.Context, playlistID string) ([
 			&track.Disc, &track.DiscTotal, &track.Track,
 			&track.TrackTotal, &track.Duration, &track.SampleRate,
 			&track.Bitrate,
+			&track.MimeType,
+			&track.Artwork,
+			&track.Loudness,
+			&track.TruePeak,
+			&track.TrackGain,
+			&track.BPM,
+			&track.BPMConfidence,
+			&track.Key,
+			&track.KeyConfidence,
+			&track.CueStart,
+			&track.CueEnd,
+			&track.ArtistID,
+			&track.AlbumID,
+			&track.GenreID,
+			&track.ContentHash,
+			&track.ContentHashAttempts,
+			&track.IngestedAt,
 			&readPlaylistID, // Here we read the readPlaylistID
 			&position,       // Here we read the position
 		); err != nil {
@@ -2,6 +2,9 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"s3MediaStreamer/app/model"
 
 	"github.com/Masterminds/squirrel"
 )
@@ -72,8 +75,8 @@ func (c *Client) GetTotalTrackCount(queryBuilder squirrel.SelectBuilder) (int, e
 	queryBuilder = queryBuilder.RemoveLimit().RemoveOffset()
 	// Manually remove ORDER BY clause
 	queryBuilder = queryBuilder.OrderBy("")
-	queryBuilder.PlaceholderFormat(squirrel.Dollar)
-	countQuery := squirrel.Select("COUNT(*)").FromSelect(queryBuilder, "subquery")
+	// The subquery is rendered with question marks, the outer query numbers them.
+	countQuery := squirrel.Select("COUNT(*)").FromSelect(queryBuilder, "subquery").PlaceholderFormat(squirrel.Dollar)
 	sql, args, err := countQuery.ToSql()
 	if err != nil {
 		return 0, err
@@ -87,3 +90,66 @@ func (c *Client) GetTotalTrackCount(queryBuilder squirrel.SelectBuilder) (int, e
 
 	return totalRows, nil
 }
+
+// countTracks counts the tracks of the list as count says, -1 for none. An
+// estimate of the whole table is the row count of its statistics, that of a
+// filtered list the row count the planner expects.
+func (c *Client) countTracks(ctx context.Context, queryBuilder squirrel.SelectBuilder, filtered bool,
+	count model.TrackCount) (int, error) {
+	switch count {
+	case model.CountNone:
+		return -1, nil
+	case model.CountEstimate:
+		var estimate int
+		var err error
+		if filtered {
+			estimate, err = c.EstimateRowCount(ctx, queryBuilder)
+		} else {
+			estimate, err = c.EstimateTableCount(ctx, "tracks")
+		}
+		// A table that was never analysed has no statistics yet.
+		if err != nil || estimate >= 0 {
+			return estimate, err
+		}
+	}
+	return c.GetTotalTrackCount(queryBuilder)
+}
+
+// EstimateTableCount returns the row count of the statistics of the table,
+// -1 when it was never vacuumed or analysed.
+func (c *Client) EstimateTableCount(ctx context.Context, table string) (int, error) {
+	var reltuples float64
+	err := c.Pool.QueryRow(ctx, "SELECT reltuples FROM pg_class WHERE oid = $1::regclass", table).Scan(&reltuples)
+	if err != nil {
+		return 0, err
+	}
+	if reltuples < 0 {
+		return -1, nil
+	}
+	return int(reltuples), nil
+}
+
+// EstimateRowCount returns the number of rows the planner expects the query
+// to return.
+func (c *Client) EstimateRowCount(ctx context.Context, queryBuilder squirrel.SelectBuilder) (int, error) {
+	sql, args, err := queryBuilder.RemoveLimit().RemoveOffset().PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+	var plan []byte
+	if err = c.Pool.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+sql, args...).Scan(&plan); err != nil {
+		return 0, err
+	}
+	var explained []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err = json.Unmarshal(plan, &explained); err != nil {
+		return 0, err
+	}
+	if len(explained) == 0 {
+		return 0, errors.New("empty query plan")
+	}
+	return int(explained[0].Plan.Rows), nil
+}
@@ -2,18 +2,35 @@ package postgres
 
 import (
 	"errors"
+	"s3MediaStreamer/app/model"
 
 	"context"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
 )
 
 type S3RepositoryInterface interface {
 	GetS3VersionByTrackID(ctx context.Context, trackID string) (string, error)
-	AddS3Version(ctx context.Context, trackID, version string) error
+	GetS3Versions(ctx context.Context, trackID string) ([]model.TrackVersion, error)
+	GetTrackIDsByObjectKey(ctx context.Context, objectKey string) ([]string, error)
+	GetTrackIDsByVersion(ctx context.Context, version string) ([]string, error)
+	LockObjectKey(ctx context.Context, objectKey string, fn func(ctx context.Context) error) error
+	AddS3Version(ctx context.Context, version *model.TrackVersion) error
+	RestoreS3Version(ctx context.Context, trackID, version string) error
 	DeleteS3Version(ctx context.Context, version string) error
+	GetS3VersionsWithoutObjectKey(ctx context.Context) ([]string, error)
+	SetS3VersionObject(ctx context.Context, version *model.TrackVersion) error
 }
 
+// promoteLatestVersions makes the latest version of every track that has
+// versions but no current one current.
+const promoteLatestVersions = `UPDATE s3version s SET is_current = TRUE
+FROM (SELECT DISTINCT ON (track_id) track_id, version FROM s3version
+      WHERE track_id NOT IN (SELECT track_id FROM s3version WHERE is_current)
+      ORDER BY track_id, uploaded_at DESC) latest
+WHERE s.track_id = latest.track_id AND s.version = latest.version`
+
 func (c *Client) GetS3VersionByTrackID(ctx context.Context, trackID string) (string, error) {
 	tracer := GetTracer(ctx)
 	_, span := tracer.Start(ctx, "GetS3VersionByTrackID")
@@ -24,7 +41,7 @@ func (c *Client) GetS3VersionByTrackID(ctx context.Context, trackID string) (str
 	// Create a SQL query to fetch
 	selectQuery := squirrel.Select("version").
 		From("s3Version").
-		Where(squirrel.Eq{"track_id": trackID}).
+		Where(squirrel.Eq{"track_id": trackID, "is_current": true}).
 		PlaceholderFormat(squirrel.Dollar)
 
 	// Convert the SQL query to SQL and arguments
@@ -49,31 +66,164 @@ func (c *Client) GetS3VersionByTrackID(ctx context.Context, trackID string) (str
 	return version, nil
 }
 
-func (c *Client) AddS3Version(ctx context.Context, trackID, version string) error {
+// GetS3Versions returns the object versions the track has had, newest first.
+func (c *Client) GetS3Versions(ctx context.Context, trackID string) ([]model.TrackVersion, error) {
 	tracer := GetTracer(ctx)
-	_, span := tracer.Start(ctx, "AddS3Version")
+	_, span := tracer.Start(ctx, "GetS3Versions")
 	defer span.End()
 
-	// Create an insert query using Squirrel
-	insertQuery := squirrel.Insert("s3Version").
-		Columns("track_id", "version").
-		Values(trackID, version).
+	selectQuery := squirrel.Select("track_id", "version", "object_key", "etag", "size", "uploaded_at", "is_current").
+		From("s3Version").
+		Where(squirrel.Eq{"track_id": trackID}).
+		OrderBy("uploaded_at DESC", "version").
 		PlaceholderFormat(squirrel.Dollar)
 
-	// Convert the insert query to SQL and arguments
-	sql, args, err := insertQuery.ToSql()
+	sql, args, err := selectQuery.ToSql()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	_, err = c.Pool.Exec(ctx, sql, args...)
+	var versions []model.TrackVersion
+	for rows.Next() {
+		var version model.TrackVersion
+		if err = rows.Scan(&version.TrackID, &version.VersionID, &version.ObjectKey, &version.ETag,
+			&version.Size, &version.UploadedAt, &version.Current); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// GetTrackIDsByObjectKey returns the tracks whose current version is a
+// version of the object.
+func (c *Client) GetTrackIDsByObjectKey(ctx context.Context, objectKey string) ([]string, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTrackIDsByObjectKey")
+	defer span.End()
+
+	return c.queryTrackIDs(ctx, squirrel.Eq{"object_key": objectKey, "is_current": true})
+}
+
+// GetTrackIDsByVersion returns the tracks that have had the object version.
+func (c *Client) GetTrackIDsByVersion(ctx context.Context, version string) ([]string, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTrackIDsByVersion")
+	defer span.End()
+
+	return c.queryTrackIDs(ctx, squirrel.Eq{"version": version})
+}
+
+func (c *Client) queryTrackIDs(ctx context.Context, where squirrel.Eq) ([]string, error) {
+	selectQuery := squirrel.Select("track_id::text").
+		From("s3Version").
+		Where(where).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := selectQuery.ToSql()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	rows, err := c.conn(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	var trackIDs []string
+	for rows.Next() {
+		var trackID string
+		if err = rows.Scan(&trackID); err != nil {
+			return nil, err
+		}
+		trackIDs = append(trackIDs, trackID)
+	}
+	return trackIDs, rows.Err()
 }
 
+// LockObjectKey runs fn in a transaction that holds an advisory lock on the
+// object key, so that the versions of an object are recorded one at a time
+// across instances. The queries made with the context fn gets are part of the
+// transaction, fn must not wait on anything but the database.
+func (c *Client) LockObjectKey(ctx context.Context, objectKey string, fn func(ctx context.Context) error) error {
+	return c.ExecuteInTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('s3version'), hashtext($1))", objectKey); err != nil {
+			return err
+		}
+		return fn(withTx(ctx, tx))
+	})
+}
+
+// AddS3Version records the object version of the track and makes it the
+// current one. Recording a version again updates it.
+func (c *Client) AddS3Version(ctx context.Context, version *model.TrackVersion) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "AddS3Version")
+	defer span.End()
+
+	demoteQuery := squirrel.Update("s3Version").
+		Set("is_current", false).
+		Where(squirrel.Eq{"track_id": version.TrackID, "is_current": true}).
+		Where(squirrel.NotEq{"version": version.VersionID}).
+		PlaceholderFormat(squirrel.Dollar)
+	upsertQuery := squirrel.Insert("s3Version").
+		Columns("track_id", "version", "object_key", "etag", "size", "uploaded_at", "is_current").
+		Values(version.TrackID, version.VersionID, version.ObjectKey, version.ETag, version.Size, version.UploadedAt, true).
+		Suffix("ON CONFLICT (track_id, version) DO UPDATE SET object_key = EXCLUDED.object_key, etag = EXCLUDED.etag, " +
+			"size = EXCLUDED.size, uploaded_at = EXCLUDED.uploaded_at, is_current = TRUE").
+		PlaceholderFormat(squirrel.Dollar)
+
+	// The current version is unique per track, demote the old one first
+	return c.ExecuteInTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ExecuteSQL(ctx, tx, demoteQuery); err != nil {
+			return err
+		}
+		return ExecuteSQL(ctx, tx, upsertQuery)
+	})
+}
+
+// RestoreS3Version makes a recorded version of the track its current one
+// again, pgx.ErrNoRows when the track has no such version.
+func (c *Client) RestoreS3Version(ctx context.Context, trackID, version string) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "RestoreS3Version")
+	defer span.End()
+
+	demoteQuery := squirrel.Update("s3Version").
+		Set("is_current", false).
+		Where(squirrel.Eq{"track_id": trackID, "is_current": true}).
+		PlaceholderFormat(squirrel.Dollar)
+	promoteQuery := squirrel.Update("s3Version").
+		Set("is_current", true).
+		Where(squirrel.Eq{"track_id": trackID, "version": version}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	return c.ExecuteInTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ExecuteSQL(ctx, tx, demoteQuery); err != nil {
+			return err
+		}
+		sql, args, err := promoteQuery.ToSql()
+		if err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// DeleteS3Version forgets the object version. The tracks it was the current
+// version of fall back to their latest remaining one.
 func (c *Client) DeleteS3Version(ctx context.Context, version string) error {
 	tracer := GetTracer(ctx)
 	_, span := tracer.Start(ctx, "DeleteS3Version")
@@ -84,16 +234,65 @@ func (c *Client) DeleteS3Version(ctx context.Context, version string) error {
 		Where(squirrel.Eq{"version": version}).
 		PlaceholderFormat(squirrel.Dollar)
 
-	// Convert the delete query to SQL and arguments
-	sql, args, err := deleteQuery.ToSql()
+	return c.ExecuteInTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ExecuteSQL(ctx, tx, deleteQuery); err != nil {
+			return err
+		}
+		return ExecuteSQL(ctx, tx, squirrel.Expr(promoteLatestVersions))
+	})
+}
+
+// GetS3VersionsWithoutObjectKey returns the object versions recorded before
+// the versions kept their object, which have no object key.
+func (c *Client) GetS3VersionsWithoutObjectKey(ctx context.Context) ([]string, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetS3VersionsWithoutObjectKey")
+	defer span.End()
+
+	sql, args, err := squirrel.Select("DISTINCT version").
+		From("s3Version").
+		Where(squirrel.Eq{"object_key": ""}).
+		PlaceholderFormat(squirrel.Dollar).
+		ToSql()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	_, err = c.Pool.Exec(ctx, sql, args...)
+	var versions []string
+	for rows.Next() {
+		var version string
+		if err = rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// SetS3VersionObject fills in the object of the recorded version that has no
+// object key. The current version of the tracks stays as it is.
+func (c *Client) SetS3VersionObject(ctx context.Context, version *model.TrackVersion) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "SetS3VersionObject")
+	defer span.End()
+
+	updateQuery := squirrel.Update("s3Version").
+		Set("object_key", version.ObjectKey).
+		Set("etag", version.ETag).
+		Set("size", version.Size).
+		Set("uploaded_at", version.UploadedAt).
+		Where(squirrel.Eq{"version": version.VersionID, "object_key": ""}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := updateQuery.ToSql()
 	if err != nil {
 		return err
 	}
-
-	return nil
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
 }
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"s3MediaStreamer/app/model"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type SearchRepositoryInterface interface {
+	SearchTracks(ctx context.Context, query model.SearchQuery) ([]model.TrackHit, error)
+	SearchAlbums(ctx context.Context, query model.SearchQuery) ([]model.AlbumHit, error)
+	SearchArtists(ctx context.Context, query model.SearchQuery) ([]model.ArtistHit, error)
+	SearchPlaylists(ctx context.Context, query model.SearchQuery) ([]model.PlaylistHit, error)
+}
+
+// trackDocument is the expression the full-text index of the tracks is built on.
+const trackDocument = "tracks_search_document(t.title, t.artist, t.album, t.composer, t.lyrics)"
+
+// albumDocument is the expression the full-text index of the albums is built on.
+const albumDocument = "albums_search_document(al.title, al.artist_name)"
+
+// searchJoin parses the term once per query: s.query is the full-text query,
+// s.term the raw term for the trigram matches, s.whole and s.fragment the
+// ts_headline options for short fields and for the lyrics.
+func searchJoin(query model.SearchQuery) squirrel.SelectBuilder {
+	whole := fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", query.StartSel, query.StopSel)
+	fragment := fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=1, MaxWords=20, MinWords=8", query.StartSel, query.StopSel)
+	return squirrel.Select().JoinClause(
+		"CROSS JOIN (SELECT websearch_to_tsquery('simple', ?::text) AS query, ?::text AS term, ?::text AS whole, ?::text AS fragment) s",
+		query.Term, query.Term, whole, fragment)
+}
+
+// SearchTracks returns the tracks whose title, artist, album, composer or
+// lyrics contain the words of the term, or whose title, artist or album are
+// close to it, best match first.
+func (c *Client) SearchTracks(ctx context.Context, query model.SearchQuery) ([]model.TrackHit, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "SearchTracks")
+	defer span.End()
+
+	selectQuery := searchJoin(query).
+		Columns("t._id",
+			"ts_rank_cd("+trackDocument+", s.query) + "+
+				"GREATEST(word_similarity(s.term, t.title), word_similarity(s.term, t.artist), word_similarity(s.term, t.album)) AS rank",
+			"ts_headline('simple', concat_ws(' — ', NULLIF(t.title, ''), NULLIF(t.artist, ''), NULLIF(t.album, '')), s.query, s.whole)",
+			"CASE WHEN to_tsvector('simple', coalesce(t.lyrics, '')) @@ s.query "+
+				"THEN ts_headline('simple', t.lyrics, s.query, s.fragment) ELSE '' END").
+		From("tracks t").
+		Where(trackDocument+" @@ s.query OR s.term <% t.title OR s.term <% t.artist OR s.term <% t.album").
+		OrderBy("rank DESC", "t.title").
+		Limit(uint64(query.Limit))
+
+	rows, err := c.querySearch(ctx, selectQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []model.TrackHit
+	var ids []uuid.UUID
+	for rows.Next() {
+		var hit model.TrackHit
+		if err = rows.Scan(&hit.Track.ID, &hit.Rank, &hit.Snippet, &hit.LyricsSnippet); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+		ids = append(ids, hit.Track.ID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return nil, nil
+	}
+
+	tracks, err := c.ExecuteSelectQuery(ctx, squirrel.Select("*").From("tracks").
+		Where(squirrel.Eq{"_id": ids}).
+		PlaceholderFormat(squirrel.Dollar))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]model.Track, len(tracks))
+	for _, track := range tracks {
+		byID[track.ID] = track
+	}
+	// Tracks deleted between the two queries are left out.
+	found := hits[:0]
+	for _, hit := range hits {
+		if track, ok := byID[hit.Track.ID]; ok {
+			hit.Track = track
+			found = append(found, hit)
+		}
+	}
+	return found, nil
+}
+
+// SearchAlbums returns the albums whose title and artist contain the words of
+// the term, or whose title is close to it, best match first.
+func (c *Client) SearchAlbums(ctx context.Context, query model.SearchQuery) ([]model.AlbumHit, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "SearchAlbums")
+	defer span.End()
+
+	selectQuery := searchJoin(query).
+		Columns("al._id", "al.title", "al.artist_id", "a.name", "al.year", "al.artwork",
+			"(SELECT COUNT(*) FROM tracks t WHERE t.album_id = al._id)",
+			"ts_rank("+albumDocument+", s.query) + word_similarity(s.term, al.title) AS rank",
+			"ts_headline('simple', al.title || ' — ' || a.name, s.query, s.whole)").
+		From("albums al").
+		Join("artists a ON a._id = al.artist_id").
+		Where(albumDocument+" @@ s.query OR s.term <% al.title").
+		OrderBy("rank DESC", "al.normalized_title").
+		Limit(uint64(query.Limit))
+
+	rows, err := c.querySearch(ctx, selectQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []model.AlbumHit
+	for rows.Next() {
+		var hit model.AlbumHit
+		album := &hit.Album
+		err = rows.Scan(&album.ID, &album.Title, &album.ArtistID, &album.Artist, &album.Year, &album.Artwork,
+			&album.TrackCount, &hit.Rank, &hit.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// SearchArtists returns the artists whose name contains the words of the
+// term or is close to it, best match first.
+func (c *Client) SearchArtists(ctx context.Context, query model.SearchQuery) ([]model.ArtistHit, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "SearchArtists")
+	defer span.End()
+
+	selectQuery := searchJoin(query).
+		Columns("a._id", "a.name",
+			"(SELECT COUNT(*) FROM albums al WHERE al.artist_id = a._id)",
+			"(SELECT COUNT(*) FROM tracks t WHERE t.artist_id = a._id)",
+			"ts_rank(to_tsvector('simple', a.name), s.query) + word_similarity(s.term, a.name) AS rank",
+			"ts_headline('simple', a.name, s.query, s.whole)").
+		From("artists a").
+		Where("to_tsvector('simple', a.name) @@ s.query OR s.term <% a.name").
+		OrderBy("rank DESC", "a.normalized_name").
+		Limit(uint64(query.Limit))
+
+	rows, err := c.querySearch(ctx, selectQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []model.ArtistHit
+	for rows.Next() {
+		var hit model.ArtistHit
+		artist := &hit.Artist
+		err = rows.Scan(&artist.ID, &artist.Name, &artist.AlbumCount, &artist.TrackCount, &hit.Rank, &hit.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// SearchPlaylists returns the playlists whose title or description contain
+// the words of the term, or whose title is close to it, best match first.
+func (c *Client) SearchPlaylists(ctx context.Context, query model.SearchQuery) ([]model.PlaylistHit, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "SearchPlaylists")
+	defer span.End()
+
+	// The expression of the full-text index of the playlists.
+	document := "to_tsvector('simple', coalesce(p.title, '') || ' ' || coalesce(p.description, ''))"
+	weighted := "setweight(to_tsvector('simple', coalesce(p.title, '')), 'A') || " +
+		"setweight(to_tsvector('simple', coalesce(p.description, '')), 'C')"
+	selectQuery := searchJoin(query).
+		Columns("p._id", "p.created_at", "p.title", "p.description", "p._creator_user",
+			"ts_rank("+weighted+", s.query) + word_similarity(s.term, p.title) AS rank",
+			"ts_headline('simple', p.title, s.query, s.whole)").
+		From("playlists p").
+		Where("("+document+" @@ s.query OR s.term <% p.title)").
+		OrderBy("rank DESC", "p.title").
+		Limit(uint64(query.Limit))
+	if query.Owner != "" {
+		selectQuery = selectQuery.Where(squirrel.Eq{"p._creator_user": query.Owner})
+	}
+
+	rows, err := c.querySearch(ctx, selectQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []model.PlaylistHit
+	for rows.Next() {
+		var hit model.PlaylistHit
+		playlist := &hit.Playlist
+		err = rows.Scan(&playlist.ID, &playlist.CreatedAt, &playlist.Title, &playlist.Description, &playlist.CreatorUser,
+			&hit.Rank, &hit.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+func (c *Client) querySearch(ctx context.Context, selectQuery squirrel.SelectBuilder) (pgx.Rows, error) {
+	sql, args, err := selectQuery.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	return c.Pool.Query(ctx, sql, args...)
+}
@@ -2,9 +2,11 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"s3MediaStreamer/app/model"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/emirpasic/gods/maps/treemap"
@@ -13,14 +15,26 @@ import (
 
 const ChunkSize = 1000
 
+// identityDurationTolerance is how far apart the durations of two tracks with
+// the same artist, album and title may be for them to be the same track, the
+// decoders of the formats do not agree on the exact length.
+const identityDurationTolerance = 2 * time.Second
+
 type TracksRepositoryInterface interface {
 	CreateTracks(ctx context.Context, list []model.Track) error
-	GetTracks(ctx context.Context, offset, limit int, sortBy, sortOrder, filter, startT, endT string) ([]model.Track, int, error)
+	GetTracks(ctx context.Context, offset, limit int, orderBy []string, where, after squirrel.Sqlizer, filter, startT, endT string,
+		analysis model.TrackAnalysisFilter, count model.TrackCount) ([]model.Track, int, error)
 	GetTracksByColumns(ctx context.Context, code, columns string) (*model.Track, error)
 	CleanTracks(ctx context.Context) error
 	DeleteTracksAll(ctx context.Context) error
 	UpdateTracks(ctx context.Context, track *model.Track) error
 	GetAllTracks(ctx context.Context) ([]model.Track, error)
+	GetTracksByAlbum(ctx context.Context, album, albumArtist string) ([]model.Track, error)
+	GetTracksWithoutAnalysis(ctx context.Context, limit int) ([]model.Track, error)
+	GetTracksWithoutContentHash(ctx context.Context, maxAttempts, limit int) ([]model.Track, error)
+	AddTrackContentHashAttempt(ctx context.Context, trackID string) error
+	GetTrackIDByIdentity(ctx context.Context, track *model.Track) (string, error)
+	SetTrackContentHash(ctx context.Context, trackIDs []string, hash string) error
 	AddTrackToPlaylist(ctx context.Context, playlistID, referenceType, referenceID, parentPath string) error
 	RemoveTrackFromPlaylist(ctx context.Context, playlistID, trackID string) error
 	GetAllTracksByPositions(ctx context.Context, playlistID string) ([]model.Track, error)
@@ -42,7 +56,7 @@ func (c *Client) CreateTracks(ctx context.Context, list []model.Track) error {
 	}
 
 	// Start a transaction
-	tx, err := c.Pool.Begin(ctx)
+	tx, err := c.conn(ctx).Begin(ctx)
 	if err != nil {
 		return err
 	}
@@ -58,9 +72,20 @@ func (c *Client) CreateTracks(ctx context.Context, list []model.Track) error {
 		"_id", "created_at", "updated_at", "album", "album_artist",
 		"composer", "genre", "lyrics", "title", "artist", "year",
 		"comment", "disc", "disc_total", "track", "track_total",
-		"duration", "sample_rate", "bitrate",
+		"duration", "sample_rate", "bitrate", "mime_type", "artwork",
+		"loudness", "true_peak", "track_gain",
+		"bpm", "bpm_confidence", "musical_key", "key_confidence",
+		"cue_start", "cue_end",
+		"artist_id", "album_id", "genre_id", "content_hash",
 	)
 
+	// Link the tracks to their artist, album and genre
+	for i := range list {
+		if err = linkLibrary(ctx, tx, &list[i]); err != nil {
+			return err
+		}
+	}
+
 	// Add INSERT queries to the batch for each track
 	for _, track := range list {
 		// Build the insert query for each track
@@ -84,6 +109,21 @@ func (c *Client) CreateTracks(ctx context.Context, list []model.Track) error {
 			track.Duration,
 			track.SampleRate,
 			track.Bitrate,
+			track.MimeType,
+			track.Artwork,
+			track.Loudness,
+			track.TruePeak,
+			track.TrackGain,
+			track.BPM,
+			track.BPMConfidence,
+			track.Key,
+			track.KeyConfidence,
+			track.CueStart,
+			track.CueEnd,
+			track.ArtistID,
+			track.AlbumID,
+			track.GenreID,
+			track.ContentHash,
 		)
 	}
 	ib = ib.PlaceholderFormat(squirrel.Dollar)
@@ -99,7 +139,7 @@ func (c *Client) CreateTracks(ctx context.Context, list []model.Track) error {
 	batch.Queue(sql, args...)
 
 	// Execute the batch
-	results := c.Pool.SendBatch(ctx, batch)
+	results := tx.SendBatch(ctx, batch)
 
 	// Check for errors in the batch execution
 	if err = results.Close(); err != nil {
@@ -115,11 +155,17 @@ func (c *Client) CreateTracks(ctx context.Context, list []model.Track) error {
 	return nil
 }
 
-// GetTracks retrieves a list of tracks with pagination and filtering.
+// GetTracks retrieves a list of tracks with pagination and filtering. The
+// tracks start after the after condition of a cursor when it is not nil. The
+// total leaves the cursor out and is counted as count says, -1 for none.
 func (c *Client) GetTracks(
 	ctx context.Context,
 	offset, limit int,
-	sortBy, sortOrder, filter, startT, endT string,
+	orderBy []string,
+	where, after squirrel.Sqlizer,
+	filter, startT, endT string,
+	analysis model.TrackAnalysisFilter,
+	count model.TrackCount,
 ) ([]model.Track, int, error) {
 	tracer := GetTracer(ctx)
 	_, span := tracer.Start(ctx, "GetTracks")
@@ -130,11 +176,28 @@ func (c *Client) GetTracks(
 	// Apply filtering
 	queryBuilder = buildFilterClause(queryBuilder, filter)
 
+	// Apply the parsed filter query
+	if where != nil {
+		queryBuilder = queryBuilder.Where(where)
+	}
+
 	// Apply time-based filtering
 	queryBuilder = applyTimeFilters(queryBuilder, startT, endT)
 
+	// Apply tempo and key filtering
+	queryBuilder = applyAnalysisFilters(queryBuilder, analysis)
+
+	// The list the total is counted of
+	listBuilder := queryBuilder
+	filtered := where != nil || filter != "" || startT != "" || endT != "" || analysis != model.TrackAnalysisFilter{}
+
+	// Apply the cursor
+	if after != nil {
+		queryBuilder = queryBuilder.Where(after)
+	}
+
 	// Apply sorting
-	queryBuilder = buildSortClause(queryBuilder, sortBy, sortOrder)
+	queryBuilder = queryBuilder.OrderBy(orderBy...)
 
 	// Apply pagination
 	queryBuilder = applyPagination(queryBuilder, offset, limit)
@@ -165,6 +228,23 @@ func (c *Client) GetTracks(
 			&track.Disc, &track.DiscTotal, &track.Track,
 			&track.TrackTotal, &track.Duration, &track.SampleRate,
 			&track.Bitrate,
+			&track.MimeType,
+			&track.Artwork,
+			&track.Loudness,
+			&track.TruePeak,
+			&track.TrackGain,
+			&track.BPM,
+			&track.BPMConfidence,
+			&track.Key,
+			&track.KeyConfidence,
+			&track.CueStart,
+			&track.CueEnd,
+			&track.ArtistID,
+			&track.AlbumID,
+			&track.GenreID,
+			&track.ContentHash,
+			&track.ContentHashAttempts,
+			&track.IngestedAt,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -172,8 +252,12 @@ func (c *Client) GetTracks(
 		tracks = append(tracks, track)
 	}
 
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
 	// Get total count
-	totalRows, countErr := c.GetTotalTrackCount(queryBuilder)
+	totalRows, countErr := c.countTracks(ctx, listBuilder, filtered, count)
 	if countErr != nil {
 		return nil, 0, countErr
 	}
@@ -215,6 +299,23 @@ func (c *Client) GetTracksByColumns(ctx context.Context, code, columns string) (
 		&track.Disc, &track.DiscTotal, &track.Track,
 		&track.TrackTotal, &track.Duration, &track.SampleRate,
 		&track.Bitrate,
+		&track.MimeType,
+		&track.Artwork,
+		&track.Loudness,
+		&track.TruePeak,
+		&track.TrackGain,
+		&track.BPM,
+		&track.BPMConfidence,
+		&track.Key,
+		&track.KeyConfidence,
+		&track.CueStart,
+		&track.CueEnd,
+		&track.ArtistID,
+		&track.AlbumID,
+		&track.GenreID,
+		&track.ContentHash,
+		&track.ContentHashAttempts,
+		&track.IngestedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -236,13 +337,13 @@ func (c *Client) CleanTracks(ctx context.Context) error {
 	generateSQLTracks = generateSQLTracks.Where("_id NOT IN (SELECT track_id FROM s3Version)")
 	generateSQLTracks = generateSQLTracks.PlaceholderFormat(squirrel.Dollar)
 
-	// Generate the SQL query and arguments
-	sql, args, err := generateSQLTracks.ToSql()
-	if err != nil {
-		return err
-	}
-
-	return c.ExecInTransaction(ctx, sql, args)
+	// Delete the tracks and the artists, albums and genres left without tracks
+	return c.ExecuteInTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ExecuteSQL(ctx, tx, generateSQLTracks); err != nil {
+			return err
+		}
+		return cleanLibrary(ctx, tx)
+	})
 }
 
 // DeleteTracksAll deletes all records from the "track" table.
@@ -254,14 +355,13 @@ func (c *Client) DeleteTracksAll(ctx context.Context) error {
 	// Create a new instance of squirrel.DeleteBuilder
 	generateSQLTracks := squirrel.Delete("").From("tracks")
 
-	// Generate the SQL query and arguments
-	sql, args, err := generateSQLTracks.ToSql()
-	if err != nil {
-		return err
-	}
-
-	// Execute the DELETE query within a transaction
-	return c.ExecInTransaction(ctx, sql, args)
+	// Execute the DELETE query and the library cleanup within a transaction
+	return c.ExecuteInTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ExecuteSQL(ctx, tx, generateSQLTracks); err != nil {
+			return err
+		}
+		return cleanLibrary(ctx, tx)
+	})
 }
 
 // UpdateTracks updates an track record in the "track" table based on the provided code.
@@ -270,59 +370,207 @@ func (c *Client) UpdateTracks(ctx context.Context, track *model.Track) error {
 	_, span := tracer.Start(ctx, "UpdateTracks")
 	defer span.End()
 
+	// Link the track to the artist, album and genre of its tags and drop the
+	// ones an edit of the tags left without tracks, within a transaction
+	return c.ExecuteInTransaction(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		if err := linkLibrary(ctx, tx, track); err != nil {
+			return err
+		}
+		if err := ExecuteSQL(ctx, tx, updateTrackQuery(track)); err != nil {
+			return err
+		}
+		return cleanLibrary(ctx, tx)
+	})
+}
+
+// updateTrackQuery is the UPDATE of every column of the track.
+func updateTrackQuery(track *model.Track) squirrel.Sqlizer {
 	// Create a new instance of squirrel.UpdateBuilder
 	updateBuilder := squirrel.Update("tracks")
 
 	// Add SET clauses to specify the columns and their new values
 	updateBuilder = updateBuilder.SetMap(map[string]interface{}{
-		"created_at":   track.CreatedAt,
-		"updated_at":   track.UpdatedAt,
-		"album":        track.Album,
-		"album_artist": track.AlbumArtist,
-		"composer":     track.Composer,
-		"genre":        track.Genre,
-		"lyrics":       track.Lyrics,
-		"title":        track.Title,
-		"artist":       track.Artist,
-		"year":         track.Year,
-		"comment":      track.Comment,
-		"disc":         track.Disc,
-		"disc_total":   track.DiscTotal,
-		"track":        track.Track,
-		"track_total":  track.TrackTotal,
-		"duration":     track.Duration,
-		"sample_rate":  track.SampleRate,
-		"bitrate":      track.Bitrate,
+		"created_at":     track.CreatedAt,
+		"updated_at":     track.UpdatedAt,
+		"album":          track.Album,
+		"album_artist":   track.AlbumArtist,
+		"composer":       track.Composer,
+		"genre":          track.Genre,
+		"lyrics":         track.Lyrics,
+		"title":          track.Title,
+		"artist":         track.Artist,
+		"year":           track.Year,
+		"comment":        track.Comment,
+		"disc":           track.Disc,
+		"disc_total":     track.DiscTotal,
+		"track":          track.Track,
+		"track_total":    track.TrackTotal,
+		"duration":       track.Duration,
+		"sample_rate":    track.SampleRate,
+		"bitrate":        track.Bitrate,
+		"mime_type":      track.MimeType,
+		"artwork":        track.Artwork,
+		"loudness":       track.Loudness,
+		"true_peak":      track.TruePeak,
+		"track_gain":     track.TrackGain,
+		"bpm":            track.BPM,
+		"bpm_confidence": track.BPMConfidence,
+		"musical_key":    track.Key,
+		"key_confidence": track.KeyConfidence,
+		"cue_start":      track.CueStart,
+		"cue_end":        track.CueEnd,
+		"artist_id":      track.ArtistID,
+		"album_id":       track.AlbumID,
+		"genre_id":       track.GenreID,
+		"content_hash":   track.ContentHash,
 	})
 
 	// Add a WHERE condition to identify the record to update based on the provided code
-	updateBuilder = updateBuilder.Where(squirrel.Eq{"_id": track.ID})
+	return updateBuilder.Where(squirrel.Eq{"_id": track.ID}).PlaceholderFormat(squirrel.Dollar)
+}
 
-	// Generate the SQL query and arguments
-	sql, args, err := updateBuilder.PlaceholderFormat(squirrel.Dollar).ToSql()
+func (c *Client) GetAllTracks(ctx context.Context) ([]model.Track, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetAllTracks")
+	defer span.End()
+
+	selectBuilder := squirrel.Select("*").
+		From("tracks").
+		Limit(ChunkSize) // Adjust the limit based on your requirements
+
+	return c.ExecuteSelectQuery(ctx, selectBuilder)
+}
+
+// GetTracksByAlbum returns the tracks of the album by the album artist.
+func (c *Client) GetTracksByAlbum(ctx context.Context, album, albumArtist string) ([]model.Track, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTracksByAlbum")
+	defer span.End()
+
+	selectBuilder := squirrel.Select("*").
+		From("tracks").
+		Where(squirrel.Eq{"album": album, "album_artist": albumArtist}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	return c.ExecuteSelectQuery(ctx, selectBuilder)
+}
+
+// GetTracksWithoutAnalysis returns up to limit tracks whose tempo and key were never estimated.
+func (c *Client) GetTracksWithoutAnalysis(ctx context.Context, limit int) ([]model.Track, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTracksWithoutAnalysis")
+	defer span.End()
+
+	selectBuilder := squirrel.Select("*").
+		From("tracks").
+		Where(squirrel.Eq{"bpm_confidence": nil}).
+		OrderBy("created_at").
+		Limit(uint64(limit)).
+		PlaceholderFormat(squirrel.Dollar)
+
+	return c.queryTracks(ctx, selectBuilder)
+}
+
+// GetTracksWithoutContentHash returns up to limit tracks whose audio payload
+// was never hashed and failed to hash fewer than maxAttempts times, first
+// ingested first. Tracks cut out by a CUE sheet share their file and are left
+// out.
+func (c *Client) GetTracksWithoutContentHash(ctx context.Context, maxAttempts, limit int) ([]model.Track, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTracksWithoutContentHash")
+	defer span.End()
+
+	selectBuilder := squirrel.Select("*").
+		From("tracks").
+		Where(squirrel.Eq{"content_hash": "", "cue_start": nil}).
+		Where(squirrel.Lt{"content_hash_attempts": maxAttempts}).
+		OrderBy("ingested_at", "_id").
+		Limit(uint64(limit)).
+		PlaceholderFormat(squirrel.Dollar)
+
+	return c.queryTracks(ctx, selectBuilder)
+}
+
+// AddTrackContentHashAttempt counts a failed attempt at hashing the audio
+// payload of the track.
+func (c *Client) AddTrackContentHashAttempt(ctx context.Context, trackID string) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "AddTrackContentHashAttempt")
+	defer span.End()
+
+	updateQuery := squirrel.Update("tracks").
+		Set("content_hash_attempts", squirrel.Expr("content_hash_attempts + 1")).
+		Where(squirrel.Eq{"_id": trackID}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := updateQuery.ToSql()
 	if err != nil {
 		return err
 	}
 
-	// Execute the UPDATE query
 	_, err = c.Pool.Exec(ctx, sql, args...)
-	if err != nil {
-		return err
+	return err
+}
+
+// GetTrackIDByIdentity returns the ID of a track that is the same as the
+// track, an empty string when there is none. A track is the same when its
+// audio payload has the same hash, or when it has the same artist, album and
+// title, ignoring case, and about the same duration. Tracks with the same
+// hash come first, then the first ingested.
+func (c *Client) GetTrackIDByIdentity(ctx context.Context, track *model.Track) (string, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTrackIDByIdentity")
+	defer span.End()
+
+	sameTags := squirrel.And{
+		squirrel.Expr("lower(artist) = lower(?)", track.Artist),
+		squirrel.Expr("lower(album) = lower(?)", track.Album),
+		squirrel.Expr("lower(title) = lower(?)", track.Title),
+		squirrel.Expr("duration BETWEEN ? AND ?", track.Duration-identityDurationTolerance, track.Duration+identityDurationTolerance),
+	}
+	var identity squirrel.Sqlizer = sameTags
+	if track.ContentHash != "" {
+		identity = squirrel.Or{squirrel.Eq{"content_hash": track.ContentHash}, sameTags}
 	}
+	selectQuery := squirrel.Select("_id::text").
+		From("tracks").
+		Where(identity).
+		OrderByClause("content_hash = ? DESC", track.ContentHash).
+		OrderBy("ingested_at").
+		Limit(1).
+		PlaceholderFormat(squirrel.Dollar)
 
-	return nil
+	sql, args, err := selectQuery.ToSql()
+	if err != nil {
+		return "", err
+	}
+	var id string
+	err = c.conn(ctx).QueryRow(ctx, sql, args...).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return id, err
 }
 
-func (c *Client) GetAllTracks(ctx context.Context) ([]model.Track, error) {
+// SetTrackContentHash stores the hash of the audio payload of the tracks,
+// except for the ones cut out by a CUE sheet.
+func (c *Client) SetTrackContentHash(ctx context.Context, trackIDs []string, hash string) error {
 	tracer := GetTracer(ctx)
-	_, span := tracer.Start(ctx, "GetAllTracks")
+	_, span := tracer.Start(ctx, "SetTrackContentHash")
 	defer span.End()
 
-	selectBuilder := squirrel.Select("*").
-		From("tracks").
-		Limit(ChunkSize) // Adjust the limit based on your requirements
+	updateQuery := squirrel.Update("tracks").
+		Set("content_hash", hash).
+		Where(squirrel.Eq{"_id": trackIDs, "cue_start": nil}).
+		PlaceholderFormat(squirrel.Dollar)
 
-	return c.ExecuteSelectQuery(ctx, selectBuilder)
+	sql, args, err := updateQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.conn(ctx).Exec(ctx, sql, args...)
+	return err
 }
 
 // AddTrackToPlaylist inserts a track or playlist into a playlist_tracks table supporting nested structures with LTREE.
@@ -491,6 +739,23 @@ func (c *Client) GetAllTracksByPositions(ctx context.Context, playlistID string)
 			&track.Disc, &track.DiscTotal, &track.Track,
 			&track.TrackTotal, &track.Duration, &track.SampleRate,
 			&track.Bitrate,
+			&track.MimeType,
+			&track.Artwork,
+			&track.Loudness,
+			&track.TruePeak,
+			&track.TrackGain,
+			&track.BPM,
+			&track.BPMConfidence,
+			&track.Key,
+			&track.KeyConfidence,
+			&track.CueStart,
+			&track.CueEnd,
+			&track.ArtistID,
+			&track.AlbumID,
+			&track.GenreID,
+			&track.ContentHash,
+			&track.ContentHashAttempts,
+			&track.IngestedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -513,55 +778,48 @@ func buildFilterClause(queryBuilder squirrel.SelectBuilder, filter string) squir
 
 	filterColumns := []string{"album_artist", "composer", "artist"}
 	var filterExprs []string
+	var args []interface{}
 
+	// The placeholders are numbered by squirrel, so that the filter combines
+	// with the other conditions of the query.
+	operator := "ILIKE"
 	if strings.HasPrefix(filter, "=") {
 		filter = strings.TrimPrefix(filter, "=")
-		for _, col := range filterColumns {
-			filterExpr := fmt.Sprintf("%s = $%d", col, 1)
-			filterExprs = append(filterExprs, filterExpr)
-		}
+		operator = "="
 	} else {
 		filter = "%" + filter + "%"
-		for _, col := range filterColumns {
-			filterExpr := fmt.Sprintf("%s ILIKE $%d", col, 1)
-			filterExprs = append(filterExprs, filterExpr)
-		}
+	}
+	for _, col := range filterColumns {
+		filterExprs = append(filterExprs, fmt.Sprintf("%s %s ?", col, operator))
+		args = append(args, filter)
 	}
 
 	orCondition := strings.Join(filterExprs, " OR ")
-	queryBuilder = queryBuilder.Where(orCondition, filter)
+	queryBuilder = queryBuilder.Where("("+orCondition+")", args...)
 	return queryBuilder
 }
 
 // Helper function to apply time filters.
 func applyTimeFilters(queryBuilder squirrel.SelectBuilder, startT, endT string) squirrel.SelectBuilder {
 	if startT != "" {
-		queryBuilder = queryBuilder.Where("updated_at >= $1", startT)
+		queryBuilder = queryBuilder.Where("updated_at >= ?", startT)
 	}
 	if endT != "" {
-		queryBuilder = queryBuilder.Where("updated_at <= $2", endT)
+		queryBuilder = queryBuilder.Where("updated_at <= ?", endT)
 	}
 	return queryBuilder
 }
 
-// Helper function to apply sorting.
-func buildSortClause(queryBuilder squirrel.SelectBuilder, sortBy, sortOrder string) squirrel.SelectBuilder {
-	// Define allowed sort columns and order directions
-	allowedSortColumns := map[string]bool{
-		"created_at":   true,
-		"updated_at":   true,
-		"album":        true,
-		"album_artist": true,
-		"title":        true,
+// Helper function to apply tempo and key filters.
+func applyAnalysisFilters(queryBuilder squirrel.SelectBuilder, analysis model.TrackAnalysisFilter) squirrel.SelectBuilder {
+	if analysis.BPMMin > 0 {
+		queryBuilder = queryBuilder.Where(squirrel.GtOrEq{"bpm": analysis.BPMMin})
 	}
-	allowedSortOrders := map[string]bool{
-		"ASC":  true,
-		"DESC": true,
+	if analysis.BPMMax > 0 {
+		queryBuilder = queryBuilder.Where(squirrel.LtOrEq{"bpm": analysis.BPMMax})
 	}
-
-	// Validate sortBy and sortOrder against allowed values
-	if allowedSortColumns[sortBy] && allowedSortOrders[sortOrder] {
-		queryBuilder = queryBuilder.OrderBy(fmt.Sprintf("%s %s", sortBy, sortOrder))
+	if analysis.Key != "" {
+		queryBuilder = queryBuilder.Where(squirrel.Eq{"musical_key": analysis.Key})
 	}
 	return queryBuilder
 }
@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"s3MediaStreamer/app/model"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type TusRepositoryInterface interface {
+	CreateTusUpload(ctx context.Context, upload *model.TusUpload) error
+	GetTusUpload(ctx context.Context, id string) (*model.TusUpload, error)
+	GetExpiredTusUploads(ctx context.Context, now time.Time) ([]model.TusUpload, error)
+	LeaseTusUpload(ctx context.Context, id string, token uuid.UUID, until time.Time) (bool, error)
+	ReleaseTusUpload(ctx context.Context, id string, token uuid.UUID) error
+	UpdateTusUpload(ctx context.Context, upload *model.TusUpload) error
+	DeleteTusUpload(ctx context.Context, id string) error
+}
+
+var tusUploadColumns = []string{
+	"_id", "object_key", "upload_id", "length", "upload_offset", "metadata", "parts", "pending",
+	"COALESCE(track_ids, '{}')", "ingest_error", "created_at", "expires_at", "completed_at",
+}
+
+// CreateTusUpload stores a new upload.
+func (c *Client) CreateTusUpload(ctx context.Context, upload *model.TusUpload) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "CreateTusUpload")
+	defer span.End()
+
+	insertQuery := squirrel.Insert("tus_uploads").
+		Columns("_id", "object_key", "upload_id", "length", "metadata", "created_at", "expires_at").
+		Values(upload.ID, upload.ObjectKey, upload.UploadID, upload.Length, upload.Metadata, upload.CreatedAt, upload.ExpiresAt).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := insertQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// GetTusUpload returns the upload with the ID, pgx.ErrNoRows when there is none.
+func (c *Client) GetTusUpload(ctx context.Context, id string) (*model.TusUpload, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTusUpload")
+	defer span.End()
+
+	uploads, err := c.queryTusUploads(ctx, squirrel.Select(tusUploadColumns...).
+		From("tus_uploads").
+		Where(squirrel.Eq{"_id": id}))
+	if err != nil {
+		return nil, err
+	}
+	if len(uploads) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return &uploads[0], nil
+}
+
+// GetExpiredTusUploads returns the uploads that expired before now.
+func (c *Client) GetExpiredTusUploads(ctx context.Context, now time.Time) ([]model.TusUpload, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetExpiredTusUploads")
+	defer span.End()
+
+	return c.queryTusUploads(ctx, squirrel.Select(tusUploadColumns...).
+		From("tus_uploads").
+		Where(squirrel.Lt{"expires_at": now}))
+}
+
+// LeaseTusUpload takes the lease of the upload for the token until the time,
+// or renews it when the token holds it, so that one request at a time writes
+// to it across instances. It reports false, without waiting, when another
+// token holds an unexpired lease or the upload does not exist.
+func (c *Client) LeaseTusUpload(ctx context.Context, id string, token uuid.UUID, until time.Time) (bool, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "LeaseTusUpload")
+	defer span.End()
+
+	// A row another request is leasing at the same time is skipped
+	leased := squirrel.Select("_id").
+		From("tus_uploads").
+		Where(squirrel.Eq{"_id": id}).
+		Where(squirrel.Or{
+			squirrel.Eq{"lease_token": nil},
+			squirrel.Eq{"lease_token": token},
+			squirrel.Expr("leased_until < now()"),
+		}).
+		Suffix("FOR UPDATE SKIP LOCKED")
+	updateQuery := squirrel.Update("tus_uploads").
+		Set("lease_token", token).
+		Set("leased_until", until).
+		Where(leased.Prefix("_id IN (").Suffix(")")).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := updateQuery.ToSql()
+	if err != nil {
+		return false, err
+	}
+	tag, err := c.Pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseTusUpload gives up the lease of the upload the token holds.
+func (c *Client) ReleaseTusUpload(ctx context.Context, id string, token uuid.UUID) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "ReleaseTusUpload")
+	defer span.End()
+
+	updateQuery := squirrel.Update("tus_uploads").
+		Set("lease_token", nil).
+		Set("leased_until", nil).
+		Where(squirrel.Eq{"_id": id, "lease_token": token}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := updateQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// UpdateTusUpload stores the progress of the upload.
+func (c *Client) UpdateTusUpload(ctx context.Context, upload *model.TusUpload) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "UpdateTusUpload")
+	defer span.End()
+
+	parts := upload.Parts
+	if parts == nil {
+		parts = []model.TusPart{}
+	}
+	updateQuery := squirrel.Update("tus_uploads").
+		SetMap(map[string]interface{}{
+			"upload_offset": upload.Offset,
+			"parts":         parts,
+			"pending":       upload.Pending,
+			"track_ids":     upload.TrackIDs,
+			"ingest_error":  upload.IngestError,
+			"expires_at":    upload.ExpiresAt,
+			"completed_at":  upload.CompletedAt,
+		}).
+		Where(squirrel.Eq{"_id": upload.ID}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := updateQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// DeleteTusUpload removes the upload.
+func (c *Client) DeleteTusUpload(ctx context.Context, id string) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "DeleteTusUpload")
+	defer span.End()
+
+	deleteQuery := squirrel.Delete("tus_uploads").
+		Where(squirrel.Eq{"_id": id}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := deleteQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+func (c *Client) queryTusUploads(ctx context.Context, selectQuery squirrel.SelectBuilder) ([]model.TusUpload, error) {
+	sql, args, err := selectQuery.PlaceholderFormat(squirrel.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []model.TusUpload
+	for rows.Next() {
+		var upload model.TusUpload
+		if err = rows.Scan(&upload.ID, &upload.ObjectKey, &upload.UploadID, &upload.Length, &upload.Offset,
+			&upload.Metadata, &upload.Parts, &upload.Pending, &upload.TrackIDs, &upload.IngestError,
+			&upload.CreatedAt, &upload.ExpiresAt, &upload.CompletedAt); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, rows.Err()
+}
@@ -29,51 +29,11 @@ func (c *Client) ExecuteSelectQuery(ctx context.Context, selectBuilder squirrel.
 	var tracks []model.Track
 
 	for {
-		// Generate the SQL query and arguments
-		sql, args, err := selectBuilder.ToSql()
+		chunk, err := c.queryTracks(ctx, selectBuilder)
 		if err != nil {
 			return nil, err
 		}
 
-		// Execute the SELECT query
-		rows, err := c.Pool.Query(ctx, sql, args...)
-		if err != nil {
-			return nil, err
-		}
-
-		var chunk []model.Track
-
-		for rows.Next() {
-			var track model.Track
-			err = rows.Scan(
-				&track.ID,
-				&track.CreatedAt,
-				&track.UpdatedAt,
-				&track.Album,
-				&track.AlbumArtist,
-				&track.Composer,
-				&track.Genre,
-				&track.Lyrics,
-				&track.Title,
-				&track.Artist,
-				&track.Year,
-				&track.Comment,
-				&track.Disc,
-				&track.DiscTotal,
-				&track.Track,
-				&track.TrackTotal,
-				&track.Duration,
-				&track.SampleRate,
-				&track.Bitrate,
-			)
-			if err != nil {
-				return nil, err
-			}
-			chunk = append(chunk, track)
-		}
-
-		rows.Close()
-
 		if len(chunk) == 0 {
 			// No more records to fetch
 			break
@@ -88,6 +48,72 @@ func (c *Client) ExecuteSelectQuery(ctx context.Context, selectBuilder squirrel.
 	return tracks, nil
 }
 
+// queryTracks runs the select query once and returns the tracks it selects.
+// Queries with a LIMIT of their own use it, ExecuteSelectQuery would page past
+// the limit.
+func (c *Client) queryTracks(ctx context.Context, selectBuilder squirrel.SelectBuilder) ([]model.Track, error) {
+	// Generate the SQL query and arguments
+	sql, args, err := selectBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute the SELECT query
+	rows, err := c.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tracks []model.Track
+	for rows.Next() {
+		var track model.Track
+		err = rows.Scan(
+			&track.ID,
+			&track.CreatedAt,
+			&track.UpdatedAt,
+			&track.Album,
+			&track.AlbumArtist,
+			&track.Composer,
+			&track.Genre,
+			&track.Lyrics,
+			&track.Title,
+			&track.Artist,
+			&track.Year,
+			&track.Comment,
+			&track.Disc,
+			&track.DiscTotal,
+			&track.Track,
+			&track.TrackTotal,
+			&track.Duration,
+			&track.SampleRate,
+			&track.Bitrate,
+			&track.MimeType,
+			&track.Artwork,
+			&track.Loudness,
+			&track.TruePeak,
+			&track.TrackGain,
+			&track.BPM,
+			&track.BPMConfidence,
+			&track.Key,
+			&track.KeyConfidence,
+			&track.CueStart,
+			&track.CueEnd,
+			&track.ArtistID,
+			&track.AlbumID,
+			&track.GenreID,
+			&track.ContentHash,
+			&track.ContentHashAttempts,
+			&track.IngestedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, rows.Err()
+}
+
 func (c *Client) Connect(_ *logs.Logger) error {
 	if c.Pool != nil {
 		conn, connErr := c.Pool.Acquire(context.Background())
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"s3MediaStreamer/app/model"
+
+	"github.com/Masterminds/squirrel"
+)
+
+type WaveformRepositoryInterface interface {
+	GetWaveform(ctx context.Context, trackID string) (string, []byte, error)
+	SaveWaveform(ctx context.Context, trackID, version string, peaks []byte) error
+	GetTracksWithoutWaveform(ctx context.Context, after string, mimeTypes []string, limit int) ([]model.Track, error)
+}
+
+// GetWaveform returns the S3 version and the encoded peaks stored for the track,
+// pgx.ErrNoRows if none were computed yet.
+func (c *Client) GetWaveform(ctx context.Context, trackID string) (string, []byte, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetWaveform")
+	defer span.End()
+
+	selectQuery := squirrel.Select("version", "peaks").
+		From("track_waveforms").
+		Where(squirrel.Eq{"track_id": trackID}).
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := selectQuery.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var version string
+	var peaks []byte
+	if err = c.Pool.QueryRow(ctx, sql, args...).Scan(&version, &peaks); err != nil {
+		return "", nil, err
+	}
+	return version, peaks, nil
+}
+
+// SaveWaveform stores the peaks of a track version, replacing the ones of the previous version.
+func (c *Client) SaveWaveform(ctx context.Context, trackID, version string, peaks []byte) error {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "SaveWaveform")
+	defer span.End()
+
+	insertQuery := squirrel.Insert("track_waveforms").
+		Columns("track_id", "version", "peaks").
+		Values(trackID, version, peaks).
+		Suffix("ON CONFLICT (track_id) DO UPDATE SET version = EXCLUDED.version, peaks = EXCLUDED.peaks, created_at = now()").
+		PlaceholderFormat(squirrel.Dollar)
+
+	sql, args, err := insertQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Pool.Exec(ctx, sql, args...)
+	return err
+}
+
+// GetTracksWithoutWaveform returns up to limit tracks without stored peaks
+// whose ID sorts after the given one, in ID order, so that a backfill can go
+// on where it stopped. Only tracks of the MIME types, or of an unknown one,
+// are returned.
+func (c *Client) GetTracksWithoutWaveform(ctx context.Context, after string, mimeTypes []string, limit int) ([]model.Track, error) {
+	tracer := GetTracer(ctx)
+	_, span := tracer.Start(ctx, "GetTracksWithoutWaveform")
+	defer span.End()
+
+	selectBuilder := squirrel.Select("tracks.*").
+		From("tracks").
+		LeftJoin("track_waveforms w ON w.track_id = tracks._id").
+		Where(squirrel.Eq{"w.track_id": nil}).
+		Where(squirrel.Or{squirrel.Eq{"tracks.mime_type": mimeTypes}, squirrel.Eq{"tracks.mime_type": ""}}).
+		OrderBy("tracks._id").
+		Limit(uint64(limit)).
+		PlaceholderFormat(squirrel.Dollar)
+	if after != "" {
+		selectBuilder = selectBuilder.Where(squirrel.Gt{"tracks._id": after})
+	}
+
+	return c.queryTracks(ctx, selectBuilder)
+}
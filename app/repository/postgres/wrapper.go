@@ -6,15 +6,41 @@ import (
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // TransactionFunc defines the signature of the function that will be executed within a transaction.
 type TransactionFunc func(ctx context.Context, tx pgx.Tx) error
 
+// dbConn runs the queries of the client, the pool or a transaction.
+type dbConn interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+// withTx returns a context whose queries run in the transaction.
+func withTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// conn returns the transaction the context carries, the pool when it carries
+// none.
+func (c *Client) conn(ctx context.Context) dbConn {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return c.Pool
+}
+
 // ExecuteInTransaction executes a function within a database transaction.
-// It handles transaction commit and rollback.
+// It handles transaction commit and rollback. Within the transaction of the
+// context it is a savepoint.
 func (c *Client) ExecuteInTransaction(ctx context.Context, fn TransactionFunc) error {
-	tx, err := c.Pool.Begin(ctx)
+	tx, err := c.conn(ctx).Begin(ctx)
 	if err != nil {
 		return err
 	}
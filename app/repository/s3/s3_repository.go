@@ -4,21 +4,32 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"s3MediaStreamer/app/internal/logs"
 	"s3MediaStreamer/app/model"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 )
 
 type RepositoryInterface interface {
-	UploadFilesS3(ctx context.Context, upload *model.UploadS3) error
+	UploadFilesS3(ctx context.Context, upload *model.UploadS3) (minio.UploadInfo, error)
 	DownloadFilesS3(ctx context.Context, name string) (string, error)
 	ListObjectS3(ctx context.Context) ([]minio.ObjectInfo, error)
 	DeleteObjectS3(ctx context.Context, object *minio.ObjectInfo) error
 	FindObjectFromVersion(ctx context.Context, s3tag string) (minio.ObjectInfo, error)
 	DownloadFilesS3Stream(ctx context.Context, name string, callback func(io.Reader) error) error
+	GetObjectS3(ctx context.Context, object *minio.ObjectInfo) (*minio.Object, error)
+	GetObjectRangeS3(ctx context.Context, object *minio.ObjectInfo, start, end int64) (*minio.Object, error)
+	PresignedGetObjectS3(ctx context.Context, object *minio.ObjectInfo, expires time.Duration, contentType string) (*url.URL, error)
+	PutObjectS3(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	StatObjectS3(ctx context.Context, key string) (minio.ObjectInfo, error)
+	NewMultipartUploadS3(ctx context.Context, key, contentType string) (string, error)
+	PutObjectPartS3(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (minio.ObjectPart, error)
+	CompleteMultipartUploadS3(ctx context.Context, key, uploadID string, parts []minio.CompletePart) (minio.UploadInfo, error)
+	AbortMultipartUploadS3(ctx context.Context, key, uploadID string) error
 	CleanTemplateFile(fileName string) error
 	OpenTemplateFile(fileName string) (*os.File, error)
 	Ping(ctx context.Context) error
@@ -39,18 +50,19 @@ func NewS3Repository(cfg *model.Config, logger *logs.Logger, client *minio.Clien
 	}
 }
 
-func (h *Repository) UploadFilesS3(ctx context.Context, upload *model.UploadS3) error {
+// UploadFilesS3 uploads the file as a new version of the object.
+func (h *Repository) UploadFilesS3(ctx context.Context, upload *model.UploadS3) (minio.UploadInfo, error) {
 	info, err := h.s3Client.FPutObject(
 		ctx, h.cfg.AppConfig.S3.BucketName,
 		upload.ObjectName,
 		upload.FilePath,
 		minio.PutObjectOptions{ContentType: upload.ContentType})
 	if err != nil {
-		h.logger.Fatal(err.Error())
+		return minio.UploadInfo{}, err
 	}
 
 	h.logger.Infof("Successfully uploaded %s of size %d\n", upload.ObjectName, info.Size)
-	return nil
+	return info, nil
 }
 
 func (h *Repository) DownloadFilesS3(ctx context.Context, name string) (string, error) {
@@ -136,6 +148,69 @@ func (h *Repository) DownloadFilesS3Stream(ctx context.Context, name string, cal
 	return callback(object)
 }
 
+// GetObjectS3 opens the exact object version for reading.
+func (h *Repository) GetObjectS3(ctx context.Context, object *minio.ObjectInfo) (*minio.Object, error) {
+	return h.s3Client.GetObject(ctx, h.cfg.AppConfig.S3.BucketName, object.Key, minio.GetObjectOptions{VersionID: object.VersionID})
+}
+
+// GetObjectRangeS3 opens the exact object version and reads the bytes start..end (inclusive).
+func (h *Repository) GetObjectRangeS3(ctx context.Context, object *minio.ObjectInfo, start, end int64) (*minio.Object, error) {
+	opts := minio.GetObjectOptions{VersionID: object.VersionID}
+	if err := opts.SetRange(start, end); err != nil {
+		return nil, err
+	}
+	return h.s3Client.GetObject(ctx, h.cfg.AppConfig.S3.BucketName, object.Key, opts)
+}
+
+// PresignedGetObjectS3 returns a GET URL of the exact object version valid for expires.
+func (h *Repository) PresignedGetObjectS3(ctx context.Context, object *minio.ObjectInfo, expires time.Duration, contentType string) (*url.URL, error) {
+	reqParams := make(url.Values)
+	if object.VersionID != "" {
+		reqParams.Set("versionId", object.VersionID)
+	}
+	if contentType != "" {
+		reqParams.Set("response-content-type", contentType)
+	}
+	return h.s3Client.PresignedGetObject(ctx, h.cfg.AppConfig.S3.BucketName, object.Key, expires, reqParams)
+}
+
+// PutObjectS3 writes size bytes of reader to the object key.
+func (h *Repository) PutObjectS3(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	_, err := h.s3Client.PutObject(ctx, h.cfg.AppConfig.S3.BucketName, key, reader, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// StatObjectS3 returns the latest version of the object key.
+func (h *Repository) StatObjectS3(ctx context.Context, key string) (minio.ObjectInfo, error) {
+	return h.s3Client.StatObject(ctx, h.cfg.AppConfig.S3.BucketName, key, minio.StatObjectOptions{})
+}
+
+// NewMultipartUploadS3 starts a multipart upload to the object key and
+// returns its ID.
+func (h *Repository) NewMultipartUploadS3(ctx context.Context, key, contentType string) (string, error) {
+	core := minio.Core{Client: h.s3Client}
+	return core.NewMultipartUpload(ctx, h.cfg.AppConfig.S3.BucketName, key, minio.PutObjectOptions{ContentType: contentType})
+}
+
+// PutObjectPartS3 uploads size bytes of reader as the part number of the
+// multipart upload. Uploading a part number again replaces the part.
+func (h *Repository) PutObjectPartS3(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (minio.ObjectPart, error) {
+	core := minio.Core{Client: h.s3Client}
+	return core.PutObjectPart(ctx, h.cfg.AppConfig.S3.BucketName, key, uploadID, number, reader, size, minio.PutObjectPartOptions{})
+}
+
+// CompleteMultipartUploadS3 joins the parts into a new version of the object.
+func (h *Repository) CompleteMultipartUploadS3(ctx context.Context, key, uploadID string, parts []minio.CompletePart) (minio.UploadInfo, error) {
+	core := minio.Core{Client: h.s3Client}
+	return core.CompleteMultipartUpload(ctx, h.cfg.AppConfig.S3.BucketName, key, uploadID, parts, minio.PutObjectOptions{})
+}
+
+// AbortMultipartUploadS3 cancels the multipart upload and frees its parts.
+func (h *Repository) AbortMultipartUploadS3(ctx context.Context, key, uploadID string) error {
+	core := minio.Core{Client: h.s3Client}
+	return core.AbortMultipartUpload(ctx, h.cfg.AppConfig.S3.BucketName, key, uploadID)
+}
+
 func (h *Repository) CleanTemplateFile(fileName string) error {
 	err := os.Remove(fileName)
 	if err != nil {
@@ -31,12 +31,33 @@ func InitRouter(ctx context.Context, app *app.App, allHandlers *handlers.Handler
 	// Tracks routes
 	initTrackRoutes(v1.Group("/tracks"), allHandlers, cacheURL, ttl, app.Cfg.Storage.Caching.Enabled)
 
+	// Resumable upload routes
+	initTusRoutes(v1.Group("/tus"), allHandlers)
+
+	// Artist, album and genre routes
+	initLibraryRoutes(v1, allHandlers, cacheURL, ttl, app.Cfg.Storage.Caching.Enabled)
+
+	// Search routes
+	initSearchRoutes(v1.Group("/search"), allHandlers)
+
 	// Swagger docs
 	initSwaggerRoutes(v1.Group("/swagger"))
 
 	// Audio routes
 	initAudioRoutes(v1.Group("/audio"), allHandlers)
 
+	// HLS key routes
+	initHLSRoutes(v1.Group("/hls"), allHandlers)
+
+	// Radio routes
+	initRadioRoutes(v1.Group("/radio"), allHandlers)
+
+	// Integrity report routes
+	initIntegrityRoutes(v1.Group("/integrity"), allHandlers)
+
+	// Admin routes
+	initAdminRoutes(v1.Group("/admin"), allHandlers)
+
 	// Playlist routes
 	initPlaylistRoutes(v1.Group("/playlist"), allHandlers, cacheURL, ttl, app.Cfg.Storage.Caching.Enabled)
 }
@@ -65,18 +86,78 @@ func initTrackRoutes(tracks *gin.RouterGroup, allHandlers *handlers.Handlers, ca
 	if cacheEnabled {
 		tracks.GET("", cache.CacheByRequestURI(cacheURL, ttl), allHandlers.Track.GetAllTracks)
 		tracks.GET("/:code", cache.CacheByRequestURI(cacheURL, ttl), allHandlers.Track.GetTrackByID)
+		tracks.PATCH("/:code", evictCachedURI(cacheURL), allHandlers.Track.PatchTrack)
 	} else {
 		tracks.GET("", allHandlers.Track.GetAllTracks)
 		tracks.GET("/:code", allHandlers.Track.GetTrackByID)
+		tracks.PATCH("/:code", allHandlers.Track.PatchTrack)
 	}
+	tracks.POST("/upload", allHandlers.Track.UploadTracks)
+	tracks.GET("/:code/cover", allHandlers.Track.GetCover)
+	tracks.GET("/:code/waveform", allHandlers.Track.GetWaveform)
+	tracks.GET("/:code/versions", allHandlers.Track.GetVersions)
+	tracks.POST("/:code/versions/:version/restore", allHandlers.Track.RestoreVersion)
+}
+
+// Artist, album and genre routes.
+func initLibraryRoutes(v1 *gin.RouterGroup, allHandlers *handlers.Handlers, cacheURL *persist.RedisStore, ttl time.Duration, cacheEnabled bool) {
+	if cacheEnabled {
+		v1.GET("/artists", cache.CacheByRequestURI(cacheURL, ttl), allHandlers.Library.GetArtists)
+		v1.GET("/artists/:id/albums", cache.CacheByRequestURI(cacheURL, ttl), allHandlers.Library.GetArtistAlbums)
+		v1.GET("/albums/:id", cache.CacheByRequestURI(cacheURL, ttl), allHandlers.Library.GetAlbum)
+		v1.GET("/genres", cache.CacheByRequestURI(cacheURL, ttl), allHandlers.Library.GetGenres)
+	} else {
+		v1.GET("/artists", allHandlers.Library.GetArtists)
+		v1.GET("/artists/:id/albums", allHandlers.Library.GetArtistAlbums)
+		v1.GET("/albums/:id", allHandlers.Library.GetAlbum)
+		v1.GET("/genres", allHandlers.Library.GetGenres)
+	}
+}
+
+// Search routes, not cached as members find their own playlists.
+func initSearchRoutes(search *gin.RouterGroup, allHandlers *handlers.Handlers) {
+	search.GET("", allHandlers.Wrapper.WrapWithUserCheck(allHandlers.Search.Search))
 }
 
 // Audio routes.
 func initAudioRoutes(audio *gin.RouterGroup, allHandlers *handlers.Handlers) {
 	audio.GET("/stream/:segment", allHandlers.Audio.StreamM3U)
+	audio.GET("/hls/:track_id/master.m3u8", allHandlers.Audio.HLSMaster)
+	audio.GET("/hls/:track_id/media.m3u8", allHandlers.Audio.HLSMedia)
+	audio.GET("/hls/:track_id/segment/:segment", allHandlers.Audio.HLSSegment)
 	audio.GET("/:playlist_id", allHandlers.Audio.Audio)
 }
 
+// Radio routes.
+func initRadioRoutes(radio *gin.RouterGroup, allHandlers *handlers.Handlers) {
+	radio.GET("/:playlist_id", allHandlers.Radio.Radio)
+	radio.GET("/:playlist_id/listeners", allHandlers.Radio.Listeners)
+}
+
+// HLS key routes, kept out of /audio so that the key policy does not follow the audio one.
+// Keys are served to signed in users and to holders of a stream token of the track.
+func initHLSRoutes(hls *gin.RouterGroup, allHandlers *handlers.Handlers) {
+	hls.GET("/keys/:track_id", allHandlers.Audio.HLSKey)
+}
+
+// Resumable upload routes of the tus protocol.
+func initTusRoutes(tus *gin.RouterGroup, allHandlers *handlers.Handlers) {
+	tus.OPTIONS("", allHandlers.Tus.Options)
+	tus.POST("", allHandlers.Tus.CreateUpload)
+	tus.HEAD("/:id", allHandlers.Tus.HeadUpload)
+	tus.PATCH("/:id", allHandlers.Tus.PatchUpload)
+	tus.DELETE("/:id", allHandlers.Tus.DeleteUpload)
+}
+
+// Integrity report routes, admin only through the ACL policy.
+func initIntegrityRoutes(integrity *gin.RouterGroup, allHandlers *handlers.Handlers) {
+	integrity.GET("", allHandlers.Integrity.Report)
+}
+
+func initAdminRoutes(admin *gin.RouterGroup, allHandlers *handlers.Handlers) {
+	admin.GET("/duplicates", allHandlers.Admin.GetDuplicates)
+}
+
 // Playlist-related routes.
 func initPlaylistRoutes(playlist *gin.RouterGroup, allHandlers *handlers.Handlers, cacheURL *persist.RedisStore, ttl time.Duration, cacheEnabled bool) {
 	playlist.POST("/create", allHandlers.Playlist.CreatePlaylist)
@@ -137,3 +218,16 @@ func setupSystemRoutes(app *app.App, allHandlers *handlers.Handlers) {
 func HandleOptions(c *gin.Context) {
 	c.AbortWithStatus(http.StatusNoContent)
 }
+
+// evictCachedURI drops the cached GET response of the request URI once the
+// request changed the resource, so that the change is visible before the
+// entry expires.
+func evictCachedURI(store *persist.RedisStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Status() < http.StatusMultipleChoices {
+			// A failed eviction leaves the entry to expire with its TTL.
+			_ = store.Delete(c.Request.URL.EscapedPath())
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package analysis
+
+import (
+	"errors"
+	"math"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/pcm"
+)
+
+const (
+	// targetRate is the rate the mono mix is decimated to before the spectra
+	// are taken, tempo and key need nothing above a few kHz.
+	targetRate = 11025
+	// processEvery is the number of decimated samples buffered between two
+	// passes over the frames.
+	processEvery = 4096
+)
+
+// Result is the estimated tempo and key of a track. The confidences are in
+// 0..1, a confidence of 0 means nothing was found.
+type Result struct {
+	BPM           float64
+	BPMConfidence float64
+	Key           string
+	KeyConfidence float64
+}
+
+// Apply stores the result on the track.
+func (r *Result) Apply(track *model.Track) {
+	track.BPM = &r.BPM
+	track.BPMConfidence = &r.BPMConfidence
+	track.Key = r.Key
+	track.KeyConfidence = &r.KeyConfidence
+}
+
+// AnalyzeFile estimates the tempo and key of the audio file.
+func AnalyzeFile(fileName string) (*Result, error) {
+	a := NewAnalyzer()
+	if err := pcm.DecodeFile(fileName, a); err != nil {
+		return nil, err
+	}
+	return a.Result(), nil
+}
+
+// Analyzer implements pcm.Handler. It mixes the channels down to mono,
+// decimates them and feeds two short-time spectra: a fine one for the onset
+// envelope the tempo is read from and a coarse one for the chromagram.
+type Analyzer struct {
+	factor  int
+	rate    float64
+	sum     float64
+	summed  int
+	samples []float64
+	onset   *onsetDetector
+	chroma  *chromagram
+}
+
+// NewAnalyzer returns an analyzer to pass to pcm.DecodeFile.
+func NewAnalyzer() *Analyzer {
+	return &Analyzer{}
+}
+
+func (a *Analyzer) Start(info pcm.Info) error {
+	if info.SampleRate <= 0 || info.Channels <= 0 {
+		return errors.New("invalid stream parameters")
+	}
+	a.factor = max(1, info.SampleRate/targetRate)
+	a.rate = float64(info.SampleRate) / float64(a.factor)
+	a.onset = newOnsetDetector(a.rate)
+	a.chroma = newChromagram(a.rate)
+	return nil
+}
+
+func (a *Analyzer) Frame(samples []float64) {
+	var mono float64
+	for _, sample := range samples {
+		mono += sample
+	}
+	// A boxcar is a poor low pass, the aliases it lets through barely move
+	// onsets or pitch classes.
+	a.sum += mono / float64(len(samples))
+	a.summed++
+	if a.summed < a.factor {
+		return
+	}
+	a.samples = append(a.samples, a.sum/float64(a.factor))
+	a.sum, a.summed = 0, 0
+	if len(a.samples) >= processEvery+max(onsetFrameSize, keyFrameSize) {
+		a.process()
+	}
+}
+
+// process runs the frames the buffered samples complete and drops the samples
+// no frame needs any more.
+func (a *Analyzer) process() {
+	a.onset.process(a.samples)
+	a.chroma.process(a.samples)
+	done := min(a.onset.pos, a.chroma.pos)
+	a.samples = append(a.samples[:0], a.samples[done:]...)
+	a.onset.pos -= done
+	a.chroma.pos -= done
+}
+
+// Result returns the tempo and key of the decoded stream.
+func (a *Analyzer) Result() *Result {
+	if a.onset == nil {
+		return &Result{}
+	}
+	a.process()
+	result := &Result{}
+	result.BPM, result.BPMConfidence = a.onset.tempo()
+	result.Key, result.KeyConfidence = a.chroma.key()
+	return result
+}
+
+// hann returns a periodic Hann window of n points.
+func hann(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return w
+}
+
+// magnitudes returns the magnitude spectrum of the windowed frame up to
+// the Nyquist bin. buf must be as long as the frame.
+func magnitudes(frame, window []float64, buf []complex128, out []float64) {
+	for i, v := range frame {
+		buf[i] = complex(v*window[i], 0)
+	}
+	fft(buf)
+	for k := range out {
+		re, im := real(buf[k]), imag(buf[k])
+		out[k] = math.Sqrt(re*re + im*im)
+	}
+}
+
+// fft is an in-place iterative radix-2 transform, len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := 0; k < size/2; k++ {
+				w := complex(math.Cos(step*float64(k)), math.Sin(step*float64(k)))
+				even, odd := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = even+odd, even-odd
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package analysis_test
+
+import (
+	"math"
+	"s3MediaStreamer/app/services/analysis"
+	"s3MediaStreamer/app/services/pcm"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzer(t *testing.T) {
+	// Twenty seconds of an A minor triad struck on every beat at 120 BPM.
+	const rate, seconds, beat = 22050, 20, 0.5
+	chord := []float64{220, 261.63, 329.63}
+
+	a := analysis.NewAnalyzer()
+	require.NoError(t, a.Start(pcm.Info{SampleRate: rate, Channels: 2, Frames: rate * seconds}))
+	samples := make([]float64, 2)
+	for i := 0; i < rate*seconds; i++ {
+		now := float64(i) / rate
+		since := math.Mod(now, beat)
+		var v float64
+		for _, freq := range chord {
+			v += math.Sin(2*math.Pi*freq*now) / float64(len(chord))
+		}
+		v *= 0.5 * math.Exp(-since*8)
+		samples[0], samples[1] = v, v
+		a.Frame(samples)
+	}
+
+	result := a.Result()
+	assert.InDelta(t, 120, result.BPM, 1)
+	assert.Greater(t, result.BPMConfidence, 0.3)
+	assert.Equal(t, "A minor", result.Key)
+	assert.Greater(t, result.KeyConfidence, 0.5)
+}
+
+func TestParseKey(t *testing.T) {
+	for input, want := range map[string]string{
+		"A minor": "A minor",
+		"am":      "A minor",
+		"F#":      "F# major",
+		"c MAJOR": "C major",
+	} {
+		got, ok := analysis.ParseKey(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, want, got, input)
+	}
+	_, ok := analysis.ParseKey("H")
+	assert.False(t, ok)
+}
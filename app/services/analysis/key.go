@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	keyFrameSize = 4096
+	keyHop       = 2048
+	minPitchHz   = 55
+	maxPitchHz   = 2000
+	pitchClasses = 12
+)
+
+// Krumhansl-Kessler key profiles, starting on the tonic.
+var (
+	majorProfile = [pitchClasses]float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = [pitchClasses]float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+	pitchNames   = [pitchClasses]string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+)
+
+// chromagram sums the spectrum of the decimated signal into pitch classes.
+type chromagram struct {
+	pos    int
+	window []float64
+	buf    []complex128
+	mags   []float64
+	// classes maps the spectrum bins to a pitch class, -1 outside the range.
+	classes []int
+	energy  [pitchClasses]float64
+}
+
+func newChromagram(rate float64) *chromagram {
+	c := &chromagram{
+		window:  hann(keyFrameSize),
+		buf:     make([]complex128, keyFrameSize),
+		mags:    make([]float64, keyFrameSize/2+1),
+		classes: make([]int, keyFrameSize/2+1),
+	}
+	for k := range c.classes {
+		c.classes[k] = -1
+		freq := float64(k) * rate / keyFrameSize
+		if freq >= minPitchHz && freq <= maxPitchHz {
+			// MIDI note 60 is middle C, so the note modulo 12 is the pitch class.
+			midi := int(math.Round(69 + pitchClasses*math.Log2(freq/440)))
+			c.classes[k] = midi % pitchClasses
+		}
+	}
+	return c
+}
+
+func (c *chromagram) process(samples []float64) {
+	for ; c.pos+keyFrameSize <= len(samples); c.pos += keyHop {
+		magnitudes(samples[c.pos:c.pos+keyFrameSize], c.window, c.buf, c.mags)
+		for k, m := range c.mags {
+			if class := c.classes[k]; class >= 0 {
+				c.energy[class] += m
+			}
+		}
+	}
+}
+
+// key correlates the chromagram with the major and minor profile of every
+// tonic and returns the best one, the confidence is its correlation.
+func (c *chromagram) key() (string, float64) {
+	bestKey, bestCorr := "", 0.0
+	for tonic := 0; tonic < pitchClasses; tonic++ {
+		var rotated [pitchClasses]float64
+		for i := range rotated {
+			rotated[i] = c.energy[(tonic+i)%pitchClasses]
+		}
+		if corr := correlation(rotated, majorProfile); corr > bestCorr {
+			bestKey, bestCorr = pitchNames[tonic]+" major", corr
+		}
+		if corr := correlation(rotated, minorProfile); corr > bestCorr {
+			bestKey, bestCorr = pitchNames[tonic]+" minor", corr
+		}
+	}
+	return bestKey, math.Min(1, bestCorr)
+}
+
+// correlation is the Pearson correlation of the two vectors, 0 when either
+// is constant.
+func correlation(x, y [pitchClasses]float64) float64 {
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i] / pitchClasses
+		meanY += y[i] / pitchClasses
+	}
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// ParseKey returns the key name as Result.Key spells it, accepting any case
+// and the short forms "Am" and "C".
+func ParseKey(name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	mode := " major"
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, " minor"):
+		name, mode = name[:len(name)-len(" minor")], " minor"
+	case strings.HasSuffix(lower, " major"):
+		name = name[:len(name)-len(" major")]
+	case strings.HasSuffix(name, "m"):
+		name, mode = name[:len(name)-1], " minor"
+	}
+	for _, pitch := range pitchNames {
+		if strings.EqualFold(name, pitch) {
+			return pitch + mode, true
+		}
+	}
+	return "", false
+}
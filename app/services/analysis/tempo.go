@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"math"
+)
+
+const (
+	onsetFrameSize = 1024
+	onsetHop       = 128
+	// onsetCompression is the gain before the log that keeps quiet partials
+	// from drowning in loud ones.
+	onsetCompression = 100
+	// onsetMeanFrames is the half width of the moving average the onset
+	// envelope is detrended with.
+	onsetMeanFrames = 8
+	minBPM          = 60
+	maxBPM          = 200
+	// The autocorrelation is weighted with a log normal prior around 120 BPM
+	// one octave wide, so that half and double tempo lose against the beat.
+	priorBPM     = 120
+	priorOctaves = 1
+	minSeconds   = 5
+)
+
+// onsetDetector keeps the spectral flux of the decimated signal, one value per hop.
+type onsetDetector struct {
+	fps      float64
+	pos      int
+	window   []float64
+	buf      []complex128
+	mags     []float64
+	previous []float64
+	envelope []float64
+}
+
+func newOnsetDetector(rate float64) *onsetDetector {
+	return &onsetDetector{
+		fps:    rate / onsetHop,
+		window: hann(onsetFrameSize),
+		buf:    make([]complex128, onsetFrameSize),
+		mags:   make([]float64, onsetFrameSize/2+1),
+	}
+}
+
+func (d *onsetDetector) process(samples []float64) {
+	for ; d.pos+onsetFrameSize <= len(samples); d.pos += onsetHop {
+		magnitudes(samples[d.pos:d.pos+onsetFrameSize], d.window, d.buf, d.mags)
+		for k, m := range d.mags {
+			d.mags[k] = math.Log1p(onsetCompression * m)
+		}
+		var flux float64
+		if d.previous != nil {
+			for k, m := range d.mags {
+				flux += math.Max(0, m-d.previous[k])
+			}
+		} else {
+			d.previous = make([]float64, len(d.mags))
+		}
+		copy(d.previous, d.mags)
+		d.envelope = append(d.envelope, flux)
+	}
+}
+
+// tempo picks the beat period from the autocorrelation of the detrended
+// onset envelope. The confidence is the correlation at the period relative
+// to the one at lag zero.
+func (d *onsetDetector) tempo() (float64, float64) {
+	if float64(len(d.envelope)) < minSeconds*d.fps {
+		return 0, 0
+	}
+	onsets := detrend(d.envelope)
+
+	minLag := int(math.Floor(d.fps * 60 / maxBPM))
+	maxLag := int(math.Ceil(d.fps * 60 / minBPM))
+	r := make([]float64, maxLag+2)
+	for lag := range r {
+		var sum float64
+		for n := 0; n+lag < len(onsets); n++ {
+			sum += onsets[n] * onsets[n+lag]
+		}
+		r[lag] = sum / float64(len(onsets)-lag)
+	}
+	if r[0] == 0 {
+		return 0, 0
+	}
+
+	best, bestScore := 0, 0.0
+	for lag := max(minLag, 1); lag <= maxLag; lag++ {
+		octaves := math.Log2(d.fps * 60 / float64(lag) / priorBPM)
+		score := r[lag] * math.Exp(-0.5*(octaves/priorOctaves)*(octaves/priorOctaves))
+		if score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+	if best == 0 {
+		return 0, 0
+	}
+
+	// A parabola through the neighbours finds the period between two hops.
+	period := float64(best)
+	if denominator := r[best-1] - 2*r[best] + r[best+1]; denominator < 0 {
+		period += 0.5 * (r[best-1] - r[best+1]) / denominator
+	}
+	bpm := math.Round(d.fps*60/period*10) / 10
+	return bpm, math.Max(0, math.Min(1, r[best]/r[0]))
+}
+
+// detrend subtracts the moving average from the envelope and keeps what is
+// left above it.
+func detrend(envelope []float64) []float64 {
+	out := make([]float64, len(envelope))
+	for n := range envelope {
+		from, to := max(0, n-onsetMeanFrames), min(len(envelope), n+onsetMeanFrames+1)
+		var sum float64
+		for _, v := range envelope[from:to] {
+			sum += v
+		}
+		out[n] = math.Max(0, envelope[n]-sum/float64(to-from))
+	}
+	return out
+}
@@ -0,0 +1,235 @@
+package artwork
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // cover art is stored as JPEG or PNG
+	"io"
+	"net/http"
+	"path"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/s3"
+	"s3MediaStreamer/app/services/tags"
+	"s3MediaStreamer/app/services/track"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/image/draw"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Prefix holds the cover art extracted from audio files and its thumbnails.
+	Prefix           = "artwork/"
+	DefaultSize      = "300"
+	thumbnailType    = "image/jpeg"
+	thumbnailQuality = 85
+)
+
+// Sizes are the thumbnail edges in pixels the cover endpoint serves.
+var Sizes = map[string]int{"64": 64, "300": 300, "1200": 1200}
+
+// folderCovers are looked up next to the audio object when the file has no embedded art.
+var folderCovers = []string{"folder.jpg", "cover.jpg", "folder.png", "cover.png"}
+
+type Service struct {
+	s3     s3.Service
+	track  track.Service
+	tags   tags.Service
+	logger *logs.Logger
+	group  singleflight.Group
+}
+
+func NewArtworkService(s3 s3.Service, track track.Service, tags tags.Service, logger *logs.Logger) *Service {
+	return &Service{
+		s3:     s3,
+		track:  track,
+		tags:   tags,
+		logger: logger,
+	}
+}
+
+// IsArtworkObject reports whether the object key is cover art rather than audio,
+// so that the ingestion and the clean up job leave it alone.
+func IsArtworkObject(key string) bool {
+	if strings.HasPrefix(key, Prefix) {
+		return true
+	}
+	switch strings.ToLower(path.Ext(key)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// StoreService uploads the cover art embedded in the audio file and returns its
+// key. The key is the hash of the image, so an album shares one object. An
+// empty key means the file has no cover art.
+func (s *Service) StoreService(ctx context.Context, fileName string) (string, error) {
+	_, span := otel.Tracer("").Start(ctx, "StoreArtworkService")
+	defer span.End()
+
+	picture, err := s.tags.ReadPicture(fileName)
+	if err != nil || picture == nil || len(picture.Data) == 0 {
+		return "", err
+	}
+	contentType := http.DetectContentType(picture.Data)
+	var ext string
+	switch contentType {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/png":
+		ext = ".png"
+	default:
+		return "", fmt.Errorf("unsupported cover art type %s", contentType)
+	}
+
+	sum := sha256.Sum256(picture.Data)
+	key := Prefix + hex.EncodeToString(sum[:]) + ext
+	if _, err = s.s3.StatObjectS3(ctx, key); err == nil {
+		return key, nil
+	} else if !isNotFound(err) {
+		return "", err
+	}
+	if err = s.s3.PutObjectS3(ctx, key, bytes.NewReader(picture.Data), int64(len(picture.Data)), contentType); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// CoverService returns the cover of the track scaled to size as JPEG. The
+// thumbnail is made on the first request and kept in S3 next to the original.
+func (s *Service) CoverService(c *gin.Context, trackID, size string) ([]byte, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "CoverService")
+	defer span.End()
+
+	if size == "" {
+		size = DefaultSize
+	}
+	edge, ok := Sizes[size]
+	if !ok {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "size must be 64, 300 or 1200"}
+	}
+
+	source, errSource := s.coverObject(ctx, trackID)
+	if errSource != nil {
+		return nil, errSource
+	}
+	sum := sha256.Sum256([]byte(source.Key + "\x00" + source.ETag))
+	thumbKey := fmt.Sprintf("%s%s/%s.jpg", Prefix, size, hex.EncodeToString(sum[:]))
+
+	// Concurrent requests share one thumbnail, which must not fail when the
+	// request that started it goes away.
+	data, err, _ := s.group.Do(thumbKey, func() (interface{}, error) {
+		return s.thumbnail(context.WithoutCancel(ctx), &source, thumbKey, edge)
+	})
+	if err != nil {
+		s.logger.Errorf("Error making cover %s of %s: %v", thumbKey, source.Key, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Error making cover"}
+	}
+	thumb, _ := data.([]byte)
+	return thumb, nil
+}
+
+// coverObject returns the embedded cover art of the track, or the folder cover
+// next to its audio object.
+func (s *Service) coverObject(ctx context.Context, trackID string) (minio.ObjectInfo, *model.RestError) {
+	notFound := &model.RestError{Code: http.StatusNotFound, Err: "Cover not found"}
+	track, err := s.track.GetTracksByColumns(ctx, trackID, "_id")
+	if err != nil {
+		return minio.ObjectInfo{}, &model.RestError{Code: http.StatusNotFound, Err: "Track not found"}
+	}
+	if track.Artwork != "" {
+		if object, errStat := s.s3.StatObjectS3(ctx, track.Artwork); errStat == nil {
+			return object, nil
+		}
+	}
+
+	version, err := s.s3.GetS3VersionByTrackID(ctx, track.ID.String())
+	if err != nil {
+		return minio.ObjectInfo{}, notFound
+	}
+	audio, err := s.s3.FindObjectFromVersion(ctx, version)
+	if err != nil {
+		return minio.ObjectInfo{}, notFound
+	}
+	for _, name := range folderCovers {
+		if object, errStat := s.s3.StatObjectS3(ctx, path.Join(path.Dir(audio.Key), name)); errStat == nil {
+			return object, nil
+		}
+	}
+	return minio.ObjectInfo{}, notFound
+}
+
+// thumbnail returns the stored thumbnail, making it from the source on a miss.
+func (s *Service) thumbnail(ctx context.Context, source *minio.ObjectInfo, thumbKey string, edge int) ([]byte, error) {
+	if cached, err := s.readObject(ctx, &minio.ObjectInfo{Key: thumbKey}); err == nil {
+		return cached, nil
+	} else if !isNotFound(err) {
+		return nil, err
+	}
+
+	original, err := s.readObject(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(original))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = jpeg.Encode(&buf, Resize(img, edge), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	if err = s.s3.PutObjectS3(ctx, thumbKey, bytes.NewReader(buf.Bytes()), int64(buf.Len()), thumbnailType); err != nil {
+		// The thumbnail is still good for this request, the next one tries again.
+		s.logger.Warnf("Error storing cover %s: %v", thumbKey, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Service) readObject(ctx context.Context, object *minio.ObjectInfo) ([]byte, error) {
+	reader, err := s.s3.GetObjectS3(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Resize scales the image to fit into an edge by edge square, keeping the
+// aspect ratio. Smaller images are not enlarged.
+func Resize(img image.Image, edge int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= edge && height <= edge {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*edge/width)
+		width = edge
+	} else {
+		width = max(1, width*edge/height)
+		height = edge
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+func isNotFound(err error) bool {
+	var resp minio.ErrorResponse
+	if errors.As(err, &resp) {
+		return resp.Code == "NoSuchKey" || resp.StatusCode == http.StatusNotFound
+	}
+	return false
+}
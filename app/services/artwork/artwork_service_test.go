@@ -0,0 +1,37 @@
+package artwork_test
+
+import (
+	"image"
+	"s3MediaStreamer/app/services/artwork"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		edge          int
+		wantW, wantH  int
+	}{
+		{name: "square", width: 1000, height: 1000, edge: 300, wantW: 300, wantH: 300},
+		{name: "landscape", width: 1200, height: 600, edge: 64, wantW: 64, wantH: 32},
+		{name: "portrait", width: 500, height: 1000, edge: 300, wantW: 150, wantH: 300},
+		{name: "not enlarged", width: 200, height: 100, edge: 1200, wantW: 200, wantH: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := artwork.Resize(image.NewRGBA(image.Rect(0, 0, tt.width, tt.height)), tt.edge)
+			assert.Equal(t, tt.wantW, got.Bounds().Dx())
+			assert.Equal(t, tt.wantH, got.Bounds().Dy())
+		})
+	}
+}
+
+func TestIsArtworkObject(t *testing.T) {
+	assert.True(t, artwork.IsArtworkObject("artwork/9f86d081.jpg"))
+	assert.True(t, artwork.IsArtworkObject("album/folder.JPG"))
+	assert.True(t, artwork.IsArtworkObject("cover.png"))
+	assert.False(t, artwork.IsArtworkObject("album/song.mp3"))
+}
@@ -0,0 +1,293 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/tags"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mewkiz/flac/frame"
+	"github.com/minio/minio-go/v7"
+	"github.com/tcolgate/mp3"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	flacSignature       = "fLaC"
+	flacBlockHeaderSize = 4
+	flacStreamInfoSize  = 34
+	flacLastBlock       = 0x80
+	flacBlockType       = 0x7f
+	// flacTotalSamples is the offset of the 36 bit sample count in STREAMINFO,
+	// the MD5 of the samples follows it.
+	flacTotalSamples = 13
+	flacMD5          = 18
+)
+
+var (
+	// ErrEmptyCut is returned when no audio frame overlaps the samples of a CUE track.
+	ErrEmptyCut     = errors.New("no audio frames in the CUE track")
+	errNoStreamInfo = errors.New("FLAC stream has no STREAMINFO")
+)
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// CutFLAC returns the slice of a FLAC stream with the frames that overlap the
+// samples from start up to end, end <= 0 meaning the end of the stream. The
+// header is the STREAMINFO rewritten for the frames of the slice, the other
+// metadata blocks are left out.
+func CutFLAC(r io.Reader, start, end int64) (*model.CueSlice, error) {
+	br := bufio.NewReader(r)
+	offset, err := skipID3v2(br)
+	if err != nil {
+		return nil, err
+	}
+	cr := &countingReader{r: br}
+
+	signature := make([]byte, len(flacSignature))
+	if _, err = io.ReadFull(cr, signature); err != nil || string(signature) != flacSignature {
+		return nil, errors.New("not a FLAC stream")
+	}
+	var streamInfo []byte
+	for last := false; !last; {
+		header := make([]byte, flacBlockHeaderSize)
+		if _, err = io.ReadFull(cr, header); err != nil {
+			return nil, err
+		}
+		last = header[0]&flacLastBlock != 0
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		if header[0]&flacBlockType == 0 && length == flacStreamInfoSize {
+			streamInfo = make([]byte, flacStreamInfoSize)
+			_, err = io.ReadFull(cr, streamInfo)
+		} else {
+			_, err = io.CopyN(io.Discard, cr, length)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if streamInfo == nil {
+		return nil, errNoStreamInfo
+	}
+
+	slice := &model.CueSlice{}
+	for {
+		frameStart := offset + cr.n
+		f, errFrame := frame.Parse(cr)
+		if errors.Is(errFrame, io.EOF) || errors.Is(errFrame, io.ErrUnexpectedEOF) {
+			break // a truncated last frame is not served
+		}
+		if errFrame != nil {
+			return nil, errFrame
+		}
+		first := int64(f.SampleNumber())
+		if end > 0 && first >= end {
+			break
+		}
+		if first+int64(f.BlockSize) > start {
+			if slice.Length == 0 {
+				slice.Offset = frameStart
+			}
+			slice.Length = offset + cr.n - slice.Offset
+			slice.Samples += int64(f.BlockSize)
+		}
+	}
+	if slice.Length == 0 {
+		return nil, ErrEmptyCut
+	}
+
+	// The total number of samples shares its first byte with the bits per sample.
+	streamInfo[flacTotalSamples] = streamInfo[flacTotalSamples]&0xf0 | byte(slice.Samples>>32)&0x0f
+	binary.BigEndian.PutUint32(streamInfo[flacTotalSamples+1:], uint32(slice.Samples))
+	// The MD5 covers the samples of the whole stream, zero means unknown.
+	clear(streamInfo[flacMD5:])
+	var header bytes.Buffer
+	header.WriteString(flacSignature)
+	header.Write([]byte{flacLastBlock, 0, 0, flacStreamInfoSize})
+	header.Write(streamInfo)
+	slice.Header = header.Bytes()
+	return slice, nil
+}
+
+// CutMP3 returns the byte range of an MP3 stream with the frames that overlap
+// the samples from start up to end, end <= 0 meaning the end of the stream.
+func CutMP3(r io.Reader, start, end int64) (*model.CueSlice, error) {
+	br := bufio.NewReader(r)
+	offset, err := skipID3v2(br)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := mp3.NewDecoder(br)
+	var f mp3.Frame
+	skipped := 0
+	var pos int64
+	slice := &model.CueSlice{}
+	for {
+		if err = dec.Decode(&f, &skipped); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, err
+		}
+		offset += int64(skipped)
+		if end > 0 && pos >= end {
+			break
+		}
+		size, samples := int64(f.Size()), int64(f.Samples())
+		if pos+samples > start {
+			if slice.Length == 0 {
+				slice.Offset = offset
+			} else {
+				// Junk between two frames stays inside the slice to keep it contiguous.
+				slice.Length += int64(skipped)
+			}
+			slice.Length += size
+			slice.Samples += samples
+		}
+		offset += size
+		pos += samples
+	}
+	if slice.Length == 0 {
+		return nil, ErrEmptyCut
+	}
+	return slice, nil
+}
+
+// isFLAC reports whether the object of the track is a FLAC stream.
+func isFLAC(object *minio.ObjectInfo, track *model.Track) bool {
+	return track.MimeType == tags.MimeFLAC || strings.EqualFold(filepath.Ext(object.Key), ".flac")
+}
+
+// CueSliceService returns the slice of the object version a CUE track is cut
+// out of, nil for tracks that are whole objects. Slices are cached.
+func (h *Service) CueSliceService(ctx context.Context, object *minio.ObjectInfo, track *model.Track) (*model.CueSlice, *model.RestError) {
+	if track.CueStart == nil {
+		return nil, nil
+	}
+	ctx, span := otel.Tracer("").Start(ctx, "CueSliceService")
+	defer span.End()
+
+	start, end := *track.CueStart, int64(0)
+	if track.CueEnd != nil {
+		end = *track.CueEnd
+	}
+	key := fmt.Sprintf("%s:%d:%d", object.VersionID, start, end)
+	if slice, ok := h.cueSlices.Get(key); ok {
+		return slice, nil
+	}
+
+	var cut func(io.Reader, int64, int64) (*model.CueSlice, error)
+	switch {
+	case IsMPEGAudio(object, track):
+		cut = CutMP3
+	case isFLAC(object, track):
+		cut = CutFLAC
+	default:
+		return nil, &model.RestError{Code: http.StatusUnsupportedMediaType, Err: "CUE tracks can only be cut out of FLAC and MP3"}
+	}
+
+	reader, err := h.OpenObject(ctx, object)
+	if err != nil {
+		h.logger.Errorf("Error opening object %s: %v", object.Key, err)
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+	}
+	defer reader.Close()
+
+	slice, err := cut(reader, start, end)
+	if err != nil {
+		h.logger.Errorf("Error cutting track %s out of %s: %v", track.ID, object.Key, err)
+		return nil, &model.RestError{Code: http.StatusUnprocessableEntity, Err: "Error reading audio frames"}
+	}
+	h.cueSlices.Add(key, slice)
+	return slice, nil
+}
+
+// OpenSlice opens the bytes of a CUE slice, its header followed by its range
+// of the object version.
+func (h Service) OpenSlice(ctx context.Context, object *minio.ObjectInfo, slice *model.CueSlice) (io.ReadCloser, error) {
+	reader, err := h.openObjectRange(ctx, object, model.HTTPRange{Start: slice.Offset, Length: slice.Length})
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(slice.Header), io.LimitReader(reader, slice.Length)), reader}, nil
+}
+
+// copySlice writes the range ra of the bytes of a CUE slice.
+func (h Service) copySlice(ctx context.Context, w io.Writer, object *minio.ObjectInfo, slice *model.CueSlice, ra model.HTTPRange) error {
+	headerSize := int64(len(slice.Header))
+	if ra.Start < headerSize {
+		n := min(ra.Length, headerSize-ra.Start)
+		if _, err := w.Write(slice.Header[ra.Start : ra.Start+n]); err != nil {
+			return err
+		}
+		ra.Start += n
+		ra.Length -= n
+	}
+	if ra.Length == 0 {
+		return nil
+	}
+	return h.copyRange(ctx, w, object, model.HTTPRange{Start: slice.Offset + ra.Start - headerSize, Length: ra.Length})
+}
+
+// StreamCueService streams a CUE track as a stream of its own, answering
+// range requests on it. The ETag of the object version is suffixed with the
+// offset of the slice.
+func (h Service) StreamCueService(c *gin.Context, object *minio.ObjectInfo, slice *model.CueSlice, contentType string) *model.RestError {
+	ctx := c.Request.Context()
+	size := int64(len(slice.Header)) + slice.Length
+	etag := strings.Trim(object.ETag, `"`) + "-" + strconv.FormatInt(slice.Offset, 10)
+	c.Header("ETag", QuoteETag(etag))
+	copySlice := func(w io.Writer, ra model.HTTPRange) error {
+		return h.copySlice(ctx, w, object, slice, ra)
+	}
+
+	if rangeHeader := c.GetHeader("Range"); rangeHeader != "" && CheckIfRange(c.GetHeader("If-Range"), etag, object.LastModified) {
+		ranges, err := ParseRange(rangeHeader, size)
+		if err != nil {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", size))
+			return &model.RestError{Code: http.StatusRequestedRangeNotSatisfiable, Err: err.Error()}
+		}
+		if SumRangesSize(ranges) <= size {
+			if err = writeRanges(c, ranges, size, contentType, copySlice); err != nil {
+				h.logger.Errorf("Error streaming ranges of %s: %v", object.Key, err)
+			}
+			return nil
+		}
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.FormatInt(size, 10))
+	c.Status(http.StatusOK)
+	err := copySlice(c.Writer, model.HTTPRange{Start: 0, Length: size})
+	switch {
+	case errors.Is(err, context.Canceled):
+		h.logger.Infof("Client disconnected while streaming a track of %s, stopping streaming.", object.Key)
+	case err != nil:
+		h.logger.Errorf("Error streaming audio_handler: %v", err)
+	}
+	return nil
+}
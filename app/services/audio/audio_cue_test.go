@@ -0,0 +1,75 @@
+package audio_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"s3MediaStreamer/app/services/audio"
+	"testing"
+
+	"github.com/mewkiz/flac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tone.flac has 4096 samples in frames of 1024 samples and 2059 bytes after
+// a 42 byte header.
+const (
+	testFLACHeader    = 42
+	testFLACFrameSize = 2059
+)
+
+func TestCutFLAC(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "tone.flac"))
+	require.NoError(t, err)
+
+	slice, err := audio.CutFLAC(bytes.NewReader(data), 1500, 2100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(testFLACHeader+testFLACFrameSize), slice.Offset)
+	assert.Equal(t, int64(2*testFLACFrameSize), slice.Length)
+	assert.Equal(t, int64(2048), slice.Samples)
+
+	// The slice is a FLAC stream of its own.
+	cut := append(append([]byte{}, slice.Header...), data[slice.Offset:slice.Offset+slice.Length]...)
+	stream, err := flac.New(bytes.NewReader(cut))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2048), stream.Info.NSamples)
+	assert.Equal(t, [16]byte{}, stream.Info.MD5sum)
+	frames := 0
+	for {
+		_, errFrame := stream.ParseNext()
+		if errors.Is(errFrame, io.EOF) {
+			break
+		}
+		require.NoError(t, errFrame)
+		frames++
+	}
+	assert.Equal(t, 2, frames)
+
+	last, err := audio.CutFLAC(bytes.NewReader(data), 3072, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data))-testFLACFrameSize, last.Offset)
+	assert.Equal(t, int64(1024), last.Samples)
+
+	_, err = audio.CutFLAC(bytes.NewReader(data), 5000, 0)
+	assert.ErrorIs(t, err, audio.ErrEmptyCut)
+}
+
+func TestCutMP3(t *testing.T) {
+	id3 := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20}
+	id3 = append(id3, make([]byte, 20)...)
+	data := testMP3(100, id3)
+
+	slice, err := audio.CutMP3(bytes.NewReader(data), 10*1152+5, 20*1152)
+	require.NoError(t, err)
+	assert.Empty(t, slice.Header)
+	assert.Equal(t, int64(len(id3)+10*testFrameSize), slice.Offset)
+	assert.Equal(t, int64(10*testFrameSize), slice.Length)
+	assert.Equal(t, int64(10*1152), slice.Samples)
+
+	last, err := audio.CutMP3(bytes.NewReader(data), 90*1152, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), last.Offset+last.Length)
+}
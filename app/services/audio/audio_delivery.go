@@ -0,0 +1,52 @@
+package audio
+
+import (
+	"net/http"
+	"s3MediaStreamer/app/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	DeliveryProxy        = "proxy"
+	DeliveryRedirect     = "redirect"
+	defaultPresignExpiry = 5 * time.Minute
+)
+
+// DeliveryModeService returns the delivery mode of a stream request, the
+// configured one unless the request asks for another.
+func (h *Service) DeliveryModeService(requested string) (string, *model.RestError) {
+	if requested == "" {
+		requested = h.cfg.AppConfig.Stream.Delivery.Mode
+	}
+	switch requested {
+	case "", DeliveryProxy:
+		return DeliveryProxy, nil
+	case DeliveryRedirect:
+		return DeliveryRedirect, nil
+	}
+	return "", &model.RestError{Code: http.StatusBadRequest, Err: "delivery must be 'proxy' or 'redirect'"}
+}
+
+// RedirectObjectService answers with a redirect to a short-lived presigned URL
+// of the object version, so the audio bytes do not pass through the app.
+func (h *Service) RedirectObjectService(c *gin.Context, object *minio.ObjectInfo, contentType string) *model.RestError {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "RedirectObjectService")
+	defer span.End()
+
+	expires := defaultPresignExpiry
+	if seconds := h.cfg.AppConfig.Stream.Delivery.PresignExpiry; seconds > 0 {
+		expires = time.Duration(seconds) * time.Second
+	}
+	presigned, err := h.s3.PresignedGetObjectS3(c.Request.Context(), object, expires, contentType)
+	if err != nil {
+		h.logger.Errorf("Error presigning object %s: %v", object.Key, err)
+		return &model.RestError{Code: http.StatusInternalServerError, Err: "Error presigning object"}
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, presigned.String())
+	return nil
+}
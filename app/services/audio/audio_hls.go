@@ -0,0 +1,403 @@
+package audio
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/tags"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/tcolgate/mp3"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	HLSContentType         = "application/vnd.apple.mpegurl"
+	hlsVersion             = 3
+	hlsCodecMP3            = "mp4a.40.34"
+	hlsSegmentExtension    = ".mp3"
+	defaultSegmentDuration = 8 * time.Second
+	minSegmentDuration     = 6 * time.Second
+	maxSegmentDuration     = 10 * time.Second
+	id3HeaderSize          = 10
+	bitsPerByte            = 8
+	// hlsTimestampOwner is the owner of the ID3 PRIV frame that carries the
+	// MPEG-2 timestamp of the first frame of a packed audio segment.
+	hlsTimestampOwner = "com.apple.streaming.transportStreamTimestamp"
+	hlsTimestampClock = 90000
+	hlsTimestampMask  = 1<<33 - 1
+	// hlsSeekTableWorkers bounds the seek tables of a playlist built at once.
+	hlsSeekTableWorkers = 4
+)
+
+// ErrNoFrames is returned when an object does not contain a single MPEG audio frame.
+var ErrNoFrames = errors.New("no MPEG audio frames found")
+
+// hlsTrack is one track of an HLS media playlist with the URI prefix and the
+// stream token query of its segments and, for encrypted segments, the URI of its key.
+type hlsTrack struct {
+	prefix string
+	query  string
+	keyURI string
+	table  *model.SeekTable
+}
+
+// BuildSeekTable splits an MP3 stream into segments of about target duration.
+// Every segment starts and ends on an MPEG frame boundary, a leading ID3v2 tag
+// is not part of any segment.
+func BuildSeekTable(r io.Reader, target time.Duration) (*model.SeekTable, error) {
+	br := bufio.NewReader(r)
+	offset, err := skipID3v2(br)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := mp3.NewDecoder(br)
+	var frame mp3.Frame
+	skipped := 0
+
+	table := &model.SeekTable{}
+	segment := model.HLSSegment{}
+	for {
+		if err = dec.Decode(&frame, &skipped); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break // a truncated last frame is not served
+			}
+			return nil, err
+		}
+		offset += int64(skipped)
+		if segment.Length == 0 {
+			segment.Offset = offset
+		} else {
+			// Junk between two frames stays inside the segment to keep it contiguous.
+			segment.Length += int64(skipped)
+		}
+		size := int64(frame.Size())
+		segment.Length += size
+		segment.Duration += frame.Duration()
+		offset += size
+
+		if segment.Duration >= target {
+			table.Segments = append(table.Segments, segment)
+			segment = model.HLSSegment{Index: len(table.Segments), Start: segment.Start + segment.Duration}
+		}
+	}
+	if segment.Length > 0 {
+		table.Segments = append(table.Segments, segment)
+	}
+	if len(table.Segments) == 0 {
+		return nil, ErrNoFrames
+	}
+
+	var totalBytes int64
+	for _, s := range table.Segments {
+		table.Duration += s.Duration
+		totalBytes += s.Length
+	}
+	if table.Duration > 0 {
+		table.Bitrate = int64(float64(totalBytes*bitsPerByte) / table.Duration.Seconds())
+	}
+	return table, nil
+}
+
+// skipID3v2 consumes an ID3v2 tag at the start of the stream and returns its size.
+func skipID3v2(br *bufio.Reader) (int64, error) {
+	header, err := br.Peek(id3HeaderSize)
+	if err != nil {
+		return 0, nil //nolint:nilerr // streams shorter than a tag header are reported by the frame decoder
+	}
+	if string(header[:3]) != "ID3" {
+		return 0, nil
+	}
+	// The tag size is a 28 bit syncsafe integer that excludes the header.
+	size := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+	if header[5]&0x10 != 0 {
+		size += id3HeaderSize // footer present
+	}
+	size += id3HeaderSize
+	n, err := br.Discard(int(size))
+	return int64(n), err
+}
+
+// TimestampTag returns the ID3v2.4 tag that starts every packed audio segment,
+// with the PRIV frame that gives the MPEG-2 timestamp of its first frame in
+// the 90 kHz clock, as the HLS specification requires for packed audio.
+func TimestampTag(start time.Duration) []byte {
+	pts := uint64(start.Seconds()*hlsTimestampClock) & hlsTimestampMask
+	data := append([]byte(hlsTimestampOwner), 0)
+	data = binary.BigEndian.AppendUint64(data, pts)
+
+	frame := append([]byte("PRIV"), synchsafe(len(data))...)
+	frame = append(frame, 0, 0)
+	frame = append(frame, data...)
+
+	tag := append([]byte{'I', 'D', '3', 4, 0, 0}, synchsafe(len(frame))...)
+	return append(tag, frame...)
+}
+
+// synchsafe encodes n as the 28 bit synchsafe integer of ID3v2 sizes.
+func synchsafe(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+// segmentDuration returns the configured HLS segment duration limited to 6..10 seconds.
+func (h *Service) segmentDuration() time.Duration {
+	duration := time.Duration(h.cfg.AppConfig.Stream.HLS.SegmentDuration) * time.Second
+	switch {
+	case duration == 0:
+		return defaultSegmentDuration
+	case duration < minSegmentDuration:
+		return minSegmentDuration
+	case duration > maxSegmentDuration:
+		return maxSegmentDuration
+	}
+	return duration
+}
+
+// IsMPEGAudio reports whether the track is an MP3 that can be cut on frame
+// boundaries, by the MIME type detected from its magic bytes. The extension of
+// the object is only used for tracks scanned before detection.
+func IsMPEGAudio(object *minio.ObjectInfo, track *model.Track) bool {
+	if track.MimeType != "" {
+		return track.MimeType == tags.MimeMP3
+	}
+	return strings.EqualFold(filepath.Ext(object.Key), ".mp3")
+}
+
+// SeekTableService returns the segmentation of the object version of the
+// track, or of the CUE slice of it when slice is not nil, building and caching
+// it on first use.
+func (h *Service) SeekTableService(ctx context.Context, object *minio.ObjectInfo, track *model.Track, slice *model.CueSlice) (*model.SeekTable, *model.RestError) {
+	if !IsMPEGAudio(object, track) {
+		return nil, &model.RestError{Code: http.StatusUnsupportedMediaType, Err: "HLS is only available for MP3 tracks"}
+	}
+	key := object.VersionID
+	if slice != nil {
+		key += ":" + strconv.FormatInt(slice.Offset, 10)
+	}
+	if table, ok := h.seekTables.Get(key); ok {
+		return table, nil
+	}
+
+	var reader io.ReadCloser
+	var err error
+	if slice == nil {
+		reader, err = h.OpenObject(ctx, object)
+	} else {
+		reader, err = h.OpenSlice(ctx, object, slice)
+	}
+	if err != nil {
+		h.logger.Errorf("Error opening object %s: %v", object.Key, err)
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+	}
+	defer reader.Close()
+
+	table, err := BuildSeekTable(reader, h.segmentDuration())
+	if err != nil {
+		h.logger.Errorf("Error building seek table for %s: %v", object.Key, err)
+		return nil, &model.RestError{Code: http.StatusUnprocessableEntity, Err: "Error reading MP3 frames"}
+	}
+	if slice != nil {
+		// MP3 slices have no header, the segments are ranges of the object.
+		for i := range table.Segments {
+			table.Segments[i].Offset += slice.Offset
+		}
+	}
+	table.Version = object.VersionID
+	h.seekTables.Add(key, table)
+	return table, nil
+}
+
+// trackSeekTable resolves the track to its object and seek table, the one of
+// its slice for a CUE track.
+func (h *Service) trackSeekTable(ctx context.Context, trackID string) (*minio.ObjectInfo, *model.SeekTable, *model.RestError) {
+	object, track, errFind := h.FindSegmentObject(ctx, trackID)
+	if errFind != nil {
+		return nil, nil, errFind
+	}
+	var slice *model.CueSlice
+	if IsMPEGAudio(object, track) {
+		var errSlice *model.RestError
+		if slice, errSlice = h.CueSliceService(ctx, object, track); errSlice != nil {
+			return nil, nil, errSlice
+		}
+	}
+	table, errTable := h.SeekTableService(ctx, object, track, slice)
+	if errTable != nil {
+		return nil, nil, errTable
+	}
+	return object, table, nil
+}
+
+// HLSMasterPlaylistService writes the master playlist of a track with its single MP3 rendition.
+func (h *Service) HLSMasterPlaylistService(c *gin.Context, trackID string) *model.RestError {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HLSMasterPlaylistService")
+	defer span.End()
+
+	_, table, errTable := h.trackSeekTable(c.Request.Context(), trackID)
+	if errTable != nil {
+		return errTable
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:%d\n", hlsVersion)
+	fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"%s\"\n", table.Bitrate, hlsCodecMP3)
+	b.WriteString("media.m3u8" + h.hlsQuery(c, trackID) + "\n")
+	h.writeHLSPlaylist(c, b.String())
+	return nil
+}
+
+// HLSMediaPlaylistService writes the VOD media playlist of a track.
+func (h *Service) HLSMediaPlaylistService(c *gin.Context, trackID string) *model.RestError {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HLSMediaPlaylistService")
+	defer span.End()
+
+	_, table, errTable := h.trackSeekTable(c.Request.Context(), trackID)
+	if errTable != nil {
+		return errTable
+	}
+	h.writeHLSPlaylist(c, buildHLSMediaPlaylist([]hlsTrack{h.newHLSTrack(trackID, "segment/", h.hlsQuery(c, trackID), table)}))
+	return nil
+}
+
+// hlsQuery returns the stream token query for the URIs of a track playlist: the
+// token the playlist was requested with, or a new one for a signed in user.
+func (h *Service) hlsQuery(c *gin.Context, trackID string) string {
+	if token := c.Query(StreamTokenParam); token != "" {
+		return "?" + StreamTokenParam + "=" + url.QueryEscape(token)
+	}
+	return h.StreamQuery(c.GetString("user_id"), trackID, c.ClientIP())
+}
+
+// newHLSTrack attaches the key URI of the track version when encryption is enabled.
+func (h *Service) newHLSTrack(trackID, prefix, query string, table *model.SeekTable) hlsTrack {
+	track := hlsTrack{prefix: prefix, query: query, table: table}
+	if h.cfg.AppConfig.Stream.HLS.Encryption {
+		track.keyURI = hlsKeyURI(trackID, table.Version, query)
+	}
+	return track
+}
+
+// HLSSegmentService serves one frame-aligned segment with a ranged GetObject,
+// encrypted with the key of the track version when encryption is enabled.
+func (h *Service) HLSSegmentService(c *gin.Context, trackID, segment string) *model.RestError {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HLSSegmentService")
+	defer span.End()
+
+	index, err := strconv.Atoi(strings.TrimSuffix(segment, hlsSegmentExtension))
+	if err != nil || index < 0 {
+		return &model.RestError{Code: http.StatusBadRequest, Err: "invalid segment index"}
+	}
+	object, table, errTable := h.trackSeekTable(c.Request.Context(), trackID)
+	if errTable != nil {
+		return errTable
+	}
+	if index >= len(table.Segments) {
+		return &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+	}
+	seg := table.Segments[index]
+	if h.cfg.AppConfig.Stream.HLS.Encryption {
+		return h.writeEncryptedSegment(c, trackID, object, seg)
+	}
+
+	tag := TimestampTag(seg.Start)
+	c.Header("Content-Type", "audio/mpeg")
+	c.Header("Content-Length", strconv.FormatInt(int64(len(tag))+seg.Length, 10))
+	c.Status(http.StatusOK)
+	if _, err = c.Writer.Write(tag); err != nil {
+		return nil
+	}
+	if err = h.copyRange(c.Request.Context(), c.Writer, object, model.HTTPRange{Start: seg.Offset, Length: seg.Length}); err != nil {
+		h.logger.Errorf("Error streaming segment %d of %s: %v", index, object.Key, err)
+	}
+	return nil
+}
+
+// PlayHLSPlaylist writes one VOD media playlist for all MP3 tracks of a playlist,
+// separating the tracks with discontinuities. Segment URIs are signed for the user.
+// The seek tables of the tracks are built a few at a time.
+func (h *Service) PlayHLSPlaylist(c *gin.Context, tracks *[]model.TrackRequest, userID string) {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "PlayHLSPlaylist")
+	defer span.End()
+
+	tables := make([]*model.SeekTable, len(*tracks))
+	var group errgroup.Group
+	group.SetLimit(hlsSeekTableWorkers)
+	for i, trackRequest := range *tracks {
+		trackID := trackRequest.Track.ID.String()
+		group.Go(func() error {
+			_, table, errTable := h.trackSeekTable(c.Request.Context(), trackID)
+			if errTable != nil {
+				h.logger.Warnf("Track %s skipped in HLS playlist: %s", trackID, errTable.Err)
+				return nil
+			}
+			tables[i] = table
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	var hlsTracks []hlsTrack
+	for i, trackRequest := range *tracks {
+		if tables[i] == nil {
+			continue
+		}
+		trackID := trackRequest.Track.ID.String()
+		hlsTracks = append(hlsTracks, h.newHLSTrack(trackID, "hls/"+trackID+"/segment/", h.StreamQuery(userID, trackID, c.ClientIP()), tables[i]))
+	}
+	if len(hlsTracks) == 0 {
+		c.JSON(http.StatusUnsupportedMediaType, model.ErrorResponse{Message: "no tracks can be played as HLS"})
+		return
+	}
+	h.writeHLSPlaylist(c, buildHLSMediaPlaylist(hlsTracks))
+}
+
+func (h *Service) writeHLSPlaylist(c *gin.Context, playlist string) {
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, HLSContentType, []byte(playlist))
+}
+
+// buildHLSMediaPlaylist renders the segments of the tracks as a VOD media playlist.
+// Encrypted segments carry an explicit IV equal to their index, since the media
+// sequence numbering restarts with every track.
+func buildHLSMediaPlaylist(tracks []hlsTrack) string {
+	var maxDuration time.Duration
+	for _, track := range tracks {
+		for _, segment := range track.table.Segments {
+			if segment.Duration > maxDuration {
+				maxDuration = segment.Duration
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#EXT-X-VERSION:%d\n", hlsVersion)
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(maxDuration.Seconds())))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n")
+	for i, track := range tracks {
+		if i > 0 {
+			b.WriteString("#EXT-X-DISCONTINUITY\n")
+		}
+		for _, segment := range track.table.Segments {
+			if track.keyURI != "" {
+				fmt.Fprintf(&b, "#EXT-X-KEY:METHOD=AES-128,URI=\"%s\",IV=0x%032x\n", track.keyURI, segment.Index)
+			}
+			fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s%d%s%s\n", segment.Duration.Seconds(), track.prefix, segment.Index, hlsSegmentExtension, track.query)
+		}
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String()
+}
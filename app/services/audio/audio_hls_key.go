@@ -0,0 +1,144 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"s3MediaStreamer/app/model"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	hlsKeyLength   = 16
+	hlsKeyPath     = "/v1/hls/keys/"
+	KeyContentType = "application/octet-stream"
+)
+
+// EncryptSegment encrypts an HLS segment with AES-128-CBC and PKCS7 padding.
+// The IV is the media sequence number of the segment, as the playlists announce it.
+func EncryptSegment(key []byte, index int, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	out := make([]byte, len(plain), len(plain)+padding)
+	copy(out, plain)
+	out = append(out, bytes.Repeat([]byte{byte(padding)}, padding)...)
+	cipher.NewCBCEncrypter(block, segmentIV(index)).CryptBlocks(out, out)
+	return out, nil
+}
+
+// segmentIV returns the segment index as a 128 bit big-endian initialization vector.
+func segmentIV(index int) []byte {
+	iv := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[aes.BlockSize-8:], uint64(index))
+	return iv
+}
+
+// hlsKeyURI returns the key endpoint of a track version, carrying the stream
+// token query of the playlist so that players without the session cookie can
+// fetch the key.
+func hlsKeyURI(trackID, version, query string) string {
+	uri := hlsKeyPath + trackID + "?v=" + url.QueryEscape(version)
+	if query != "" {
+		uri += "&" + strings.TrimPrefix(query, "?")
+	}
+	return uri
+}
+
+// hlsKey returns the key of the track version, generating it on first use.
+// Generating the key of a new version drops the keys of the older ones.
+func (h *Service) hlsKey(ctx context.Context, trackID, version string) ([]byte, error) {
+	key, err := h.repository.GetHLSKey(ctx, trackID, version)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	key = make([]byte, hlsKeyLength)
+	if _, err = io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if err = h.repository.AddHLSKey(ctx, trackID, version, key); err != nil {
+		return nil, err
+	}
+	if err = h.repository.DeleteHLSKeys(ctx, trackID, version); err != nil {
+		h.logger.Warnf("Error rotating HLS keys of track %s: %v", trackID, err)
+	}
+	// Read back, another request may have stored its key first.
+	return h.repository.GetHLSKey(ctx, trackID, version)
+}
+
+// HLSKeyService writes the AES-128 key of the current version of a track to
+// signed in users and to holders of a stream token of the track. Keys of
+// replaced versions are no longer served.
+func (h *Service) HLSKeyService(c *gin.Context, trackID, version string) *model.RestError {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "HLSKeyService")
+	defer span.End()
+
+	if !h.cfg.AppConfig.Stream.HLS.Encryption {
+		return &model.RestError{Code: http.StatusNotFound, Err: "HLS encryption is disabled"}
+	}
+	if errAuth := h.AuthorizeStreamService(c, trackID); errAuth != nil {
+		return errAuth
+	}
+	object, _, errFind := h.FindSegmentObject(c.Request.Context(), trackID)
+	if errFind != nil {
+		return errFind
+	}
+	if version != object.VersionID {
+		return &model.RestError{Code: http.StatusNotFound, Err: "Key not found"}
+	}
+	key, err := h.hlsKey(c.Request.Context(), trackID, version)
+	if err != nil {
+		h.logger.Errorf("Error loading HLS key of track %s: %v", trackID, err)
+		return &model.RestError{Code: http.StatusInternalServerError, Err: "Error loading key"}
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, KeyContentType, key)
+	return nil
+}
+
+// writeEncryptedSegment reads the segment into memory behind its timestamp tag
+// and writes it AES-128 encrypted.
+func (h *Service) writeEncryptedSegment(c *gin.Context, trackID string, object *minio.ObjectInfo, seg model.HLSSegment) *model.RestError {
+	key, err := h.hlsKey(c.Request.Context(), trackID, object.VersionID)
+	if err != nil {
+		h.logger.Errorf("Error loading HLS key of track %s: %v", trackID, err)
+		return &model.RestError{Code: http.StatusInternalServerError, Err: "Error loading key"}
+	}
+
+	tag := TimestampTag(seg.Start)
+	var plain bytes.Buffer
+	plain.Grow(len(tag) + int(seg.Length))
+	plain.Write(tag)
+	if err = h.copyRange(c.Request.Context(), &plain, object, model.HTTPRange{Start: seg.Offset, Length: seg.Length}); err != nil {
+		h.logger.Errorf("Error reading segment %d of %s: %v", seg.Index, object.Key, err)
+		return &model.RestError{Code: http.StatusInternalServerError, Err: "Error reading segment"}
+	}
+	encrypted, err := EncryptSegment(key, seg.Index, plain.Bytes())
+	if err != nil {
+		h.logger.Errorf("Error encrypting segment %d of %s: %v", seg.Index, object.Key, err)
+		return &model.RestError{Code: http.StatusInternalServerError, Err: "Error encrypting segment"}
+	}
+
+	c.Header("Content-Length", strconv.Itoa(len(encrypted)))
+	c.Data(http.StatusOK, "audio/mpeg", encrypted)
+	return nil
+}
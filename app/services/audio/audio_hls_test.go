@@ -0,0 +1,92 @@
+package audio_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"s3MediaStreamer/app/services/audio"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MPEG-1 Layer III, 128 kbit/s, 44100 Hz, no padding: 417 bytes and 1152 samples per frame.
+const testFrameSize = 417
+
+func testMP3(frames int, id3 []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(id3)
+	for i := 0; i < frames; i++ {
+		frame := make([]byte, testFrameSize)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		buf.Write(frame)
+	}
+	return buf.Bytes()
+}
+
+func TestBuildSeekTable(t *testing.T) {
+	id3 := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20}
+	id3 = append(id3, make([]byte, 20)...)
+	data := testMP3(1000, id3)
+
+	table, err := audio.BuildSeekTable(bytes.NewReader(data), 8*time.Second)
+	require.NoError(t, err)
+	require.Len(t, table.Segments, 4)
+
+	offset := int64(len(id3))
+	var start time.Duration
+	for i, segment := range table.Segments {
+		assert.Equal(t, i, segment.Index)
+		assert.Equal(t, offset, segment.Offset, "segment %d must start on a frame", i)
+		assert.Equal(t, start, segment.Start, "segment %d must start where the previous one ends", i)
+		assert.Zero(t, segment.Length%testFrameSize, "segment %d must end on a frame", i)
+		if i < len(table.Segments)-1 {
+			assert.GreaterOrEqual(t, segment.Duration, 8*time.Second)
+			assert.Less(t, segment.Duration, 9*time.Second)
+		}
+		offset += segment.Length
+		start += segment.Duration
+	}
+	assert.Equal(t, int64(len(data)), offset)
+	assert.InDelta(t, 26.1, table.Duration.Seconds(), 0.1)
+	assert.InDelta(t, 128000, table.Bitrate, 1000)
+}
+
+func TestBuildSeekTableNoFrames(t *testing.T) {
+	_, err := audio.BuildSeekTable(bytes.NewReader(make([]byte, 64)), 8*time.Second)
+	assert.ErrorIs(t, err, audio.ErrNoFrames)
+}
+
+func TestTimestampTag(t *testing.T) {
+	tag := audio.TimestampTag(10 * time.Second)
+
+	owner := "com.apple.streaming.transportStreamTimestamp\x00"
+	require.Len(t, tag, 10+10+len(owner)+8)
+	assert.Equal(t, []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, byte(10 + len(owner) + 8)}, tag[:10])
+	assert.Equal(t, "PRIV", string(tag[10:14]))
+	assert.Equal(t, owner, string(tag[20:20+len(owner)]))
+	assert.Equal(t, uint64(900000), binary.BigEndian.Uint64(tag[20+len(owner):]))
+}
+
+func TestEncryptSegment(t *testing.T) {
+	key := bytes.Repeat([]byte{0x2a}, 16)
+	plain := testMP3(1, nil)
+
+	encrypted, err := audio.EncryptSegment(key, 3, plain)
+	require.NoError(t, err)
+	require.Len(t, encrypted, (len(plain)/aes.BlockSize+1)*aes.BlockSize)
+
+	// Decrypt the way a player does with IV=0x...03 from the playlist.
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	iv := make([]byte, aes.BlockSize)
+	iv[aes.BlockSize-1] = 3
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, encrypted)
+
+	padding := int(decrypted[len(decrypted)-1])
+	assert.Equal(t, plain, decrypted[:len(decrypted)-padding])
+}
@@ -0,0 +1,134 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"s3MediaStreamer/app/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidRange is returned if the Range header is malformed.
+	ErrInvalidRange = errors.New("invalid range")
+	// ErrNoOverlap is returned if none of the requested ranges overlap the object.
+	ErrNoOverlap = errors.New("invalid range: failed to overlap")
+)
+
+// ParseRange parses a Range header string as per RFC 7233.
+// ErrNoOverlap is returned if none of the ranges overlap the object of the given size.
+func ParseRange(s string, size int64) ([]model.HTTPRange, error) {
+	if s == "" {
+		return nil, nil // header not present
+	}
+	const b = "bytes="
+	if !strings.HasPrefix(s, b) {
+		return nil, ErrInvalidRange
+	}
+	var ranges []model.HTTPRange
+	noOverlap := false
+	for _, ra := range strings.Split(s[len(b):], ",") {
+		ra = textproto.TrimString(ra)
+		if ra == "" {
+			continue
+		}
+		start, end, ok := strings.Cut(ra, "-")
+		if !ok {
+			return nil, ErrInvalidRange
+		}
+		start, end = textproto.TrimString(start), textproto.TrimString(end)
+		var r model.HTTPRange
+		if start == "" {
+			// If no start is specified, end specifies the
+			// range start relative to the end of the object,
+			// and we are dealing with <suffix-length>
+			// which has to be a non-negative integer as per
+			// RFC 7233 Section 2.1 "Byte-Ranges".
+			if end == "" || end[0] == '-' {
+				return nil, ErrInvalidRange
+			}
+			i, err := strconv.ParseInt(end, 10, 64)
+			if i < 0 || err != nil {
+				return nil, ErrInvalidRange
+			}
+			if i > size {
+				i = size
+			}
+			r.Start = size - i
+			r.Length = size - r.Start
+		} else {
+			i, err := strconv.ParseInt(start, 10, 64)
+			if err != nil || i < 0 {
+				return nil, ErrInvalidRange
+			}
+			if i >= size {
+				// If the range begins after the size of the object,
+				// there is no overlap.
+				noOverlap = true
+				continue
+			}
+			r.Start = i
+			if end == "" {
+				// If no end is specified, range extends to end of the object.
+				r.Length = size - r.Start
+			} else {
+				i, err = strconv.ParseInt(end, 10, 64)
+				if err != nil || r.Start > i {
+					return nil, ErrInvalidRange
+				}
+				if i >= size {
+					i = size - 1
+				}
+				r.Length = i - r.Start + 1
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	if noOverlap && len(ranges) == 0 {
+		// The specified ranges did not overlap with the object.
+		return nil, ErrNoOverlap
+	}
+	return ranges, nil
+}
+
+// SumRangesSize returns the total number of bytes covered by the ranges.
+func SumRangesSize(ranges []model.HTTPRange) int64 {
+	var size int64
+	for _, ra := range ranges {
+		size += ra.Length
+	}
+	return size
+}
+
+// ContentRange formats the Content-Range header value of a range.
+func ContentRange(r model.HTTPRange, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+// CheckIfRange reports whether the Range header should be honoured for the
+// given If-Range value. The validator is either a strong ETag or an HTTP date.
+func CheckIfRange(ifRange, etag string, lastModified time.Time) bool {
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		// Weak validators never match for ranges (RFC 7233 Section 3.2).
+		return !strings.HasPrefix(ifRange, "W/") && ifRange == QuoteETag(etag)
+	}
+	t, err := http.ParseTime(ifRange)
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+	return lastModified.Truncate(time.Second).Equal(t)
+}
+
+// QuoteETag returns the ETag in its quoted header form.
+func QuoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
@@ -0,0 +1,47 @@
+package audio_test
+
+import (
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/audio"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRange(t *testing.T) {
+	const size = 10000
+	tests := []struct {
+		header string
+		want   []model.HTTPRange
+		err    error
+	}{
+		{"", nil, nil},
+		{"bytes=0-499", []model.HTTPRange{{Start: 0, Length: 500}}, nil},
+		{"bytes=9500-", []model.HTTPRange{{Start: 9500, Length: 500}}, nil},
+		{"bytes=-500", []model.HTTPRange{{Start: 9500, Length: 500}}, nil},
+		{"bytes=9500-20000", []model.HTTPRange{{Start: 9500, Length: 500}}, nil},
+		{"bytes=0-0, -1", []model.HTTPRange{{Start: 0, Length: 1}, {Start: 9999, Length: 1}}, nil},
+		{"bytes=500-700,601-999", []model.HTTPRange{{Start: 500, Length: 201}, {Start: 601, Length: 399}}, nil},
+		{"bytes=10000-", nil, audio.ErrNoOverlap},
+		{"bytes=700-600", nil, audio.ErrInvalidRange},
+		{"bytes=--5", nil, audio.ErrInvalidRange},
+		{"items=0-5", nil, audio.ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		got, err := audio.ParseRange(tt.header, size)
+		assert.ErrorIs(t, err, tt.err, tt.header)
+		assert.Equal(t, tt.want, got, tt.header)
+	}
+}
+
+func TestCheckIfRange(t *testing.T) {
+	modified := time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+
+	assert.True(t, audio.CheckIfRange("", "abc", modified))
+	assert.True(t, audio.CheckIfRange(`"abc"`, "abc", modified))
+	assert.False(t, audio.CheckIfRange(`"abd"`, "abc", modified))
+	assert.False(t, audio.CheckIfRange(`W/"abc"`, "abc", modified))
+	assert.True(t, audio.CheckIfRange("Wed, 01 May 2024 10:00:00 GMT", "abc", modified))
+	assert.False(t, audio.CheckIfRange("Wed, 01 May 2024 09:00:00 GMT", "abc", modified))
+}
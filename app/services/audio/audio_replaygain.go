@@ -0,0 +1,60 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/loudness"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+)
+
+// AlbumGainService sets the album gain of the playlist entries. It is worked
+// out across all tracks sharing the album and album artist, not only those in
+// the playlist. Tracks without an album keep their track gain only.
+func (h *Service) AlbumGainService(ctx context.Context, tracks *[]model.TrackRequest, playlist []*model.PlaylistM3U) {
+	ctx, span := otel.Tracer("").Start(ctx, "AlbumGainService")
+	defer span.End()
+
+	gains := make(map[[2]string]*float64)
+	for i, trackRequest := range *tracks {
+		if i >= len(playlist) || trackRequest.Album == "" {
+			continue
+		}
+		key := [2]string{trackRequest.Album, trackRequest.AlbumArtist}
+		gain, ok := gains[key]
+		if !ok {
+			gain = h.albumGain(ctx, trackRequest.Album, trackRequest.AlbumArtist)
+			gains[key] = gain
+		}
+		playlist[i].AlbumGain = gain
+	}
+}
+
+func (h *Service) albumGain(ctx context.Context, album, albumArtist string) *float64 {
+	albumTracks, err := h.track.GetTracksByAlbum(ctx, album, albumArtist)
+	if err != nil {
+		h.logger.Errorf("Error getting tracks of album %s: %v", album, err)
+		return nil
+	}
+	integrated, ok := loudness.AlbumLoudness(albumTracks)
+	if !ok {
+		return nil
+	}
+	gain := loudness.Gain(integrated)
+	return &gain
+}
+
+// replayGainTags returns the ReplayGain comment lines written before the
+// #EXTINF line of the entry. Players that do not know them skip them.
+func replayGainTags(segment *model.PlaylistM3U) string {
+	var tags strings.Builder
+	if segment.TrackGain != nil {
+		fmt.Fprintf(&tags, "#REPLAYGAIN_TRACK_GAIN:%.2f dB\n", *segment.TrackGain)
+	}
+	if segment.AlbumGain != nil {
+		fmt.Fprintf(&tags, "#REPLAYGAIN_ALBUM_GAIN:%.2f dB\n", *segment.AlbumGain)
+	}
+	return tags.String()
+}
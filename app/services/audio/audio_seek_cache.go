@@ -0,0 +1,67 @@
+package audio
+
+import (
+	"container/list"
+	"sync"
+)
+
+const defaultSeekTableCacheSize = 256
+
+// lruCache keeps the most recently used values, seek tables and CUE slices,
+// keyed by S3 version ID.
+type lruCache[V any] struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRUCache[V any](size int) *lruCache[V] {
+	if size <= 0 {
+		size = defaultSeekTableCacheSize
+	}
+	return &lruCache[V]{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *lruCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	entry, _ := element.Value.(*lruEntry[V])
+	return entry.value, true
+}
+
+func (c *lruCache[V]) Add(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &lruEntry[V]{key: key, value: value}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		if evicted, ok := oldest.Value.(*lruEntry[V]); ok {
+			delete(c.entries, evicted.key)
+		}
+	}
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"net/textproto"
 	"path/filepath"
 	"s3MediaStreamer/app/internal/logs"
 	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/diskcache"
 	"s3MediaStreamer/app/services/playlist"
 	"s3MediaStreamer/app/services/s3"
 	"s3MediaStreamer/app/services/track"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
@@ -19,81 +22,190 @@ import (
 )
 
 type Repository interface {
+	GetHLSKey(ctx context.Context, trackID, version string) ([]byte, error)
+	AddHLSKey(ctx context.Context, trackID, version string, key []byte) error
+	DeleteHLSKeys(ctx context.Context, trackID, exceptVersion string) error
 }
 
 type Service struct {
-	track    track.Service
-	s3       s3.Service
-	playlist playlist.Service
-	logger   *logs.Logger
+	cfg        *model.Config
+	track      track.Service
+	s3         s3.Service
+	playlist   playlist.Service
+	repository Repository
+	cache      *diskcache.Service
+	logger     *logs.Logger
+	seekTables *lruCache[*model.SeekTable]
+	cueSlices  *lruCache[*model.CueSlice]
 }
 
-func NewAudioService(track track.Service, s3 s3.Service, playlist playlist.Service, logger *logs.Logger) *Service {
-	return &Service{track, s3, playlist, logger}
-}
-
-func (h Service) StreamFileService(c *gin.Context, fileName string, f *os.File) {
-	// Use a channel to signal completion
-	done := make(chan struct{})
-	defer close(done)
-
-	// Use a goroutine to stream the file and check for client disconnection
-	go func() {
-		defer func() {
-			// Clean up after streaming is done
-			err := h.s3.CleanTemplateFile(fileName)
-			if err != nil {
-				h.logger.Errorf("Error cleaning up file: %v", err)
-				return
-			}
-		}()
-
-		_, err := io.Copy(c.Writer, f)
-		if err != nil {
-			// Log the error, but don't treat it as a critical error
-			h.logger.Errorf("Error streaming audio_handler: %v", err)
-			return
-		}
-	}()
+func NewAudioService(cfg *model.Config, track track.Service, s3 s3.Service, playlist playlist.Service,
+	repository Repository, cache *diskcache.Service, logger *logs.Logger) *Service {
+	return &Service{
+		cfg:        cfg,
+		track:      track,
+		s3:         s3,
+		playlist:   playlist,
+		repository: repository,
+		cache:      cache,
+		logger:     logger,
+		seekTables: newLRUCache[*model.SeekTable](cfg.AppConfig.Stream.HLS.SeekTableCacheSize),
+		cueSlices:  newLRUCache[*model.CueSlice](cfg.AppConfig.Stream.HLS.SeekTableCacheSize),
+	}
 }
 
-func (h Service) StreamM3UReadFileService(ctx context.Context, segmentPath string) (*minio.ObjectInfo, string, *os.File, *model.Track, *model.RestError) {
+// FindSegmentObject resolves the S3 object version that backs the requested track.
+func (h Service) FindSegmentObject(ctx context.Context, segmentPath string) (*minio.ObjectInfo, *model.Track, *model.RestError) {
 	track, err := h.track.GetTracksByColumns(ctx, segmentPath, "_id")
 	if err != nil {
-		return nil, "", nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+		return nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
 	}
 
 	trackID, err := h.s3.GetS3VersionByTrackID(ctx, track.ID.String())
 	if err != nil {
-		return nil, "", nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+		return nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+	}
+	findObject, err := h.s3.FindObjectFromVersion(ctx, trackID)
+	if err != nil {
+		return nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+	}
+	return &findObject, track, nil
+}
+
+// FindVersionObject resolves a recorded S3 object version of the track, the
+// current one or an older one.
+func (h Service) FindVersionObject(ctx context.Context, segmentPath, version string) (*minio.ObjectInfo, *model.Track, *model.RestError) {
+	track, err := h.track.GetTracksByColumns(ctx, segmentPath, "_id")
+	if err != nil {
+		return nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
 	}
-	findObject, err := h.s3.FindObjectFromVersion(context.Background(), trackID)
+	versions, err := h.s3.GetS3Versions(ctx, track.ID.String())
 	if err != nil {
-		return nil, "", nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+		h.logger.Errorf("Error getting the versions of track %s: %v", track.ID, err)
+		return nil, nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	for _, recorded := range versions {
+		if recorded.VersionID != version {
+			continue
+		}
+		findObject, errFind := h.s3.FindObjectFromVersion(ctx, version)
+		if errFind != nil {
+			return nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Version not found"}
+		}
+		return &findObject, track, nil
 	}
+	return nil, nil, &model.RestError{Code: http.StatusNotFound, Err: "Version not found"}
+}
 
-	fileName, err := h.s3.DownloadFilesS3(context.Background(), findObject.Key)
+// ParseRangeService validates the Range and If-Range headers against the object.
+// A nil result means the whole object has to be sent.
+func (h Service) ParseRangeService(rangeHeader, ifRange string, object *minio.ObjectInfo) ([]model.HTTPRange, *model.RestError) {
+	if rangeHeader == "" || !CheckIfRange(ifRange, object.ETag, object.LastModified) {
+		return nil, nil
+	}
+	ranges, err := ParseRange(rangeHeader, object.Size)
 	if err != nil {
-		return nil, "", nil, nil, &model.RestError{Code: http.StatusNotAcceptable, Err: "Error downloading file"}
+		return nil, &model.RestError{Code: http.StatusRequestedRangeNotSatisfiable, Err: err.Error()}
+	}
+	if SumRangesSize(ranges) > object.Size {
+		// The total number of bytes in all the ranges is larger than the
+		// object itself, so this is probably an attack, or a dumb client.
+		// Ignore the range request.
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// StreamRangeService answers with 206 Partial Content, fetching every range
+// with a ranged GetObject on the exact object version.
+func (h Service) StreamRangeService(c *gin.Context, object *minio.ObjectInfo, ranges []model.HTTPRange, contentType string) error {
+	ctx := c.Request.Context()
+	return writeRanges(c, ranges, object.Size, contentType, func(w io.Writer, ra model.HTTPRange) error {
+		return h.copyRange(ctx, w, object, ra)
+	})
+}
+
+// writeRanges writes the ranges of a body of size bytes as 206 Partial
+// Content, a multipart one for more than one range.
+func writeRanges(c *gin.Context, ranges []model.HTTPRange, size int64, contentType string,
+	copyRange func(w io.Writer, ra model.HTTPRange) error) error {
+	if len(ranges) == 1 {
+		ra := ranges[0]
+		c.Header("Content-Range", ContentRange(ra, size))
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Length", strconv.FormatInt(ra.Length, 10))
+		c.Status(http.StatusPartialContent)
+		return copyRange(c.Writer, ra)
+	}
+
+	mw := multipart.NewWriter(c.Writer)
+	c.Header("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	c.Header("Content-Length", strconv.FormatInt(rangesMIMESize(ranges, mw.Boundary(), contentType, size), 10))
+	c.Status(http.StatusPartialContent)
+	for _, ra := range ranges {
+		part, err := mw.CreatePart(rangeMIMEHeader(ra, contentType, size))
+		if err != nil {
+			return err
+		}
+		if err = copyRange(part, ra); err != nil {
+			return err
+		}
 	}
-	// Open the file
-	f, err := h.s3.OpenTemplateFile(fileName)
+	return mw.Close()
+}
+
+func (h Service) copyRange(ctx context.Context, w io.Writer, object *minio.ObjectInfo, ra model.HTTPRange) error {
+	reader, err := h.openObjectRange(ctx, object, ra)
 	if err != nil {
-		return nil, "", nil, nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Error reading file data"}
+		return err
+	}
+	defer reader.Close()
+	_, err = copyWithContext(ctx, w, io.LimitReader(reader, ra.Length))
+	return err
+}
+
+func rangeMIMEHeader(ra model.HTTPRange, contentType string, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {ContentRange(ra, size)},
+		"Content-Type":  {contentType},
+	}
+}
+
+// rangesMIMESize returns the number of bytes it takes to encode the
+// provided ranges as a multipart response.
+func rangesMIMESize(ranges []model.HTTPRange, boundary, contentType string, size int64) int64 {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	_ = mw.SetBoundary(boundary)
+	var encSize int64
+	for _, ra := range ranges {
+		_, _ = mw.CreatePart(rangeMIMEHeader(ra, contentType, size))
+		encSize += ra.Length
 	}
-	return &findObject, fileName, f, track, nil
+	_ = mw.Close()
+	return encSize + int64(w)
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
 }
 
-func (h *Service) GenerateM3U8Playlist(filePaths *[]model.TrackRequest) []*model.PlaylistM3U {
+// GenerateM3U8Playlist lists the stream URIs of the tracks, signed for the user
+// so that players without the session cookie can fetch them.
+func (h *Service) GenerateM3U8Playlist(filePaths *[]model.TrackRequest, userID, clientIP string) []*model.PlaylistM3U {
 	var generatePlaylist []*model.PlaylistM3U
 
 	var prefixURI = "stream/"
 	for _, trackRequest := range *filePaths {
 		item := trackRequest.Track // Access the embedded Track struct
 		segment := &model.PlaylistM3U{
-			URI:      prefixURI + item.ID.String(),
-			Title:    filepath.Base(item.Artist) + " - " + filepath.Base(item.Title),
-			Duration: item.Duration.Seconds(),
+			URI:       prefixURI + item.ID.String() + h.StreamQuery(userID, item.ID.String(), clientIP),
+			Title:     filepath.Base(item.Artist) + " - " + filepath.Base(item.Title),
+			Duration:  item.Duration.Seconds(),
+			TrackGain: item.TrackGain,
 		}
 		generatePlaylist = append(generatePlaylist, segment)
 	}
@@ -113,7 +225,7 @@ func (h *Service) PlayM3UPlaylist(playlist []*model.PlaylistM3U, c *gin.Context)
 
 	// Write each segment information
 	for _, segment := range playlist {
-		_, err = fmt.Fprintf(c.Writer, "#EXTINF:%d,%s\n%s\n", int(segment.Duration), segment.Title, segment.URI)
+		_, err = fmt.Fprintf(c.Writer, "%s#EXTINF:%d,%s\n%s\n", replayGainTags(segment), int(segment.Duration), segment.Title, segment.URI)
 		if err != nil {
 			h.logger.Errorf("Error writing segment information: %v", err)
 			return
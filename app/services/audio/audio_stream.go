@@ -0,0 +1,119 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"s3MediaStreamer/app/model"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+)
+
+const streamBufferSize = 32 * 1024
+
+var streamBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, streamBufferSize)
+		return &buf
+	},
+}
+
+// OpenObject opens the object version from the disk cache when it is enabled,
+// otherwise straight from S3.
+func (h Service) OpenObject(ctx context.Context, object *minio.ObjectInfo) (io.ReadCloser, error) {
+	if h.cache.Enabled() {
+		return h.cache.Open(ctx, object)
+	}
+	reader, err := h.s3.GetObjectS3(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+	// Stat performs the GET request, so S3 errors surface before any header is written.
+	if _, err = reader.Stat(); err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// openObjectRange opens the object version positioned at ra.Start. Without the
+// disk cache only the range is requested from S3.
+func (h Service) openObjectRange(ctx context.Context, object *minio.ObjectInfo, ra model.HTTPRange) (io.ReadCloser, error) {
+	if !h.cache.Enabled() {
+		return h.s3.GetObjectRangeS3(ctx, object, ra.Start, ra.Start+ra.Length-1)
+	}
+	f, err := h.cache.Open(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = f.Seek(ra.Start, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// StreamObjectService pipes the object version to the client. S3 is only read
+// as fast as the client consumes the response unless the disk cache holds the
+// object, and the transfer stops when the request context is canceled.
+func (h Service) StreamObjectService(c *gin.Context, object *minio.ObjectInfo, contentType string) *model.RestError {
+	ctx := c.Request.Context()
+	reader, err := h.OpenObject(ctx, object)
+	if err != nil {
+		h.logger.Errorf("Error opening object %s: %v", object.Key, err)
+		return &model.RestError{Code: http.StatusNotFound, Err: "Segment not found"}
+	}
+	defer reader.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.FormatInt(object.Size, 10))
+	c.Status(http.StatusOK)
+
+	written, err := copyWithContext(ctx, c.Writer, reader)
+	switch {
+	case errors.Is(err, context.Canceled):
+		h.logger.Infof("Client disconnected after %d of %d bytes of %s, stopping streaming.", written, object.Size, object.Key)
+	case err != nil:
+		h.logger.Errorf("Error streaming audio_handler: %v", err)
+	}
+	return nil
+}
+
+// copyWithContext copies src to dst with a pooled buffer and stops as soon as ctx is done.
+func copyWithContext(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	bufPtr, ok := streamBufferPool.Get().(*[]byte)
+	if !ok {
+		buf := make([]byte, streamBufferSize)
+		bufPtr = &buf
+	}
+	defer streamBufferPool.Put(bufPtr)
+	buf := *bufPtr
+
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		nr, readErr := src.Read(buf)
+		if nr > 0 {
+			nw, writeErr := dst.Write(buf[:nr])
+			written += int64(nw)
+			if writeErr != nil {
+				return written, writeErr
+			}
+			if nw != nr {
+				return written, io.ErrShortWrite
+			}
+		}
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return written, nil
+			}
+			return written, readErr
+		}
+	}
+}
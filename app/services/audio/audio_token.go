@@ -0,0 +1,130 @@
+package audio
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"s3MediaStreamer/app/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StreamTokenParam      = "token"
+	defaultStreamTokenTTL = 6 * time.Hour
+	streamTokenParts      = 4
+	streamTokenSeparator  = "."
+	// streamTokenPlaceholder is the secret of the sample configurations, never a real key.
+	streamTokenPlaceholder = "YOUR_STREAM_TOKEN_SECRET"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid stream token")
+	ErrExpiredToken = errors.New("stream token expired")
+)
+
+// CheckStreamTokenKeys returns an error unless the active key is configured
+// and no key is empty or still the placeholder of the sample configuration.
+func CheckStreamTokenKeys(keys map[string]string, activeKey string) error {
+	if keys[activeKey] == "" {
+		return fmt.Errorf("stream token key %q is not set, configure it with STREAM_TOKEN_KEYS", activeKey)
+	}
+	for kid, secret := range keys {
+		if secret == "" || secret == streamTokenPlaceholder {
+			return fmt.Errorf("stream token key %q has no secret, configure it with STREAM_TOKEN_KEYS", kid)
+		}
+	}
+	return nil
+}
+
+// SignStreamToken returns a token granting userID access to trackID until expires.
+// The token is "<kid>.<user>.<expires>.<mac>", the track and the optional client
+// IP are only part of the MAC.
+func SignStreamToken(kid string, secret []byte, userID, trackID, ip string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return strings.Join([]string{kid, userID, exp, streamTokenMAC(secret, kid, userID, trackID, exp, ip)}, streamTokenSeparator)
+}
+
+// VerifyStreamToken checks the token against trackID and ip with the key named
+// in the token and returns the user it was issued to. Tokens signed with any
+// configured key are accepted, so a key can be rotated out gradually.
+func VerifyStreamToken(keys map[string]string, token, trackID, ip string, now time.Time) (string, error) {
+	parts := strings.Split(token, streamTokenSeparator)
+	if len(parts) != streamTokenParts {
+		return "", ErrInvalidToken
+	}
+	kid, userID, exp, mac := parts[0], parts[1], parts[2], parts[3]
+	secret, ok := keys[kid]
+	if !ok || secret == "" {
+		return "", ErrInvalidToken
+	}
+	if !hmac.Equal([]byte(mac), []byte(streamTokenMAC([]byte(secret), kid, userID, trackID, exp, ip))) {
+		return "", ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	if now.Unix() > expires {
+		return "", ErrExpiredToken
+	}
+	return userID, nil
+}
+
+func streamTokenMAC(secret []byte, kid, userID, trackID, exp, ip string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{kid, userID, trackID, exp, ip}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// streamTokenTTL returns the configured token lifetime.
+func (h *Service) streamTokenTTL() time.Duration {
+	if ttl := h.cfg.AppConfig.Stream.Token.TTL; ttl > 0 {
+		return time.Duration(ttl) * time.Second
+	}
+	return defaultStreamTokenTTL
+}
+
+// tokenIP returns the address a token is bound to, empty when IP binding is off.
+func (h *Service) tokenIP(clientIP string) string {
+	if h.cfg.AppConfig.Stream.Token.BindIP {
+		return clientIP
+	}
+	return ""
+}
+
+// StreamQuery returns the query string carrying a stream token of the user for
+// the track, or an empty string when no signing key is configured.
+func (h *Service) StreamQuery(userID, trackID, clientIP string) string {
+	cfg := h.cfg.AppConfig.Stream.Token
+	secret := cfg.Keys[cfg.ActiveKey]
+	if userID == "" || secret == "" {
+		return ""
+	}
+	token := SignStreamToken(cfg.ActiveKey, []byte(secret), userID, trackID, h.tokenIP(clientIP), time.Now().Add(h.streamTokenTTL()))
+	return "?" + StreamTokenParam + "=" + url.QueryEscape(token)
+}
+
+// AuthorizeStreamService lets signed in users through and checks the stream
+// token of everyone else.
+func (h *Service) AuthorizeStreamService(c *gin.Context, trackID string) *model.RestError {
+	if _, ok := c.Get("user_id"); ok {
+		return nil
+	}
+	token := c.Query(StreamTokenParam)
+	if token == "" {
+		return &model.RestError{Code: http.StatusUnauthorized, Err: "unauthenticated"}
+	}
+	if _, err := VerifyStreamToken(h.cfg.AppConfig.Stream.Token.Keys, token, trackID, h.tokenIP(c.ClientIP()), time.Now()); err != nil {
+		h.logger.Debugf("Stream token of track %s rejected: %v", trackID, err)
+		return &model.RestError{Code: http.StatusUnauthorized, Err: err.Error()}
+	}
+	return nil
+}
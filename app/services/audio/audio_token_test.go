@@ -0,0 +1,55 @@
+package audio_test
+
+import (
+	"s3MediaStreamer/app/services/audio"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamToken(t *testing.T) {
+	const (
+		userID  = "1f2b64a4-5c1e-4a77-9a0e-3b1d1f0c8e21"
+		trackID = "7d9a1f6e-2f6b-4c55-8b0a-52c8e6a4d0b3"
+	)
+	keys := map[string]string{"k1": "old-secret", "k2": "new-secret"}
+	now := time.Unix(1700000000, 0)
+	token := audio.SignStreamToken("k1", []byte(keys["k1"]), userID, trackID, "10.0.0.7", now.Add(time.Hour))
+
+	user, err := audio.VerifyStreamToken(keys, token, trackID, "10.0.0.7", now)
+	require.NoError(t, err)
+	assert.Equal(t, userID, user)
+
+	tests := []struct {
+		name    string
+		keys    map[string]string
+		token   string
+		trackID string
+		ip      string
+		now     time.Time
+		err     error
+	}{
+		{"other track", keys, token, userID, "10.0.0.7", now, audio.ErrInvalidToken},
+		{"other ip", keys, token, trackID, "10.0.0.8", now, audio.ErrInvalidToken},
+		{"expired", keys, token, trackID, "10.0.0.7", now.Add(2 * time.Hour), audio.ErrExpiredToken},
+		{"rotated out", map[string]string{"k2": "new-secret"}, token, trackID, "10.0.0.7", now, audio.ErrInvalidToken},
+		{"tampered", keys, token[:len(token)-1] + "x", trackID, "10.0.0.7", now, audio.ErrInvalidToken},
+		{"malformed", keys, "k1.abc", trackID, "10.0.0.7", now, audio.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := audio.VerifyStreamToken(tt.keys, tt.token, tt.trackID, tt.ip, tt.now)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestCheckStreamTokenKeys(t *testing.T) {
+	require.NoError(t, audio.CheckStreamTokenKeys(map[string]string{"k1": "secret1", "k2": "secret2"}, "k2"))
+	assert.Error(t, audio.CheckStreamTokenKeys(nil, "k1"))
+	assert.Error(t, audio.CheckStreamTokenKeys(map[string]string{"k1": "secret1"}, "k2"))
+	assert.Error(t, audio.CheckStreamTokenKeys(map[string]string{"k1": "YOUR_STREAM_TOKEN_SECRET"}, "k1"))
+	assert.Error(t, audio.CheckStreamTokenKeys(map[string]string{"k1": "secret1", "k0": "YOUR_STREAM_TOKEN_SECRET"}, "k1"))
+}
@@ -0,0 +1,248 @@
+package cue
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FramesPerSecond is the number of CD frames per second INDEX times count in.
+const FramesPerSecond = 75
+
+// Extension is the file name extension of CUE sheets.
+const Extension = ".cue"
+
+var (
+	errNoTracks = errors.New("CUE sheet has no tracks")
+	utf8BOM     = []byte{0xef, 0xbb, 0xbf}
+)
+
+// Sheet is a parsed CUE sheet. Fields the sheet leaves out are empty.
+type Sheet struct {
+	Title      string
+	Performer  string
+	Songwriter string
+	Genre      string
+	Date       string
+	Files      []File
+}
+
+// File is one FILE of a sheet with the tracks cut out of it.
+type File struct {
+	Name   string
+	Type   string
+	Tracks []Track
+}
+
+// Track is one TRACK of a sheet. Start is its INDEX 01 in CD frames from the
+// start of the file.
+type Track struct {
+	Number     int
+	Title      string
+	Performer  string
+	Songwriter string
+	Start      int64
+}
+
+// StartSample converts the start of the track to a sample offset at rate.
+func (t Track) StartSample(rate uint32) int64 {
+	return t.Start * int64(rate) / FramesPerSecond
+}
+
+// IsSheet reports whether the object key names a CUE sheet.
+func IsSheet(key string) bool {
+	return strings.EqualFold(filepath.Ext(key), Extension)
+}
+
+// Parse reads a CUE sheet. Sheets that are not UTF-8 are read as Latin-1,
+// which is what most rippers write.
+func Parse(r io.Reader) (*Sheet, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(data) {
+		data = latin1ToUTF8(data)
+	}
+
+	sheet := &Sheet{}
+	var file *File
+	var track *Track
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		command, args := splitLine(scanner.Text())
+		if err = sheet.apply(command, args, &file, &track); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, f := range sheet.Files {
+		if len(f.Tracks) > 0 {
+			return sheet, nil
+		}
+	}
+	return nil, errNoTracks
+}
+
+func (s *Sheet) apply(command string, args []string, file **File, track **Track) error {
+	arg := ""
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	switch command {
+	case "FILE":
+		s.Files = append(s.Files, File{Name: arg})
+		*file, *track = &s.Files[len(s.Files)-1], nil
+		if len(args) > 1 {
+			(*file).Type = args[1]
+		}
+	case "TRACK":
+		if *file == nil {
+			return errors.New("TRACK before FILE")
+		}
+		number, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid track number %q", arg)
+		}
+		(*file).Tracks = append((*file).Tracks, Track{Number: number, Start: -1})
+		*track = &(*file).Tracks[len((*file).Tracks)-1]
+	case "INDEX":
+		if *track == nil || len(args) < 2 {
+			return errors.New("INDEX outside a TRACK")
+		}
+		if args[0] != "01" && args[0] != "1" {
+			// INDEX 00 marks the pregap, which belongs to the previous track.
+			return nil
+		}
+		start, err := parseTime(args[1])
+		if err != nil {
+			return err
+		}
+		(*track).Start = start
+	case "TITLE", "PERFORMER", "SONGWRITER":
+		s.setText(command, arg, *track)
+	case "REM":
+		if len(args) > 1 {
+			switch strings.ToUpper(args[0]) {
+			case "GENRE":
+				s.Genre = args[1]
+			case "DATE":
+				s.Date = args[1]
+			}
+		}
+	}
+	return nil
+}
+
+// setText sets TITLE, PERFORMER or SONGWRITER on the track, or on the sheet
+// before the first track.
+func (s *Sheet) setText(command, value string, track *Track) {
+	title, performer, songwriter := &s.Title, &s.Performer, &s.Songwriter
+	if track != nil {
+		title, performer, songwriter = &track.Title, &track.Performer, &track.Songwriter
+	}
+	switch command {
+	case "TITLE":
+		*title = value
+	case "PERFORMER":
+		*performer = value
+	case "SONGWRITER":
+		*songwriter = value
+	}
+}
+
+// Match returns the FILE of the sheet the audio file was ripped to. A sheet
+// with a single FILE matches any name, rippers often name a WAVE file the
+// audio was converted from afterwards.
+func (s *Sheet) Match(name string) (*File, bool) {
+	if len(s.Files) == 1 {
+		return &s.Files[0], len(s.Files[0].Tracks) > 0
+	}
+	stem := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	for i := range s.Files {
+		fileName := filepath.Base(strings.ReplaceAll(s.Files[i].Name, `\`, "/"))
+		if strings.EqualFold(strings.TrimSuffix(fileName, filepath.Ext(fileName)), stem) {
+			return &s.Files[i], len(s.Files[i].Tracks) > 0
+		}
+	}
+	return nil, false
+}
+
+// Indexed returns the tracks of the file that have an INDEX 01, in order.
+func (f *File) Indexed() []Track {
+	tracks := make([]Track, 0, len(f.Tracks))
+	for _, t := range f.Tracks {
+		if t.Start >= 0 && (len(tracks) == 0 || t.Start > tracks[len(tracks)-1].Start) {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// parseTime parses an mm:ss:ff time into CD frames.
+func parseTime(value string) (int64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid INDEX time %q", value)
+	}
+	var n [3]int64
+	for i, part := range parts {
+		v, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid INDEX time %q", value)
+		}
+		n[i] = v
+	}
+	if n[1] >= 60 || n[2] >= FramesPerSecond {
+		return 0, fmt.Errorf("invalid INDEX time %q", value)
+	}
+	return (n[0]*60+n[1])*FramesPerSecond + n[2], nil
+}
+
+// splitLine splits a line into its upper case command and the arguments,
+// which may be double quoted.
+func splitLine(line string) (string, []string) {
+	var fields []string
+	line = strings.TrimSpace(line)
+	for line != "" {
+		var field string
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				field, line = line[1:], ""
+			} else {
+				field, line = line[1:end+1], line[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			field, line = line[:end], line[end:]
+		}
+		fields = append(fields, field)
+		line = strings.TrimLeft(line, " \t")
+	}
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToUpper(fields[0]), fields[1:]
+}
+
+func latin1ToUTF8(data []byte) []byte {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return []byte(string(runes))
+}
@@ -0,0 +1,68 @@
+package cue_test
+
+import (
+	"s3MediaStreamer/app/services/cue"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSheet = `REM GENRE "Progressive Rock"
+REM DATE 1973
+PERFORMER "Pink Floyd"
+TITLE "The Dark Side of the Moon"
+FILE "Pink Floyd - The Dark Side of the Moon.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Speak to Me"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Breathe"
+    PERFORMER "Roger Waters"
+    INDEX 00 01:05:60
+    INDEX 01 01:07:50
+  TRACK 03 AUDIO
+    TITLE "On the Run"
+    INDEX 01 03:53:01
+`
+
+func TestParse(t *testing.T) {
+	sheet, err := cue.Parse(strings.NewReader("\xef\xbb\xbf" + testSheet))
+	require.NoError(t, err)
+
+	assert.Equal(t, "The Dark Side of the Moon", sheet.Title)
+	assert.Equal(t, "Pink Floyd", sheet.Performer)
+	assert.Equal(t, "Progressive Rock", sheet.Genre)
+	assert.Equal(t, "1973", sheet.Date)
+	require.Len(t, sheet.Files, 1)
+	assert.Equal(t, "WAVE", sheet.Files[0].Type)
+
+	tracks := sheet.Files[0].Indexed()
+	require.Len(t, tracks, 3)
+	assert.Equal(t, cue.Track{Number: 1, Title: "Speak to Me", Start: 0}, tracks[0])
+	assert.Equal(t, cue.Track{Number: 2, Title: "Breathe", Performer: "Roger Waters", Start: 67*75 + 50}, tracks[1])
+	assert.Equal(t, int64((3*60+53)*44100+44100/75), tracks[2].StartSample(44100))
+
+	// A single FILE matches the audio it was converted to.
+	file, ok := sheet.Match("Pink Floyd - The Dark Side of the Moon.flac")
+	assert.True(t, ok)
+	assert.Equal(t, &sheet.Files[0], file)
+}
+
+func TestParseLatin1(t *testing.T) {
+	sheet, err := cue.Parse(strings.NewReader("FILE \"a.flac\" WAVE\nTRACK 1 AUDIO\nTITLE \"Caf\xe9\"\nINDEX 01 00:00:00\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Café", sheet.Files[0].Tracks[0].Title)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for name, sheet := range map[string]string{
+		"no tracks":    "TITLE \"Album\"\nFILE \"a.flac\" WAVE\n",
+		"track first":  "TRACK 01 AUDIO\n",
+		"invalid time": "FILE \"a.flac\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:61:00\n",
+	} {
+		_, err := cue.Parse(strings.NewReader(sheet))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,334 @@
+package diskcache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/monitoring"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	tempSuffix     = ".tmp"
+	bytesPerMB     = 1 << 20
+	defaultMaxSize = 1024 * bytesPerMB
+	fillRetries    = 3
+)
+
+var errEvicted = errors.New("cache entry evicted before use")
+
+// FetchFunc opens the exact object version in S3.
+type FetchFunc func(ctx context.Context, object *minio.ObjectInfo) (io.ReadCloser, error)
+
+type entry struct {
+	name    string
+	size    int64
+	created time.Time
+	refs    int
+	elem    *list.Element
+}
+
+// Service keeps whole S3 object versions on local disk. Files are named after
+// the object key and version ID, so a new version never serves stale bytes.
+// The least recently used files are evicted above MaxSize, files older than
+// MaxAge are fetched again.
+type Service struct {
+	enabled bool
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	fetch   FetchFunc
+	metrics *monitoring.CacheMetrics
+
+	mu      sync.Mutex
+	lru     *list.List // front is the most recently used
+	entries map[string]*entry
+	size    int64
+	group   singleflight.Group
+}
+
+func NewDiskCacheService(cfg *model.Config, fetch FetchFunc, metrics *monitoring.CacheMetrics) (*Service, error) {
+	c := cfg.AppConfig.S3.Cache
+	s := &Service{
+		enabled: c.Enabled,
+		dir:     c.Dir,
+		maxSize: int64(c.MaxSize) * bytesPerMB,
+		maxAge:  time.Duration(c.MaxAge) * time.Second,
+		fetch:   fetch,
+		metrics: metrics,
+		lru:     list.New(),
+		entries: make(map[string]*entry),
+	}
+	if !s.enabled {
+		return s, nil
+	}
+	if s.dir == "" {
+		s.dir = filepath.Join(os.TempDir(), "s3-cache")
+	}
+	if s.maxSize <= 0 {
+		s.maxSize = defaultMaxSize
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Enabled reports whether objects are cached on disk.
+func (s *Service) Enabled() bool {
+	return s.enabled
+}
+
+// load indexes the files left by a previous run, dropping unfinished fills.
+func (s *Service) load() error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return err
+	}
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var found []*entry
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(file.Name(), tempSuffix) {
+			_ = os.Remove(filepath.Join(s.dir, file.Name()))
+			continue
+		}
+		info, errInfo := file.Info()
+		if errInfo != nil {
+			continue
+		}
+		found = append(found, &entry{name: file.Name(), size: info.Size(), created: info.ModTime()})
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].created.Before(found[j].created) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range found {
+		e.elem = s.lru.PushFront(e)
+		s.entries[e.name] = e
+		s.size += e.size
+	}
+	s.evictLocked(nil)
+	return nil
+}
+
+// FileName returns the cache file name of an object version. The extension of
+// the key is kept, since readers pick the audio format by it.
+func FileName(object *minio.ObjectInfo) string {
+	sum := sha256.Sum256([]byte(object.Key + "\x00" + object.VersionID))
+	return hex.EncodeToString(sum[:]) + strings.ToLower(filepath.Ext(object.Key))
+}
+
+// Open returns the cached file of the object version, filling it on a miss.
+// The open file stays readable after its entry is evicted or refilled.
+func (s *Service) Open(ctx context.Context, object *minio.ObjectInfo) (*os.File, error) {
+	e, err := s.acquire(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+	defer s.release(e)
+	return os.Open(filepath.Join(s.dir, e.name))
+}
+
+// Fetch calls fn with a local path of the object version. The file is not
+// evicted while fn runs. Without the cache the object is downloaded to a
+// temporary file that is removed afterwards.
+func (s *Service) Fetch(ctx context.Context, object *minio.ObjectInfo, fn func(path string) error) error {
+	if !s.enabled {
+		return s.fetchTemp(ctx, object, fn)
+	}
+	e, err := s.acquire(ctx, object)
+	if err != nil {
+		return err
+	}
+	defer s.release(e)
+	return fn(filepath.Join(s.dir, e.name))
+}
+
+// FetchUncached calls fn with a local path of the object version like Fetch,
+// but leaves the cache alone: a cached file is used as it is, without counting
+// as a use, and a missing one is downloaded to a temporary file. Jobs reading
+// the whole bucket use it so that they do not evict the files being played.
+func (s *Service) FetchUncached(ctx context.Context, object *minio.ObjectInfo, fn func(path string) error) error {
+	if s.enabled {
+		if e := s.pin(FileName(object), false); e != nil {
+			defer s.release(e)
+			return fn(filepath.Join(s.dir, e.name))
+		}
+	}
+	return s.fetchTemp(ctx, object, fn)
+}
+
+func (s *Service) fetchTemp(ctx context.Context, object *minio.ObjectInfo, fn func(path string) error) error {
+	f, err := os.CreateTemp("", "s3-object-*"+strings.ToLower(filepath.Ext(object.Key)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err = s.download(ctx, object, f); err != nil {
+		return err
+	}
+	return fn(f.Name())
+}
+
+// download copies the object version into f and closes it.
+func (s *Service) download(ctx context.Context, object *minio.ObjectInfo, f *os.File) error {
+	reader, err := s.fetch(ctx, object)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	defer reader.Close()
+	if _, err = io.Copy(f, reader); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// acquire pins the entry of the object version, filling it once for all
+// concurrent callers on a miss.
+func (s *Service) acquire(ctx context.Context, object *minio.ObjectInfo) (*entry, error) {
+	name := FileName(object)
+	if e := s.pin(name, true); e != nil {
+		s.metrics.HitCounter.Inc()
+		return e, nil
+	}
+	s.metrics.MissCounter.Inc()
+	for i := 0; i < fillRetries; i++ {
+		// The fill must not stop when the request that started it goes away,
+		// other callers may be waiting for it.
+		if _, err, _ := s.group.Do(name, func() (interface{}, error) {
+			return nil, s.fill(context.WithoutCancel(ctx), object, name)
+		}); err != nil {
+			return nil, err
+		}
+		if e := s.pin(name, true); e != nil {
+			return e, nil
+		}
+	}
+	return nil, errEvicted
+}
+
+// pin returns the fresh entry with one more reference, nil on a miss. A
+// touched entry becomes the most recently used one.
+func (s *Service) pin(name string, touch bool) *entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[name]
+	if !ok {
+		return nil
+	}
+	if s.expired(e) {
+		if e.refs == 0 {
+			s.removeLocked(e)
+		}
+		return nil
+	}
+	e.refs++
+	if touch {
+		s.lru.MoveToFront(e.elem)
+	}
+	return e
+}
+
+func (s *Service) release(e *entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.refs--
+}
+
+// fill downloads the object version into a temporary file and renames it into
+// place, so readers never see a partial file.
+func (s *Service) fill(ctx context.Context, object *minio.ObjectInfo, name string) error {
+	if e := s.pin(name, true); e != nil {
+		s.release(e) // filled by the previous flight
+		return nil
+	}
+	f, err := os.CreateTemp(s.dir, name+".*"+tempSuffix)
+	if err != nil {
+		return err
+	}
+	if err = s.download(ctx, object, f); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	path := filepath.Join(s.dir, name)
+	if err = os.Rename(f.Name(), path); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.entries[name]; ok {
+		// An expired entry still in use, the rename replaced its file already.
+		s.unlinkLocked(old)
+	}
+	e := &entry{name: name, size: info.Size(), created: time.Now()}
+	e.elem = s.lru.PushFront(e)
+	s.entries[name] = e
+	s.size += e.size
+	s.evictLocked(e)
+	return nil
+}
+
+func (s *Service) expired(e *entry) bool {
+	return s.maxAge > 0 && time.Since(e.created) > s.maxAge
+}
+
+// evictLocked drops expired entries and the least recently used ones above
+// the size limit. Entries in use and keep are skipped.
+func (s *Service) evictLocked(keep *entry) {
+	for elem := s.lru.Back(); elem != nil; {
+		e, _ := elem.Value.(*entry)
+		elem = elem.Prev()
+		if e == keep || e.refs > 0 {
+			continue
+		}
+		if s.size > s.maxSize || s.expired(e) {
+			s.removeLocked(e)
+		}
+	}
+}
+
+// removeLocked deletes an entry nobody reads. Entries in use are never
+// removed, a refill renames its new file over theirs instead.
+func (s *Service) removeLocked(e *entry) {
+	s.unlinkLocked(e)
+	s.metrics.EvictionCounter.Inc()
+	_ = os.Remove(filepath.Join(s.dir, e.name))
+}
+
+// unlinkLocked takes the entry out of the index without touching its file.
+func (s *Service) unlinkLocked(e *entry) {
+	if s.entries[e.name] == e {
+		delete(s.entries, e.name)
+	}
+	s.lru.Remove(e.elem)
+	s.size -= e.size
+}
@@ -0,0 +1,152 @@
+package diskcache_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/diskcache"
+	"s3MediaStreamer/app/services/monitoring"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mb = 1 << 20
+
+type fakeS3 struct {
+	fetches atomic.Int32
+	release chan struct{}
+}
+
+func (f *fakeS3) fetch(_ context.Context, object *minio.ObjectInfo) (io.ReadCloser, error) {
+	f.fetches.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	return io.NopCloser(bytes.NewReader(bytes.Repeat([]byte(object.VersionID[:1]), mb))), nil
+}
+
+func newCache(t *testing.T, dir string, maxSize int, s3 *fakeS3) (*diskcache.Service, *monitoring.CacheMetrics) {
+	cfg := &model.Config{}
+	cfg.AppConfig.S3.Cache.Enabled = true
+	cfg.AppConfig.S3.Cache.Dir = dir
+	cfg.AppConfig.S3.Cache.MaxSize = maxSize
+	metrics := &monitoring.CacheMetrics{
+		HitCounter:      prometheus.NewCounter(prometheus.CounterOpts{Name: "hit"}),
+		MissCounter:     prometheus.NewCounter(prometheus.CounterOpts{Name: "miss"}),
+		EvictionCounter: prometheus.NewCounter(prometheus.CounterOpts{Name: "eviction"}),
+	}
+	cache, err := diskcache.NewDiskCacheService(cfg, s3.fetch, metrics)
+	require.NoError(t, err)
+	return cache, metrics
+}
+
+func TestFetchSingleFlight(t *testing.T) {
+	s3 := &fakeS3{release: make(chan struct{})}
+	cache, metrics := newCache(t, t.TempDir(), 8, s3)
+	object := &minio.ObjectInfo{Key: "song.mp3", VersionID: "a1"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, cache.Fetch(context.Background(), object, func(path string) error {
+				assert.Equal(t, ".mp3", filepath.Ext(path))
+				info, err := os.Stat(path)
+				require.NoError(t, err)
+				assert.Equal(t, int64(mb), info.Size())
+				return nil
+			}))
+		}()
+	}
+	close(s3.release)
+	wg.Wait()
+	assert.Equal(t, int32(1), s3.fetches.Load())
+
+	f, err := cache.Open(context.Background(), object)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, int32(1), s3.fetches.Load())
+	assert.Equal(t, float64(6), testutil.ToFloat64(metrics.HitCounter)+testutil.ToFloat64(metrics.MissCounter))
+}
+
+func TestEvictionBySize(t *testing.T) {
+	s3 := &fakeS3{}
+	dir := t.TempDir()
+	cache, metrics := newCache(t, dir, 2, s3)
+	ctx := context.Background()
+	noop := func(string) error { return nil }
+
+	first := &minio.ObjectInfo{Key: "a.mp3", VersionID: "1"}
+	require.NoError(t, cache.Fetch(ctx, first, noop))
+	require.NoError(t, cache.Fetch(ctx, &minio.ObjectInfo{Key: "b.mp3", VersionID: "2"}, noop))
+	require.NoError(t, cache.Fetch(ctx, first, noop)) // a is now the most recently used
+	require.NoError(t, cache.Fetch(ctx, &minio.ObjectInfo{Key: "c.mp3", VersionID: "3"}, noop))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.EvictionCounter))
+	assert.FileExists(t, filepath.Join(dir, diskcache.FileName(first)))
+	assert.NoFileExists(t, filepath.Join(dir, diskcache.FileName(&minio.ObjectInfo{Key: "b.mp3", VersionID: "2"})))
+
+	// A new version of a cached key is a different file.
+	require.NoError(t, cache.Fetch(ctx, &minio.ObjectInfo{Key: "a.mp3", VersionID: "9"}, noop))
+	assert.Equal(t, int32(4), s3.fetches.Load())
+}
+
+func TestFetchUncachedKeepsTheCache(t *testing.T) {
+	s3 := &fakeS3{}
+	dir := t.TempDir()
+	cache, metrics := newCache(t, dir, 2, s3)
+	ctx := context.Background()
+	noop := func(string) error { return nil }
+
+	first := &minio.ObjectInfo{Key: "a.mp3", VersionID: "1"}
+	second := &minio.ObjectInfo{Key: "b.mp3", VersionID: "2"}
+	require.NoError(t, cache.Fetch(ctx, first, noop))
+	require.NoError(t, cache.Fetch(ctx, second, noop))
+
+	var size int64
+	require.NoError(t, cache.FetchUncached(ctx, &minio.ObjectInfo{Key: "c.mp3", VersionID: "3"}, func(path string) error {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		size = info.Size()
+		return nil
+	}))
+	assert.Equal(t, int64(mb), size)
+	require.NoError(t, cache.FetchUncached(ctx, first, noop)) // served from the cache, a stays the least recently used
+	require.NoError(t, cache.Fetch(ctx, &minio.ObjectInfo{Key: "d.mp3", VersionID: "4"}, noop))
+
+	assert.Equal(t, int32(4), s3.fetches.Load())
+	assert.NoFileExists(t, filepath.Join(dir, diskcache.FileName(first)))
+	assert.FileExists(t, filepath.Join(dir, diskcache.FileName(second)))
+	assert.NoFileExists(t, filepath.Join(dir, diskcache.FileName(&minio.ObjectInfo{Key: "c.mp3", VersionID: "3"})))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.EvictionCounter))
+}
+
+func TestLoadDropsUnfinishedFills(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partial.mp3.123.tmp"), []byte("x"), 0o600))
+	object := &minio.ObjectInfo{Key: "a.mp3", VersionID: "1"}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, diskcache.FileName(object)), []byte("cached"), 0o600))
+
+	s3 := &fakeS3{}
+	cache, _ := newCache(t, dir, 8, s3)
+	assert.NoFileExists(t, filepath.Join(dir, "partial.mp3.123.tmp"))
+
+	f, err := cache.Open(context.Background(), object)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", string(data))
+	assert.Zero(t, s3.fetches.Load())
+}
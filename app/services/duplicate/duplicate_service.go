@@ -0,0 +1,87 @@
+package duplicate
+
+import (
+	"context"
+	"net/http"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+type Repository interface {
+	GetDuplicateGroups(ctx context.Context, offset, limit int) ([]model.DuplicateGroup, int, error)
+	GetTracksByIDs(ctx context.Context, ids []string) ([]model.Track, error)
+}
+
+// MaxPageSize is the most groups a page lists, a larger page_size is cut
+// down to it.
+const MaxPageSize = 100
+
+type Service struct {
+	repository Repository
+	logger     *logs.Logger
+}
+
+func NewDuplicateService(repository Repository, logger *logs.Logger) *Service {
+	return &Service{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+// DuplicatesService returns a page of the groups of tracks suspected to be
+// duplicates and their total count. The tracks are only listed, nothing is
+// merged or deleted.
+func (s *Service) DuplicatesService(c *gin.Context, page, pageSize string) ([]model.DuplicateGroup, int, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "DuplicatesService")
+	defer span.End()
+
+	pageInt, errPage := strconv.Atoi(page)
+	pageSizeInt, errPageSize := strconv.Atoi(pageSize)
+	if errPage != nil || errPageSize != nil || pageInt < 1 || pageSizeInt < 1 {
+		return nil, 0, &model.RestError{Code: http.StatusBadRequest, Err: "invalid page or page_size parameters"}
+	}
+	pageSizeInt = min(pageSizeInt, MaxPageSize)
+
+	groups, total, err := s.repository.GetDuplicateGroups(ctx, (pageInt-1)*pageSizeInt, pageSizeInt)
+	if err != nil {
+		s.logger.Errorf("Error fetching duplicate tracks: %v", err)
+		return nil, 0, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	if err = s.addTracks(ctx, groups); err != nil {
+		s.logger.Errorf("Error fetching duplicate tracks: %v", err)
+		return nil, 0, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	return groups, total, nil
+}
+
+// addTracks puts the tracks of the groups into them in the order of their
+// IDs. A track deleted since its group was listed is left out.
+func (s *Service) addTracks(ctx context.Context, groups []model.DuplicateGroup) error {
+	var ids []string
+	for _, group := range groups {
+		ids = append(ids, group.TrackIDs...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	tracks, err := s.repository.GetTracksByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]model.Track, len(tracks))
+	for _, track := range tracks {
+		byID[track.ID.String()] = track
+	}
+	for i := range groups {
+		for _, id := range groups[i].TrackIDs {
+			if track, ok := byID[id]; ok {
+				groups[i].Tracks = append(groups[i].Tracks, track)
+			}
+		}
+	}
+	return nil
+}
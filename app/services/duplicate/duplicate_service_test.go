@@ -0,0 +1,103 @@
+package duplicate_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/duplicate"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicatesGroupsTracks(t *testing.T) {
+	first, second, third, deleted := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	repository := &memoryRepository{
+		groups: []model.DuplicateGroup{
+			{Reason: "content_hash", Key: "9f86d081", TrackIDs: []string{second.String(), first.String()}},
+			{Reason: "tags", Key: "daft punk / discovery / one more time", TrackIDs: []string{first.String(), deleted.String(), third.String()}},
+		},
+		// Tracks are returned in no particular order
+		tracks: []model.Track{{ID: third, Title: "3"}, {ID: first, Title: "1"}, {ID: second, Title: "2"}},
+	}
+
+	groups, total, restErr := newService(repository).DuplicatesService(testContext(), "1", "50")
+	require.Nil(t, restErr)
+	assert.Equal(t, 2, total)
+	require.Len(t, groups, 2)
+	assert.Equal(t, []string{"2", "1"}, titles(groups[0].Tracks))
+	assert.Equal(t, []string{"1", "3"}, titles(groups[1].Tracks))
+}
+
+func TestDuplicatesPages(t *testing.T) {
+	repository := &memoryRepository{}
+	service := newService(repository)
+
+	_, _, restErr := service.DuplicatesService(testContext(), "3", "10")
+	require.Nil(t, restErr)
+	assert.Equal(t, 20, repository.offset)
+	assert.Equal(t, 10, repository.limit)
+
+	_, _, restErr = service.DuplicatesService(testContext(), "2", "5000")
+	require.Nil(t, restErr)
+	assert.Equal(t, duplicate.MaxPageSize, repository.offset)
+	assert.Equal(t, duplicate.MaxPageSize, repository.limit)
+
+	for _, page := range [][2]string{{"0", "10"}, {"1", "0"}, {"one", "10"}} {
+		_, _, restErr = service.DuplicatesService(testContext(), page[0], page[1])
+		require.NotNil(t, restErr, page)
+		assert.Equal(t, http.StatusBadRequest, restErr.Code)
+	}
+}
+
+func newService(repository duplicate.Repository) *duplicate.Service {
+	return duplicate.NewDuplicateService(repository, &logs.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+}
+
+func testContext() *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/v1/admin/duplicates", http.NoBody)
+	return c
+}
+
+func titles(tracks []model.Track) []string {
+	result := make([]string, len(tracks))
+	for i, track := range tracks {
+		result[i] = track.Title
+	}
+	return result
+}
+
+// memoryRepository lists every group on any page and records the page asked
+// for.
+type memoryRepository struct {
+	groups        []model.DuplicateGroup
+	tracks        []model.Track
+	offset, limit int
+}
+
+func (r *memoryRepository) GetDuplicateGroups(_ context.Context, offset, limit int) ([]model.DuplicateGroup, int, error) {
+	r.offset, r.limit = offset, limit
+	return append([]model.DuplicateGroup{}, r.groups...), len(r.groups), nil
+}
+
+func (r *memoryRepository) GetTracksByIDs(_ context.Context, ids []string) ([]model.Track, error) {
+	var tracks []model.Track
+	for _, track := range r.tracks {
+		for _, id := range ids {
+			if track.ID.String() == id {
+				tracks = append(tracks, track)
+				break
+			}
+		}
+	}
+	return tracks, nil
+}
@@ -0,0 +1,62 @@
+package integrity_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"s3MediaStreamer/app/services/integrity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mpegFrames returns count silent MPEG-1 Layer III frames at 128 kbit/s and
+// 44.1 kHz. Protected frames carry the CRC of their all-zero side information.
+func mpegFrames(count int, protected bool) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	if protected {
+		frame[1] = 0xfa
+		frame[4], frame[5] = 0xc0, 0x5c
+	}
+	return bytes.Repeat(frame, count)
+}
+
+func TestCheckFile(t *testing.T) {
+	flacData, err := os.ReadFile(filepath.Join("testdata", "tone.flac"))
+	require.NoError(t, err)
+	flipped := func(data []byte, at int) []byte {
+		out := bytes.Clone(data)
+		out[at] ^= 0xff
+		return out
+	}
+	protected := mpegFrames(10, true)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		status string
+	}{
+		{name: "flac", data: flacData, status: integrity.StatusOK},
+		{name: "flac truncated", data: flacData[:len(flacData)-100], status: integrity.StatusTruncated},
+		{name: "flac missing frame", data: flacData[:len(flacData)-2059], status: integrity.StatusTruncated},
+		{name: "flac sample flipped", data: flipped(flacData, 3000), status: integrity.StatusCorrupt},
+		{name: "flac md5 mismatch", data: flipped(flacData, 30), status: integrity.StatusCorrupt},
+		{name: "mp3", data: append(mpegFrames(10, false), append([]byte("TAG"), make([]byte, 125)...)...), status: integrity.StatusOK},
+		{name: "mp3 with crc", data: protected, status: integrity.StatusOK},
+		{name: "mp3 crc mismatch", data: flipped(protected, 417*3+10), status: integrity.StatusCorrupt},
+		{name: "mp3 truncated", data: mpegFrames(10, false)[:4000], status: integrity.StatusTruncated},
+		{name: "mp3 lost sync", data: append(mpegFrames(2, false), append([]byte("junk"), mpegFrames(2, false)...)...), status: integrity.StatusCorrupt},
+		{name: "ogg", data: []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1e\x01vorbis"), status: integrity.StatusUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audio")
+			require.NoError(t, os.WriteFile(path, tt.data, 0o600))
+			result, errCheck := integrity.CheckFile(path)
+			require.NoError(t, errCheck)
+			assert.Equal(t, tt.status, result.Status, result.Detail)
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package integrity
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+const (
+	mpegHeaderSize = 4
+	mpegCRCSize    = 2
+	id3v2Size      = 10
+	id3v2Footer    = 0x10
+	id3v1Size      = 128
+	mpeg1          = 3
+	mpeg2          = 2
+	layer1         = 3
+	layer2         = 2
+	layer3         = 1
+	channelMono    = 3
+	crc16Poly      = 0x8005
+	// maxFrameSize is above the longest frame, MPEG 2.5 Layer II at 160 kbit/s and 8 kHz.
+	maxFrameSize = 4096
+)
+
+// Bitrates in kbit/s by bitrate index.
+var (
+	bitratesV1L1  = [16]int{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448}
+	bitratesV1L2  = [16]int{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384}
+	bitratesV1L3  = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	bitratesV2L1  = [16]int{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256}
+	bitratesV2L23 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+	sampleRates   = map[int][3]int{mpeg1: {44100, 48000, 32000}, mpeg2: {22050, 24000, 16000}, 0: {11025, 12000, 8000}}
+)
+
+var errNotFrame = errors.New("no MPEG audio frame header")
+
+// mpegHeader is the part of an MPEG audio frame header the walk needs.
+type mpegHeader struct {
+	version   int
+	layer     int
+	protected bool
+	mono      bool
+	length    int
+}
+
+func parseMPEGHeader(h []byte) (mpegHeader, error) {
+	if h[0] != 0xff || h[1]&0xe0 != 0xe0 {
+		return mpegHeader{}, errNotFrame
+	}
+	header := mpegHeader{
+		version:   int(h[1]>>3) & 0x03,
+		layer:     int(h[1]>>1) & 0x03,
+		protected: h[1]&0x01 == 0,
+		mono:      int(h[3]>>6) == channelMono,
+	}
+	bitrateIndex, rateIndex, padding := int(h[2]>>4), int(h[2]>>2)&0x03, int(h[2]>>1)&0x01
+	if header.version == 1 || header.layer == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		// Reserved values, or a free format bitrate the length cannot be worked out for.
+		return mpegHeader{}, errNotFrame
+	}
+
+	var kbps int
+	switch {
+	case header.version == mpeg1 && header.layer == layer1:
+		kbps = bitratesV1L1[bitrateIndex]
+	case header.version == mpeg1 && header.layer == layer2:
+		kbps = bitratesV1L2[bitrateIndex]
+	case header.version == mpeg1:
+		kbps = bitratesV1L3[bitrateIndex]
+	case header.layer == layer1:
+		kbps = bitratesV2L1[bitrateIndex]
+	default:
+		kbps = bitratesV2L23[bitrateIndex]
+	}
+	rate := sampleRates[header.version][rateIndex]
+
+	switch {
+	case header.layer == layer1:
+		header.length = (12*kbps*1000/rate + padding) * 4
+	case header.layer == layer3 && header.version != mpeg1:
+		header.length = 72*kbps*1000/rate + padding
+	default:
+		header.length = 144*kbps*1000/rate + padding
+	}
+	return header, nil
+}
+
+// sideInfoSize is the length of the Layer III side information the frame
+// CRC covers together with the last two header bytes.
+func (h mpegHeader) sideInfoSize() int {
+	switch {
+	case h.version == mpeg1 && h.mono:
+		return 17
+	case h.version == mpeg1:
+		return 32
+	case h.mono:
+		return 9
+	}
+	return 17
+}
+
+// checkMP3 walks the frames from the first to the last, skipping the ID3 and
+// APE tags around them. Every frame must follow the previous one and Layer III
+// frames with a CRC must match it.
+func checkMP3(r io.ReaderAt, size int64) (Result, error) {
+	pos, err := skipID3v2(r)
+	if err != nil {
+		return Result{}, err
+	}
+	br := bufio.NewReader(io.NewSectionReader(r, pos, size-pos))
+	buf := make([]byte, maxFrameSize)
+	frames := 0
+	for pos < size {
+		head, _ := br.Peek(mpegHeaderSize)
+		if len(head) < mpegHeaderSize || isTrailingTag(head, size-pos) {
+			break
+		}
+		header, errHeader := parseMPEGHeader(head)
+		if errHeader != nil {
+			if frames == 0 {
+				return corrupt("no MPEG audio frame at byte %d", pos), nil
+			}
+			return corrupt("lost frame sync at byte %d after %d frames", pos, frames), nil
+		}
+		if int64(header.length) > size-pos {
+			return truncated("frame %d at byte %d needs %d bytes, %d left", frames, pos, header.length, size-pos), nil
+		}
+		frame := buf[:header.length]
+		if _, err = io.ReadFull(br, frame); err != nil {
+			return Result{}, err
+		}
+		if header.protected && header.layer == layer3 && !layer3CRCMatches(frame, header) {
+			return corrupt("frame %d at byte %d: CRC mismatch", frames, pos), nil
+		}
+		pos += int64(header.length)
+		frames++
+	}
+	if frames == 0 {
+		return corrupt("no MPEG audio frames"), nil
+	}
+	return Result{Status: StatusOK}, nil
+}
+
+func layer3CRCMatches(frame []byte, header mpegHeader) bool {
+	end := mpegHeaderSize + mpegCRCSize + header.sideInfoSize()
+	if len(frame) < end {
+		return false
+	}
+	crc := crc16(0xffff, frame[2:mpegHeaderSize])
+	crc = crc16(crc, frame[mpegHeaderSize+mpegCRCSize:end])
+	return crc == uint16(frame[mpegHeaderSize])<<8|uint16(frame[mpegHeaderSize+1])
+}
+
+func crc16(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ crc16Poly
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// skipID3v2 returns the offset of the audio after a leading ID3v2 tag.
+func skipID3v2(r io.ReaderAt) (int64, error) {
+	head := make([]byte, id3v2Size)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if !bytes.HasPrefix(head, []byte("ID3")) {
+		return 0, nil
+	}
+	size := int64(head[6])<<21 | int64(head[7])<<14 | int64(head[8])<<7 | int64(head[9])
+	size += id3v2Size
+	if head[5]&id3v2Footer != 0 {
+		size += id3v2Size
+	}
+	return size, nil
+}
+
+// isTrailingTag reports whether the bytes left are an ID3v1 or APE tag.
+func isTrailingTag(head []byte, left int64) bool {
+	return (bytes.HasPrefix(head, []byte("TAG")) && left == id3v1Size) ||
+		bytes.HasPrefix(head, []byte("APET")) || bytes.HasPrefix(head, []byte("LYRI"))
+}
@@ -0,0 +1,155 @@
+package integrity
+
+import (
+	"context"
+	"net/http"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/artwork"
+	"s3MediaStreamer/app/services/cue"
+	"s3MediaStreamer/app/services/diskcache"
+	"s3MediaStreamer/app/services/s3"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+)
+
+// reportStatuses are the statuses the report lists unless asked otherwise.
+var reportStatuses = []string{StatusCorrupt, StatusTruncated}
+
+type Repository interface {
+	ListIntegrityChecks(ctx context.Context) ([]model.IntegrityCheck, error)
+	SaveIntegrityCheck(ctx context.Context, check *model.IntegrityCheck) error
+	DeleteIntegrityCheck(ctx context.Context, objectKey, versionID string) error
+	GetIntegrityReport(ctx context.Context, statuses []string, offset, limit int) ([]model.IntegrityCheck, int, error)
+}
+
+type Service struct {
+	repository Repository
+	s3         s3.Service
+	cache      *diskcache.Service
+	logger     *logs.Logger
+}
+
+func NewIntegrityService(repository Repository, s3 s3.Service, cache *diskcache.Service, logger *logs.Logger) *Service {
+	return &Service{
+		repository: repository,
+		s3:         s3,
+		cache:      cache,
+		logger:     logger,
+	}
+}
+
+func checkKey(objectKey, versionID string) string {
+	return objectKey + "\x00" + versionID
+}
+
+// RunService checks every audio object version in the bucket whose ETag
+// changed since its last check, and forgets the versions that are gone.
+// Nothing is deleted from S3. It returns the number of versions checked.
+func (s *Service) RunService(ctx context.Context) (int, error) {
+	ctx, span := otel.Tracer("").Start(ctx, "IntegrityRunService")
+	defer span.End()
+
+	stored, err := s.repository.ListIntegrityChecks(ctx)
+	if err != nil {
+		return 0, err
+	}
+	previous := make(map[string]model.IntegrityCheck, len(stored))
+	for _, check := range stored {
+		previous[checkKey(check.ObjectKey, check.VersionID)] = check
+	}
+
+	objects, err := s.s3.ListObjectS3(ctx)
+	if err != nil {
+		return 0, err
+	}
+	checked := 0
+	for i := range objects {
+		object := &objects[i]
+		if object.IsDeleteMarker || artwork.IsArtworkObject(object.Key) || cue.IsSheet(object.Key) {
+			continue
+		}
+		key := checkKey(object.Key, object.VersionID)
+		last, seen := previous[key]
+		delete(previous, key)
+		if seen && last.ETag == object.ETag {
+			continue
+		}
+		if errCheck := s.checkObject(ctx, object); errCheck != nil {
+			s.logger.Errorf("Error checking integrity of %s version %s: %v", object.Key, object.VersionID, errCheck)
+			continue
+		}
+		checked++
+	}
+
+	for _, gone := range previous {
+		if err = s.repository.DeleteIntegrityCheck(ctx, gone.ObjectKey, gone.VersionID); err != nil {
+			s.logger.Errorf("Error removing integrity check of %s: %v", gone.ObjectKey, err)
+		}
+	}
+	return checked, nil
+}
+
+// checkObject checks the object version, read past the disk cache so that
+// the check of the whole bucket does not evict the files being played.
+func (s *Service) checkObject(ctx context.Context, object *minio.ObjectInfo) error {
+	var result Result
+	err := s.cache.FetchUncached(ctx, object, func(path string) error {
+		var errCheck error
+		result, errCheck = CheckFile(path)
+		return errCheck
+	})
+	if err != nil {
+		return err
+	}
+	if result.Status != StatusOK {
+		s.logger.Warnf("Object %s version %s is %s: %s", object.Key, object.VersionID, result.Status, result.Detail)
+	}
+	return s.repository.SaveIntegrityCheck(ctx, &model.IntegrityCheck{
+		ObjectKey: object.Key,
+		VersionID: object.VersionID,
+		ETag:      object.ETag,
+		Size:      object.Size,
+		Status:    result.Status,
+		Detail:    result.Detail,
+		CheckedAt: time.Now().UTC(),
+	})
+}
+
+// ReportService returns a page of the checked object versions with one of the
+// comma separated statuses, corrupted and truncated ones by default, and
+// their total count.
+func (s *Service) ReportService(c *gin.Context, status, page, pageSize string) ([]model.IntegrityCheck, int, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "IntegrityReportService")
+	defer span.End()
+
+	pageInt, errPage := strconv.Atoi(page)
+	pageSizeInt, errPageSize := strconv.Atoi(pageSize)
+	if errPage != nil || errPageSize != nil || pageInt < 1 || pageSizeInt < 1 {
+		return nil, 0, &model.RestError{Code: http.StatusBadRequest, Err: "invalid page or page_size parameters"}
+	}
+
+	statuses := reportStatuses
+	if status != "" {
+		statuses = strings.Split(status, ",")
+		for _, value := range statuses {
+			switch value {
+			case StatusOK, StatusCorrupt, StatusTruncated, StatusUnsupported:
+			default:
+				return nil, 0, &model.RestError{Code: http.StatusBadRequest, Err: "invalid status: " + value}
+			}
+		}
+	}
+
+	checks, total, err := s.repository.GetIntegrityReport(ctx, statuses, (pageInt-1)*pageSizeInt, pageSizeInt)
+	if err != nil {
+		s.logger.Errorf("Error fetching integrity report: %v", err)
+		return nil, 0, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	return checks, total, nil
+}
@@ -0,0 +1,161 @@
+// Package keyset pages through listings by the sort keys of the last row of
+// a page instead of an offset, so that deep pages cost as much as the first.
+// The position is handed to clients as an opaque cursor.
+package keyset
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+// IDColumn ends every sort order, it makes the order total.
+const IDColumn = "_id"
+
+// ErrInvalidCursor is returned for cursors that do not decode or that were
+// made for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Key is a column a listing is sorted by. NULLs sort last in both
+// directions, NotNull columns leave them out so that a plain index on the
+// column serves the order and the bound of a cursor.
+type Key struct {
+	Column  string
+	Desc    bool
+	NotNull bool
+}
+
+func (k Key) String() string {
+	if k.Desc {
+		return k.Column + " DESC"
+	}
+	return k.Column + " ASC"
+}
+
+// idKey is the ID that ends the keys, in the direction of the last key so
+// that an index on the last key and the ID can be scanned in one direction.
+func idKey(keys []Key) Key {
+	id := Key{Column: IDColumn, NotNull: true}
+	if len(keys) > 0 {
+		id.Desc = keys[len(keys)-1].Desc
+	}
+	return id
+}
+
+// OrderBy returns the ORDER BY terms of the keys followed by the ID.
+func OrderBy(keys []Key) []string {
+	terms := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		if key.NotNull {
+			terms = append(terms, key.String())
+		} else {
+			terms = append(terms, key.String()+" NULLS LAST")
+		}
+	}
+	return append(terms, idKey(keys).String())
+}
+
+// cursor is the position after a row: its sort order, the values of its
+// sort keys and its ID.
+type cursor struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+	ID     uuid.UUID         `json:"i"`
+}
+
+func signature(keys []Key) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Encode returns the cursor after the row with the values of the keys and the ID.
+func Encode(keys []Key, values []interface{}, id uuid.UUID) (string, error) {
+	if len(values) != len(keys) {
+		return "", fmt.Errorf("%d values for %d sort keys", len(values), len(keys))
+	}
+	c := cursor{Sort: signature(keys), Values: make([]json.RawMessage, len(values)), ID: id}
+	for i, value := range values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		c.Values[i] = raw
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decode returns the raw values of the keys and the ID of the cursor, the
+// caller decodes the values into the types of the columns.
+func Decode(token string, keys []Key) ([]json.RawMessage, uuid.UUID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil || c.Sort != signature(keys) || len(c.Values) != len(keys) {
+		return nil, uuid.Nil, ErrInvalidCursor
+	}
+	return c.Values, c.ID, nil
+}
+
+// After is the condition of the rows that sort after the row with the values
+// of the keys and the ID. A nil value is a NULL.
+func After(keys []Key, values []interface{}, id uuid.UUID) squirrel.Sqlizer {
+	var after squirrel.Or
+	var equal squirrel.And
+	for i, key := range keys {
+		value := values[i]
+		if value == nil {
+			// Nothing sorts after NULL but the other NULLs.
+			equal = append(equal, squirrel.Eq{key.Column: nil})
+			continue
+		}
+		after = append(after, and(equal, later(key, value)))
+		equal = append(equal, squirrel.Eq{key.Column: value})
+	}
+	after = append(after, and(equal, later(idKey(keys), id)))
+
+	// The OR cannot bound an index scan, the first key of a NOT NULL column
+	// can.
+	if len(keys) > 0 && keys[0].NotNull && values[0] != nil {
+		op := ">="
+		if keys[0].Desc {
+			op = "<="
+		}
+		return squirrel.And{squirrel.Expr(keys[0].Column+" "+op+" ?", values[0]), after}
+	}
+	return after
+}
+
+// later is the condition of the values of the key that sort after the value.
+func later(key Key, value interface{}) squirrel.Sqlizer {
+	op := ">"
+	if key.Desc {
+		op = "<"
+	}
+	condition := squirrel.Expr(key.Column+" "+op+" ?", value)
+	if key.NotNull {
+		return condition
+	}
+	return squirrel.Or{condition, squirrel.Eq{key.Column: nil}}
+}
+
+// and is the condition that the keys are equal and the next one is later.
+func and(equal squirrel.And, later squirrel.Sqlizer) squirrel.Sqlizer {
+	if len(equal) == 0 {
+		return later
+	}
+	return append(append(squirrel.And{}, equal...), later)
+}
@@ -0,0 +1,154 @@
+package library
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+)
+
+type Repository interface {
+	GetArtists(ctx context.Context, offset, limit int) ([]model.Artist, int, error)
+	GetArtist(ctx context.Context, id uuid.UUID) (*model.Artist, error)
+	GetArtistAlbums(ctx context.Context, artistID uuid.UUID, offset, limit int) ([]model.Album, int, error)
+	GetAlbum(ctx context.Context, id uuid.UUID) (*model.Album, error)
+	GetAlbumTracks(ctx context.Context, albumID uuid.UUID) ([]model.Track, error)
+	GetGenres(ctx context.Context, offset, limit int) ([]model.Genre, int, error)
+}
+
+type Service struct {
+	repository Repository
+	logger     *logs.Logger
+}
+
+func NewLibraryService(repository Repository, logger *logs.Logger) *Service {
+	return &Service{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+var errInternal = &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+
+// page is a parsed page and page_size pair.
+type page struct {
+	number, size int
+}
+
+func parsePage(pageNumber, pageSize string) (page, *model.RestError) {
+	number, errPage := strconv.Atoi(pageNumber)
+	size, errPageSize := strconv.Atoi(pageSize)
+	if errPage != nil || errPageSize != nil || number < 1 || size < 1 {
+		return page{}, &model.RestError{Code: http.StatusBadRequest, Err: "invalid page or page_size parameters"}
+	}
+	return page{number: number, size: size}, nil
+}
+
+func (p page) offset() int {
+	return (p.number - 1) * p.size
+}
+
+func (p page) total(countTotal int) int {
+	return int(math.Ceil(float64(countTotal) / float64(p.size)))
+}
+
+// ArtistsService returns a page of the artists ordered by name, their total
+// count, the page number and the number of pages.
+func (s *Service) ArtistsService(c *gin.Context, pageNumber, pageSize string) ([]model.Artist, int, int, int, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "ArtistsService")
+	defer span.End()
+
+	p, errPage := parsePage(pageNumber, pageSize)
+	if errPage != nil {
+		return nil, 0, 0, 0, errPage
+	}
+	artists, countTotal, err := s.repository.GetArtists(ctx, p.offset(), p.size)
+	if err != nil {
+		s.logger.Errorf("Error fetching artists: %v", err)
+		return nil, 0, 0, 0, errInternal
+	}
+	return artists, countTotal, p.number, p.total(countTotal), nil
+}
+
+// ArtistAlbumsService returns a page of the albums of the album artist, their
+// total count, the page number and the number of pages.
+func (s *Service) ArtistAlbumsService(c *gin.Context, id, pageNumber, pageSize string) ([]model.Album, int, int, int, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "ArtistAlbumsService")
+	defer span.End()
+
+	p, errPage := parsePage(pageNumber, pageSize)
+	if errPage != nil {
+		return nil, 0, 0, 0, errPage
+	}
+	artistID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, 0, 0, 0, &model.RestError{Code: http.StatusNotFound, Err: "artist not found"}
+	}
+	if _, err = s.repository.GetArtist(ctx, artistID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, 0, 0, &model.RestError{Code: http.StatusNotFound, Err: "artist not found"}
+		}
+		s.logger.Errorf("Error fetching artist %s: %v", id, err)
+		return nil, 0, 0, 0, errInternal
+	}
+	albums, countTotal, err := s.repository.GetArtistAlbums(ctx, artistID, p.offset(), p.size)
+	if err != nil {
+		s.logger.Errorf("Error fetching albums of artist %s: %v", id, err)
+		return nil, 0, 0, 0, errInternal
+	}
+	return albums, countTotal, p.number, p.total(countTotal), nil
+}
+
+// AlbumService returns the album with its tracks ordered by disc and track number.
+func (s *Service) AlbumService(c *gin.Context, id string) (*model.AlbumDetail, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "AlbumService")
+	defer span.End()
+
+	albumID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "album not found"}
+	}
+	album, err := s.repository.GetAlbum(ctx, albumID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &model.RestError{Code: http.StatusNotFound, Err: "album not found"}
+		}
+		s.logger.Errorf("Error fetching album %s: %v", id, err)
+		return nil, errInternal
+	}
+	tracks, err := s.repository.GetAlbumTracks(ctx, albumID)
+	if err != nil {
+		s.logger.Errorf("Error fetching tracks of album %s: %v", id, err)
+		return nil, errInternal
+	}
+	if tracks == nil {
+		tracks = []model.Track{}
+	}
+	return &model.AlbumDetail{Album: *album, Tracks: tracks}, nil
+}
+
+// GenresService returns a page of the genres ordered by name, their total
+// count, the page number and the number of pages.
+func (s *Service) GenresService(c *gin.Context, pageNumber, pageSize string) ([]model.Genre, int, int, int, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "GenresService")
+	defer span.End()
+
+	p, errPage := parsePage(pageNumber, pageSize)
+	if errPage != nil {
+		return nil, 0, 0, 0, errPage
+	}
+	genres, countTotal, err := s.repository.GetGenres(ctx, p.offset(), p.size)
+	if err != nil {
+		s.logger.Errorf("Error fetching genres: %v", err)
+		return nil, 0, 0, 0, errInternal
+	}
+	return genres, countTotal, p.number, p.total(countTotal), nil
+}
@@ -0,0 +1,306 @@
+package loudness
+
+import (
+	"errors"
+	"math"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/pcm"
+)
+
+// ReferenceLoudness is the ReplayGain 2.0 target track gains are relative to, in LUFS.
+const ReferenceLoudness = -18
+
+const (
+	blockSeconds   = 0.4
+	blockSteps     = 4
+	absoluteGate   = -70
+	relativeGate   = -10
+	loudnessOffset = -0.691
+	surroundWeight = 1.41
+	// Peaks are looked for between the samples at four times the rate below
+	// 96 kHz and twice the rate below 192 kHz.
+	oversampleTaps = 49
+)
+
+// ErrSilent is returned for streams too short or too quiet to pass the gates.
+var ErrSilent = errors.New("no audio above the loudness gate")
+
+// Result is the loudness of a track. Integrated is in LUFS, TruePeak in
+// dBTP and TrackGain in dB.
+type Result struct {
+	Integrated float64
+	TruePeak   float64
+	TrackGain  float64
+}
+
+// Apply stores the result on the track.
+func (r *Result) Apply(track *model.Track) {
+	track.Loudness = &r.Integrated
+	track.TruePeak = &r.TruePeak
+	track.TrackGain = &r.TrackGain
+}
+
+// Gain returns the gain in dB that brings the loudness to the reference.
+func Gain(integrated float64) float64 {
+	return ReferenceLoudness - integrated
+}
+
+// AnalyzeFile measures the loudness of the audio file as per EBU R128.
+func AnalyzeFile(fileName string) (*Result, error) {
+	m := NewMeter()
+	if err := pcm.DecodeFile(fileName, m); err != nil {
+		return nil, err
+	}
+	return m.Result()
+}
+
+// Meter implements pcm.Handler. It keeps the mean square of the K-weighted
+// channels per 100 ms step, blocks of 400 ms overlap by three steps.
+type Meter struct {
+	weights  []float64
+	filters  []kWeighting
+	peaks    []*truePeak
+	stepSize int
+	stepPos  int
+	step     float64
+	steps    []float64
+	blocks   []float64
+	peak     float64
+}
+
+// NewMeter returns a meter to pass to pcm.DecodeFile.
+func NewMeter() *Meter {
+	return &Meter{}
+}
+
+func (m *Meter) Start(info pcm.Info) error {
+	if info.SampleRate <= 0 || info.Channels <= 0 {
+		return errors.New("invalid stream parameters")
+	}
+	m.stepSize = int(math.Round(float64(info.SampleRate) * blockSeconds / blockSteps))
+	m.weights = make([]float64, info.Channels)
+	m.filters = make([]kWeighting, info.Channels)
+	m.peaks = make([]*truePeak, info.Channels)
+	for c := range m.weights {
+		m.weights[c] = channelWeight(c, info.Channels)
+		m.filters[c] = newKWeighting(float64(info.SampleRate))
+		m.peaks[c] = newTruePeak(info.SampleRate)
+	}
+	return nil
+}
+
+func (m *Meter) Frame(samples []float64) {
+	for c, sample := range samples {
+		m.peak = math.Max(m.peak, m.peaks[c].add(sample))
+		if m.weights[c] == 0 {
+			continue
+		}
+		y := m.filters[c].process(sample)
+		m.step += m.weights[c] * y * y
+	}
+	m.stepPos++
+	if m.stepPos < m.stepSize {
+		return
+	}
+	m.steps = append(m.steps, m.step/float64(m.stepSize))
+	m.step, m.stepPos = 0, 0
+	if n := len(m.steps); n >= blockSteps {
+		var power float64
+		for _, p := range m.steps[n-blockSteps:] {
+			power += p
+		}
+		m.blocks = append(m.blocks, power/blockSteps)
+		m.steps = m.steps[n-blockSteps+1:]
+	}
+}
+
+// Result returns the loudness of the decoded stream.
+func (m *Meter) Result() (*Result, error) {
+	integrated, ok := gatedLoudness(m.blocks)
+	if !ok || m.peak == 0 {
+		return nil, ErrSilent
+	}
+	return &Result{
+		Integrated: integrated,
+		TruePeak:   20 * math.Log10(m.peak),
+		TrackGain:  Gain(integrated),
+	}, nil
+}
+
+// gatedLoudness applies the absolute and the relative gate to the block powers
+// and returns the loudness of the blocks left.
+func gatedLoudness(blocks []float64) (float64, bool) {
+	mean := func(threshold float64) (float64, bool) {
+		var sum float64
+		var n int
+		for _, power := range blocks {
+			if power > 0 && powerToLoudness(power) > threshold {
+				sum += power
+				n++
+			}
+		}
+		if n == 0 {
+			return 0, false
+		}
+		return sum / float64(n), true
+	}
+
+	power, ok := mean(absoluteGate)
+	if !ok {
+		return 0, false
+	}
+	if power, ok = mean(powerToLoudness(power) + relativeGate); !ok {
+		return 0, false
+	}
+	return powerToLoudness(power), true
+}
+
+func powerToLoudness(power float64) float64 {
+	return loudnessOffset + 10*math.Log10(power)
+}
+
+// channelWeight follows the ITU-R BS.1770 weights for the WAVE 5.1 order:
+// the LFE channel is left out and the surround channels count more.
+func channelWeight(channel, channels int) float64 {
+	if channels != 6 {
+		return 1
+	}
+	switch channel {
+	case 3:
+		return 0
+	case 4, 5:
+		return surroundWeight
+	}
+	return 1
+}
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+// kWeighting is the high shelf and high pass pre-filter of BS.1770, with the
+// coefficients worked out for the sample rate rather than tabled for 48 kHz.
+type kWeighting struct {
+	shelf, highPass biquad
+}
+
+func newKWeighting(sampleRate float64) kWeighting {
+	const (
+		shelfFreq  = 1681.974450955533
+		shelfGain  = 3.999843853973347
+		shelfQ     = 0.7071752369554196
+		highPassHz = 38.13547087602444
+		highPassQ  = 0.5003270373238773
+		shelfVbPow = 0.4996667741545416
+	)
+	k := math.Tan(math.Pi * shelfFreq / sampleRate)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, shelfVbPow)
+	a0 := 1 + k/shelfQ + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * highPassHz / sampleRate)
+	a0 = 1 + k/highPassQ + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/highPassQ + k*k) / a0,
+	}
+	return kWeighting{shelf: shelf, highPass: highPass}
+}
+
+func (f *kWeighting) process(x float64) float64 {
+	return f.highPass.process(f.shelf.process(x))
+}
+
+// truePeak upsamples a channel with a polyphase windowed sinc filter and
+// tracks the largest absolute value.
+type truePeak struct {
+	phases  [][]float64
+	history []float64
+	pos     int
+}
+
+func newTruePeak(sampleRate int) *truePeak {
+	factor := 4
+	switch {
+	case sampleRate >= 192000:
+		factor = 1
+	case sampleRate >= 96000:
+		factor = 2
+	}
+	if factor == 1 {
+		return &truePeak{}
+	}
+
+	perPhase := (oversampleTaps + factor - 1) / factor
+	phases := make([][]float64, factor)
+	for p := range phases {
+		phases[p] = make([]float64, perPhase)
+	}
+	center := float64(oversampleTaps-1) / 2
+	for i := 0; i < oversampleTaps; i++ {
+		x := (float64(i) - center) / float64(factor)
+		h := 1.0
+		if x != 0 {
+			h = math.Sin(math.Pi*x) / (math.Pi * x)
+		}
+		window := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i+1)/float64(oversampleTaps+1))
+		phases[i%factor][i/factor] = h * window
+	}
+	return &truePeak{phases: phases, history: make([]float64, perPhase)}
+}
+
+// add feeds the next sample and returns the largest absolute value of it and
+// of the values interpolated before it.
+func (t *truePeak) add(sample float64) float64 {
+	peak := math.Abs(sample)
+	if t.phases == nil {
+		return peak
+	}
+	t.pos = (t.pos + len(t.history) - 1) % len(t.history)
+	t.history[t.pos] = sample
+	for _, taps := range t.phases {
+		var v float64
+		for k, tap := range taps {
+			v += tap * t.history[(t.pos+k)%len(t.history)]
+		}
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return peak
+}
+
+// AlbumLoudness combines the loudness of the tracks of an album, weighting
+// the power of each track by its duration. Tracks without loudness are left
+// out. The gates are applied per track, so the result is close to but not
+// the same as measuring the album as one stream.
+func AlbumLoudness(tracks []model.Track) (float64, bool) {
+	var energy, seconds float64
+	for _, track := range tracks {
+		if track.Loudness == nil || track.Duration <= 0 {
+			continue
+		}
+		energy += track.Duration.Seconds() * math.Pow(10, *track.Loudness/10)
+		seconds += track.Duration.Seconds()
+	}
+	if seconds == 0 {
+		return 0, false
+	}
+	return 10 * math.Log10(energy/seconds), true
+}
@@ -0,0 +1,37 @@
+package loudness_test
+
+import (
+	"path/filepath"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/loudness"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeFile(t *testing.T) {
+	// Two seconds of a 1 kHz mono sine at -20 dBFS, which BS.1770 puts at -23 LUFS.
+	result, err := loudness.AnalyzeFile(filepath.Join("testdata", "sine.flac"))
+	require.NoError(t, err)
+	assert.InDelta(t, -23, result.Integrated, 0.2)
+	assert.InDelta(t, -20, result.TruePeak, 0.1)
+	assert.InDelta(t, 5, result.TrackGain, 0.2)
+}
+
+func TestAlbumLoudness(t *testing.T) {
+	loud, quiet := -10.0, -20.0
+	tracks := []model.Track{
+		{Duration: time.Minute, Loudness: &loud},
+		{Duration: time.Minute, Loudness: &quiet},
+		{Duration: time.Hour},
+	}
+	integrated, ok := loudness.AlbumLoudness(tracks)
+	require.True(t, ok)
+	// The louder track dominates the energy average.
+	assert.InDelta(t, -12.6, integrated, 0.05)
+
+	_, ok = loudness.AlbumLoudness(tracks[2:])
+	assert.False(t, ok)
+}
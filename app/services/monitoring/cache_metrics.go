@@ -0,0 +1,35 @@
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+type CacheMetrics struct {
+	HitCounter      prometheus.Counter
+	MissCounter     prometheus.Counter
+	EvictionCounter prometheus.Counter
+}
+
+func NewCacheMetrics(reg prometheus.Registerer) *CacheMetrics {
+	m := &CacheMetrics{
+		HitCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "s3_cache_hit_count_total",
+			Help: "Total number of S3 objects served from the disk cache",
+		}),
+		MissCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "s3_cache_miss_count_total",
+			Help: "Total number of S3 objects missing in the disk cache",
+		}),
+		EvictionCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "s3_cache_eviction_count_total",
+			Help: "Total number of files evicted from the disk cache",
+		}),
+	}
+	reg.MustRegister(
+		m.HitCounter,
+		m.MissCounter,
+		m.EvictionCounter,
+	)
+	return m
+}
@@ -10,16 +10,22 @@ import (
 func NewMonitoringService(postgresMetrics *connect.DBMetrics) *CombinedMetrics {
 	registry := prometheus.NewRegistry()
 	userMetrics := NewMetrics(registry)
+	streamMetrics := NewStreamMetrics(registry)
+	cacheMetrics := NewCacheMetrics(registry)
 
 	return &CombinedMetrics{
 		PostgresMetrics: postgresMetrics,
 		UserMetrics:     userMetrics,
+		StreamMetrics:   streamMetrics,
+		CacheMetrics:    cacheMetrics,
 	}
 }
 
 type CombinedMetrics struct {
 	PostgresMetrics *connect.DBMetrics // Postgres
 	UserMetrics     *Metrics           // User service metrics
+	StreamMetrics   *StreamMetrics     // Audio stream metrics
+	CacheMetrics    *CacheMetrics      // S3 disk cache metrics
 }
 
 type Metrics struct {
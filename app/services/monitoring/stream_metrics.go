@@ -0,0 +1,23 @@
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+type StreamMetrics struct {
+	DeliveryCounter *prometheus.CounterVec
+}
+
+func NewStreamMetrics(reg prometheus.Registerer) *StreamMetrics {
+	m := &StreamMetrics{
+		DeliveryCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "stream_delivery_count_total",
+			Help: "Total number of streamed tracks by delivery mode",
+		}, []string{"mode"}),
+	}
+	reg.MustRegister(
+		m.DeliveryCounter,
+	)
+	return m
+}
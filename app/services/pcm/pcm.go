@@ -0,0 +1,198 @@
+package pcm
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"s3MediaStreamer/app/services/tags"
+
+	gomp3 "github.com/hajimehoshi/go-mp3"
+	"github.com/mewkiz/flac"
+)
+
+const (
+	// mp3FrameSize is one stereo frame of the 16 bit PCM go-mp3 decodes to.
+	mp3FrameSize     = 4
+	mp3Channels      = 2
+	mp3FramesPerRead = 1024
+)
+
+// ErrUnsupported is returned for audio formats there is no decoder for.
+var ErrUnsupported = errors.New("decoding needs FLAC or MP3 audio")
+
+// MimeTypes are the audio formats DecodeFile decodes.
+var MimeTypes = []string{tags.MimeFLAC, tags.MimeMP3}
+
+// Info describes the decoded stream. Frames is the number of samples per
+// channel, estimated from the frame headers for MP3.
+type Info struct {
+	SampleRate int
+	Channels   int
+	Frames     int64
+}
+
+// Handler receives the audio of a stream one frame at a time.
+type Handler interface {
+	// Start is called once before the first frame.
+	Start(info Info) error
+	// Frame gets one sample in -1..1 per channel. The slice is reused for the
+	// next frame.
+	Frame(samples []float64)
+}
+
+// DecodeFile detects the format of the audio file and decodes it once into
+// all the handlers.
+func DecodeFile(fileName string, handlers ...Handler) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	mimeType, err := tags.DetectFormat(f)
+	if err != nil {
+		return err
+	}
+	switch mimeType {
+	case tags.MimeFLAC:
+		return decodeFLAC(f, multiHandler(handlers))
+	case tags.MimeMP3:
+		return decodeMP3(f, multiHandler(handlers))
+	}
+	return ErrUnsupported
+}
+
+type multiHandler []Handler
+
+func (m multiHandler) Start(info Info) error {
+	for _, h := range m {
+		if err := h.Start(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiHandler) Frame(samples []float64) {
+	for _, h := range m {
+		h.Frame(samples)
+	}
+}
+
+// Split routes the frames of a stream to parts that start at the sample
+// offsets of starts, in increasing order, and run up to the next one. Frames
+// before the first start are dropped. Each part is told its own length.
+func Split(starts []int64, parts [][]Handler) Handler {
+	return &splitHandler{starts: starts, parts: parts, current: -1}
+}
+
+// Range routes the frames from start up to end, end <= 0 meaning the end of
+// the stream, to the handlers.
+func Range(start, end int64, handlers ...Handler) Handler {
+	starts, parts := []int64{start}, [][]Handler{handlers}
+	if end > 0 {
+		starts, parts = append(starts, end), append(parts, nil)
+	}
+	return Split(starts, parts)
+}
+
+type splitHandler struct {
+	starts  []int64
+	parts   [][]Handler
+	pos     int64
+	current int
+}
+
+func (s *splitHandler) Start(info Info) error {
+	for i, part := range s.parts {
+		partInfo := info
+		partInfo.Frames = info.Frames - s.starts[i]
+		if i+1 < len(s.starts) {
+			partInfo.Frames = s.starts[i+1] - s.starts[i]
+		}
+		if err := multiHandler(part).Start(partInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *splitHandler) Frame(samples []float64) {
+	for s.current+1 < len(s.starts) && s.pos >= s.starts[s.current+1] {
+		s.current++
+	}
+	s.pos++
+	if s.current >= 0 {
+		multiHandler(s.parts[s.current]).Frame(samples)
+	}
+}
+
+// decodeFLAC decodes a FLAC stream.
+func decodeFLAC(r io.Reader, h Handler) error {
+	stream, err := flac.New(r)
+	if err != nil {
+		return err
+	}
+	info := Info{
+		SampleRate: int(stream.Info.SampleRate),
+		Channels:   int(stream.Info.NChannels),
+		Frames:     int64(stream.Info.NSamples),
+	}
+	if err = h.Start(info); err != nil {
+		return err
+	}
+	scale := float64(int64(1) << (stream.Info.BitsPerSample - 1))
+
+	samples := make([]float64, info.Channels)
+	for {
+		frame, errFrame := stream.ParseNext()
+		if errors.Is(errFrame, io.EOF) {
+			return nil
+		}
+		if errFrame != nil {
+			return errFrame
+		}
+		for i := 0; i < int(frame.BlockSize); i++ {
+			for c, subframe := range frame.Subframes {
+				samples[c] = float64(subframe.Samples[i]) / scale
+			}
+			h.Frame(samples)
+		}
+	}
+}
+
+// decodeMP3 decodes an MP3 stream. The reader must seek, go-mp3 scans the
+// frames for the length up front.
+func decodeMP3(r io.ReadSeeker, h Handler) error {
+	dec, err := gomp3.NewDecoder(r)
+	if err != nil {
+		return err
+	}
+	info := Info{
+		SampleRate: dec.SampleRate(),
+		Channels:   mp3Channels,
+		Frames:     dec.Length() / mp3FrameSize,
+	}
+	if err = h.Start(info); err != nil {
+		return err
+	}
+
+	buf := make([]byte, mp3FramesPerRead*mp3FrameSize)
+	samples := make([]float64, mp3Channels)
+	for {
+		n, errRead := io.ReadFull(dec, buf)
+		for i := 0; i+mp3FrameSize <= n; i += mp3FrameSize {
+			samples[0] = float64(int16(binary.LittleEndian.Uint16(buf[i:]))) / math.MaxInt16
+			samples[1] = float64(int16(binary.LittleEndian.Uint16(buf[i+2:]))) / math.MaxInt16
+			h.Frame(samples)
+		}
+		if errors.Is(errRead, io.EOF) || errors.Is(errRead, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if errRead != nil {
+			return errRead
+		}
+	}
+}
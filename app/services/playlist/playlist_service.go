@@ -2,17 +2,21 @@ package playlist
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"s3MediaStreamer/app/internal/logs"
 	"s3MediaStreamer/app/model"
 	"s3MediaStreamer/app/services/auth"
+	"s3MediaStreamer/app/services/keyset"
 	"s3MediaStreamer/app/services/session"
 	"s3MediaStreamer/app/services/track"
 	"s3MediaStreamer/app/services/tree"
 	"s3MediaStreamer/app/services/user"
+	"strconv"
 	"time"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -26,7 +30,7 @@ type Repository interface {
 	DeletePlaylist(ctx context.Context, playlistID string) error
 	UpdatePlaylistDetails(ctx context.Context, playlistID, title, description string) error
 	GetPlaylistOwner(ctx context.Context, playlistID string) (uuid.UUID, error)
-	GetPlaylists(ctx context.Context, userID string) ([]model.PLayList, error)
+	GetPlaylists(ctx context.Context, userID string, orderBy []string, after squirrel.Sqlizer, limit int) ([]model.PLayList, error)
 	GetPlaylistAllTracks(ctx context.Context, playlistID string) ([]model.TrackRequest, error)
 	GetPlaylistPath(ctx context.Context, playlistID string) (string, error)
 }
@@ -73,8 +77,8 @@ func (s *Service) GetPlaylistOwner(ctx context.Context, playlistID string) (uuid
 	return s.playlistRepository.GetPlaylistOwner(ctx, playlistID)
 }
 
-func (s *Service) GetPlaylists(ctx context.Context, userID string) ([]model.PLayList, error) {
-	return s.playlistRepository.GetPlaylists(ctx, userID)
+func (s *Service) GetPlaylists(ctx context.Context, userID string, orderBy []string, after squirrel.Sqlizer, limit int) ([]model.PLayList, error) {
+	return s.playlistRepository.GetPlaylists(ctx, userID, orderBy, after, limit)
 }
 
 func (s *Service) GetPlaylistAllTracks(ctx context.Context, playlistID string) ([]model.TrackRequest, error) {
@@ -224,19 +228,72 @@ func (s Service) GetTracksInPlaylist(ctx context.Context, userContext *model.Use
 	return playlistContents, nil
 }
 
+// playlistKeys are the sort keys of the playlist listing, newest first.
+var playlistKeys = []keyset.Key{{Column: "created_at", Desc: true, NotNull: true}}
+
 func (s Service) GetUserPlaylists(ctx context.Context, userContext *model.UserContext) (*model.PlaylistsResponse, *model.RestError) {
-	var playlists []model.PLayList
-	var err error
+	playlists, err := s.GetPlaylists(ctx, playlistOwner(userContext), keyset.OrderBy(playlistKeys), nil, 0)
+	if err != nil {
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "Playlists not found"}
+	}
+	return playlistsResponse(playlists), nil
+}
+
+// GetUserPlaylistsPage returns the page of the playlists of the user that
+// comes after the cursor and the cursor of the next page, empty on the last
+// one. An empty cursor starts at the newest playlist.
+func (s Service) GetUserPlaylistsPage(ctx context.Context, userContext *model.UserContext,
+	cursor, pageSize string) (*model.PlaylistsResponse, string, *model.RestError) {
+	size, err := strconv.Atoi(pageSize)
+	if err != nil || size < 1 {
+		return nil, "", &model.RestError{Code: http.StatusBadRequest, Err: "invalid page_size parameter"}
+	}
+	var after squirrel.Sqlizer
+	if cursor != "" {
+		if after, err = playlistsAfter(cursor); err != nil {
+			return nil, "", &model.RestError{Code: http.StatusBadRequest, Err: "invalid cursor"}
+		}
+	}
+
+	// One playlist more than the page tells whether there is a next page.
+	playlists, err := s.GetPlaylists(ctx, playlistOwner(userContext), keyset.OrderBy(playlistKeys), after, size+1)
+	if err != nil {
+		return nil, "", &model.RestError{Code: http.StatusNotFound, Err: "Playlists not found"}
+	}
+	if len(playlists) <= size {
+		return playlistsResponse(playlists), "", nil
+	}
+	playlists = playlists[:size]
+	last := playlists[size-1]
+	next, err := keyset.Encode(playlistKeys, []interface{}{last.CreatedAt}, last.ID)
+	if err != nil {
+		return nil, "", &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	return playlistsResponse(playlists), next, nil
+}
 
+// playlistOwner is the user whose playlists the user lists, empty for all of
+// them for admins.
+func playlistOwner(userContext *model.UserContext) string {
 	if userContext.UserRole == adminPolicy {
-		playlists, err = s.GetPlaylists(ctx, "")
-	} else {
-		playlists, err = s.GetPlaylists(ctx, userContext.UserID)
+		return ""
 	}
+	return userContext.UserID
+}
+
+func playlistsAfter(cursor string) (squirrel.Sqlizer, error) {
+	raw, id, err := keyset.Decode(cursor, playlistKeys)
 	if err != nil {
-		return nil, &model.RestError{Code: http.StatusNotFound, Err: "Playlists not found"}
+		return nil, err
 	}
+	var createdAt time.Time
+	if err = json.Unmarshal(raw[0], &createdAt); err != nil {
+		return nil, err
+	}
+	return keyset.After(playlistKeys, []interface{}{createdAt}, id), nil
+}
 
+func playlistsResponse(playlists []model.PLayList) *model.PlaylistsResponse {
 	response := &model.PlaylistsResponse{
 		PLayLists: make([]model.PLayList, len(playlists)),
 	}
@@ -251,7 +308,7 @@ func (s Service) GetUserPlaylists(ctx context.Context, userContext *model.UserCo
 		}
 	}
 
-	return response, nil
+	return response
 }
 
 func (s *Service) RemoveTrackFromPlaylist(ctx context.Context, userContext *model.UserContext, playlistID, trackID string) *model.RestError {
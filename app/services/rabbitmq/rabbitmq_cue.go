@@ -0,0 +1,162 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/analysis"
+	"s3MediaStreamer/app/services/cue"
+	"s3MediaStreamer/app/services/loudness"
+	"s3MediaStreamer/app/services/pcm"
+	"s3MediaStreamer/app/services/s3"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+)
+
+// cueAudioExtensions are the extensions of the audio files a CUE sheet is
+// looked up for, the sheet shares the name of the file.
+var cueAudioExtensions = []string{".flac", ".mp3"}
+
+// cueSheetEvent ingests the audio file a newly put CUE sheet belongs to again,
+// so that its tracks replace the whole-file track. An audio file put after the
+// sheet is cut when it is ingested.
+func (s *Service) cueSheetEvent(ctx context.Context, key string) error {
+	stem := strings.TrimSuffix(key, filepath.Ext(key))
+	for _, ext := range cueAudioExtensions {
+		object, err := s.s3.StatObjectS3(ctx, stem+ext)
+		if err != nil {
+			continue
+		}
+		_, err = s.ingestObject(ctx, &object)
+		return err
+	}
+	s.logger.Infof("No audio file for CUE sheet %s yet", key)
+	return nil
+}
+
+// findCueSheet returns the sibling CUE sheet of the audio object and its FILE
+// for the object, nil when there is none.
+func (s *Service) findCueSheet(ctx context.Context, key string) (*cue.Sheet, *cue.File) {
+	sheetKey := strings.TrimSuffix(key, filepath.Ext(key)) + cue.Extension
+	if _, err := s.s3.StatObjectS3(ctx, sheetKey); err != nil {
+		return nil, nil
+	}
+	var sheet *cue.Sheet
+	err := s.s3.DownloadFilesS3Stream(ctx, sheetKey, func(r io.Reader) error {
+		var errParse error
+		sheet, errParse = cue.Parse(r)
+		return errParse
+	})
+	if err != nil {
+		s.logger.Warnf("Error reading CUE sheet %s: %v", sheetKey, err)
+		return nil, nil
+	}
+	file, ok := sheet.Match(key)
+	if !ok {
+		s.logger.Warnf("CUE sheet %s has no tracks for %s", sheetKey, key)
+		return nil, nil
+	}
+	return sheet, file
+}
+
+// cueTracks cuts the tags of the audio file into one track per indexed track
+// of the CUE file, nil when the sample rate of the file is unknown.
+func (s *Service) cueTracks(file *model.Track, sheet *cue.Sheet, cueFile *cue.File) []model.Track {
+	indexed := cueFile.Indexed()
+	if file.SampleRate == 0 || len(indexed) == 0 {
+		s.logger.Warnf("CUE sheet of '%s' ignored, the sample rate is unknown", file.Title)
+		return nil
+	}
+	rate := float64(file.SampleRate)
+	total := int64(file.Duration.Seconds() * rate)
+
+	tracks := make([]model.Track, len(indexed))
+	for i, entry := range indexed {
+		track := *file
+		track.ID = uuid.New()
+		start, end := entry.StartSample(file.SampleRate), total
+		track.CueStart = &start
+		if i+1 < len(indexed) {
+			end = indexed[i+1].StartSample(file.SampleRate)
+			track.CueEnd = &end
+		}
+		track.Duration = time.Duration(float64(end-start) / rate * float64(time.Second))
+
+		track.Title = entry.Title
+		if track.Title == "" {
+			track.Title = fmt.Sprintf("Track %02d", entry.Number)
+		}
+		track.Artist = firstNonEmpty(entry.Performer, sheet.Performer, file.Artist)
+		track.AlbumArtist = firstNonEmpty(sheet.Performer, file.AlbumArtist)
+		track.Album = firstNonEmpty(sheet.Title, file.Album)
+		track.Composer = firstNonEmpty(entry.Songwriter, sheet.Songwriter, file.Composer)
+		track.Genre = firstNonEmpty(sheet.Genre, file.Genre)
+		if year, err := strconv.Atoi(strings.SplitN(sheet.Date, "-", 2)[0]); err == nil {
+			track.Year = year
+		}
+		track.Track, track.TrackTotal = entry.Number, len(indexed)
+		track.Lyrics = ""
+		tracks[i] = track
+	}
+	return tracks
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// analyzeCueAudio measures the loudness, tempo and key of every CUE track in
+// one decoding pass of the file.
+func (s *Service) analyzeCueAudio(tracks []model.Track, fileName, key string) {
+	starts := make([]int64, len(tracks))
+	parts := make([][]pcm.Handler, len(tracks))
+	meters := make([]*loudness.Meter, len(tracks))
+	analyzers := make([]*analysis.Analyzer, len(tracks))
+	for i := range tracks {
+		starts[i] = *tracks[i].CueStart
+		meters[i], analyzers[i] = loudness.NewMeter(), analysis.NewAnalyzer()
+		parts[i] = []pcm.Handler{meters[i], analyzers[i]}
+	}
+	if err := pcm.DecodeFile(fileName, pcm.Split(starts, parts)); err != nil {
+		s.logAnalysisError(key, err)
+		return
+	}
+	for i := range tracks {
+		s.applyAnalysis(&tracks[i], meters[i], analyzers[i], fmt.Sprintf("%s track %d", key, tracks[i].Track))
+	}
+}
+
+// replaceCueTracks stores the CUE tracks of the object version in place of
+// the tracks it was ingested as before, the whole-file track ingested before
+// the sheet was put or the CUE tracks of an earlier sheet.
+func (s *Service) replaceCueTracks(ctx context.Context, tracks []model.Track, object *minio.ObjectInfo) ([]string, error) {
+	if err := s.s3.DeleteS3Version(ctx, object.VersionID); err != nil {
+		return nil, fmt.Errorf("error deleting S3 version: %w", err)
+	}
+	if err := s.track.CleanTracks(ctx); err != nil {
+		return nil, fmt.Errorf("error deleting replaced tracks: %w", err)
+	}
+	if err := s.track.CreateTracks(ctx, tracks); err != nil {
+		return nil, fmt.Errorf("error creating track: %w", err)
+	}
+	trackIDs := make([]string, len(tracks))
+	for i := range tracks {
+		if err := s.s3.AddS3Version(ctx, s3.NewTrackVersion(tracks[i].ID, object)); err != nil {
+			return nil, fmt.Errorf("error adding S3 version: %w", err)
+		}
+		trackIDs[i] = tracks[i].ID.String()
+	}
+	s.logger.Infof("%d CUE tracks of '%s' saved to the database.\n", len(tracks), tracks[0].Album)
+	return trackIDs, nil
+}
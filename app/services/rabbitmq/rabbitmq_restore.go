@@ -0,0 +1,109 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"s3MediaStreamer/app/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/minio/minio-go/v7"
+)
+
+// RestoreVersion makes a recorded version of the track its current version
+// again and reads the track from it the way it is ingested, so that its tags,
+// format, duration, analysis and hash are the ones of the version. A track cut
+// out by a CUE sheet keeps the tags of the sheet and its cut, only its format
+// is read again. It returns pgx.ErrNoRows when the track has no such version.
+func (s *Service) RestoreVersion(ctx context.Context, trackID, version string) error {
+	current, err := s.track.GetTracksByColumns(ctx, trackID, "_id")
+	if err != nil {
+		return err
+	}
+	object, err := s.versionObject(ctx, trackID, version)
+	if err != nil {
+		return err
+	}
+
+	var restored *model.Track
+	var errReadTags error
+	err = s.cache.Fetch(ctx, object, func(fileName string) error {
+		restored, errReadTags = s.tags.ReadTags(fileName)
+		if errReadTags != nil || current.CueStart != nil {
+			return nil
+		}
+		var errArtwork error
+		if restored.Artwork, errArtwork = s.artwork.StoreService(ctx, fileName); errArtwork != nil {
+			s.logger.Warnf("Error storing cover art of %s: %v", object.Key, errArtwork)
+		}
+		s.analyzeAudio(restored, fileName, object.Key)
+		s.hashAudio(restored, fileName, object.Key)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error downloading version %s of %s: %w", version, object.Key, err)
+	}
+	if errReadTags != nil {
+		return fmt.Errorf("error reading version %s of %s: %w", version, object.Key, errReadTags)
+	}
+	track := restoredTrack(current, restored)
+
+	err = s.s3.LockObjectKey(ctx, object.Key, func(ctx context.Context) error {
+		if errLocked := s.s3.RestoreS3Version(ctx, trackID, version); errLocked != nil {
+			return errLocked
+		}
+		return s.track.UpdateTracks(ctx, track)
+	})
+	if err != nil {
+		return err
+	}
+	s.computeWaveforms(ctx, []string{trackID})
+	return nil
+}
+
+// versionObject returns the object version recorded for the track,
+// pgx.ErrNoRows when there is none. Versions recorded before their object
+// key was are looked up in the bucket.
+func (s *Service) versionObject(ctx context.Context, trackID, version string) (*minio.ObjectInfo, error) {
+	versions, err := s.s3.GetS3Versions(ctx, trackID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the versions of track %s: %w", trackID, err)
+	}
+	for _, v := range versions {
+		if v.VersionID != version {
+			continue
+		}
+		if v.ObjectKey == "" {
+			object, errFind := s.s3.FindObjectFromVersion(ctx, version)
+			if errFind != nil {
+				return nil, fmt.Errorf("error finding version %s: %w", version, errFind)
+			}
+			return &object, nil
+		}
+		return &minio.ObjectInfo{
+			Key:          v.ObjectKey,
+			VersionID:    v.VersionID,
+			ETag:         v.ETag,
+			Size:         v.Size,
+			LastModified: v.UploadedAt,
+		}, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// restoredTrack is the current track with what was read from the restored
+// version. The track keeps its ID and when it was ingested.
+func restoredTrack(current, restored *model.Track) *model.Track {
+	if current.CueStart != nil {
+		track := *current
+		track.MimeType = restored.MimeType
+		track.SampleRate = restored.SampleRate
+		track.Bitrate = restored.Bitrate
+		track.UpdatedAt = restored.UpdatedAt
+		return &track
+	}
+	track := *restored
+	track.ID = current.ID
+	track.IngestedAt = current.IngestedAt
+	track.ContentHashAttempts = current.ContentHashAttempts
+	return &track
+}
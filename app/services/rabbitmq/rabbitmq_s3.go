@@ -3,9 +3,20 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
+	"path/filepath"
 	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/analysis"
+	"s3MediaStreamer/app/services/artwork"
+	"s3MediaStreamer/app/services/cue"
+	"s3MediaStreamer/app/services/loudness"
+	"s3MediaStreamer/app/services/pcm"
+	"s3MediaStreamer/app/services/s3"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
 )
 
 func (s *Service) S3BucketActionEventQueue(ctx context.Context, messageBody map[string]interface{}) {
@@ -88,67 +99,263 @@ func (s *Service) putEvent(ctx context.Context, s3event *model.MessageBody) erro
 
 // checkObjectS3 checks the object in S3 and processes it.
 func (s *Service) checkObjectS3(ctx context.Context, object *model.MessageBody) error {
-	// Download file data from S3
-	fileName, err := s.s3.DownloadFilesS3(ctx, object.Key)
-	if err != nil {
-		s.logger.Errorf("Error downloading file %s from S3: %v\n", object.Key, err)
-		return err
+	if artwork.IsArtworkObject(object.Key) {
+		s.logger.Debugf("Skipping cover art %s", object.Key)
+		return nil
 	}
+	if cue.IsSheet(object.Key) {
+		return s.cueSheetEvent(ctx, filepath.Base(object.Key))
+	}
+	record := object.Records[0]
+	uploadedAt, _ := time.Parse(time.RFC3339, record.EventTime)
+	_, err := s.IngestObject(ctx, &minio.ObjectInfo{
+		Key:          filepath.Base(object.Key),
+		VersionID:    record.S3.Object.VersionID,
+		ETag:         record.S3.Object.Etag,
+		Size:         int64(record.S3.Object.Size),
+		LastModified: uploadedAt,
+	})
+	return err
+}
 
-	// Create a Track from the file data
-	objectTags, errReadTags := s.tags.ReadTags(fileName)
-	err = s.s3.CleanTemplateFile(fileName)
+// IngestObject ingests the audio object version unless it was ingested
+// before, by an upload through the API for one, and returns the IDs of its
+// tracks. They are none when the same track exists already, one with the same
+// audio payload or with the same artist, album, title and duration.
+func (s *Service) IngestObject(ctx context.Context, objectInfo *minio.ObjectInfo) ([]string, error) {
+	trackIDs, err := s.s3.GetTrackIDsByVersion(ctx, objectInfo.VersionID)
+	if err != nil || len(trackIDs) > 0 {
+		return trackIDs, err
+	}
+	return s.ingestObject(ctx, objectInfo)
+}
+
+// ingestObject creates the track of an audio object version, or the tracks
+// its sibling CUE sheet cuts it into, and returns their IDs. A new version of
+// the object of existing tracks becomes their current version instead. The
+// file is read and analysed first, the tracks are then recorded under the
+// lock of the object key.
+func (s *Service) ingestObject(ctx context.Context, objectInfo *minio.ObjectInfo) ([]string, error) {
+	key := objectInfo.Key
+	sheet, cueFile := s.findCueSheet(ctx, key)
+	if cueFile == nil {
+		if trackIDs, err := s.addObjectVersion(ctx, objectInfo); len(trackIDs) > 0 || err != nil {
+			return trackIDs, err
+		}
+	}
+
+	// Create a Track from the file data, downloaded through the disk cache
+	var objectTags *model.Track
+	var cueTracks []model.Track
+	var errReadTags error
+	err := s.cache.Fetch(ctx, objectInfo, func(fileName string) error {
+		objectTags, errReadTags = s.tags.ReadTags(fileName)
+		if errReadTags != nil {
+			return nil
+		}
+		var errArtwork error
+		if objectTags.Artwork, errArtwork = s.artwork.StoreService(ctx, fileName); errArtwork != nil {
+			s.logger.Warnf("Error storing cover art of %s: %v", key, errArtwork)
+		}
+		if cueFile != nil {
+			cueTracks = s.cueTracks(objectTags, sheet, cueFile)
+		}
+		if cueTracks != nil {
+			s.analyzeCueAudio(cueTracks, fileName, key)
+		} else {
+			s.analyzeAudio(objectTags, fileName, key)
+			s.hashAudio(objectTags, fileName, key)
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		s.logger.Errorf("Error downloading file %s from S3: %v\n", key, err)
+		return nil, err
 	}
 	if errReadTags != nil {
-		s.logger.Errorf("Error processing file: %s Error: %v\n", object.Records[0].S3.Object.Key, errReadTags)
-		return err
+		s.logger.Errorf("Error processing file: %s Error: %v\n", key, errReadTags)
+		return nil, errReadTags
 	}
-	err = s.checkIfTrackExists(ctx, objectTags, object.Records[0].S3.Object.VersionID)
+
+	var trackIDs []string
+	created := false
+	err = s.s3.LockObjectKey(ctx, key, func(ctx context.Context) error {
+		var errLocked error
+		if cueTracks != nil {
+			trackIDs, errLocked = s.replaceCueTracks(ctx, cueTracks, objectInfo)
+			created = errLocked == nil
+			return errLocked
+		}
+		// The version or an earlier one may have been recorded meanwhile
+		if trackIDs, errLocked = s.s3.GetTrackIDsByVersion(ctx, objectInfo.VersionID); errLocked != nil || len(trackIDs) > 0 {
+			return errLocked
+		}
+		if trackIDs, errLocked = s.recordObjectVersion(ctx, objectInfo, objectTags.ContentHash); errLocked != nil || len(trackIDs) > 0 {
+			return errLocked
+		}
+		trackIDs, errLocked = s.checkIfTrackExists(ctx, objectTags, objectInfo)
+		created = len(trackIDs) > 0
+		return errLocked
+	})
 	if err != nil {
-		s.logger.Errorf("%v\n", err)
+		return nil, err
 	}
-	return nil
+	if created {
+		s.computeWaveforms(ctx, trackIDs)
+	}
+	return trackIDs, nil
+}
+
+// addObjectVersion records the object version as the current version of the
+// tracks whose current version is an earlier version of the object, and
+// returns their IDs. The hash of their audio payload is renewed, the audio of
+// the new version may differ.
+func (s *Service) addObjectVersion(ctx context.Context, object *minio.ObjectInfo) ([]string, error) {
+	trackIDs, err := s.s3.GetTrackIDsByObjectKey(ctx, object.Key)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the tracks of %s: %w", object.Key, err)
+	}
+	if len(trackIDs) == 0 {
+		return nil, nil
+	}
+
+	hash := s.hashObject(ctx, object)
+	err = s.s3.LockObjectKey(ctx, object.Key, func(ctx context.Context) error {
+		var errLocked error
+		if trackIDs, errLocked = s.s3.GetTrackIDsByVersion(ctx, object.VersionID); errLocked != nil || len(trackIDs) > 0 {
+			return errLocked
+		}
+		trackIDs, errLocked = s.recordObjectVersion(ctx, object, hash)
+		return errLocked
+	})
+	return trackIDs, err
 }
 
-// checkIfTrackExists checks if the track already exists in the database.
-func (s *Service) checkIfTrackExists(ctx context.Context, track *model.Track, s3id string) error {
-	_, err := s.track.GetTracksByColumns(ctx, track.Title, "title")
+// recordObjectVersion makes the object version the current version of the
+// tracks of the object and stores the hash of its audio payload on them, and
+// returns their IDs. Tracks keep their hash when it is empty. The caller
+// holds the lock of the object key.
+func (s *Service) recordObjectVersion(ctx context.Context, object *minio.ObjectInfo, hash string) ([]string, error) {
+	trackIDs, err := s.s3.GetTrackIDsByObjectKey(ctx, object.Key)
 	if err != nil {
-		if s.isNoRecordsFound(err.Error()) {
-			return s.handleNonexistentTrack(ctx, track, s3id)
+		return nil, fmt.Errorf("error getting the tracks of %s: %w", object.Key, err)
+	}
+	for _, trackID := range trackIDs {
+		id, errParse := uuid.Parse(trackID)
+		if errParse != nil {
+			return nil, errParse
 		}
-		return fmt.Errorf("error getting existing tracks: %w", err)
+		if err = s.s3.AddS3Version(ctx, s3.NewTrackVersion(id, object)); err != nil {
+			return nil, fmt.Errorf("error adding S3 version: %w", err)
+		}
+		s.logger.Infof("Version %s of %s is the current version of track %s", object.VersionID, object.Key, trackID)
 	}
-	return nil
+	if len(trackIDs) > 0 && hash != "" {
+		if err = s.track.SetTrackContentHash(ctx, trackIDs, hash); err != nil {
+			return nil, fmt.Errorf("error renewing the content hash of %s: %w", object.Key, err)
+		}
+	}
+	return trackIDs, nil
+}
+
+// hashObject returns the hash of the audio payload of the object version, an
+// empty string when it could not be hashed.
+func (s *Service) hashObject(ctx context.Context, object *minio.ObjectInfo) string {
+	var hash string
+	err := s.cache.Fetch(ctx, object, func(fileName string) error {
+		var errHash error
+		hash, errHash = s.tags.HashAudio(fileName)
+		return errHash
+	})
+	if err != nil {
+		s.logger.Warnf("Error hashing the audio of %s: %v", object.Key, err)
+		return ""
+	}
+	return hash
+}
+
+// hashAudio stores the hash of the audio payload of the file on the track.
+// A track without one is still matched by its tags.
+func (s *Service) hashAudio(track *model.Track, fileName, key string) {
+	hash, err := s.tags.HashAudio(fileName)
+	if err != nil {
+		s.logger.Warnf("Error hashing audio of %s: %v", key, err)
+		return
+	}
+	track.ContentHash = hash
+}
+
+// analyzeAudio measures the loudness, tempo and key of the file in one
+// decoding pass and stores them on the track. Formats there is no decoder for
+// are left without them.
+func (s *Service) analyzeAudio(track *model.Track, fileName, key string) {
+	meter, analyzer := loudness.NewMeter(), analysis.NewAnalyzer()
+	if err := pcm.DecodeFile(fileName, meter, analyzer); err != nil {
+		s.logAnalysisError(key, err)
+		return
+	}
+	s.applyAnalysis(track, meter, analyzer, key)
+}
+
+func (s *Service) applyAnalysis(track *model.Track, meter *loudness.Meter, analyzer *analysis.Analyzer, key string) {
+	analyzer.Result().Apply(track)
+	if result, err := meter.Result(); err == nil {
+		result.Apply(track)
+	} else {
+		s.logger.Debugf("No loudness for %s: %v", key, err)
+	}
+}
+
+func (s *Service) logAnalysisError(key string, err error) {
+	if errors.Is(err, pcm.ErrUnsupported) {
+		s.logger.Debugf("No audio analysis for %s: %v", key, err)
+	} else {
+		s.logger.Warnf("Error analysing audio of %s: %v", key, err)
+	}
+}
+
+// checkIfTrackExists checks if the same track already exists in the
+// database, and returns the ID of the track when it was created.
+func (s *Service) checkIfTrackExists(ctx context.Context, track *model.Track, object *minio.ObjectInfo) ([]string, error) {
+	existing, err := s.track.GetTrackIDByIdentity(ctx, track)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing tracks: %w", err)
+	}
+	if existing == "" {
+		return s.handleNonexistentTrack(ctx, track, object)
+	}
+	s.logger.Infof("Track '%s' already exists as track %s, %s is not ingested", track.Title, existing, object.Key)
+	return nil, nil
 }
 
 // handleNonexistentTrack handles the case where a track is not found in the database.
-func (s *Service) handleNonexistentTrack(ctx context.Context, track *model.Track, s3id string) error {
+func (s *Service) handleNonexistentTrack(ctx context.Context, track *model.Track, object *minio.ObjectInfo) ([]string, error) {
 	s.logger.Infof("Track '%s' not found in the database.\n", track.Title)
 
 	existingTracksSlice := []model.Track{*track}
 	if len(existingTracksSlice) == 1 {
 		if err := s.track.CreateTracks(ctx, existingTracksSlice); err != nil {
-			return fmt.Errorf("error creating track: %w", err)
+			return nil, fmt.Errorf("error creating track: %w", err)
 		}
 	} else {
 		s.logger.Errorf("Track '%s' already exists\n", track.Artist)
 	}
 
-	err := s.s3.AddS3Version(ctx, existingTracksSlice[0].ID.String(), s3id)
+	err := s.s3.AddS3Version(ctx, s3.NewTrackVersion(existingTracksSlice[0].ID, object))
 	if err != nil {
-		return fmt.Errorf("error adding S3 version: %w", err)
+		return nil, fmt.Errorf("error adding S3 version: %w", err)
 	}
 	s.logger.Infof("Track '%s' saved to the database.\n", track.Artist)
-	return nil
+
+	return []string{existingTracksSlice[0].ID.String()}, nil
 }
 
-// isNoRecordsFound checks if the error message indicates no records were found.
-func (s *Service) isNoRecordsFound(errMsg string) bool {
-	// Use a regex pattern to check for the "no records found" message
-	pattern := `no records found (.+)`
-	matched, _ := regexp.MatchString(pattern, errMsg)
-	return matched
+// computeWaveforms computes the waveforms of new tracks. Tracks left without
+// one get it from the waveform job or on first request.
+func (s *Service) computeWaveforms(ctx context.Context, trackIDs []string) {
+	for _, trackID := range trackIDs {
+		if _, err := s.waveform.UpdateService(ctx, trackID); err != nil && !errors.Is(err, pcm.ErrUnsupported) {
+			s.logger.Warnf("Error computing waveform of track %s: %v", trackID, err)
+		}
+	}
 }
@@ -3,21 +3,27 @@ package rabbitmq
 import (
 	"s3MediaStreamer/app/internal/logs"
 	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/artwork"
 	"s3MediaStreamer/app/services/db"
+	"s3MediaStreamer/app/services/diskcache"
 	"s3MediaStreamer/app/services/s3"
 	"s3MediaStreamer/app/services/tags"
 	"s3MediaStreamer/app/services/track"
+	"s3MediaStreamer/app/services/waveform"
 )
 
 type Repository interface{}
 
 type Service struct {
-	cfg     *model.Config
-	logger  *logs.Logger
-	storage db.Repository
-	s3      s3.Service
-	track   track.Service
-	tags    tags.Service
+	cfg      *model.Config
+	logger   *logs.Logger
+	storage  db.Repository
+	s3       s3.Service
+	track    track.Service
+	tags     tags.Service
+	cache    *diskcache.Service
+	artwork  *artwork.Service
+	waveform *waveform.Service
 }
 
 func NewMessageService(cfg *model.Config,
@@ -26,6 +32,9 @@ func NewMessageService(cfg *model.Config,
 	s3 s3.Service,
 	track track.Service,
 	tags tags.Service,
+	cache *diskcache.Service,
+	artwork *artwork.Service,
+	waveform *waveform.Service,
 ) *Service {
 	return &Service{
 		cfg,
@@ -34,5 +43,8 @@ func NewMessageService(cfg *model.Config,
 		s3,
 		track,
 		tags,
+		cache,
+		artwork,
+		waveform,
 	}
 }
@@ -0,0 +1,73 @@
+package radio
+
+import (
+	"io"
+	"strings"
+)
+
+const (
+	icyBlockSize = 16
+	icyMaxBlocks = 255
+)
+
+// ICYWriter interleaves SHOUTcast metadata blocks into an audio stream: every
+// metaint audio bytes a length byte follows, counting 16 byte blocks of
+// StreamTitle metadata. The title is only repeated after it changed.
+type ICYWriter struct {
+	w         io.Writer
+	metaint   int
+	remaining int
+	title     string
+	sent      string
+}
+
+func NewICYWriter(w io.Writer, metaint int) *ICYWriter {
+	return &ICYWriter{w: w, metaint: metaint, remaining: metaint}
+}
+
+// SetTitle sets the StreamTitle sent with the next metadata block.
+func (w *ICYWriter) SetTitle(title string) {
+	w.title = title
+}
+
+func (w *ICYWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), w.remaining)
+		m, err := w.w.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+		w.remaining -= n
+		if w.remaining == 0 {
+			if err = w.writeMetadata(); err != nil {
+				return written, err
+			}
+			w.remaining = w.metaint
+		}
+	}
+	return written, nil
+}
+
+func (w *ICYWriter) writeMetadata() error {
+	if w.title == w.sent {
+		_, err := w.w.Write([]byte{0})
+		return err
+	}
+	// ICY has no escaping, a quote would end the title early.
+	meta := "StreamTitle='" + strings.ReplaceAll(w.title, "'", "’") + "';"
+	if len(meta) > icyMaxBlocks*icyBlockSize {
+		meta = meta[:icyMaxBlocks*icyBlockSize]
+	}
+	blocks := (len(meta) + icyBlockSize - 1) / icyBlockSize
+	buf := make([]byte, 1+blocks*icyBlockSize)
+	buf[0] = byte(blocks)
+	copy(buf[1:], meta)
+	if _, err := w.w.Write(buf); err != nil {
+		return err
+	}
+	w.sent = w.title
+	return nil
+}
@@ -0,0 +1,38 @@
+package radio_test
+
+import (
+	"bytes"
+	"s3MediaStreamer/app/services/radio"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestICYWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := radio.NewICYWriter(&out, 4)
+
+	w.SetTitle("Artist - Title")
+	n, err := w.Write([]byte("abcdef"))
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	_, err = w.Write([]byte("gh"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("ijkl"))
+	require.NoError(t, err)
+
+	meta := "StreamTitle='Artist - Title';"
+	block := make([]byte, 32)
+	copy(block, meta)
+
+	var want bytes.Buffer
+	want.WriteString("abcd")
+	want.WriteByte(2) // 2 blocks of 16 bytes
+	want.Write(block)
+	want.WriteString("efgh")
+	want.WriteByte(0) // title unchanged
+	want.WriteString("ijkl")
+	want.WriteByte(0)
+	assert.Equal(t, want.Bytes(), out.Bytes())
+}
@@ -0,0 +1,186 @@
+package radio
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/audio"
+	"s3MediaStreamer/app/services/playlist"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+const defaultMetaInt = 16000
+
+type Service struct {
+	cfg      *model.Config
+	audio    audio.Service
+	playlist playlist.Service
+	logger   *logs.Logger
+
+	// mu guards the stations and the listeners joining and leaving them, so
+	// that a station is never stopped while a listener joins it.
+	mu       sync.Mutex
+	stations map[string]*station
+}
+
+func NewRadioService(cfg *model.Config, audio audio.Service, playlist playlist.Service, logger *logs.Logger) *Service {
+	return &Service{
+		cfg:      cfg,
+		audio:    audio,
+		playlist: playlist,
+		logger:   logger,
+		stations: make(map[string]*station),
+	}
+}
+
+// join adds a listener to the station of the playlist, starting the station
+// if nobody listens to it yet. Every loop and shuffle combination is a station
+// of its own.
+func (s *Service) join(playlistID string, loop, shuffle bool) (*station, *listener) {
+	key := playlistID + "/" + strconv.FormatBool(loop) + "/" + strconv.FormatBool(shuffle)
+	l := &listener{ch: make(chan chunk, listenerBuffer)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stations[key]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		st = &station{
+			key:        key,
+			playlistID: playlistID,
+			loop:       loop,
+			shuffle:    shuffle,
+			cancel:     cancel,
+			listeners:  make(map[*listener]struct{}),
+		}
+		s.stations[key] = st
+		go s.run(ctx, st)
+	}
+	st.add(l)
+	return st, l
+}
+
+// leave removes the listener and stops the station after its last listener left.
+func (s *Service) leave(st *station, l *listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st.remove(l) == 0 {
+		s.stop(st)
+	}
+}
+
+// removeStation drops a station that stopped playing and ends its streams.
+func (s *Service) removeStation(st *station) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop(st)
+	st.closeAll()
+}
+
+// stop cancels the station and unregisters it, s.mu must be held.
+func (s *Service) stop(st *station) {
+	st.cancel()
+	if s.stations[st.key] == st {
+		delete(s.stations, st.key)
+	}
+}
+
+// queryBool parses an optional boolean query parameter.
+func queryBool(c *gin.Context, name string, def bool) (bool, *model.RestError) {
+	value := c.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, &model.RestError{Code: http.StatusBadRequest, Err: name + " must be a boolean"}
+	}
+	return b, nil
+}
+
+// ListenService streams the playlist as an endless MP3 radio stream, with ICY
+// metadata when the client asks for it, until the client disconnects or the
+// playlist ends. Only users who may read the playlist can tune in.
+func (s *Service) ListenService(c *gin.Context, playlistID string) *model.RestError {
+	_, span := otel.Tracer("").Start(c.Request.Context(), "ListenService")
+	defer span.End()
+
+	cfg := s.cfg.AppConfig.Stream.Radio
+	loop, errLoop := queryBool(c, "loop", cfg.Loop)
+	if errLoop != nil {
+		return errLoop
+	}
+	shuffle, errShuffle := queryBool(c, "shuffle", cfg.Shuffle)
+	if errShuffle != nil {
+		return errShuffle
+	}
+	userContext := &model.UserContext{UserID: c.GetString("user_id"), UserRole: c.GetString("userRole")}
+	tracks, errTracks := s.playlist.GetTracksInPlaylist(c.Request.Context(), userContext, playlistID)
+	if errTracks != nil {
+		return errTracks
+	}
+	if len(tracks) == 0 {
+		return &model.RestError{Code: http.StatusNotFound, Err: "No tracks to play"}
+	}
+
+	st, l := s.join(playlistID, loop, shuffle)
+	defer s.leave(st, l)
+
+	c.Header("Content-Type", "audio/mpeg")
+	c.Header("Cache-Control", "no-cache, no-store")
+	c.Header("icy-name", playlistID)
+	c.Header("icy-pub", "0")
+	var w io.Writer = c.Writer
+	var icy *ICYWriter
+	if c.GetHeader("Icy-MetaData") == "1" {
+		metaint := cfg.MetaInt
+		if metaint <= 0 {
+			metaint = defaultMetaInt
+		}
+		c.Header("icy-metaint", strconv.Itoa(metaint))
+		icy = NewICYWriter(c.Writer, metaint)
+		w = icy
+	}
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case frame, ok := <-l.ch:
+			if !ok {
+				return nil
+			}
+			if icy != nil {
+				icy.SetTitle(frame.title)
+			}
+			if _, err := w.Write(frame.data); err != nil {
+				return nil
+			}
+			if len(l.ch) == 0 {
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// ListenersService returns the number of listeners of all stations of the playlist.
+func (s *Service) ListenersService(playlistID string) model.RadioListeners {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := model.RadioListeners{PlaylistID: playlistID}
+	for _, st := range s.stations {
+		if st.playlistID == playlistID {
+			result.Listeners += st.count()
+		}
+	}
+	return result
+}
@@ -0,0 +1,194 @@
+package radio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/audio"
+	"sync"
+	"time"
+
+	"github.com/tcolgate/mp3"
+)
+
+const (
+	// listenerBuffer is the number of frames a listener may fall behind before it is dropped.
+	listenerBuffer = 512
+	// burstFrames are sent to a joining listener at once so that playback starts immediately.
+	burstFrames = 64
+	// radioLead is how far the station runs ahead of real time.
+	radioLead = 2 * time.Second
+)
+
+// chunk is one MP3 frame with the title of the track it belongs to.
+type chunk struct {
+	data  []byte
+	title string
+}
+
+type listener struct {
+	ch chan chunk
+}
+
+// station plays a playlist once in real time and fans the frames out to all
+// listeners, so everybody hears the same position.
+type station struct {
+	key        string
+	playlistID string
+	loop       bool
+	shuffle    bool
+	cancel     context.CancelFunc
+
+	mu        sync.Mutex
+	listeners map[*listener]struct{}
+	burst     []chunk
+}
+
+func (st *station) add(l *listener) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for _, c := range st.burst {
+		l.ch <- c
+	}
+	st.listeners[l] = struct{}{}
+}
+
+// remove unregisters the listener and returns the number of listeners left.
+func (st *station) remove(l *listener) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.listeners[l]; ok {
+		delete(st.listeners, l)
+		close(l.ch)
+	}
+	return len(st.listeners)
+}
+
+func (st *station) count() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.listeners)
+}
+
+// broadcast sends the frame to every listener, dropping the ones that cannot keep up.
+func (st *station) broadcast(c chunk) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.burst = append(st.burst, c)
+	if len(st.burst) > burstFrames {
+		st.burst = st.burst[len(st.burst)-burstFrames:]
+	}
+	for l := range st.listeners {
+		select {
+		case l.ch <- c:
+		default:
+			delete(st.listeners, l)
+			close(l.ch)
+		}
+	}
+}
+
+// closeAll ends the stream of every listener.
+func (st *station) closeAll() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for l := range st.listeners {
+		delete(st.listeners, l)
+		close(l.ch)
+	}
+}
+
+// run plays the playlist until it ends, or forever when looping, and removes
+// the station when done.
+func (s *Service) run(ctx context.Context, st *station) {
+	defer s.removeStation(st)
+
+	start := time.Now()
+	var elapsed time.Duration
+	for {
+		tracks, err := s.audio.PlayPlaylist(ctx, st.playlistID)
+		if err != nil {
+			s.logger.Errorf("Radio %s: error loading playlist: %v", st.playlistID, err)
+			return
+		}
+		order := make([]model.TrackRequest, len(*tracks))
+		copy(order, *tracks)
+		if st.shuffle {
+			rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] }) //nolint:gosec // playback order only
+		}
+
+		played := false
+		for i := range order {
+			ok, errPlay := s.playTrack(ctx, st, &order[i].Track, start, &elapsed)
+			if ctx.Err() != nil {
+				return
+			}
+			if errPlay != nil {
+				s.logger.Warnf("Radio %s: track %s skipped: %v", st.playlistID, order[i].ID, errPlay)
+			}
+			played = played || ok
+		}
+		if !st.loop || !played {
+			return
+		}
+	}
+}
+
+// playTrack broadcasts the MP3 frames of the track paced to real time and
+// reports whether any frame was played.
+func (s *Service) playTrack(ctx context.Context, st *station, track *model.Track, start time.Time, elapsed *time.Duration) (bool, error) {
+	object, _, errFind := s.audio.FindSegmentObject(ctx, track.ID.String())
+	if errFind != nil {
+		return false, errors.New(errFind.Err)
+	}
+	if !audio.IsMPEGAudio(object, track) {
+		return false, errors.New("not an MP3 track")
+	}
+	slice, errSlice := s.audio.CueSliceService(ctx, object, track)
+	if errSlice != nil {
+		return false, errors.New(errSlice.Err)
+	}
+	var reader io.ReadCloser
+	var err error
+	if slice != nil {
+		reader, err = s.audio.OpenSlice(ctx, object, slice)
+	} else {
+		reader, err = s.audio.OpenObject(ctx, object)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	title := track.Artist + " - " + track.Title
+	dec := mp3.NewDecoder(reader)
+	var frame mp3.Frame
+	skipped := 0
+	played := false
+	for {
+		if err = dec.Decode(&frame, &skipped); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return played, nil
+			}
+			return played, err
+		}
+		data, errRead := io.ReadAll(frame.Reader())
+		if errRead != nil {
+			return played, errRead
+		}
+		if wait := time.Until(start.Add(*elapsed)) - radioLead; wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return played, ctx.Err()
+			case <-timer.C:
+			}
+		}
+		st.broadcast(chunk{data: data, title: title})
+		*elapsed += frame.Duration()
+		played = true
+	}
+}
@@ -3,19 +3,32 @@ package s3
 import (
 	"context"
 	"io"
+	"net/url"
 	"os"
 	"s3MediaStreamer/app/model"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
 )
 
 type Repository interface {
-	UploadFilesS3(ctx context.Context, upload *model.UploadS3) error
+	UploadFilesS3(ctx context.Context, upload *model.UploadS3) (minio.UploadInfo, error)
 	DownloadFilesS3(ctx context.Context, name string) (string, error)
 	ListObjectS3(ctx context.Context) ([]minio.ObjectInfo, error)
 	DeleteObjectS3(ctx context.Context, object *minio.ObjectInfo) error
 	FindObjectFromVersion(ctx context.Context, s3tag string) (minio.ObjectInfo, error)
 	DownloadFilesS3Stream(ctx context.Context, name string, callback func(io.Reader) error) error
+	GetObjectS3(ctx context.Context, object *minio.ObjectInfo) (*minio.Object, error)
+	GetObjectRangeS3(ctx context.Context, object *minio.ObjectInfo, start, end int64) (*minio.Object, error)
+	PresignedGetObjectS3(ctx context.Context, object *minio.ObjectInfo, expires time.Duration, contentType string) (*url.URL, error)
+	PutObjectS3(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	StatObjectS3(ctx context.Context, key string) (minio.ObjectInfo, error)
+	NewMultipartUploadS3(ctx context.Context, key, contentType string) (string, error)
+	PutObjectPartS3(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (minio.ObjectPart, error)
+	CompleteMultipartUploadS3(ctx context.Context, key, uploadID string, parts []minio.CompletePart) (minio.UploadInfo, error)
+	AbortMultipartUploadS3(ctx context.Context, key, uploadID string) error
 	CleanTemplateFile(fileName string) error
 	OpenTemplateFile(fileName string) (*os.File, error)
 	Ping(ctx context.Context) error
@@ -23,8 +36,15 @@ type Repository interface {
 
 type DBRepository interface {
 	GetS3VersionByTrackID(ctx context.Context, trackID string) (string, error)
-	AddS3Version(ctx context.Context, trackID, version string) error
+	GetS3Versions(ctx context.Context, trackID string) ([]model.TrackVersion, error)
+	GetTrackIDsByObjectKey(ctx context.Context, objectKey string) ([]string, error)
+	GetTrackIDsByVersion(ctx context.Context, version string) ([]string, error)
+	LockObjectKey(ctx context.Context, objectKey string, fn func(ctx context.Context) error) error
+	AddS3Version(ctx context.Context, version *model.TrackVersion) error
+	RestoreS3Version(ctx context.Context, trackID, version string) error
 	DeleteS3Version(ctx context.Context, version string) error
+	GetS3VersionsWithoutObjectKey(ctx context.Context) ([]string, error)
+	SetS3VersionObject(ctx context.Context, version *model.TrackVersion) error
 }
 
 type Service struct {
@@ -39,7 +59,7 @@ func NewS3Service(fileRepository Repository, s3DBRepository DBRepository) *Servi
 	}
 }
 
-func (s *Service) UploadFilesS3(ctx context.Context, upload *model.UploadS3) error {
+func (s *Service) UploadFilesS3(ctx context.Context, upload *model.UploadS3) (minio.UploadInfo, error) {
 	return s.s3Repository.UploadFilesS3(ctx, upload)
 }
 
@@ -62,6 +82,42 @@ func (s *Service) FindObjectFromVersion(ctx context.Context, s3tag string) (mini
 func (s *Service) DownloadFilesS3Stream(ctx context.Context, name string, callback func(io.Reader) error) error {
 	return s.s3Repository.DownloadFilesS3Stream(ctx, name, callback)
 }
+func (s *Service) GetObjectS3(ctx context.Context, object *minio.ObjectInfo) (*minio.Object, error) {
+	return s.s3Repository.GetObjectS3(ctx, object)
+}
+
+func (s *Service) GetObjectRangeS3(ctx context.Context, object *minio.ObjectInfo, start, end int64) (*minio.Object, error) {
+	return s.s3Repository.GetObjectRangeS3(ctx, object, start, end)
+}
+
+func (s *Service) PresignedGetObjectS3(ctx context.Context, object *minio.ObjectInfo, expires time.Duration, contentType string) (*url.URL, error) {
+	return s.s3Repository.PresignedGetObjectS3(ctx, object, expires, contentType)
+}
+
+func (s *Service) PutObjectS3(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	return s.s3Repository.PutObjectS3(ctx, key, reader, size, contentType)
+}
+
+func (s *Service) StatObjectS3(ctx context.Context, key string) (minio.ObjectInfo, error) {
+	return s.s3Repository.StatObjectS3(ctx, key)
+}
+
+func (s *Service) NewMultipartUploadS3(ctx context.Context, key, contentType string) (string, error) {
+	return s.s3Repository.NewMultipartUploadS3(ctx, key, contentType)
+}
+
+func (s *Service) PutObjectPartS3(ctx context.Context, key, uploadID string, number int, reader io.Reader, size int64) (minio.ObjectPart, error) {
+	return s.s3Repository.PutObjectPartS3(ctx, key, uploadID, number, reader, size)
+}
+
+func (s *Service) CompleteMultipartUploadS3(ctx context.Context, key, uploadID string, parts []minio.CompletePart) (minio.UploadInfo, error) {
+	return s.s3Repository.CompleteMultipartUploadS3(ctx, key, uploadID, parts)
+}
+
+func (s *Service) AbortMultipartUploadS3(ctx context.Context, key, uploadID string) error {
+	return s.s3Repository.AbortMultipartUploadS3(ctx, key, uploadID)
+}
+
 func (s *Service) CleanTemplateFile(fileName string) error {
 	return s.s3Repository.CleanTemplateFile(fileName)
 }
@@ -77,9 +133,48 @@ func (s *Service) Ping(ctx context.Context) error {
 func (s *Service) GetS3VersionByTrackID(ctx context.Context, trackID string) (string, error) {
 	return s.s3DBRepository.GetS3VersionByTrackID(ctx, trackID)
 }
-func (s *Service) AddS3Version(ctx context.Context, trackID, version string) error {
-	return s.s3DBRepository.AddS3Version(ctx, trackID, version)
+func (s *Service) GetS3Versions(ctx context.Context, trackID string) ([]model.TrackVersion, error) {
+	return s.s3DBRepository.GetS3Versions(ctx, trackID)
+}
+func (s *Service) GetTrackIDsByObjectKey(ctx context.Context, objectKey string) ([]string, error) {
+	return s.s3DBRepository.GetTrackIDsByObjectKey(ctx, objectKey)
+}
+func (s *Service) GetTrackIDsByVersion(ctx context.Context, version string) ([]string, error) {
+	return s.s3DBRepository.GetTrackIDsByVersion(ctx, version)
+}
+func (s *Service) LockObjectKey(ctx context.Context, objectKey string, fn func(ctx context.Context) error) error {
+	return s.s3DBRepository.LockObjectKey(ctx, objectKey, fn)
+}
+func (s *Service) AddS3Version(ctx context.Context, version *model.TrackVersion) error {
+	return s.s3DBRepository.AddS3Version(ctx, version)
+}
+func (s *Service) RestoreS3Version(ctx context.Context, trackID, version string) error {
+	return s.s3DBRepository.RestoreS3Version(ctx, trackID, version)
 }
 func (s *Service) DeleteS3Version(ctx context.Context, version string) error {
 	return s.s3DBRepository.DeleteS3Version(ctx, version)
 }
+func (s *Service) GetS3VersionsWithoutObjectKey(ctx context.Context) ([]string, error) {
+	return s.s3DBRepository.GetS3VersionsWithoutObjectKey(ctx)
+}
+func (s *Service) SetS3VersionObject(ctx context.Context, version *model.TrackVersion) error {
+	return s.s3DBRepository.SetS3VersionObject(ctx, version)
+}
+
+// NewTrackVersion is the record of the object version as the current version
+// of the track.
+func NewTrackVersion(trackID uuid.UUID, object *minio.ObjectInfo) *model.TrackVersion {
+	uploadedAt := object.LastModified
+	if uploadedAt.IsZero() {
+		uploadedAt = time.Now()
+	}
+	return &model.TrackVersion{
+		TrackID:    trackID,
+		VersionID:  object.VersionID,
+		ObjectKey:  object.Key,
+		ETag:       strings.Trim(object.ETag, `"`),
+		Size:       object.Size,
+		UploadedAt: uploadedAt,
+		Current:    true,
+	}
+}
@@ -0,0 +1,133 @@
+package search
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+	maxTermSize  = 256
+	adminPolicy  = "admin"
+
+	// The matched words are marked with control characters that cannot be in
+	// a tag, the snippets are escaped before the markers become <b> tags.
+	startSel = "\x02"
+	stopSel  = "\x03"
+)
+
+var highlighter = strings.NewReplacer(startSel, "<b>", stopSel, "</b>")
+
+type Repository interface {
+	SearchTracks(ctx context.Context, query model.SearchQuery) ([]model.TrackHit, error)
+	SearchAlbums(ctx context.Context, query model.SearchQuery) ([]model.AlbumHit, error)
+	SearchArtists(ctx context.Context, query model.SearchQuery) ([]model.ArtistHit, error)
+	SearchPlaylists(ctx context.Context, query model.SearchQuery) ([]model.PlaylistHit, error)
+}
+
+type Service struct {
+	repository Repository
+	logger     *logs.Logger
+}
+
+func NewSearchService(repository Repository, logger *logs.Logger) *Service {
+	return &Service{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+// Highlight escapes a snippet for HTML and turns the marks around the matched
+// words into <b> tags.
+func Highlight(snippet string) string {
+	return highlighter.Replace(html.EscapeString(snippet))
+}
+
+// SearchService searches the tracks, albums, artists and playlists for the
+// term, returning at most limit of each. Members only find their own
+// playlists.
+func (s *Service) SearchService(c *gin.Context, userContext *model.UserContext, term, limit string) (*model.SearchResult, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "SearchService")
+	defer span.End()
+
+	term = strings.TrimSpace(strings.NewReplacer(startSel, "", stopSel, "").Replace(term))
+	if term == "" {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "q is required"}
+	}
+	if utf8.RuneCountInString(term) > maxTermSize {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "q is longer than " + strconv.Itoa(maxTermSize) + " characters"}
+	}
+	query := model.SearchQuery{Term: term, Limit: defaultLimit, StartSel: startSel, StopSel: stopSel}
+	if limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 || query.Limit > maxLimit {
+			return nil, &model.RestError{Code: http.StatusBadRequest, Err: "limit must be between 1 and " + strconv.Itoa(maxLimit)}
+		}
+	}
+	if userContext.UserRole != adminPolicy {
+		query.Owner = userContext.UserID
+	}
+
+	result, err := s.search(ctx, query)
+	if err != nil {
+		s.logger.Errorf("Error searching for %q: %v", term, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	return result, nil
+}
+
+func (s *Service) search(ctx context.Context, query model.SearchQuery) (*model.SearchResult, error) {
+	result := &model.SearchResult{
+		Tracks:    []model.TrackHit{},
+		Albums:    []model.AlbumHit{},
+		Artists:   []model.ArtistHit{},
+		Playlists: []model.PlaylistHit{},
+	}
+
+	tracks, err := s.repository.SearchTracks(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range tracks {
+		hit.Snippet, hit.LyricsSnippet = Highlight(hit.Snippet), Highlight(hit.LyricsSnippet)
+		result.Tracks = append(result.Tracks, hit)
+	}
+
+	albums, err := s.repository.SearchAlbums(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range albums {
+		hit.Snippet = Highlight(hit.Snippet)
+		result.Albums = append(result.Albums, hit)
+	}
+
+	artists, err := s.repository.SearchArtists(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range artists {
+		hit.Snippet = Highlight(hit.Snippet)
+		result.Artists = append(result.Artists, hit)
+	}
+
+	playlists, err := s.repository.SearchPlaylists(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, hit := range playlists {
+		hit.Snippet = Highlight(hit.Snippet)
+		result.Playlists = append(result.Playlists, hit)
+	}
+	return result, nil
+}
@@ -0,0 +1,14 @@
+package search_test
+
+import (
+	"s3MediaStreamer/app/services/search"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "<b>Yesterday</b> — The Beatles", search.Highlight("\x02Yesterday\x03 — The Beatles"))
+	assert.Equal(t, "&lt;script&gt; <b>Rock</b> &amp; Roll", search.Highlight("<script> \x02Rock\x03 & Roll"))
+	assert.Equal(t, "", search.Highlight(""))
+}
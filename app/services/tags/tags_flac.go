@@ -0,0 +1,197 @@
+package tags
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"s3MediaStreamer/app/model"
+	"strconv"
+	"strings"
+)
+
+const (
+	flacBlockHeader   = 4
+	flacLastBlock     = 0x80
+	flacBlockType     = 0x7f
+	flacPadding       = 1
+	flacVorbisComment = 4
+	flacMaxBlock      = 1<<24 - 1
+	vorbisVendor      = "s3MediaStreamer"
+)
+
+// vorbisManaged are the comments WriteTags writes from the fields of the
+// track, with the other names readers take those fields from.
+var vorbisManaged = map[string]bool{
+	"TITLE": true, "ARTIST": true, "ALBUM": true, "ALBUMARTIST": true, "ALBUM ARTIST": true,
+	"COMPOSER": true, "GENRE": true, "DATE": true, "YEAR": true,
+	"TRACKNUMBER": true, "TRACKTOTAL": true, "TOTALTRACKS": true,
+	"DISCNUMBER": true, "DISCTOTAL": true, "TOTALDISCS": true,
+	"COMMENT": true, "DESCRIPTION": true, "LYRICS": true, "UNSYNCEDLYRICS": true,
+}
+
+var errVorbisComment = errors.New("invalid Vorbis comment block")
+
+type flacBlock struct {
+	kind byte
+	data []byte
+}
+
+// writeFLAC copies the FLAC file of size bytes to out with the Vorbis
+// comments of the track. The other metadata blocks are kept, the padding is
+// renewed and an ID3v2 tag in front of the stream is copied as it is.
+func writeFLAC(in io.ReaderAt, size int64, out io.Writer, track *model.Track) error {
+	_, _, start, err := readID3v2(in, size)
+	if err != nil {
+		return err
+	}
+	r := io.NewSectionReader(in, 0, size)
+	if _, err = io.CopyN(out, r, start); err != nil {
+		return err
+	}
+	marker, err := readFull(r, 4)
+	if err != nil {
+		return err
+	}
+	if string(marker) != "fLaC" {
+		return errors.New("missing FLAC stream marker")
+	}
+
+	var blocks []flacBlock
+	comment := -1
+	for {
+		header, errHeader := readFull(r, flacBlockHeader)
+		if errHeader != nil {
+			return errHeader
+		}
+		data, errData := readFull(r, int(header[1])<<16|int(header[2])<<8|int(header[3]))
+		if errData != nil {
+			return errData
+		}
+		switch kind := header[0] & flacBlockType; kind {
+		case flacPadding:
+		case flacVorbisComment:
+			comment = len(blocks)
+			fallthrough
+		default:
+			blocks = append(blocks, flacBlock{kind: kind, data: data})
+		}
+		if header[0]&flacLastBlock != 0 {
+			break
+		}
+	}
+
+	var old []byte
+	if comment >= 0 {
+		old = blocks[comment].data
+	}
+	data, err := buildVorbisComment(old, track)
+	if err != nil {
+		return err
+	}
+	if len(data) > flacMaxBlock {
+		return errors.New("Vorbis comments do not fit into a FLAC metadata block")
+	}
+	if comment >= 0 {
+		blocks[comment].data = data
+	} else {
+		// The stream info is always the first block.
+		blocks = append(blocks[:1], append([]flacBlock{{kind: flacVorbisComment, data: data}}, blocks[1:]...)...)
+	}
+	blocks = append(blocks, flacBlock{kind: flacPadding, data: make([]byte, tagPadding)})
+
+	if _, err = io.WriteString(out, "fLaC"); err != nil {
+		return err
+	}
+	for i, block := range blocks {
+		header := []byte{block.kind, byte(len(block.data) >> 16), byte(len(block.data) >> 8), byte(len(block.data))}
+		if i == len(blocks)-1 {
+			header[0] |= flacLastBlock
+		}
+		if _, err = out.Write(header); err != nil {
+			return err
+		}
+		if _, err = out.Write(block.data); err != nil {
+			return err
+		}
+	}
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// buildVorbisComment returns the Vorbis comment block of the track, with the
+// vendor and the comments the track has no field for taken from the old one.
+func buildVorbisComment(old []byte, track *model.Track) ([]byte, error) {
+	vendor := vorbisVendor
+	var kept []string
+	if old != nil {
+		var err error
+		if vendor, kept, err = parseVorbisComment(old); err != nil {
+			return nil, err
+		}
+	}
+
+	var comments []string
+	for _, field := range []struct{ name, value string }{
+		{"TITLE", track.Title}, {"ARTIST", track.Artist}, {"ALBUM", track.Album}, {"ALBUMARTIST", track.AlbumArtist},
+		{"COMPOSER", track.Composer}, {"GENRE", track.Genre}, {"DATE", yearOf(track)},
+		{"TRACKNUMBER", positive(track.Track)}, {"TRACKTOTAL", positive(track.TrackTotal)},
+		{"DISCNUMBER", positive(track.Disc)}, {"DISCTOTAL", positive(track.DiscTotal)},
+		{"COMMENT", track.Comment}, {"LYRICS", track.Lyrics},
+	} {
+		if field.value != "" {
+			comments = append(comments, field.name+"="+field.value)
+		}
+	}
+	for _, c := range kept {
+		name, _, _ := strings.Cut(c, "=")
+		if !vorbisManaged[strings.ToUpper(name)] {
+			comments = append(comments, c)
+		}
+	}
+
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(vendor)))
+	data = append(data, vendor...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(comments)))
+	for _, c := range comments {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(c)))
+		data = append(data, c...)
+	}
+	return data, nil
+}
+
+func parseVorbisComment(data []byte) (string, []string, error) {
+	next := func() (string, error) {
+		if len(data) < 4 {
+			return "", errVorbisComment
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(n) > uint64(len(data)-4) {
+			return "", errVorbisComment
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, nil
+	}
+	vendor, err := next()
+	if err != nil || len(data) < 4 {
+		return "", nil, errVorbisComment
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+	var comments []string
+	for i := uint32(0); i < count; i++ {
+		c, errComment := next()
+		if errComment != nil {
+			return "", nil, errComment
+		}
+		comments = append(comments, c)
+	}
+	return vendor, comments, nil
+}
+
+func positive(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
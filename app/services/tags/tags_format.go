@@ -0,0 +1,83 @@
+package tags
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// MIME types of the audio formats ReadTags understands.
+const (
+	MimeMP3  = "audio/mpeg"
+	MimeFLAC = "audio/flac"
+	MimeOgg  = "audio/ogg"
+	MimeOpus = "audio/opus"
+	MimeMP4  = "audio/mp4"
+	MimeWAV  = "audio/wav"
+	MimeAIFF = "audio/aiff"
+)
+
+const (
+	sniffSize       = 64
+	id3HeaderSize   = 10
+	id3FooterFlag   = 0x10
+	mpegSyncMask    = 0xe0
+	mpegLayerMask   = 0x06
+	oggSegmentCount = 26
+)
+
+// ErrUnknownFormat is returned for files that match none of the known magic bytes.
+var ErrUnknownFormat = errors.New("unknown audio format")
+
+// DetectFormat returns the MIME type of an audio file from its magic bytes,
+// whatever the file is named.
+func DetectFormat(r io.ReaderAt) (string, error) {
+	head := make([]byte, sniffSize)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("ID3")) && len(head) >= id3HeaderSize:
+		// FLAC files are sometimes prefixed with an ID3v2 tag as well.
+		if isFLACAfterID3(r, head) {
+			return MimeFLAC, nil
+		}
+		return MimeMP3, nil
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return MimeFLAC, nil
+	case bytes.HasPrefix(head, []byte("OggS")) && len(head) > oggSegmentCount:
+		if body := oggSegmentCount + 1 + int(head[oggSegmentCount]); body < len(head) &&
+			bytes.HasPrefix(head[body:], []byte("OpusHead")) {
+			return MimeOpus, nil
+		}
+		return MimeOgg, nil
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return MimeMP4, nil
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		return MimeWAV, nil
+	case len(head) >= 12 && string(head[:4]) == "FORM" && (string(head[8:12]) == "AIFF" || string(head[8:12]) == "AIFC"):
+		return MimeAIFF, nil
+	case len(head) >= 2 && head[0] == 0xff && head[1]&mpegSyncMask == mpegSyncMask && head[1]&mpegLayerMask != 0:
+		// A bare MPEG audio frame, layer bits of zero would be AAC in ADTS.
+		return MimeMP3, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// isFLACAfterID3 reports whether the ID3v2 tag at the start of the file is
+// followed by a FLAC stream.
+func isFLACAfterID3(r io.ReaderAt, head []byte) bool {
+	size := int64(head[6])<<21 | int64(head[7])<<14 | int64(head[8])<<7 | int64(head[9])
+	size += id3HeaderSize
+	if head[5]&id3FooterFlag != 0 {
+		size += id3HeaderSize
+	}
+	marker := make([]byte, 4)
+	if _, err := r.ReadAt(marker, size); err != nil {
+		return false
+	}
+	return string(marker) == "fLaC"
+}
@@ -0,0 +1,220 @@
+package tags
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+)
+
+const (
+	apeFooterSize  = 32
+	apeHeaderFlag  = 1 << 31
+	vorbisHeaders  = 3
+	opusHeaders    = 2
+	oggLacingLimit = 255
+)
+
+// HashAudio returns the hex SHA-256 of the audio payload of the file, what is
+// left of it without its tags, so that the copies of a file whose tags were
+// edited hash the same. The payload is the frames between the ID3v2 tag and
+// the ID3v1 or APE tags of MP3 files, the frames after the metadata blocks of
+// FLAC files, the audio packets of the first Ogg stream, the mdat atoms of
+// MP4 files and the sample chunk of WAV and AIFF files. Files of an unknown
+// format and files whose structure cannot be read are hashed whole.
+func (s *Service) HashAudio(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := info.Size()
+
+	hash := sha256.New()
+	if err = writePayload(f, size, hash); err != nil {
+		hash.Reset()
+		if _, err = io.Copy(hash, io.NewSectionReader(f, 0, size)); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writePayload writes the audio payload of the file of size bytes to w.
+func writePayload(r io.ReaderAt, size int64, w io.Writer) error {
+	mimeType, err := DetectFormat(r)
+	if err != nil {
+		return err
+	}
+	var start, end int64
+	switch mimeType {
+	case MimeMP3:
+		if _, _, start, err = readID3v2(r, size); err != nil {
+			return err
+		}
+		end = stripTrailingTags(r, start, size)
+	case MimeFLAC:
+		if start, err = flacFrames(r, size); err != nil {
+			return err
+		}
+		end = stripTrailingTags(r, start, size)
+	case MimeOgg, MimeOpus:
+		return writeOggPackets(r, size, w)
+	case MimeMP4:
+		return writeMP4Data(r, size, w)
+	case MimeWAV:
+		return writeChunk(r, size, binary.LittleEndian, "data", w)
+	case MimeAIFF:
+		return writeChunk(r, size, binary.BigEndian, "SSND", w)
+	}
+	_, err = io.Copy(w, io.NewSectionReader(r, start, end-start))
+	return err
+}
+
+// stripTrailingTags returns where the ID3v1 and APEv2 tags at the end of the
+// audio between start and end begin, end when there are none.
+func stripTrailingTags(r io.ReaderAt, start, end int64) int64 {
+	marker, footer := make([]byte, 3), make([]byte, apeFooterSize)
+	for {
+		if end-start >= id3v1Size {
+			if _, err := r.ReadAt(marker, end-id3v1Size); err == nil && string(marker) == "TAG" {
+				end -= id3v1Size
+				continue
+			}
+		}
+		if end-start >= apeFooterSize {
+			if _, err := r.ReadAt(footer, end-apeFooterSize); err == nil && string(footer[:8]) == "APETAGEX" {
+				// The size counts the items and the footer, not the header.
+				tagSize := int64(binary.LittleEndian.Uint32(footer[12:16]))
+				if binary.LittleEndian.Uint32(footer[20:24])&apeHeaderFlag != 0 {
+					tagSize += apeFooterSize
+				}
+				if tagSize >= apeFooterSize && tagSize <= end-start {
+					end -= tagSize
+					continue
+				}
+			}
+		}
+		return end
+	}
+}
+
+// flacFrames returns where the frames of the FLAC file start, after the
+// stream marker and the metadata blocks.
+func flacFrames(r io.ReaderAt, size int64) (int64, error) {
+	_, _, pos, err := readID3v2(r, size)
+	if err != nil {
+		return 0, err
+	}
+	marker := make([]byte, 4)
+	if _, err = r.ReadAt(marker, pos); err != nil {
+		return 0, err
+	}
+	if string(marker) != "fLaC" {
+		return 0, errors.New("missing FLAC stream marker")
+	}
+	pos += 4
+	header := make([]byte, flacBlockHeader)
+	for {
+		if _, err = r.ReadAt(header, pos); err != nil {
+			return 0, err
+		}
+		pos += flacBlockHeader + (int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3]))
+		if pos > size {
+			return 0, errors.New("FLAC metadata block runs past the end of the file")
+		}
+		if header[0]&flacLastBlock != 0 {
+			return pos, nil
+		}
+	}
+}
+
+// writeOggPackets writes the packets of the first logical stream after its
+// header packets, which hold the comments. Packets are written without the
+// framing of the pages, which is rebuilt when the comments change size.
+func writeOggPackets(r io.ReaderAt, size int64, w io.Writer) error {
+	br := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	header := make([]byte, oggPageHeaderSize)
+	var serial uint32
+	headers, packets := 0, 0
+	for first := true; ; first = false {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if errors.Is(err, io.EOF) && !first {
+				return nil
+			}
+			return err
+		}
+		if string(header[:4]) != "OggS" {
+			return errors.New("invalid ogg page")
+		}
+		lacing := make([]byte, header[26])
+		if _, err := io.ReadFull(br, lacing); err != nil {
+			return err
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if first {
+			serial = pageSerial
+		}
+		for _, l := range lacing {
+			segment := make([]byte, l)
+			if _, err := io.ReadFull(br, segment); err != nil {
+				return err
+			}
+			if pageSerial != serial {
+				continue
+			}
+			if headers == 0 {
+				headers = vorbisHeaders
+				if len(segment) >= 8 && string(segment[:8]) == "OpusHead" {
+					headers = opusHeaders
+				}
+			}
+			if packets >= headers {
+				if _, err := w.Write(segment); err != nil {
+					return err
+				}
+			}
+			if l < oggLacingLimit {
+				packets++
+			}
+		}
+	}
+}
+
+// writeMP4Data writes the bodies of the top level mdat atoms, which hold the
+// samples. The tags are in the moov atom.
+func writeMP4Data(r io.ReaderAt, size int64, w io.Writer) error {
+	for pos := int64(0); pos+mp4AtomHeaderSize <= size; {
+		name, atomSize, headerSize, err := readMP4Atom(r, pos, size)
+		if err != nil {
+			return err
+		}
+		if name == "mdat" {
+			if _, err = io.Copy(w, io.NewSectionReader(r, pos+headerSize, atomSize-headerSize)); err != nil {
+				return err
+			}
+		}
+		pos += atomSize
+	}
+	return nil
+}
+
+// writeChunk writes the bodies of the chunks with the ID of a RIFF or IFF
+// form.
+func writeChunk(r io.ReaderAt, size int64, order binary.ByteOrder, id string, w io.Writer) error {
+	return walkChunks(r, size, order, func(chunk string, body *io.SectionReader) error {
+		if chunk != id {
+			return nil
+		}
+		_, err := io.Copy(w, body)
+		return err
+	})
+}
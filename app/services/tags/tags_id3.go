@@ -0,0 +1,268 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"s3MediaStreamer/app/model"
+	"unicode/utf16"
+)
+
+const (
+	id3UnsyncFlag   = 0x80
+	id3ExtendedFlag = 0x40
+	id3FrameHeader  = 10
+	id3v1Size       = 128
+	id3v23          = 3
+	id3v24          = 4
+	id3UTF16        = 1
+	id3UTF8         = 3
+)
+
+// id3Managed are the frames WriteTags writes from the fields of the track.
+var id3Managed = map[string]bool{
+	"TIT2": true, "TPE1": true, "TALB": true, "TPE2": true, "TCOM": true, "TCON": true,
+	"TYER": true, "TDRC": true, "TRCK": true, "TPOS": true,
+}
+
+// id3Frame is a frame of an ID3v2.3 or ID3v2.4 tag with its data as stored.
+type id3Frame struct {
+	id    string
+	flags [2]byte
+	data  []byte
+}
+
+// writeMP3 copies the MP3 file of size bytes to out behind a new ID3v2 tag
+// and updates its ID3v1 tag when it has one. The new tag keeps the version
+// of the old one. Files without one get an ID3v2.4 tag, and so do those with
+// an ID3v2.2 tag, whose frames are dropped.
+func writeMP3(in io.ReaderAt, size int64, out io.Writer, track *model.Track) error {
+	version, frames, audioStart, err := readID3v2(in, size)
+	if err != nil {
+		return err
+	}
+	audioEnd := size
+	v1 := make([]byte, id3v1Size)
+	if size-id3v1Size < audioStart {
+		v1 = nil
+	} else if _, err = in.ReadAt(v1, size-id3v1Size); err != nil {
+		return err
+	} else if string(v1[:3]) != "TAG" {
+		v1 = nil
+	} else {
+		audioEnd -= id3v1Size
+	}
+
+	if _, err = out.Write(buildID3v2(version, frames, track)); err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, io.NewSectionReader(in, audioStart, audioEnd-audioStart)); err != nil {
+		return err
+	}
+	if v1 != nil {
+		_, err = out.Write(updateID3v1(v1, track))
+	}
+	return err
+}
+
+// readID3v2 returns the version of the ID3v2 tag at the start of the file,
+// the frames of it that WriteTags keeps and where the audio starts.
+func readID3v2(r io.ReaderAt, size int64) (byte, []id3Frame, int64, error) {
+	header := make([]byte, id3HeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil || string(header[:3]) != "ID3" {
+		return id3v24, nil, 0, nil
+	}
+	tagSize := int64(synchsafe(header[6:10]))
+	end := id3HeaderSize + tagSize
+	if header[5]&id3FooterFlag != 0 {
+		end += id3HeaderSize
+	}
+	if end > size {
+		return 0, nil, 0, errors.New("ID3v2 tag runs past the end of the file")
+	}
+	version := header[3]
+	if version != id3v23 && version != id3v24 {
+		return id3v24, nil, end, nil
+	}
+
+	body := make([]byte, tagSize)
+	if _, err := r.ReadAt(body, id3HeaderSize); err != nil {
+		return 0, nil, 0, err
+	}
+	if version == id3v23 && header[5]&id3UnsyncFlag != 0 {
+		body = bytes.ReplaceAll(body, []byte{0xff, 0x00}, []byte{0xff})
+	}
+	if header[5]&id3ExtendedFlag != 0 && len(body) >= 4 {
+		skip := synchsafe(body[:4])
+		if version == id3v23 {
+			skip = int(binary.BigEndian.Uint32(body[:4])) + 4
+		}
+		if skip > len(body) {
+			return 0, nil, 0, errors.New("invalid ID3v2 extended header")
+		}
+		body = body[skip:]
+	}
+
+	var frames []id3Frame
+	for len(body) >= id3FrameHeader && body[0] != 0 {
+		n := synchsafe(body[4:8])
+		if version == id3v23 {
+			n = int(binary.BigEndian.Uint32(body[4:8]))
+		}
+		if n < 0 || n > len(body)-id3FrameHeader {
+			break
+		}
+		frame := id3Frame{id: string(body[:4]), flags: [2]byte{body[8], body[9]}, data: body[id3FrameHeader : id3FrameHeader+n]}
+		body = body[id3FrameHeader+n:]
+		if !frame.replaced(version) {
+			frames = append(frames, frame)
+		}
+	}
+	return version, frames, end, nil
+}
+
+// replaced reports whether the frame is one WriteTags writes, or one that
+// asks to be dropped when the tag changes.
+func (f *id3Frame) replaced(version byte) bool {
+	alterPreservation := byte(0x40)
+	if version == id3v23 {
+		alterPreservation = 0x80
+	}
+	if id3Managed[f.id] || f.flags[0]&alterPreservation != 0 {
+		return true
+	}
+	// The comment and the lyrics are the frames without a description.
+	if (f.id == "COMM" || f.id == "USLT") && f.flags[1] == 0 {
+		if len(f.data) < 5 {
+			return true
+		}
+		rest := f.data[4:]
+		if f.data[0] != id3UTF16 && f.data[0] != 2 {
+			return rest[0] == 0
+		}
+		if bytes.HasPrefix(rest, []byte{0xff, 0xfe}) || bytes.HasPrefix(rest, []byte{0xfe, 0xff}) {
+			rest = rest[2:]
+		}
+		return len(rest) >= 2 && rest[0] == 0 && rest[1] == 0
+	}
+	return false
+}
+
+func buildID3v2(version byte, kept []id3Frame, track *model.Track) []byte {
+	yearID := "TDRC"
+	if version == id3v23 {
+		yearID = "TYER"
+	}
+	var frames []id3Frame
+	for _, text := range []struct{ id, value string }{
+		{"TIT2", track.Title}, {"TPE1", track.Artist}, {"TALB", track.Album}, {"TPE2", track.AlbumArtist},
+		{"TCOM", track.Composer}, {"TCON", track.Genre}, {yearID, yearOf(track)},
+		{"TRCK", numberOf(track.Track, track.TrackTotal)}, {"TPOS", numberOf(track.Disc, track.DiscTotal)},
+	} {
+		if text.value != "" {
+			frames = append(frames, id3Frame{id: text.id, data: id3Text(version, text.value)})
+		}
+	}
+	if track.Comment != "" {
+		frames = append(frames, id3Frame{id: "COMM", data: id3Described(version, track.Comment)})
+	}
+	if track.Lyrics != "" {
+		frames = append(frames, id3Frame{id: "USLT", data: id3Described(version, track.Lyrics)})
+	}
+	frames = append(frames, kept...)
+
+	var body bytes.Buffer
+	for _, frame := range frames {
+		body.WriteString(frame.id)
+		size := make([]byte, 4)
+		if version == id3v23 {
+			binary.BigEndian.PutUint32(size, uint32(len(frame.data)))
+		} else {
+			putSynchsafe(size, len(frame.data))
+		}
+		body.Write(size)
+		body.Write(frame.flags[:])
+		body.Write(frame.data)
+	}
+
+	tag := make([]byte, id3HeaderSize, id3HeaderSize+body.Len()+tagPadding)
+	copy(tag, "ID3")
+	tag[3] = version
+	putSynchsafe(tag[6:10], body.Len()+tagPadding)
+	tag = append(tag, body.Bytes()...)
+	return append(tag, make([]byte, tagPadding)...)
+}
+
+// id3Text is the data of a text frame, UTF-8 in ID3v2.4 and UTF-16 in
+// ID3v2.3, which has no UTF-8.
+func id3Text(version byte, value string) []byte {
+	if version == id3v23 {
+		return append([]byte{id3UTF16}, utf16WithBOM(value)...)
+	}
+	return append([]byte{id3UTF8}, value...)
+}
+
+// id3Described is the data of a comment or lyrics frame with an empty
+// description.
+func id3Described(version byte, value string) []byte {
+	if version == id3v23 {
+		data := append([]byte{id3UTF16}, "eng"...)
+		data = append(data, 0xff, 0xfe, 0, 0)
+		return append(data, utf16WithBOM(value)...)
+	}
+	data := append([]byte{id3UTF8}, "eng"...)
+	data = append(data, 0)
+	return append(data, value...)
+}
+
+func utf16WithBOM(value string) []byte {
+	units := utf16.Encode([]rune(value))
+	b := make([]byte, 2+2*len(units))
+	b[0], b[1] = 0xff, 0xfe
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(b[2+2*i:], unit)
+	}
+	return b
+}
+
+// updateID3v1 writes the fields of the track that fit into the ID3v1.1 tag,
+// the genre is kept.
+func updateID3v1(v1 []byte, track *model.Track) []byte {
+	tag := append([]byte(nil), v1...)
+	putLatin1(tag[3:33], track.Title)
+	putLatin1(tag[33:63], track.Artist)
+	putLatin1(tag[63:93], track.Album)
+	putLatin1(tag[93:97], yearOf(track))
+	putLatin1(tag[97:125], track.Comment)
+	tag[125], tag[126] = 0, 0
+	if track.Track > 0 && track.Track <= 0xff {
+		tag[126] = byte(track.Track)
+	}
+	return tag
+}
+
+// putLatin1 fills the field with the value, cut to its length and with the
+// characters outside ISO-8859-1 replaced.
+func putLatin1(field []byte, value string) {
+	clear(field)
+	i := 0
+	for _, r := range value {
+		if i == len(field) {
+			return
+		}
+		if r > 0xff {
+			r = '?'
+		}
+		field[i] = byte(r)
+		i++
+	}
+}
+
+func synchsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+func putSynchsafe(b []byte, n int) {
+	b[0], b[1], b[2], b[3] = byte(n>>21&0x7f), byte(n>>14&0x7f), byte(n>>7&0x7f), byte(n&0x7f)
+}
@@ -0,0 +1,120 @@
+package tags
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	mp4AtomHeaderSize = 8
+	// mp4SampleRateOffset is the offset of the 16.16 sample rate in the stsd
+	// atom body: version and entry count, the sample entry header and the
+	// audio sample entry fields before it.
+	mp4SampleRateOffset = 40
+)
+
+// mp4Containers are the atoms on the path from the file root to stsd.
+var mp4Containers = map[string]bool{
+	"moov": true,
+	"trak": true,
+	"mdia": true,
+	"minf": true,
+	"stbl": true,
+}
+
+// getMP4Info reads the duration from the mvhd atom and the sample rate from
+// the first audio sample entry of the stsd atom.
+func getMP4Info(r io.ReaderAt, size int64) (audioInfo, error) {
+	var info audioInfo
+	if err := walkMP4Atoms(r, 0, size, &info); err != nil {
+		return info, err
+	}
+	if info.duration == 0 {
+		return info, errors.New("mvhd atom not found")
+	}
+	info.bitrate = averageBitrate(size, info.duration)
+	return info, nil
+}
+
+func walkMP4Atoms(r io.ReaderAt, pos, end int64, info *audioInfo) error {
+	for pos+mp4AtomHeaderSize <= end {
+		name, size, headerSize, err := readMP4Atom(r, pos, end)
+		if err != nil {
+			return err
+		}
+
+		body := io.NewSectionReader(r, pos+headerSize, size-headerSize)
+		switch {
+		case mp4Containers[name]:
+			if err := walkMP4Atoms(r, pos+headerSize, pos+size, info); err != nil {
+				return err
+			}
+		case name == "mvhd":
+			if err := readMvhd(body, info); err != nil {
+				return err
+			}
+		case name == "stsd" && info.sampleRate == 0:
+			buf := make([]byte, mp4SampleRateOffset+4)
+			if _, err := io.ReadFull(body, buf); err == nil {
+				info.sampleRate = uint32(binary.BigEndian.Uint16(buf[mp4SampleRateOffset:]))
+			}
+		}
+		pos += size
+	}
+	return nil
+}
+
+// readMP4Atom reads the header of the atom at pos of a parent ending at end
+// and returns its name, its size and the size of the header.
+func readMP4Atom(r io.ReaderAt, pos, end int64) (string, int64, int64, error) {
+	var header [16]byte
+	if _, err := r.ReadAt(header[:mp4AtomHeaderSize], pos); err != nil {
+		return "", 0, 0, err
+	}
+	size := int64(binary.BigEndian.Uint32(header[:4]))
+	headerSize := int64(mp4AtomHeaderSize)
+	switch size {
+	case 0: // the atom extends to the end of its parent
+		size = end - pos
+	case 1: // a 64 bit size follows the name
+		if _, err := r.ReadAt(header[8:16], pos+mp4AtomHeaderSize); err != nil {
+			return "", 0, 0, err
+		}
+		size = int64(binary.BigEndian.Uint64(header[8:16]))
+		headerSize += 8
+	}
+	if size < headerSize || pos+size > end {
+		return "", 0, 0, errors.New("invalid mp4 atom size")
+	}
+	return string(header[4:8]), size, headerSize, nil
+}
+
+// readMvhd reads the movie timescale and duration, 32 bit in version 0 and
+// 64 bit in version 1.
+func readMvhd(r io.Reader, info *audioInfo) error {
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(r, buf[:4]); err != nil {
+		return err
+	}
+	var timescale, duration uint64
+	if buf[0] == 1 {
+		if _, err := io.ReadFull(r, buf[:28]); err != nil {
+			return err
+		}
+		timescale = uint64(binary.BigEndian.Uint32(buf[16:20]))
+		duration = binary.BigEndian.Uint64(buf[20:28])
+	} else {
+		if _, err := io.ReadFull(r, buf[:16]); err != nil {
+			return err
+		}
+		timescale = uint64(binary.BigEndian.Uint32(buf[8:12]))
+		duration = uint64(binary.BigEndian.Uint32(buf[12:16]))
+	}
+	if timescale == 0 {
+		return errors.New("invalid mvhd timescale")
+	}
+	info.duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+	return nil
+}
@@ -0,0 +1,83 @@
+package tags
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	oggPageHeaderSize = 27
+	opusGranuleRate   = 48000
+)
+
+// getOggInfo reads the sample rate from the identification header of the
+// first logical stream and the duration from the granule position of its last
+// page. Opus granules count 48 kHz samples including the encoder pre-skip.
+func getOggInfo(r io.ReaderAt, size int64) (audioInfo, error) {
+	var info audioInfo
+	br := bufio.NewReader(io.NewSectionReader(r, 0, size))
+	header := make([]byte, oggPageHeaderSize)
+	var (
+		err         error
+		serial      uint32
+		granuleRate uint64
+		preSkip     uint64
+		lastGranule uint64
+	)
+	for first := true; ; first = false {
+		if _, err = io.ReadFull(br, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return info, err
+		}
+		if string(header[:4]) != "OggS" {
+			return info, errors.New("invalid ogg page")
+		}
+		lacing := make([]byte, header[26])
+		if _, err = io.ReadFull(br, lacing); err != nil {
+			return info, err
+		}
+		bodySize := 0
+		for _, l := range lacing {
+			bodySize += int(l)
+		}
+		body := make([]byte, bodySize)
+		if _, err = io.ReadFull(br, body); err != nil {
+			return info, err
+		}
+
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if first {
+			serial = pageSerial
+			switch {
+			case bytes.HasPrefix(body, []byte("\x01vorbis")) && len(body) >= 16:
+				info.sampleRate = binary.LittleEndian.Uint32(body[12:16])
+				granuleRate = uint64(info.sampleRate)
+			case bytes.HasPrefix(body, []byte("OpusHead")) && len(body) >= 16:
+				// Opus always decodes at 48 kHz, the header only records the input rate.
+				preSkip = uint64(binary.LittleEndian.Uint16(body[10:12]))
+				info.sampleRate = opusGranuleRate
+				granuleRate = opusGranuleRate
+			default:
+				return info, errors.New("unsupported ogg codec")
+			}
+		}
+		// A granule of -1 marks a page on which no packet ends.
+		if granule := binary.LittleEndian.Uint64(header[6:14]); pageSerial == serial && granule != math.MaxUint64 {
+			lastGranule = granule
+		}
+	}
+
+	if granuleRate == 0 || lastGranule <= preSkip {
+		return info, errors.New("no audio pages in ogg stream")
+	}
+	info.duration = time.Duration(float64(lastGranule-preSkip) / float64(granuleRate) * float64(time.Second))
+	info.bitrate = averageBitrate(size, info.duration)
+	return info, nil
+}
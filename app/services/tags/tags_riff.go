@@ -0,0 +1,239 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhowden/tag"
+)
+
+const (
+	chunkHeaderSize = 8
+	// extendedBias is the exponent bias of the 80 bit IEEE 754 extended
+	// float holding the AIFF sample rate, plus the 63 bit mantissa.
+	extendedBias = 16383 + 63
+)
+
+// chunkMetadata holds the text chunks of a WAV or AIFF file, which
+// dhowden/tag does not read.
+type chunkMetadata struct {
+	title, artist, album, genre, comment string
+	year, track                          int
+	raw                                  map[string]interface{}
+}
+
+func newChunkMetadata() *chunkMetadata {
+	return &chunkMetadata{raw: make(map[string]interface{})}
+}
+
+func (m *chunkMetadata) Format() tag.Format          { return tag.UnknownFormat }
+func (m *chunkMetadata) FileType() tag.FileType      { return tag.UnknownFileType }
+func (m *chunkMetadata) Title() string               { return m.title }
+func (m *chunkMetadata) Album() string               { return m.album }
+func (m *chunkMetadata) Artist() string              { return m.artist }
+func (m *chunkMetadata) AlbumArtist() string         { return "" }
+func (m *chunkMetadata) Composer() string            { return "" }
+func (m *chunkMetadata) Year() int                   { return m.year }
+func (m *chunkMetadata) Genre() string               { return m.genre }
+func (m *chunkMetadata) Track() (int, int)           { return m.track, 0 }
+func (m *chunkMetadata) Disc() (int, int)            { return 0, 0 }
+func (m *chunkMetadata) Picture() *tag.Picture       { return nil }
+func (m *chunkMetadata) Lyrics() string              { return "" }
+func (m *chunkMetadata) Comment() string             { return m.comment }
+func (m *chunkMetadata) Raw() map[string]interface{} { return m.raw }
+
+// set stores a text chunk under its ID and fills the matching field.
+func (m *chunkMetadata) set(id string, value []byte) {
+	text := strings.TrimRight(string(value), "\x00 ")
+	m.raw[id] = text
+	switch id {
+	case "INAM", "NAME":
+		m.title = text
+	case "IART", "AUTH":
+		m.artist = text
+	case "IPRD":
+		m.album = text
+	case "IGNR":
+		m.genre = text
+	case "ICMT", "ANNO":
+		m.comment = text
+	case "ICRD":
+		if len(text) >= 4 {
+			m.year, _ = strconv.Atoi(text[:4])
+		}
+	case "ITRK":
+		m.track, _ = strconv.Atoi(text)
+	}
+}
+
+// riffChunk is called with the ID and body of every top level chunk.
+type riffChunk func(id string, body *io.SectionReader) error
+
+// walkChunks visits the chunks of a RIFF or IFF form after its 12 byte
+// header. Chunk bodies are padded to an even size.
+func walkChunks(r io.ReaderAt, size int64, order binary.ByteOrder, fn riffChunk) error {
+	header := make([]byte, chunkHeaderSize)
+	for pos := int64(12); pos+chunkHeaderSize <= size; {
+		if _, err := r.ReadAt(header, pos); err != nil {
+			return err
+		}
+		chunkSize := int64(order.Uint32(header[4:8]))
+		body := pos + chunkHeaderSize
+		if body+chunkSize > size {
+			// Writers that stream the file leave the size of the last chunk unset.
+			chunkSize = size - body
+		}
+		if err := fn(string(header[:4]), io.NewSectionReader(r, body, chunkSize)); err != nil {
+			return err
+		}
+		pos = body + chunkSize + chunkSize&1
+	}
+	return nil
+}
+
+// readWAV reads the format and the data size of a WAV file together with the
+// LIST INFO or embedded ID3 tags.
+func readWAV(r io.ReaderAt, size int64) (audioInfo, tag.Metadata, error) {
+	var info audioInfo
+	form := make([]byte, 12)
+	if _, err := r.ReadAt(form, 0); err != nil {
+		return info, nil, err
+	}
+	if string(form[:4]) != "RIFF" || string(form[8:12]) != "WAVE" {
+		return info, nil, errors.New("invalid wav header")
+	}
+
+	meta := newChunkMetadata()
+	var (
+		id3      tag.Metadata
+		byteRate uint32
+		dataSize int64
+	)
+	err := walkChunks(r, size, binary.LittleEndian, func(id string, body *io.SectionReader) error {
+		switch id {
+		case "fmt ":
+			buf := make([]byte, 12)
+			if _, err := io.ReadFull(body, buf); err != nil {
+				return err
+			}
+			info.sampleRate = binary.LittleEndian.Uint32(buf[4:8])
+			byteRate = binary.LittleEndian.Uint32(buf[8:12])
+		case "data":
+			dataSize = body.Size()
+		case "LIST":
+			return readInfoList(body, meta)
+		case "id3 ", "ID3 ":
+			id3, _ = tag.ReadID3v2Tags(body)
+		}
+		return nil
+	})
+	if err != nil {
+		return info, nil, err
+	}
+	if byteRate == 0 {
+		return info, nil, errors.New("fmt chunk not found")
+	}
+	info.duration = time.Duration(float64(dataSize) / float64(byteRate) * float64(time.Second))
+	info.bitrate = byteRate * 8 / millisecondsPerSecond
+	return info, preferID3(id3, meta), nil
+}
+
+// readInfoList reads the text subchunks of a LIST INFO chunk.
+func readInfoList(body *io.SectionReader, meta *chunkMetadata) error {
+	list, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(list, []byte("INFO")) {
+		return nil
+	}
+	for pos := 4; pos+chunkHeaderSize <= len(list); {
+		id := string(list[pos : pos+4])
+		end := pos + chunkHeaderSize + int(binary.LittleEndian.Uint32(list[pos+4:pos+8]))
+		if end > len(list) {
+			return nil
+		}
+		meta.set(id, list[pos+chunkHeaderSize:end])
+		pos = end + end&1
+	}
+	return nil
+}
+
+// readAIFF reads the COMM chunk of an AIFF or AIFF-C file together with the
+// embedded ID3 tags or the NAME, AUTH and ANNO text chunks.
+func readAIFF(r io.ReaderAt, size int64) (audioInfo, tag.Metadata, error) {
+	var info audioInfo
+	form := make([]byte, 12)
+	if _, err := r.ReadAt(form, 0); err != nil {
+		return info, nil, err
+	}
+	if string(form[:4]) != "FORM" || (string(form[8:12]) != "AIFF" && string(form[8:12]) != "AIFC") {
+		return info, nil, errors.New("invalid aiff header")
+	}
+
+	meta := newChunkMetadata()
+	var (
+		id3        tag.Metadata
+		channels   uint16
+		frames     uint32
+		sampleSize uint16
+		sampleRate float64
+	)
+	err := walkChunks(r, size, binary.BigEndian, func(id string, body *io.SectionReader) error {
+		switch id {
+		case "COMM":
+			buf := make([]byte, 18)
+			if _, err := io.ReadFull(body, buf); err != nil {
+				return err
+			}
+			channels = binary.BigEndian.Uint16(buf[0:2])
+			frames = binary.BigEndian.Uint32(buf[2:6])
+			sampleSize = binary.BigEndian.Uint16(buf[6:8])
+			sampleRate = extendedToFloat(buf[8:18])
+		case "NAME", "AUTH", "ANNO":
+			text, err := io.ReadAll(body)
+			if err != nil {
+				return err
+			}
+			meta.set(id, text)
+		case "ID3 ", "id3 ":
+			id3, _ = tag.ReadID3v2Tags(body)
+		}
+		return nil
+	})
+	if err != nil {
+		return info, nil, err
+	}
+	if sampleRate < 1 {
+		return info, nil, errors.New("COMM chunk not found")
+	}
+	info.sampleRate = uint32(sampleRate)
+	info.duration = time.Duration(float64(frames) / sampleRate * float64(time.Second))
+	info.bitrate = uint32(sampleRate * float64(channels) * float64(sampleSize) / millisecondsPerSecond)
+	return info, preferID3(id3, meta), nil
+}
+
+// extendedToFloat converts a big endian 80 bit extended float.
+func extendedToFloat(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]) & 0x7fff)
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	return math.Ldexp(float64(mantissa), exponent-extendedBias)
+}
+
+// preferID3 returns the embedded ID3 tag when it names the track, the text
+// chunks otherwise.
+func preferID3(id3 tag.Metadata, meta *chunkMetadata) tag.Metadata {
+	if id3 != nil && id3.Title() != "" && id3.Artist() != "" {
+		return id3
+	}
+	return meta
+}
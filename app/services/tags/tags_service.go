@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"s3MediaStreamer/app/model"
 	"time"
 
@@ -17,9 +16,16 @@ import (
 
 const millisecondsPerSecond = 1000
 
+// audioInfo is the stream information read from the audio container.
+type audioInfo struct {
+	sampleRate uint32
+	duration   time.Duration
+	bitrate    uint32 // kbit/s
+}
+
 type Repository interface {
 	ReadTags(filename string) (*model.Track, error)
-	getSampleRate(fileName string) (uint32, time.Duration, uint32)
+	getSampleRate(fileName string) (uint32, time.Duration, uint32, error)
 	getMp3Info(f io.Reader) (uint32, time.Duration, uint32, error)
 }
 
@@ -42,30 +48,33 @@ func (s *Service) ReadTags(filename string) (*model.Track, error) {
 	}
 	defer f.Close()
 
-	tags, err := tag.ReadFrom(f)
+	info, err := f.Stat()
 	if err != nil {
 		return nil, err
 	}
 
-	fileExtension := filepath.Ext(filename)
-
 	var (
-		duration   time.Duration
-		sampleRate uint32
-		bitrate    uint32
+		tags  tag.Metadata
+		audio audioInfo
 	)
-
-	switch fileExtension {
-	case ".flac":
-		sampleRate, duration, bitrate = s.getSampleRate(filename)
-	case ".mp3":
-		sampleRate, duration, bitrate, err = s.getMp3Info(f)
+	mimeType, err := DetectFormat(f)
+	if err != nil {
+		return nil, err
+	}
+	switch mimeType {
+	case MimeWAV:
+		audio, tags, err = readWAV(f, info.Size())
+	case MimeAIFF:
+		audio, tags, err = readAIFF(f, info.Size())
+	default:
+		tags, err = tag.ReadFrom(f)
 		if err != nil {
 			return nil, err
 		}
-
-	default:
-		return nil, fmt.Errorf("unsupported audio_handler format")
+		audio, err = s.getAudioInfo(f, info.Size(), filename, mimeType)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert the year to a time.Time value
@@ -95,22 +104,92 @@ func (s *Service) ReadTags(filename string) (*model.Track, error) {
 		DiscTotal:   discTotal,
 		Track:       trackNumber,
 		TrackTotal:  trackTotal,
-		Duration:    duration,
-		SampleRate:  sampleRate,
-		Bitrate:     bitrate,
+		Duration:    audio.duration,
+		SampleRate:  audio.sampleRate,
+		Bitrate:     audio.bitrate,
+		MimeType:    mimeType,
 	}, nil
 }
 
-func (s *Service) getSampleRate(fileName string) (uint32, time.Duration, uint32) {
+// ReadPicture returns the cover art embedded in the file, nil when there is none.
+func (s *Service) ReadPicture(filename string) (*tag.Picture, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	mimeType, err := DetectFormat(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags tag.Metadata
+	switch mimeType {
+	case MimeWAV:
+		_, tags, err = readWAV(f, info.Size())
+	case MimeAIFF:
+		_, tags, err = readAIFF(f, info.Size())
+	default:
+		tags, err = tag.ReadFrom(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tags.Picture(), nil
+}
+
+// getAudioInfo reads the stream information of the formats whose tags are
+// read by dhowden/tag. The MP3 decoder continues where the tag reader stopped.
+func (s *Service) getAudioInfo(f *os.File, size int64, filename, mimeType string) (audioInfo, error) {
+	var audio audioInfo
+	var err error
+	switch mimeType {
+	case MimeFLAC:
+		audio.sampleRate, audio.duration, audio.bitrate, err = s.getSampleRate(filename)
+	case MimeMP3:
+		audio.sampleRate, audio.duration, audio.bitrate, err = s.getMp3Info(f)
+	case MimeOgg, MimeOpus:
+		audio, err = getOggInfo(f, size)
+	case MimeMP4:
+		audio, err = getMP4Info(f, size)
+	default:
+		err = fmt.Errorf("unsupported audio_handler format")
+	}
+	return audio, err
+}
+
+// averageBitrate returns the bitrate in kbit/s of size bytes played over duration.
+func averageBitrate(size int64, duration time.Duration) uint32 {
+	if duration <= 0 {
+		return 0
+	}
+	return uint32(float64(size) * 8 / duration.Seconds() / millisecondsPerSecond)
+}
+
+// getSampleRate reads the STREAMINFO of the FLAC file, an error when it is
+// malformed.
+func (s *Service) getSampleRate(fileName string) (uint32, time.Duration, uint32, error) {
 	f, err := flac.ParseFile(fileName)
 	if err != nil {
-		panic(err)
+		return 0, 0, 0, fmt.Errorf("invalid FLAC stream: %w", err)
 	}
+	defer f.Close()
 	data := f.Info
+	if data.SampleRate == 0 {
+		return 0, 0, 0, errors.New("invalid FLAC stream: sample rate 0")
+	}
 
-	duration := time.Duration(float64(f.Info.NSamples) / float64(f.Info.SampleRate) * float64(time.Second))
-	bitrate := uint32(float64(data.NSamples) * float64(data.BitsPerSample) / duration.Seconds() / millisecondsPerSecond)
-	return data.SampleRate, duration, bitrate
+	duration := time.Duration(float64(data.NSamples) / float64(data.SampleRate) * float64(time.Second))
+	var bitrate uint32
+	if duration > 0 {
+		bitrate = uint32(float64(data.NSamples) * float64(data.BitsPerSample) / duration.Seconds() / millisecondsPerSecond)
+	}
+	return data.SampleRate, duration, bitrate, nil
 }
 
 func (s *Service) getMp3Info(f io.Reader) (uint32, time.Duration, uint32, error) {
@@ -0,0 +1,232 @@
+package tags_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"s3MediaStreamer/app/services/tags"
+	"testing"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTagsFormats(t *testing.T) {
+	tests := []struct {
+		file       string
+		title      string
+		artist     string
+		duration   time.Duration
+		sampleRate uint32
+		bitrate    uint32
+		mimeType   string
+	}{
+		// Compressed fixtures carry no real audio, their bitrate is the file size over the duration.
+		{file: "sample.ogg", title: "Vorbis Song", artist: "Ogg Artist", duration: 2 * time.Second, sampleRate: 44100, bitrate: 2, mimeType: tags.MimeOgg},
+		{file: "sample.opus", title: "Opus Song", artist: "Ogg Artist", duration: 3 * time.Second, sampleRate: 48000, bitrate: 1, mimeType: tags.MimeOpus},
+		{file: "sample.m4a", title: "AAC Song", artist: "MP4 Artist", duration: 1500 * time.Millisecond, sampleRate: 44100, bitrate: 4, mimeType: tags.MimeMP4},
+		{file: "sample.wav", title: "Wave Song", artist: "RIFF Artist", duration: 500 * time.Millisecond, sampleRate: 8000, bitrate: 128, mimeType: tags.MimeWAV},
+		{file: "sample.aiff", title: "Aiff Song", artist: "IFF Artist", duration: 500 * time.Millisecond, sampleRate: 8000, bitrate: 64, mimeType: tags.MimeAIFF},
+	}
+
+	s := tags.NewTagsService()
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			track, err := s.ReadTags(filepath.Join("testdata", tt.file))
+			require.NoError(t, err)
+			assert.Equal(t, tt.title, track.Title)
+			assert.Equal(t, tt.artist, track.Artist)
+			assert.Equal(t, tt.duration, track.Duration)
+			assert.Equal(t, tt.sampleRate, track.SampleRate)
+			assert.Equal(t, tt.bitrate, track.Bitrate)
+			assert.Equal(t, tt.mimeType, track.MimeType)
+		})
+	}
+}
+
+func TestReadTagsWithoutExtension(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "sample.opus"))
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "track.MP3")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	track, err := tags.NewTagsService().ReadTags(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Opus Song", track.Title)
+	assert.Equal(t, tags.MimeOpus, track.MimeType)
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{name: "id3", head: []byte("ID3\x04\x00\x00\x00\x00\x00\x00\xff\xfb\x90\x00"), want: tags.MimeMP3},
+		{name: "id3 before flac", head: []byte("ID3\x04\x00\x00\x00\x00\x00\x02\x00\x00fLaC"), want: tags.MimeFLAC},
+		{name: "mpeg frame", head: []byte{0xff, 0xfb, 0x90, 0x00}, want: tags.MimeMP3},
+		{name: "flac", head: []byte("fLaC\x00\x00\x00\x22"), want: tags.MimeFLAC},
+		{name: "wav", head: []byte("RIFF\x24\x00\x00\x00WAVEfmt "), want: tags.MimeWAV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tags.DetectFormat(bytes.NewReader(tt.head))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := tags.DetectFormat(bytes.NewReader([]byte{0xff, 0xf1, 0x50, 0x80})) // AAC in ADTS
+	assert.ErrorIs(t, err, tags.ErrUnknownFormat)
+}
+
+// id3v23Frame is an ID3v2.3 frame with its plain size.
+func id3v23Frame(id string, data []byte) []byte {
+	frame := append([]byte(id), byte(len(data)>>24), byte(len(data)>>16), byte(len(data)>>8), byte(len(data)), 0, 0)
+	return append(frame, data...)
+}
+
+func TestWriteTagsMP3(t *testing.T) {
+	picture := []byte("\x89PNG\r\n\x1a\nnot really")
+	var frames []byte
+	frames = append(frames, id3v23Frame("TIT2", []byte("\x00Old Title"))...)
+	frames = append(frames, id3v23Frame("TPE1", []byte("\x00Old Artist"))...)
+	frames = append(frames, id3v23Frame("APIC", append([]byte("\x00image/png\x00\x03\x00"), picture...))...)
+	size := len(frames)
+	file := append([]byte("ID3\x03\x00\x00"), byte(size>>21&0x7f), byte(size>>14&0x7f), byte(size>>7&0x7f), byte(size&0x7f))
+	file = append(file, frames...)
+	// Ten silent MPEG-1 layer III frames at 128 kbit/s and 44.1 kHz.
+	for i := 0; i < 10; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xff, 0xfb, 0x90, 0x64})
+		file = append(file, frame...)
+	}
+	src := filepath.Join(t.TempDir(), "old.mp3")
+	require.NoError(t, os.WriteFile(src, file, 0o600))
+
+	s := tags.NewTagsService()
+	before, err := s.ReadTags(src)
+	require.NoError(t, err)
+	edited := *before
+	edited.Title, edited.Artist, edited.Year, edited.Track, edited.TrackTotal = "Новое название", "New Artist", 1999, 3, 12
+	edited.Comment = "Remastered"
+	dst := filepath.Join(t.TempDir(), "new.mp3")
+	require.NoError(t, s.WriteTags(src, dst, &edited))
+
+	after, err := s.ReadTags(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "Новое название", after.Title)
+	assert.Equal(t, "New Artist", after.Artist)
+	assert.Equal(t, 1999, after.Year)
+	assert.Equal(t, 3, after.Track)
+	assert.Equal(t, 12, after.TrackTotal)
+	assert.Equal(t, "Remastered", after.Comment)
+	assert.Equal(t, before.Duration, after.Duration)
+	kept, err := s.ReadPicture(dst)
+	require.NoError(t, err)
+	require.NotNil(t, kept)
+	assert.Equal(t, picture, kept.Data)
+}
+
+func TestWriteTagsFLAC(t *testing.T) {
+	// STREAMINFO of one second of 16 bit stereo at 44.1 kHz.
+	info := make([]byte, 34)
+	copy(info, []byte{0x10, 0x00, 0x10, 0x00})
+	packed := uint64(44100)<<44 | uint64(1)<<41 | uint64(15)<<36 | 44100
+	for i := 0; i < 8; i++ {
+		info[10+i] = byte(packed >> (56 - 8*i))
+	}
+	comments := []string{"TITLE=Old Title", "ARTIST=Old Artist", "REPLAYGAIN_TRACK_GAIN=-6.00 dB"}
+	comment := []byte{6, 0, 0, 0, 'v', 'e', 'n', 'd', 'o', 'r', byte(len(comments)), 0, 0, 0}
+	for _, c := range comments {
+		comment = append(comment, byte(len(c)), 0, 0, 0)
+		comment = append(comment, c...)
+	}
+	file := append([]byte("fLaC\x00\x00\x00\x22"), info...)
+	file = append(file, 0x84, 0, 0, byte(len(comment)))
+	file = append(file, comment...)
+	src := filepath.Join(t.TempDir(), "old.flac")
+	require.NoError(t, os.WriteFile(src, file, 0o600))
+
+	s := tags.NewTagsService()
+	before, err := s.ReadTags(src)
+	require.NoError(t, err)
+	edited := *before
+	edited.Title, edited.Album, edited.Disc, edited.DiscTotal = "New Title", "New Album", 2, 2
+	dst := filepath.Join(t.TempDir(), "new.flac")
+	require.NoError(t, s.WriteTags(src, dst, &edited))
+
+	after, err := s.ReadTags(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "New Title", after.Title)
+	assert.Equal(t, "Old Artist", after.Artist)
+	assert.Equal(t, "New Album", after.Album)
+	assert.Equal(t, 2, after.Disc)
+	assert.Equal(t, 2, after.DiscTotal)
+	assert.Equal(t, time.Second, after.Duration)
+
+	f, err := os.Open(dst)
+	require.NoError(t, err)
+	defer f.Close()
+	metadata, err := tag.ReadFrom(f)
+	require.NoError(t, err)
+	assert.Equal(t, "-6.00 dB", metadata.Raw()["replaygain_track_gain"])
+	assert.Equal(t, "vendor", metadata.Raw()["vendor"])
+
+	assert.ErrorIs(t, s.WriteTags(filepath.Join("testdata", "sample.ogg"), filepath.Join(t.TempDir(), "x.ogg"), &edited),
+		tags.ErrWriteUnsupported)
+}
+
+func TestHashAudio(t *testing.T) {
+	var file []byte
+	// Ten MPEG-1 layer III frames at 128 kbit/s and 44.1 kHz behind an
+	// ID3v2.3 tag and in front of an ID3v1 tag.
+	frames := append(id3v23Frame("TIT2", []byte("\x00Old Title")), id3v23Frame("TPE1", []byte("\x00Old Artist"))...)
+	file = append([]byte("ID3\x03\x00\x00\x00\x00\x00"), byte(len(frames)))
+	file = append(file, frames...)
+	for i := 0; i < 10; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xff, 0xfb, 0x90, 0x64, byte(i)})
+		file = append(file, frame...)
+	}
+	v1 := make([]byte, 128)
+	copy(v1, "TAGOld Title")
+	file = append(file, v1...)
+	src := filepath.Join(t.TempDir(), "old.mp3")
+	require.NoError(t, os.WriteFile(src, file, 0o600))
+
+	s := tags.NewTagsService()
+	hash, err := s.HashAudio(src)
+	require.NoError(t, err)
+	whole := sha256.Sum256(file)
+	assert.NotEqual(t, hex.EncodeToString(whole[:]), hash)
+
+	track, err := s.ReadTags(src)
+	require.NoError(t, err)
+	track.Title, track.Artist = "A Much Longer New Title", "New Artist"
+	retagged := filepath.Join(t.TempDir(), "new.mp3")
+	require.NoError(t, s.WriteTags(src, retagged, track))
+	retaggedHash, err := s.HashAudio(retagged)
+	require.NoError(t, err)
+	assert.Equal(t, hash, retaggedHash)
+
+	file[len(file)-129] ^= 0xff
+	changed := filepath.Join(t.TempDir(), "changed.mp3")
+	require.NoError(t, os.WriteFile(changed, file, 0o600))
+	changedHash, err := s.HashAudio(changed)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changedHash)
+
+	seen := map[string]string{}
+	for _, fixture := range []string{"sample.ogg", "sample.opus", "sample.m4a", "sample.wav", "sample.aiff"} {
+		fixtureHash, errHash := s.HashAudio(filepath.Join("testdata", fixture))
+		require.NoError(t, errHash)
+		assert.Len(t, fixtureHash, 64)
+		assert.NotContains(t, seen, fixtureHash, "%s hashes like %s", fixture, seen[fixtureHash])
+		seen[fixtureHash] = fixture
+	}
+}
@@ -0,0 +1,83 @@
+package tags
+
+import (
+	"errors"
+	"io"
+	"os"
+	"s3MediaStreamer/app/model"
+	"strconv"
+)
+
+// tagPadding is the room left in a written tag, so that the next edit of a
+// player can rewrite the tag in place.
+const tagPadding = 1024
+
+// ErrWriteUnsupported is returned by WriteTags for formats it cannot write.
+var ErrWriteUnsupported = errors.New("tags can only be written to MP3 and FLAC files")
+
+// CanWriteTags reports whether WriteTags writes the tags of files of the MIME type.
+func CanWriteTags(mimeType string) bool {
+	return mimeType == MimeMP3 || mimeType == MimeFLAC
+}
+
+// WriteTags copies the audio file src to dst with the tags of the track, as
+// ID3v2 for MP3 and as Vorbis comments for FLAC. The tags the track has no
+// field for, such as the cover art, are kept. The audio is copied unchanged.
+func (s *Service) WriteTags(src, dst string, track *model.Track) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	mimeType, err := DetectFormat(in)
+	if err != nil {
+		return err
+	}
+	if !CanWriteTags(mimeType) {
+		return ErrWriteUnsupported
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if mimeType == MimeMP3 {
+		err = writeMP3(in, info.Size(), out, track)
+	} else {
+		err = writeFLAC(in, info.Size(), out, track)
+	}
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// numberOf formats a track or disc number with its total, empty when both
+// are unknown.
+func numberOf(n, total int) string {
+	switch {
+	case total > 0:
+		return strconv.Itoa(n) + "/" + strconv.Itoa(total)
+	case n > 0:
+		return strconv.Itoa(n)
+	}
+	return ""
+}
+
+func yearOf(track *model.Track) string {
+	if track.Year <= 0 {
+		return ""
+	}
+	return strconv.Itoa(track.Year)
+}
+
+// readFull reads exactly n bytes.
+func readFull(r io.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
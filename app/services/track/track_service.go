@@ -8,9 +8,13 @@ import (
 	"net/http"
 	"s3MediaStreamer/app/internal/logs"
 	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/analysis"
+	"s3MediaStreamer/app/services/keyset"
+	"s3MediaStreamer/app/services/trackquery"
 	"s3MediaStreamer/app/services/tree"
 	"strconv"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/emirpasic/gods/maps/treemap"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
@@ -18,12 +22,19 @@ import (
 
 type Repository interface {
 	CreateTracks(ctx context.Context, list []model.Track) error
-	GetTracks(ctx context.Context, offset, limit int, sortBy, sortOrder, filter, startT, endT string) ([]model.Track, int, error)
+	GetTracks(ctx context.Context, offset, limit int, orderBy []string, where, after squirrel.Sqlizer, filter, startT, endT string,
+		analysis model.TrackAnalysisFilter, count model.TrackCount) ([]model.Track, int, error)
 	GetTracksByColumns(ctx context.Context, code, columns string) (*model.Track, error)
 	CleanTracks(ctx context.Context) error
 	DeleteTracksAll(ctx context.Context) error
 	UpdateTracks(ctx context.Context, track *model.Track) error
 	GetAllTracks(ctx context.Context) ([]model.Track, error)
+	GetTracksByAlbum(ctx context.Context, album, albumArtist string) ([]model.Track, error)
+	GetTracksWithoutAnalysis(ctx context.Context, limit int) ([]model.Track, error)
+	GetTracksWithoutContentHash(ctx context.Context, maxAttempts, limit int) ([]model.Track, error)
+	AddTrackContentHashAttempt(ctx context.Context, trackID string) error
+	GetTrackIDByIdentity(ctx context.Context, track *model.Track) (string, error)
+	SetTrackContentHash(ctx context.Context, trackIDs []string, hash string) error
 	AddTrackToPlaylist(ctx context.Context, playlistID, referenceType, referenceID, parentPath string) error
 	RemoveTrackFromPlaylist(ctx context.Context, playlistID, trackID string) error
 	GetPlaylistItems(ctx context.Context, playlistID string) ([]model.PlaylistStruct, error)
@@ -47,8 +58,9 @@ func (s *Service) CreateTracks(ctx context.Context, list []model.Track) error {
 	return s.trackRepository.CreateTracks(ctx, list)
 }
 
-func (s *Service) GetTracks(ctx context.Context, offset, limit int, sortBy, sortOrder, filter, startT, endT string) ([]model.Track, int, error) {
-	return s.trackRepository.GetTracks(ctx, offset, limit, sortBy, sortOrder, filter, startT, endT)
+func (s *Service) GetTracks(ctx context.Context, offset, limit int, orderBy []string, where, after squirrel.Sqlizer, filter, startT, endT string,
+	analysis model.TrackAnalysisFilter, count model.TrackCount) ([]model.Track, int, error) {
+	return s.trackRepository.GetTracks(ctx, offset, limit, orderBy, where, after, filter, startT, endT, analysis, count)
 }
 
 func (s *Service) GetTracksByColumns(ctx context.Context, code, columns string) (*model.Track, error) {
@@ -71,6 +83,29 @@ func (s *Service) GetAllTracks(ctx context.Context) ([]model.Track, error) {
 	return s.trackRepository.GetAllTracks(ctx)
 }
 
+func (s *Service) GetTracksByAlbum(ctx context.Context, album, albumArtist string) ([]model.Track, error) {
+	return s.trackRepository.GetTracksByAlbum(ctx, album, albumArtist)
+}
+
+func (s *Service) GetTracksWithoutAnalysis(ctx context.Context, limit int) ([]model.Track, error) {
+	return s.trackRepository.GetTracksWithoutAnalysis(ctx, limit)
+}
+
+func (s *Service) GetTracksWithoutContentHash(ctx context.Context, maxAttempts, limit int) ([]model.Track, error) {
+	return s.trackRepository.GetTracksWithoutContentHash(ctx, maxAttempts, limit)
+}
+func (s *Service) AddTrackContentHashAttempt(ctx context.Context, trackID string) error {
+	return s.trackRepository.AddTrackContentHashAttempt(ctx, trackID)
+}
+
+func (s *Service) GetTrackIDByIdentity(ctx context.Context, track *model.Track) (string, error) {
+	return s.trackRepository.GetTrackIDByIdentity(ctx, track)
+}
+
+func (s *Service) SetTrackContentHash(ctx context.Context, trackIDs []string, hash string) error {
+	return s.trackRepository.SetTrackContentHash(ctx, trackIDs, hash)
+}
+
 func (s *Service) AddTrackToPlaylist(ctx context.Context, playlistID, referenceType, referenceID, parentPath string) error {
 	return s.trackRepository.AddTrackToPlaylist(ctx, playlistID, referenceType, referenceID, parentPath)
 }
@@ -120,29 +155,68 @@ func (s *Service) InsertPositionInDB(ctx context.Context, tree *treemap.Map) err
 	return s.trackRepository.InsertPositionInDB(ctx, tree)
 }
 
-func (s *Service) GetTracksService(c *gin.Context, page, pageSize, filter string, sortBy, sortOrder string) ([]model.Track, int, int, int, *model.RestError) {
-	// Convert page, pageSize, and totalPages to integers
+// trackList is a parsed track list request.
+type trackList struct {
+	size     int
+	keys     []keyset.Key
+	where    squirrel.Sqlizer
+	analysis model.TrackAnalysisFilter
+	count    model.TrackCount
+}
+
+// parseTrackList checks the page size, the sort, the filter query, the
+// tempo and key filter and the count of a track list request. An empty count
+// is defaultCount.
+func parseTrackList(pageSize, query, sortBy, sortOrder, bpmMin, bpmMax, key, count string,
+	defaultCount model.TrackCount) (*trackList, *model.RestError) {
+	size, err := strconv.Atoi(pageSize)
+	if err != nil || size < 1 {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "invalid page or page_size parameters"}
+	}
+	list := &trackList{size: size, count: defaultCount}
+	if list.keys, err = trackquery.ParseSort(sortBy, sortOrder); err != nil {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: err.Error()}
+	}
+	if list.where, err = trackquery.Parse(query); err != nil {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: err.Error()}
+	}
+	var errAnalysis *model.RestError
+	if list.analysis, errAnalysis = parseAnalysisFilter(bpmMin, bpmMax, key); errAnalysis != nil {
+		return nil, errAnalysis
+	}
+	switch model.TrackCount(count) {
+	case "":
+	case model.CountExact, model.CountEstimate, model.CountNone:
+		list.count = model.TrackCount(count)
+	default:
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "count must be exact, estimate or none"}
+	}
+	return list, nil
+}
+
+// GetTracksService returns a page of the track list, the total count, the
+// page number and the number of pages. The count is exact unless count asks
+// for an estimate.
+func (s *Service) GetTracksService(c *gin.Context, page, pageSize, filter, query string, sortBy, sortOrder string,
+	bpmMin, bpmMax, key, count string) ([]model.Track, int, int, int, *model.RestError) {
 	pageInt, errPage := strconv.Atoi(page)
-	pageSizeInt, errPageSize := strconv.Atoi(pageSize)
-	if errPage != nil || errPageSize != nil {
-		s.logger.Error("Invalid page or page_size parameters")
+	if errPage != nil || pageInt < 1 {
 		return nil, 0, 0, 0, &model.RestError{Code: http.StatusBadRequest, Err: "invalid page or page_size parameters"}
 	}
-	var validSortOrders = map[string]string{
-		"asc":  "ASC",
-		"desc": "DESC",
+	list, errList := parseTrackList(pageSize, query, sortBy, sortOrder, bpmMin, bpmMax, key, count, model.CountExact)
+	if errList != nil {
+		return nil, 0, 0, 0, errList
 	}
-
-	// Check if provided sort_order parameter is valid
-	if _, validSortOrderExists := validSortOrders[sortOrder]; !validSortOrderExists {
-		sortOrder = "desc" // Default to descending order
+	if list.count == model.CountNone {
+		return nil, 0, 0, 0, &model.RestError{Code: http.StatusBadRequest, Err: "count=none needs cursor pagination"}
 	}
 
 	// Calculate the offset based on the pagination parameters
-	offset := (pageInt - 1) * pageSizeInt
+	offset := (pageInt - 1) * list.size
 
 	// Retrieve paginated tracks from the storage
-	tracks, countTotal, err := s.GetTracks(c.Request.Context(), offset, pageSizeInt, sortBy, sortOrder, filter, "", "")
+	tracks, countTotal, err := s.GetTracks(c.Request.Context(), offset, list.size, keyset.OrderBy(list.keys),
+		list.where, nil, filter, "", "", list.analysis, list.count)
 	if err != nil {
 		s.logger.Error(err.Error())
 
@@ -150,13 +224,75 @@ func (s *Service) GetTracksService(c *gin.Context, page, pageSize, filter string
 	}
 
 	// Calculate total pages based on total count and page size
-	totalPages := int(math.Ceil(float64(countTotal) / float64(pageSizeInt)))
+	totalPages := int(math.Ceil(float64(countTotal) / float64(list.size)))
 
 	res, _ := json.Marshal(tracks)
 	s.logger.Debugf("Tracks response: %s", res)
 	return tracks, countTotal, pageInt, totalPages, nil
 }
 
+// GetTracksCursorService returns the tracks of the track list that come
+// after the cursor, the cursor of the next page, empty on the last one, and
+// the total count, -1 for count=none. An empty cursor starts at the first
+// track. The count is estimated unless count says otherwise.
+func (s *Service) GetTracksCursorService(c *gin.Context, cursor, pageSize, filter, query string, sortBy, sortOrder string,
+	bpmMin, bpmMax, key, count string) ([]model.Track, string, int, *model.RestError) {
+	list, errList := parseTrackList(pageSize, query, sortBy, sortOrder, bpmMin, bpmMax, key, count, model.CountEstimate)
+	if errList != nil {
+		return nil, "", 0, errList
+	}
+	var after squirrel.Sqlizer
+	if cursor != "" {
+		var err error
+		if after, err = trackquery.After(list.keys, cursor); err != nil {
+			return nil, "", 0, &model.RestError{Code: http.StatusBadRequest, Err: "invalid cursor, it belongs to another sort or is broken"}
+		}
+	}
+
+	// One track more than the page tells whether there is a next page.
+	tracks, countTotal, err := s.GetTracks(c.Request.Context(), 0, list.size+1, keyset.OrderBy(list.keys),
+		list.where, after, filter, "", "", list.analysis, list.count)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, "", 0, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	if len(tracks) <= list.size {
+		return tracks, "", countTotal, nil
+	}
+	tracks = tracks[:list.size]
+	next, err := trackquery.Cursor(list.keys, &tracks[len(tracks)-1])
+	if err != nil {
+		s.logger.Error(err.Error())
+		return nil, "", 0, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	return tracks, next, countTotal, nil
+}
+
+// parseAnalysisFilter checks the tempo range and the key of the track list query.
+func parseAnalysisFilter(bpmMin, bpmMax, key string) (model.TrackAnalysisFilter, *model.RestError) {
+	var result model.TrackAnalysisFilter
+	for _, bound := range []struct {
+		value string
+		dst   *float64
+	}{{bpmMin, &result.BPMMin}, {bpmMax, &result.BPMMax}} {
+		if bound.value == "" {
+			continue
+		}
+		bpm, err := strconv.ParseFloat(bound.value, 64)
+		if err != nil || bpm <= 0 {
+			return result, &model.RestError{Code: http.StatusBadRequest, Err: "bpm_min and bpm_max must be positive numbers"}
+		}
+		*bound.dst = bpm
+	}
+	if key != "" {
+		var ok bool
+		if result.Key, ok = analysis.ParseKey(key); !ok {
+			return result, &model.RestError{Code: http.StatusBadRequest, Err: "key must be a pitch with major or minor, e.g. 'A minor' or 'F#'"}
+		}
+	}
+	return result, nil
+}
+
 func (s *Service) GetTrackByID(c *gin.Context, id string) (*model.Track, *model.RestError) {
 	result, err := s.GetTracksByColumns(c.Request.Context(), id, "code")
 	if err != nil {
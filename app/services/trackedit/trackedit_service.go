@@ -0,0 +1,185 @@
+package trackedit
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/diskcache"
+	"s3MediaStreamer/app/services/s3"
+	"s3MediaStreamer/app/services/tags"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+)
+
+// maxYear is the last year a four digit year tag holds.
+const maxYear = 9999
+
+type Repository interface {
+	GetTracksByColumns(ctx context.Context, code, columns string) (*model.Track, error)
+	UpdateTracks(ctx context.Context, track *model.Track) error
+}
+
+type Service struct {
+	repository Repository
+	s3         s3.Service
+	tags       tags.Service
+	cache      *diskcache.Service
+	logger     *logs.Logger
+}
+
+func NewTrackEditService(repository Repository, s3 s3.Service, tags tags.Service, cache *diskcache.Service, logger *logs.Logger) *Service {
+	return &Service{
+		repository: repository,
+		s3:         s3,
+		tags:       tags,
+		cache:      cache,
+		logger:     logger,
+	}
+}
+
+// EditService applies the patch to the track and returns the edited track.
+// With write_tags the tags are written into a copy of the audio file first,
+// which is uploaded as a new version of its object once the track is saved.
+// The track is saved before the upload, so the ingestion of the new version
+// finds it and leaves it alone, and the version is recorded after it.
+func (s *Service) EditService(c *gin.Context, id string, patch *model.TrackPatch) (*model.Track, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "TrackEditService")
+	defer span.End()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "track not found"}
+	}
+	track, err := s.repository.GetTracksByColumns(ctx, id, "_id")
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "no records found") {
+			return nil, &model.RestError{Code: http.StatusNotFound, Err: "track not found"}
+		}
+		s.logger.Errorf("Error getting track %s: %v", id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	if restErr := apply(track, patch); restErr != nil {
+		return nil, restErr
+	}
+
+	var object, edited string
+	if patch.WriteTags {
+		var restErr *model.RestError
+		if object, edited, restErr = s.writeTags(ctx, track); restErr != nil {
+			return nil, restErr
+		}
+		defer os.Remove(edited)
+	}
+
+	// Once saved, the upload and its record must not be cut short by the client
+	ctx = context.WithoutCancel(ctx)
+	track.UpdatedAt = time.Now()
+	if err = s.repository.UpdateTracks(ctx, track); err != nil {
+		s.logger.Errorf("Error updating track %s: %v", id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	if !patch.WriteTags {
+		return track, nil
+	}
+
+	info, err := s.s3.UploadFilesS3(ctx, &model.UploadS3{ObjectName: object, FilePath: edited, ContentType: track.MimeType})
+	if err != nil {
+		s.logger.Errorf("Error uploading the edited file of track %s: %v", id, err)
+		return nil, &model.RestError{Code: http.StatusBadGateway, Err: "the track was saved but its file could not be uploaded"}
+	}
+	uploaded := minio.ObjectInfo{Key: object, VersionID: info.VersionID, ETag: info.ETag, Size: info.Size, LastModified: info.LastModified}
+	if err = s.s3.AddS3Version(ctx, s3.NewTrackVersion(track.ID, &uploaded)); err != nil {
+		s.logger.Errorf("Error recording version %s of track %s: %v", info.VersionID, id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	s.logger.Infof("Tags of track %s written to version %s of %s", id, info.VersionID, object)
+	return track, nil
+}
+
+// writeTags writes the tags of the track into a temporary copy of its
+// current audio object and returns the key of the object and the copy.
+func (s *Service) writeTags(ctx context.Context, track *model.Track) (string, string, *model.RestError) {
+	if track.CueStart != nil {
+		return "", "", &model.RestError{Code: http.StatusBadRequest,
+			Err: "the track is cut out of a single-file album by a CUE sheet, edit the sheet instead"}
+	}
+	if !tags.CanWriteTags(track.MimeType) {
+		return "", "", &model.RestError{Code: http.StatusBadRequest, Err: tags.ErrWriteUnsupported.Error()}
+	}
+	id := track.ID.String()
+	version, err := s.s3.GetS3VersionByTrackID(ctx, id)
+	if err != nil {
+		s.logger.Errorf("Error getting the S3 version of track %s: %v", id, err)
+		return "", "", &model.RestError{Code: http.StatusNotFound, Err: "the track has no audio object"}
+	}
+	object, err := s.s3.FindObjectFromVersion(ctx, version)
+	if err != nil {
+		s.logger.Errorf("Error finding version %s of track %s: %v", version, id, err)
+		return "", "", &model.RestError{Code: http.StatusNotFound, Err: "the track has no audio object"}
+	}
+
+	file, err := os.CreateTemp("", "tags-*"+filepath.Ext(object.Key))
+	if err != nil {
+		s.logger.Errorf("Error creating a temporary file: %v", err)
+		return "", "", &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	edited := file.Name()
+	file.Close()
+	err = s.cache.Fetch(ctx, &object, func(path string) error {
+		return s.tags.WriteTags(path, edited, track)
+	})
+	if err != nil {
+		os.Remove(edited)
+		s.logger.Errorf("Error writing the tags of track %s: %v", id, err)
+		return "", "", &model.RestError{Code: http.StatusUnprocessableEntity, Err: "the tags could not be written: " + err.Error()}
+	}
+	return object.Key, edited, nil
+}
+
+// apply sets the fields of the patch on the track and checks them.
+func apply(track *model.Track, patch *model.TrackPatch) *model.RestError {
+	for _, field := range []struct {
+		value *string
+		to    *string
+	}{
+		{patch.Title, &track.Title}, {patch.Artist, &track.Artist}, {patch.Album, &track.Album},
+		{patch.AlbumArtist, &track.AlbumArtist}, {patch.Composer, &track.Composer}, {patch.Genre, &track.Genre},
+		{patch.Comment, &track.Comment}, {patch.Lyrics, &track.Lyrics},
+	} {
+		if field.value != nil {
+			*field.to = strings.TrimSpace(*field.value)
+		}
+	}
+	for _, field := range []struct {
+		value *int
+		to    *int
+	}{
+		{patch.Year, &track.Year}, {patch.Disc, &track.Disc}, {patch.DiscTotal, &track.DiscTotal},
+		{patch.Track, &track.Track}, {patch.TrackTotal, &track.TrackTotal},
+	} {
+		if field.value == nil {
+			continue
+		}
+		if *field.value < 0 {
+			return &model.RestError{Code: http.StatusBadRequest, Err: "numbers must not be negative"}
+		}
+		*field.to = *field.value
+	}
+
+	switch {
+	case patch.Title != nil && track.Title == "":
+		return &model.RestError{Code: http.StatusBadRequest, Err: "title must not be empty"}
+	case patch.Artist != nil && track.Artist == "":
+		return &model.RestError{Code: http.StatusBadRequest, Err: "artist must not be empty"}
+	case track.Year > maxYear:
+		return &model.RestError{Code: http.StatusBadRequest, Err: "year must be at most 9999"}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package trackquery
+
+import (
+	"encoding/json"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/keyset"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// sortValues read the sortable columns of a track, nil for NULL.
+var sortValues = map[string]func(track *model.Track) interface{}{
+	"title":        func(track *model.Track) interface{} { return track.Title },
+	"artist":       func(track *model.Track) interface{} { return track.Artist },
+	"album":        func(track *model.Track) interface{} { return track.Album },
+	"album_artist": func(track *model.Track) interface{} { return track.AlbumArtist },
+	"genre":        func(track *model.Track) interface{} { return track.Genre },
+	"musical_key":  func(track *model.Track) interface{} { return track.Key },
+	"year":         func(track *model.Track) interface{} { return track.Year },
+	"bpm":          func(track *model.Track) interface{} { return nullable(track.BPM) },
+	"loudness":     func(track *model.Track) interface{} { return nullable(track.Loudness) },
+	"duration":     func(track *model.Track) interface{} { return track.Duration },
+	"created_at":   func(track *model.Track) interface{} { return track.CreatedAt },
+	"updated_at":   func(track *model.Track) interface{} { return track.UpdatedAt },
+}
+
+func nullable(value *float64) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// Cursor returns the cursor of the track list sorted by the keys that
+// continues after the track.
+func Cursor(keys []keyset.Key, track *model.Track) (string, error) {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = sortValues[key.Column](track)
+	}
+	return keyset.Encode(keys, values, track.ID)
+}
+
+// After returns the condition of the tracks that come after the cursor of the
+// track list sorted by the keys, keyset.ErrInvalidCursor when the cursor is
+// broken or belongs to a different sort.
+func After(keys []keyset.Key, cursor string) (squirrel.Sqlizer, error) {
+	raw, id, err := keyset.Decode(cursor, keys)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if values[i], err = decodeValue(columnKind(key.Column), raw[i]); err != nil {
+			return nil, keyset.ErrInvalidCursor
+		}
+	}
+	return keyset.After(keys, values, id), nil
+}
+
+func decodeValue(k kind, raw json.RawMessage) (interface{}, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	switch k {
+	case kindInt:
+		return decode[int](raw)
+	case kindFloat:
+		return decode[float64](raw)
+	case kindDuration:
+		return decode[time.Duration](raw)
+	case kindDate:
+		return decode[time.Time](raw)
+	}
+	return decode[string](raw)
+}
+
+func decode[T any](raw json.RawMessage) (interface{}, error) {
+	var value T
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
+
+func columnKind(column string) kind {
+	for _, f := range fields {
+		if f.column == column {
+			return f.kind
+		}
+	}
+	return kindText
+}
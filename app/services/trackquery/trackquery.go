@@ -0,0 +1,442 @@
+// Package trackquery parses the query language of the track list into SQL
+// conditions and the sort keys into keyset keys, and makes the cursors of
+// the track list.
+//
+// A query is a list of terms that all have to match:
+//
+//	genre:rock year:1990..1999 duration:>300 artist:"Pink Floyd" -live
+//
+// A field term compares a column, text fields contain the value unless it is
+// prefixed with '=' for an exact match. Numbers, durations and dates take
+// '>', '>=', '<', '<=' and ranges 'from..to' with either end left open.
+// Durations are seconds, 'mm:ss' or Go durations such as '4m30s', dates are
+// days (2006-01-02) or RFC 3339 times. A bare word or quoted phrase is looked
+// for in the title, artist, album, album artist and composer. A leading '-'
+// negates a term.
+package trackquery
+
+import (
+	"errors"
+	"fmt"
+	"s3MediaStreamer/app/services/analysis"
+	"s3MediaStreamer/app/services/keyset"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Masterminds/squirrel"
+)
+
+type kind int
+
+const (
+	kindText kind = iota
+	kindKey
+	kindInt
+	kindFloat
+	kindDuration
+	kindDate
+)
+
+// field is a column the query language can filter on. Sortable columns
+// have an index.
+type field struct {
+	column   string
+	kind     kind
+	sortable bool
+}
+
+var fields = map[string]field{
+	"title":        {"title", kindText, true},
+	"artist":       {"artist", kindText, true},
+	"album":        {"album", kindText, true},
+	"album_artist": {"album_artist", kindText, true},
+	"composer":     {"composer", kindText, false},
+	"genre":        {"genre", kindText, true},
+	"comment":      {"comment", kindText, false},
+	"mime_type":    {"mime_type", kindText, false},
+	"key":          {"musical_key", kindKey, true},
+	"musical_key":  {"musical_key", kindKey, true},
+	"year":         {"year", kindInt, true},
+	"disc":         {"disc", kindInt, false},
+	"track":        {"track", kindInt, false},
+	"bitrate":      {"bitrate", kindInt, false},
+	"sample_rate":  {"sample_rate", kindInt, false},
+	"bpm":          {"bpm", kindFloat, true},
+	"loudness":     {"loudness", kindFloat, true},
+	"duration":     {"duration", kindDuration, true},
+	"created_at":   {"created_at", kindDate, true},
+	"updated_at":   {"updated_at", kindDate, true},
+}
+
+// notNull are the sortable columns that are never NULL.
+var notNull = map[string]bool{"created_at": true, "musical_key": true}
+
+// textColumns are the columns a bare word is looked for in.
+var textColumns = []string{"title", "artist", "album", "album_artist", "composer"}
+
+const (
+	day      = 24 * time.Hour
+	instant  = time.Microsecond // the precision of timestamptz
+	dateOnly = "2006-01-02"
+)
+
+// Error is a query that does not parse, Pos is the 1-based character it
+// went wrong at.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Msg)
+}
+
+// parser walks the query by byte offset, errors report character positions.
+type parser struct {
+	query string
+	pos   int
+}
+
+func (p *parser) errorf(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: utf8.RuneCountInString(p.query[:pos]) + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Parse parses the query into the condition every term of it makes, nil for
+// an empty query. The error is an *Error.
+func Parse(query string) (squirrel.Sqlizer, error) {
+	p := &parser{query: query}
+	var conditions squirrel.And
+	for {
+		p.skipSpace()
+		if p.pos == len(p.query) {
+			break
+		}
+		condition, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+	return conditions, nil
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.query) {
+		r, size := utf8.DecodeRuneInString(p.query[p.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.pos += size
+	}
+}
+
+func (p *parser) term() (squirrel.Sqlizer, error) {
+	start := p.pos
+	negate := false
+	if p.query[p.pos] == '-' {
+		negate = true
+		p.pos++
+		if p.pos == len(p.query) || p.atSpace() {
+			return nil, p.errorf(start, "'-' must be followed by a term")
+		}
+	}
+
+	var condition squirrel.Sqlizer
+	var err error
+	if name, ok := p.fieldName(); ok {
+		condition, err = p.fieldTerm(name)
+	} else {
+		var word string
+		if word, err = p.value(); err == nil {
+			condition = containsAny(textColumns, word)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if negate {
+		return not{condition}, nil
+	}
+	return condition, nil
+}
+
+func (p *parser) atSpace() bool {
+	r, _ := utf8.DecodeRuneInString(p.query[p.pos:])
+	return unicode.IsSpace(r)
+}
+
+// fieldName consumes 'name:' when the term starts with one.
+func (p *parser) fieldName() (string, bool) {
+	end := p.pos
+	for end < len(p.query) && (p.query[end] == '_' || p.query[end] >= 'a' && p.query[end] <= 'z' ||
+		p.query[end] >= 'A' && p.query[end] <= 'Z') {
+		end++
+	}
+	if end == p.pos || end == len(p.query) || p.query[end] != ':' {
+		return "", false
+	}
+	name := p.query[p.pos:end]
+	p.pos = end + 1
+	return name, true
+}
+
+// value consumes a quoted string or the characters up to the next space.
+func (p *parser) value() (string, error) {
+	start := p.pos
+	if p.pos < len(p.query) && p.query[p.pos] == '"' {
+		end := strings.IndexByte(p.query[p.pos+1:], '"')
+		if end < 0 {
+			return "", p.errorf(start, "unterminated quote")
+		}
+		p.pos += end + 2
+		return p.query[start+1 : start+1+end], nil
+	}
+	for p.pos < len(p.query) && !p.atSpace() {
+		_, size := utf8.DecodeRuneInString(p.query[p.pos:])
+		p.pos += size
+	}
+	return p.query[start:p.pos], nil
+}
+
+// operator consumes a comparison operator, "" when there is none.
+func (p *parser) operator() string {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(p.query[p.pos:], op) {
+			p.pos += len(op)
+			return op
+		}
+	}
+	return ""
+}
+
+func (p *parser) fieldTerm(name string) (squirrel.Sqlizer, error) {
+	nameStart := p.pos - len(name) - 1
+	f, ok := fields[strings.ToLower(name)]
+	if !ok {
+		return nil, p.errorf(nameStart, "unknown field %q, expected one of %s", name, strings.Join(fieldNames(), ", "))
+	}
+	opStart := p.pos
+	op := p.operator()
+	valueStart := p.pos
+	quoted := p.pos < len(p.query) && p.query[p.pos] == '"'
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if value == "" && !quoted {
+		return nil, p.errorf(valueStart, "missing value of %s", name)
+	}
+
+	switch f.kind {
+	case kindText:
+		switch op {
+		case "":
+			return contains(f.column, value), nil
+		case "=":
+			return squirrel.Eq{f.column: value}, nil
+		}
+		return nil, p.errorf(opStart, "%s is text and only takes '=' for an exact match", name)
+	case kindKey:
+		key, ok := analysis.ParseKey(value)
+		if !ok || op != "" && op != "=" {
+			return nil, p.errorf(valueStart, "%s must be a pitch with major or minor, e.g. 'A minor' or 'F#'", name)
+		}
+		return squirrel.Eq{f.column: key}, nil
+	}
+
+	parse := p.parser(f.kind, valueStart)
+	if lo, hi, isRange := strings.Cut(value, ".."); isRange && !quoted && op == "" {
+		var conditions squirrel.And
+		if lo == "" && hi == "" {
+			return nil, p.errorf(valueStart, "range of %s needs at least one end", name)
+		}
+		if lo != "" {
+			from, _, err := parse(lo, 0)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, squirrel.GtOrEq{f.column: from})
+		}
+		if hi != "" {
+			from, until, err := parse(hi, len(lo)+2)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, upTo(f.column, from, until))
+		}
+		return conditions, nil
+	}
+
+	from, until, err := parse(value, 0)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case ">":
+		if until != nil {
+			return squirrel.GtOrEq{f.column: until}, nil
+		}
+		return squirrel.Gt{f.column: from}, nil
+	case ">=":
+		return squirrel.GtOrEq{f.column: from}, nil
+	case "<":
+		return squirrel.Lt{f.column: from}, nil
+	case "<=":
+		return upTo(f.column, from, until), nil
+	}
+	if until != nil {
+		return squirrel.And{squirrel.GtOrEq{f.column: from}, squirrel.Lt{f.column: until}}, nil
+	}
+	return squirrel.Eq{f.column: from}, nil
+}
+
+// valueParser parses a value that starts offset bytes after the value of the
+// term into the value to compare with and, for dates, the end of the span it
+// covers.
+type valueParser func(value string, offset int) (from, until interface{}, err error)
+
+func (p *parser) parser(k kind, valueStart int) valueParser {
+	fail := func(offset int, format string, args ...interface{}) (interface{}, interface{}, error) {
+		return nil, nil, p.errorf(valueStart+offset, format, args...)
+	}
+	return func(value string, offset int) (interface{}, interface{}, error) {
+		switch k {
+		case kindInt:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fail(offset, "%q is not a whole number", value)
+			}
+			return n, nil, nil
+		case kindFloat:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fail(offset, "%q is not a number", value)
+			}
+			return n, nil, nil
+		case kindDuration:
+			d, err := parseDuration(value)
+			if err != nil {
+				return fail(offset, "%q is not a duration, use seconds, mm:ss or e.g. 4m30s", value)
+			}
+			return d, nil, nil
+		case kindDate:
+			if t, err := time.Parse(dateOnly, value); err == nil {
+				return t, t.Add(day), nil
+			}
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fail(offset, "%q is not a date, use 2006-01-02 or RFC 3339", value)
+			}
+			return t, t.Add(instant), nil
+		}
+		return fail(offset, "unsupported value %q", value)
+	}
+}
+
+// upTo is the condition of an inclusive upper bound, a date covers its span.
+func upTo(column string, from, until interface{}) squirrel.Sqlizer {
+	if until != nil {
+		return squirrel.Lt{column: until}
+	}
+	return squirrel.LtOrEq{column: from}
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	if parts := strings.Split(value, ":"); len(parts) == 2 || len(parts) == 3 {
+		var d time.Duration
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 || i > 0 && n >= 60 {
+				return 0, errors.New("invalid clock duration")
+			}
+			d = d*60 + time.Duration(n)
+		}
+		return d * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, errors.New("invalid duration")
+	}
+	return d, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func contains(column, value string) squirrel.Sqlizer {
+	return squirrel.ILike{column: "%" + likeEscaper.Replace(value) + "%"}
+}
+
+func containsAny(columns []string, value string) squirrel.Sqlizer {
+	var conditions squirrel.Or
+	for _, column := range columns {
+		conditions = append(conditions, contains(column, value))
+	}
+	return conditions
+}
+
+// not negates a condition, rows it is NULL for match too.
+type not struct {
+	condition squirrel.Sqlizer
+}
+
+func (n not) ToSql() (string, []interface{}, error) {
+	sql, args, err := n.condition.ToSql()
+	return "(" + sql + ") IS NOT TRUE", args, err
+}
+
+func fieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSort turns a comma separated list of sort keys into the keys of the
+// track list. A key prefixed with '-' sorts descending and one prefixed with
+// '+' ascending, the others in sortOrder, 'asc' or 'desc'. Unanalysed tracks
+// go last either way.
+func ParseSort(sortBy, sortOrder string) ([]keyset.Key, error) {
+	desc := !strings.EqualFold(sortOrder, "asc")
+	var keys []keyset.Key
+	for _, name := range strings.Split(sortBy, ",") {
+		name = strings.TrimSpace(name)
+		keyDesc := desc
+		switch {
+		case strings.HasPrefix(name, "-"):
+			name, keyDesc = name[1:], true
+		case strings.HasPrefix(name, "+"):
+			name, keyDesc = name[1:], false
+		}
+		if name == "" {
+			continue
+		}
+		f, ok := fields[strings.ToLower(name)]
+		if !ok || !f.sortable {
+			return nil, fmt.Errorf("invalid sort key %q, expected one of %s", name, strings.Join(sortKeys(), ", "))
+		}
+		keys = append(keys, keyset.Key{Column: f.column, Desc: keyDesc, NotNull: notNull[f.column]})
+	}
+	return keys, nil
+}
+
+func sortKeys() []string {
+	var names []string
+	for _, name := range fieldNames() {
+		if fields[name].sortable {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,139 @@
+package trackquery_test
+
+import (
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/keyset"
+	"s3MediaStreamer/app/services/trackquery"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	day := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query string
+		sql   string
+		args  []interface{}
+	}{
+		{"genre:rock", "genre ILIKE ?", []interface{}{"%rock%"}},
+		{`artist:="Pink Floyd"`, "artist = ?", []interface{}{"Pink Floyd"}},
+		{"year:1990..1999", "(year >= ? AND year <= ?)", []interface{}{1990, 1999}},
+		{"year:..1999", "(year <= ?)", []interface{}{1999}},
+		{"duration:>300", "duration > ?", []interface{}{300 * time.Second}},
+		{"duration:<=4:30", "duration <= ?", []interface{}{270 * time.Second}},
+		{"bpm:>=120.5", "bpm >= ?", []interface{}{120.5}},
+		{"key:Am", "musical_key = ?", []interface{}{"A minor"}},
+		{"created_at:2024-01-31", "(created_at >= ? AND created_at < ?)", []interface{}{day, day.Add(24 * time.Hour)}},
+		{"created_at:>2024-01-31", "created_at >= ?", []interface{}{day.Add(24 * time.Hour)}},
+		{"-live", "((title ILIKE ? OR artist ILIKE ? OR album ILIKE ? OR album_artist ILIKE ? OR composer ILIKE ?)) IS NOT TRUE",
+			[]interface{}{"%live%", "%live%", "%live%", "%live%", "%live%"}},
+		{"title:100%", "title ILIKE ?", []interface{}{`%100\%%`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			condition, err := trackquery.Parse(tt.query)
+			require.NoError(t, err)
+			sql, args, err := condition.ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, "("+tt.sql+")", sql)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestParseCombinesTerms(t *testing.T) {
+	condition, err := trackquery.Parse(`genre:rock  year:1990..1999 duration:>300 artist:"Pink Floyd" -live`)
+	require.NoError(t, err)
+	sql, args, err := condition.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "(genre ILIKE ? AND (year >= ? AND year <= ?) AND duration > ? AND artist ILIKE ? AND "+
+		"((title ILIKE ? OR artist ILIKE ? OR album ILIKE ? OR album_artist ILIKE ? OR composer ILIKE ?)) IS NOT TRUE)", sql)
+	assert.Len(t, args, 10)
+
+	condition, err = trackquery.Parse("   ")
+	require.NoError(t, err)
+	assert.Nil(t, condition)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{"genre:rock gnere:pop", 12},
+		{"year:199x", 6},
+		{"year:1990..19x9", 12},
+		{"duration:>5min", 11},
+		{`artist:"Pink Floyd`, 8},
+		{"title:>abc", 7},
+		{"genre:", 7},
+		{"rock -", 6},
+		{"ключ genre:рок created_at:yesterday", 27},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := trackquery.Parse(tt.query)
+			var queryErr *trackquery.Error
+			require.ErrorAs(t, err, &queryErr)
+			assert.Equal(t, tt.pos, queryErr.Pos, queryErr.Error())
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	keys, err := trackquery.ParseSort("year, -title,+key", "desc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"year DESC NULLS LAST", "title DESC NULLS LAST", "musical_key ASC", "_id ASC"},
+		keyset.OrderBy(keys))
+
+	keys, err = trackquery.ParseSort("created_at", "asc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created_at ASC", "_id ASC"}, keyset.OrderBy(keys))
+
+	_, err = trackquery.ParseSort("lyrics", "asc")
+	assert.Error(t, err)
+	_, err = trackquery.ParseSort("composer", "asc")
+	assert.Error(t, err)
+}
+
+func TestCursor(t *testing.T) {
+	keys, err := trackquery.ParseSort("-bpm,title", "asc")
+	require.NoError(t, err)
+	bpm := 128.0
+	track := model.Track{ID: uuid.MustParse("8c6a0f3e-2b1d-4e5f-9a7b-6c5d4e3f2a1b"), BPM: &bpm, Title: "Intro"}
+
+	cursor, err := trackquery.Cursor(keys, &track)
+	require.NoError(t, err)
+	after, err := trackquery.After(keys, cursor)
+	require.NoError(t, err)
+	sql, args, err := after.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "((bpm < ? OR bpm IS NULL) OR (bpm = ? AND (title > ? OR title IS NULL)) OR "+
+		"(bpm = ? AND title = ? AND _id > ?))", sql)
+	assert.Equal(t, []interface{}{128.0, 128.0, "Intro", 128.0, "Intro", track.ID}, args)
+
+	// The first key of a NOT NULL column bounds the index scan.
+	newest, err := trackquery.ParseSort("created_at", "desc")
+	require.NoError(t, err)
+	track.CreatedAt = time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	cursor, err = trackquery.Cursor(newest, &track)
+	require.NoError(t, err)
+	after, err = trackquery.After(newest, cursor)
+	require.NoError(t, err)
+	sql, args, err = after.ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "(created_at <= ? AND (created_at < ? OR (created_at = ? AND _id < ?)))", sql)
+	assert.Equal(t, []interface{}{track.CreatedAt, track.CreatedAt, track.CreatedAt, track.ID}, args)
+
+	// A cursor of another sort does not continue this one.
+	other, err := trackquery.ParseSort("title", "asc")
+	require.NoError(t, err)
+	_, err = trackquery.After(other, cursor)
+	assert.ErrorIs(t, err, keyset.ErrInvalidCursor)
+	_, err = trackquery.After(keys, "not a cursor")
+	assert.ErrorIs(t, err, keyset.ErrInvalidCursor)
+}
@@ -0,0 +1,101 @@
+package trackversion
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/s3"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+)
+
+type Repository interface {
+	GetTracksByColumns(ctx context.Context, code, columns string) (*model.Track, error)
+}
+
+// Restorer makes a recorded version of a track its current version again and
+// reads the track from it, the S3 event consumer.
+type Restorer interface {
+	RestoreVersion(ctx context.Context, trackID, version string) error
+}
+
+type Service struct {
+	repository Repository
+	s3         s3.Service
+	restorer   Restorer
+	logger     *logs.Logger
+}
+
+func NewTrackVersionService(repository Repository, s3 s3.Service, restorer Restorer, logger *logs.Logger) *Service {
+	return &Service{
+		repository: repository,
+		s3:         s3,
+		restorer:   restorer,
+		logger:     logger,
+	}
+}
+
+// VersionsService returns the S3 object versions the track has had, newest
+// first.
+func (s *Service) VersionsService(c *gin.Context, id string) ([]model.TrackVersion, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "TrackVersionsService")
+	defer span.End()
+
+	if restErr := s.checkTrack(ctx, id); restErr != nil {
+		return nil, restErr
+	}
+	versions, err := s.s3.GetS3Versions(ctx, id)
+	if err != nil {
+		s.logger.Errorf("Error getting the versions of track %s: %v", id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	if versions == nil {
+		versions = []model.TrackVersion{}
+	}
+	return versions, nil
+}
+
+// RestoreService makes a recorded version of the track its current version
+// again and returns the versions of the track. The tags, format, duration
+// and hash of the track are read again from the version.
+func (s *Service) RestoreService(c *gin.Context, id, version string) ([]model.TrackVersion, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "TrackVersionRestoreService")
+	defer span.End()
+
+	if restErr := s.checkTrack(ctx, id); restErr != nil {
+		return nil, restErr
+	}
+	// Version IDs are opaque, a version is valid when it is recorded for the track.
+	if version == "" {
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "version not found"}
+	}
+	if err := s.restorer.RestoreVersion(ctx, id, version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &model.RestError{Code: http.StatusNotFound, Err: "version not found"}
+		}
+		s.logger.Errorf("Error restoring version %s of track %s: %v", version, id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	s.logger.Infof("Version %s of track %s restored", version, id)
+	return s.VersionsService(c, id)
+}
+
+func (s *Service) checkTrack(ctx context.Context, id string) *model.RestError {
+	if _, err := uuid.Parse(id); err != nil {
+		return &model.RestError{Code: http.StatusNotFound, Err: "track not found"}
+	}
+	if _, err := s.repository.GetTracksByColumns(ctx, id, "_id"); err != nil {
+		if strings.HasPrefix(err.Error(), "no records found") {
+			return &model.RestError{Code: http.StatusNotFound, Err: "track not found"}
+		}
+		s.logger.Errorf("Error getting track %s: %v", id, err)
+		return &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	return nil
+}
@@ -0,0 +1,465 @@
+package tus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/s3"
+	"s3MediaStreamer/app/services/upload"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// Version is the version of the tus protocol the server speaks.
+	Version = "1.0.0"
+	// Extensions are the tus extensions the server supports.
+	Extensions = "creation,termination,expiration"
+	// ContentType is the content type of the body of a PATCH request.
+	ContentType = "application/offset+octet-stream"
+
+	// partSize is the size of the S3 parts, the smallest S3 takes for every
+	// part but the last. Less is kept pending in Postgres.
+	partSize = 5 << 20
+
+	// leaseDuration is how long the lease of an upload holds, the request
+	// that holds it renews it every leaseRenewal.
+	leaseDuration = time.Minute
+	leaseRenewal  = leaseDuration / 3
+
+	bytesPerMB        = 1 << 20
+	defaultMaxSize    = 4096
+	defaultExpiration = 24
+)
+
+type Repository interface {
+	CreateTusUpload(ctx context.Context, upload *model.TusUpload) error
+	GetTusUpload(ctx context.Context, id string) (*model.TusUpload, error)
+	GetExpiredTusUploads(ctx context.Context, now time.Time) ([]model.TusUpload, error)
+	LeaseTusUpload(ctx context.Context, id string, token uuid.UUID, until time.Time) (bool, error)
+	ReleaseTusUpload(ctx context.Context, id string, token uuid.UUID) error
+	UpdateTusUpload(ctx context.Context, upload *model.TusUpload) error
+	DeleteTusUpload(ctx context.Context, id string) error
+}
+
+// Ingester creates the tracks of an uploaded object version, the S3 event
+// consumer.
+type Ingester interface {
+	IngestObject(ctx context.Context, object *minio.ObjectInfo) ([]string, error)
+}
+
+type Service struct {
+	repository Repository
+	s3         s3.Service
+	ingester   Ingester
+	logger     *logs.Logger
+	maxSize    int64
+	expiration time.Duration
+}
+
+func NewTusService(cfg *model.Config, repository Repository, s3 s3.Service, ingester Ingester, logger *logs.Logger) *Service {
+	s := &Service{
+		repository: repository,
+		s3:         s3,
+		ingester:   ingester,
+		logger:     logger,
+		maxSize:    int64(cfg.AppConfig.Tus.MaxSize) * bytesPerMB,
+		expiration: time.Duration(cfg.AppConfig.Tus.Expiration) * time.Hour,
+	}
+	if s.maxSize <= 0 {
+		s.maxSize = defaultMaxSize * bytesPerMB
+	}
+	if s.expiration <= 0 {
+		s.expiration = defaultExpiration * time.Hour
+	}
+	return s
+}
+
+// MaxSize is the largest file an upload takes, in bytes.
+func (s *Service) MaxSize() int64 {
+	return s.maxSize
+}
+
+// CreateService creates an upload of length bytes. The filename of the
+// metadata names the object the upload completes to.
+func (s *Service) CreateService(c *gin.Context, length, metadata string) (*model.TusUpload, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "TusCreateService")
+	defer span.End()
+
+	if length == "" {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "Upload-Length is required, deferred lengths are not supported"}
+	}
+	size, err := strconv.ParseInt(length, 10, 64)
+	if err != nil || size <= 0 {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "Upload-Length must be a positive number"}
+	}
+	if size > s.maxSize {
+		return nil, &model.RestError{Code: http.StatusRequestEntityTooLarge, Err: fmt.Sprintf("the file is larger than %d MB", s.maxSize/bytesPerMB)}
+	}
+	values, err := ParseMetadata(metadata)
+	if err != nil {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: err.Error()}
+	}
+	if values["filename"] == "" {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "the filename is missing in Upload-Metadata"}
+	}
+	key, err := upload.ObjectKey(values["filename"])
+	if err != nil {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: err.Error()}
+	}
+	contentType := ""
+	if strings.HasPrefix(values["filetype"], "audio/") {
+		contentType = values["filetype"]
+	}
+
+	uploadID, err := s.s3.NewMultipartUploadS3(ctx, key, contentType)
+	if err != nil {
+		s.logger.Errorf("Error starting the multipart upload of %s: %v", key, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	now := time.Now()
+	created := &model.TusUpload{
+		ID:        uuid.New(),
+		ObjectKey: key,
+		UploadID:  uploadID,
+		Length:    size,
+		Metadata:  metadata,
+		Parts:     []model.TusPart{},
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.expiration),
+	}
+	if err = s.repository.CreateTusUpload(ctx, created); err != nil {
+		s.logger.Errorf("Error creating the upload of %s: %v", key, err)
+		if errAbort := s.s3.AbortMultipartUploadS3(ctx, key, uploadID); errAbort != nil {
+			s.logger.Errorf("Error aborting the multipart upload %s of %s: %v", uploadID, key, errAbort)
+		}
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	s.logger.Infof("Upload %s of %s created, %d bytes", created.ID, key, size)
+	return created, nil
+}
+
+// HeadService returns the upload and how far it got.
+func (s *Service) HeadService(c *gin.Context, id string) (*model.TusUpload, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "TusHeadService")
+	defer span.End()
+
+	return s.get(ctx, id, true)
+}
+
+// PatchService appends the body of the request to the upload, which must be
+// at offset. The bytes received are kept even when the request breaks off.
+// The upload completes to a new version of its object with the last byte and
+// is ingested at once.
+func (s *Service) PatchService(c *gin.Context, id, offset string) (*model.TusUpload, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "TusPatchService")
+	defer span.End()
+
+	at, err := strconv.ParseInt(offset, 10, 64)
+	if err != nil || at < 0 {
+		return nil, &model.RestError{Code: http.StatusBadRequest, Err: "Upload-Offset must be a number"}
+	}
+	unlock, restErr := s.lock(ctx, id)
+	if restErr != nil {
+		return nil, restErr
+	}
+	defer unlock()
+	patched, restErr := s.get(ctx, id, true)
+	if restErr != nil {
+		return nil, restErr
+	}
+	if at != patched.Offset {
+		return nil, &model.RestError{Code: http.StatusConflict, Err: fmt.Sprintf("the upload is at offset %d", patched.Offset)}
+	}
+	if patched.CompletedAt != nil {
+		return patched, nil
+	}
+	if c.Request.ContentLength > patched.Length-patched.Offset {
+		return nil, &model.RestError{Code: http.StatusRequestEntityTooLarge, Err: "the body runs past Upload-Length"}
+	}
+
+	// What was received is stored even when the client goes away
+	ctx = context.WithoutCancel(ctx)
+	if err = s.write(ctx, patched, c.Request.Body); err != nil {
+		s.logger.Errorf("Error writing upload %s: %v", id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	if patched.Offset < patched.Length {
+		return patched, nil
+	}
+	if err = s.complete(ctx, patched); err != nil {
+		s.logger.Errorf("Error completing upload %s: %v", id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	return patched, nil
+}
+
+// DeleteService terminates the upload and frees its parts. The object and
+// the tracks of a completed upload are kept.
+func (s *Service) DeleteService(c *gin.Context, id string) *model.RestError {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "TusDeleteService")
+	defer span.End()
+
+	unlock, restErr := s.lock(ctx, id)
+	if restErr != nil {
+		return restErr
+	}
+	defer unlock()
+	terminated, restErr := s.get(ctx, id, false)
+	if restErr != nil {
+		return restErr
+	}
+	if err := s.remove(ctx, terminated); err != nil {
+		s.logger.Errorf("Error terminating upload %s: %v", id, err)
+		return &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	s.logger.Infof("Upload %s of %s terminated", id, terminated.ObjectKey)
+	return nil
+}
+
+// ExpireService removes the uploads that expired and returns how many. The
+// ones a request is writing to are left for the next run.
+func (s *Service) ExpireService(ctx context.Context) (int, error) {
+	expired, err := s.repository.GetExpiredTusUploads(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for i := range expired {
+		id := expired[i].ID.String()
+		unlock, locked, errLock := s.lease(ctx, id)
+		if errLock != nil || !locked {
+			continue
+		}
+		if err = s.remove(ctx, &expired[i]); err != nil {
+			s.logger.Errorf("Error removing expired upload %s: %v", id, err)
+		} else {
+			removed++
+		}
+		unlock()
+	}
+	return removed, nil
+}
+
+// ParseMetadata decodes an Upload-Metadata header, comma separated pairs of
+// a key and its base64 encoded value, which may be left out.
+func ParseMetadata(header string) (map[string]string, error) {
+	values := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return values, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("empty key in Upload-Metadata")
+		}
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("duplicate key %q in Upload-Metadata", key)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("the value of %q in Upload-Metadata is not base64", key)
+		}
+		values[key] = string(decoded)
+	}
+	return values, nil
+}
+
+// lock takes the lease of the upload, a request writing to it already is a
+// conflict.
+func (s *Service) lock(ctx context.Context, id string) (func(), *model.RestError) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "upload not found"}
+	}
+	unlock, locked, err := s.lease(ctx, id)
+	if err != nil {
+		s.logger.Errorf("Error locking upload %s: %v", id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	if !locked {
+		if _, restErr := s.get(ctx, id, false); restErr != nil {
+			return nil, restErr
+		}
+		return nil, &model.RestError{Code: http.StatusLocked, Err: "another request is writing to the upload"}
+	}
+	return unlock, nil
+}
+
+// lease takes the lease of the upload and renews it until the returned
+// unlock is called, which releases it. It reports false when another request
+// holds the lease or the upload does not exist.
+func (s *Service) lease(ctx context.Context, id string) (func(), bool, error) {
+	ctx = context.WithoutCancel(ctx)
+	token := uuid.New()
+	locked, err := s.repository.LeaseTusUpload(ctx, id, token, time.Now().Add(leaseDuration))
+	if err != nil || !locked {
+		return nil, false, err
+	}
+
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(leaseRenewal)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				renewed, errRenew := s.repository.LeaseTusUpload(ctx, id, token, time.Now().Add(leaseDuration))
+				if errRenew != nil || !renewed {
+					s.logger.Warnf("Lease of upload %s not renewed: %v", id, errRenew)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		if errRelease := s.repository.ReleaseTusUpload(ctx, id, token); errRelease != nil {
+			s.logger.Warnf("Error releasing the lease of upload %s: %v", id, errRelease)
+		}
+	}, true, nil
+}
+
+// get returns the upload, with live only one that has not expired.
+func (s *Service) get(ctx context.Context, id string, live bool) (*model.TusUpload, *model.RestError) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "upload not found"}
+	}
+	found, err := s.repository.GetTusUpload(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &model.RestError{Code: http.StatusNotFound, Err: "upload not found"}
+		}
+		s.logger.Errorf("Error getting upload %s: %v", id, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Internal Server Error"}
+	}
+	if live && time.Now().After(found.ExpiresAt) {
+		return nil, &model.RestError{Code: http.StatusGone, Err: "the upload expired"}
+	}
+	return found, nil
+}
+
+// write appends the body to the upload. Every part that fills up on the way
+// is uploaded to S3 and the progress saved, the rest of the body is kept
+// pending. The bytes read are saved on errors as well, a part is numbered
+// after the saved ones and so replaces a part uploaded but not saved.
+func (s *Service) write(ctx context.Context, patched *model.TusUpload, body io.Reader) error {
+	pending := bytes.NewBuffer(patched.Pending)
+	body = io.LimitReader(body, patched.Length-patched.Offset)
+	var errRead, errPart error
+	for errRead == nil && patched.Offset < patched.Length {
+		var n int64
+		n, errRead = io.CopyN(pending, body, partSize-int64(pending.Len()))
+		patched.Offset += n
+		if pending.Len() == partSize && patched.Offset < patched.Length {
+			if errPart = s.putPart(ctx, patched, pending); errPart != nil {
+				break
+			}
+		}
+	}
+	if errRead != nil && !errors.Is(errRead, io.EOF) {
+		s.logger.Warnf("Upload %s broke off at offset %d: %v", patched.ID, patched.Offset, errRead)
+	}
+
+	patched.Pending = pending.Bytes()
+	patched.ExpiresAt = time.Now().Add(s.expiration)
+	errSave := s.repository.UpdateTusUpload(ctx, patched)
+	if errPart != nil {
+		return errPart
+	}
+	return errSave
+}
+
+// putPart uploads the pending bytes as the next part and saves the progress.
+func (s *Service) putPart(ctx context.Context, patched *model.TusUpload, pending *bytes.Buffer) error {
+	number := len(patched.Parts) + 1
+	size := int64(pending.Len())
+	part, err := s.s3.PutObjectPartS3(ctx, patched.ObjectKey, patched.UploadID, number, bytes.NewReader(pending.Bytes()), size)
+	if err != nil {
+		return fmt.Errorf("error uploading part %d: %w", number, err)
+	}
+	patched.Parts = append(patched.Parts, model.TusPart{Number: number, ETag: part.ETag, Size: size})
+	pending.Reset()
+	patched.Pending = nil
+	return s.repository.UpdateTusUpload(ctx, patched)
+}
+
+// complete uploads the pending bytes as the last part, joins the parts into
+// the object and ingests it. Why the object did not become a track is kept
+// with the upload.
+func (s *Service) complete(ctx context.Context, completed *model.TusUpload) error {
+	if len(completed.Pending) > 0 {
+		if err := s.putPart(ctx, completed, bytes.NewBuffer(completed.Pending)); err != nil {
+			return err
+		}
+	}
+	parts := make([]minio.CompletePart, len(completed.Parts))
+	for i, part := range completed.Parts {
+		parts[i] = minio.CompletePart{PartNumber: part.Number, ETag: part.ETag}
+	}
+	info, err := s.s3.CompleteMultipartUploadS3(ctx, completed.ObjectKey, completed.UploadID, parts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	completed.CompletedAt = &now
+	completed.ExpiresAt = now.Add(s.expiration)
+	if err = s.repository.UpdateTusUpload(ctx, completed); err != nil {
+		return err
+	}
+
+	object := &minio.ObjectInfo{
+		Key: completed.ObjectKey, VersionID: info.VersionID, ETag: info.ETag, Size: completed.Length, LastModified: now,
+	}
+	trackIDs, err := s.ingester.IngestObject(ctx, object)
+	switch {
+	case err != nil:
+		s.logger.Errorf("Error ingesting %s version %s of upload %s: %v", completed.ObjectKey, info.VersionID, completed.ID, err)
+		s.deleteRejected(ctx, object)
+		completed.IngestError = err.Error()
+	case len(trackIDs) == 0:
+		s.deleteRejected(ctx, object)
+		completed.IngestError = "the same track exists already"
+	default:
+		completed.TrackIDs = trackIDs
+		s.logger.Infof("Upload %s completed as tracks %s", completed.ID, strings.Join(trackIDs, ", "))
+	}
+	return s.repository.UpdateTusUpload(ctx, completed)
+}
+
+// deleteRejected deletes the completed object version that was not ingested,
+// so that the bucket does not keep a version no track streams.
+func (s *Service) deleteRejected(ctx context.Context, object *minio.ObjectInfo) {
+	if err := s.s3.DeleteObjectS3(ctx, object); err != nil {
+		s.logger.Errorf("Error deleting the rejected version %s of %s: %v", object.VersionID, object.Key, err)
+	}
+}
+
+// remove aborts the multipart upload of an unfinished upload and deletes the
+// upload.
+func (s *Service) remove(ctx context.Context, removed *model.TusUpload) error {
+	if removed.CompletedAt == nil {
+		if err := s.s3.AbortMultipartUploadS3(ctx, removed.ObjectKey, removed.UploadID); err != nil {
+			var s3Err minio.ErrorResponse
+			if !errors.As(err, &s3Err) || s3Err.Code != "NoSuchUpload" {
+				return err
+			}
+		}
+	}
+	return s.repository.DeleteTusUpload(ctx, removed.ID.String())
+}
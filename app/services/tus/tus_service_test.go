@@ -0,0 +1,336 @@
+package tus_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/s3"
+	"s3MediaStreamer/app/services/tus"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const partSize = 5 << 20
+
+func TestParseMetadata(t *testing.T) {
+	values, err := tus.ParseMetadata("filename MDEgTWFyY28gUG9sby5mbGFj,filetype YXVkaW8vZmxhYw==, is_confidential")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"filename": "01 Marco Polo.flac", "filetype": "audio/flac", "is_confidential": ""}, values)
+
+	values, err = tus.ParseMetadata("")
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	_, err = tus.ParseMetadata("filename not-base64!")
+	assert.Error(t, err)
+	_, err = tus.ParseMetadata("filename YQ==,filename Yg==")
+	assert.Error(t, err)
+	_, err = tus.ParseMetadata("filename YQ==,,")
+	assert.Error(t, err)
+}
+
+func TestPatchCompletesInParts(t *testing.T) {
+	env := newTestEnv(t)
+	data := randomBytes(2*partSize + 123)
+	id := env.create(t, len(data))
+
+	patched, restErr := env.patch(id, 0, data)
+	require.Nil(t, restErr)
+	assert.Equal(t, int64(len(data)), patched.Offset)
+	require.NotNil(t, patched.CompletedAt)
+	assert.Equal(t, []string{"track-1"}, patched.TrackIDs)
+	assert.Equal(t, []model.TusPart{
+		{Number: 1, ETag: "etag-1", Size: partSize},
+		{Number: 2, ETag: "etag-2", Size: partSize},
+		{Number: 3, ETag: "etag-3", Size: 123},
+	}, patched.Parts)
+	assert.Equal(t, data, env.objects.completed)
+	assert.Equal(t, "version-1", env.ingester.object.VersionID)
+	assert.Equal(t, int64(len(data)), env.ingester.object.Size)
+}
+
+func TestPatchResumesAtOffset(t *testing.T) {
+	env := newTestEnv(t)
+	data := randomBytes(2*partSize + 123)
+	id := env.create(t, len(data))
+
+	first := partSize + 2<<20
+	patched, restErr := env.patch(id, 0, data[:first])
+	require.Nil(t, restErr)
+	assert.Equal(t, int64(first), patched.Offset)
+	assert.Nil(t, patched.CompletedAt)
+	saved, err := env.repository.GetTusUpload(context.Background(), id)
+	require.NoError(t, err)
+	assert.Len(t, saved.Parts, 1)
+	assert.Equal(t, data[partSize:first], saved.Pending)
+
+	_, restErr = env.patch(id, 0, data)
+	require.NotNil(t, restErr)
+	assert.Equal(t, http.StatusConflict, restErr.Code)
+
+	patched, restErr = env.patch(id, int64(first), data[first:])
+	require.Nil(t, restErr)
+	require.NotNil(t, patched.CompletedAt)
+	assert.Equal(t, data, env.objects.completed)
+}
+
+func TestPatchReplacesAnUnsavedPart(t *testing.T) {
+	env := newTestEnv(t)
+	data := randomBytes(2*partSize + 123)
+	id := env.create(t, len(data))
+
+	// The second part fails, the bytes of it are kept pending
+	env.objects.failPart = 2
+	_, restErr := env.patch(id, 0, data)
+	require.NotNil(t, restErr)
+	assert.Equal(t, http.StatusInternalServerError, restErr.Code)
+	saved, err := env.repository.GetTusUpload(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*partSize), saved.Offset)
+	assert.Len(t, saved.Parts, 1)
+	assert.Len(t, saved.Pending, partSize)
+
+	patched, restErr := env.patch(id, saved.Offset, data[saved.Offset:])
+	require.Nil(t, restErr)
+	require.NotNil(t, patched.CompletedAt)
+	assert.Len(t, patched.Parts, 3)
+	assert.Equal(t, data, env.objects.completed)
+}
+
+func TestPatchLeasedUpload(t *testing.T) {
+	env := newTestEnv(t)
+	data := randomBytes(123)
+	id := env.create(t, len(data))
+
+	ctx, other := context.Background(), uuid.New()
+	locked, err := env.repository.LeaseTusUpload(ctx, id, other, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, locked)
+	_, restErr := env.patch(id, 0, data)
+	require.NotNil(t, restErr)
+	assert.Equal(t, http.StatusLocked, restErr.Code)
+
+	require.NoError(t, env.repository.ReleaseTusUpload(ctx, id, other))
+	patched, restErr := env.patch(id, 0, data)
+	require.Nil(t, restErr)
+	require.NotNil(t, patched.CompletedAt)
+
+	// The lease is released with the request
+	locked, err = env.repository.LeaseTusUpload(ctx, id, other, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	_, restErr = env.patch(uuid.NewString(), 0, data)
+	require.NotNil(t, restErr)
+	assert.Equal(t, http.StatusNotFound, restErr.Code)
+}
+
+func TestPatchDeletesRejectedVersion(t *testing.T) {
+	env := newTestEnv(t)
+	data := randomBytes(123)
+	id := env.create(t, len(data))
+
+	env.ingester.trackIDs = nil
+	patched, restErr := env.patch(id, 0, data)
+	require.Nil(t, restErr)
+	require.NotNil(t, patched.CompletedAt)
+	assert.Empty(t, patched.TrackIDs)
+	assert.Equal(t, "the same track exists already", patched.IngestError)
+	assert.Equal(t, []string{"version-1"}, env.objects.deleted)
+
+	id = env.create(t, len(data))
+	env.ingester.err = errors.New("invalid FLAC stream")
+	patched, restErr = env.patch(id, 0, data)
+	require.Nil(t, restErr)
+	assert.Equal(t, "invalid FLAC stream", patched.IngestError)
+	assert.Equal(t, []string{"version-1", "version-1"}, env.objects.deleted)
+}
+
+type testEnv struct {
+	service    *tus.Service
+	repository *memoryRepository
+	objects    *memoryObjects
+	ingester   *recordingIngester
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	env := &testEnv{
+		repository: &memoryRepository{uploads: make(map[string]model.TusUpload), leases: make(map[string]uuid.UUID)},
+		objects:    &memoryObjects{parts: make(map[int][]byte)},
+		ingester:   &recordingIngester{trackIDs: []string{"track-1"}},
+	}
+	logger := &logs.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	env.service = tus.NewTusService(&model.Config{}, env.repository, *s3.NewS3Service(env.objects, nil), env.ingester, logger)
+	return env
+}
+
+// create creates an upload of size bytes and returns its ID.
+func (env *testEnv) create(t *testing.T, size int) string {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/tus", http.NoBody)
+	created, restErr := env.service.CreateService(c, strconv.Itoa(size), "filename dHJhY2suZmxhYw==")
+	require.Nil(t, restErr)
+	return created.ID.String()
+}
+
+func (env *testEnv) patch(id string, offset int64, body []byte) (*model.TusUpload, *model.RestError) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPatch, "/v1/tus/"+id, bytes.NewReader(body))
+	return env.service.PatchService(c, id, strconv.FormatInt(offset, 10))
+}
+
+func randomBytes(n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(data)
+	return data
+}
+
+// memoryRepository keeps the uploads and their leases in memory.
+type memoryRepository struct {
+	mu      sync.Mutex
+	uploads map[string]model.TusUpload
+	leases  map[string]uuid.UUID
+}
+
+func (r *memoryRepository) CreateTusUpload(_ context.Context, upload *model.TusUpload) error {
+	return r.UpdateTusUpload(context.Background(), upload)
+}
+
+func (r *memoryRepository) GetTusUpload(_ context.Context, id string) (*model.TusUpload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	upload, ok := r.uploads[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	upload.Parts = append([]model.TusPart{}, upload.Parts...)
+	upload.Pending = bytes.Clone(upload.Pending)
+	return &upload, nil
+}
+
+func (r *memoryRepository) GetExpiredTusUploads(_ context.Context, now time.Time) ([]model.TusUpload, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var expired []model.TusUpload
+	for _, upload := range r.uploads {
+		if upload.ExpiresAt.Before(now) {
+			expired = append(expired, upload)
+		}
+	}
+	return expired, nil
+}
+
+func (r *memoryRepository) LeaseTusUpload(_ context.Context, id string, token uuid.UUID, _ time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.uploads[id]; !ok {
+		return false, nil
+	}
+	if holder, ok := r.leases[id]; ok && holder != token {
+		return false, nil
+	}
+	r.leases[id] = token
+	return true, nil
+}
+
+func (r *memoryRepository) ReleaseTusUpload(_ context.Context, id string, token uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.leases[id] == token {
+		delete(r.leases, id)
+	}
+	return nil
+}
+
+func (r *memoryRepository) UpdateTusUpload(_ context.Context, upload *model.TusUpload) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *upload
+	saved.Parts = append([]model.TusPart{}, upload.Parts...)
+	saved.Pending = bytes.Clone(upload.Pending)
+	r.uploads[upload.ID.String()] = saved
+	return nil
+}
+
+func (r *memoryRepository) DeleteTusUpload(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.uploads, id)
+	return nil
+}
+
+// memoryObjects keeps the parts of one multipart upload in memory. The upload
+// of the part numbered failPart fails once.
+type memoryObjects struct {
+	s3.Repository
+	parts     map[int][]byte
+	failPart  int
+	completed []byte
+	deleted   []string
+}
+
+func (o *memoryObjects) NewMultipartUploadS3(context.Context, string, string) (string, error) {
+	return "upload-1", nil
+}
+
+func (o *memoryObjects) PutObjectPartS3(_ context.Context, _, _ string, number int, reader io.Reader, size int64) (minio.ObjectPart, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return minio.ObjectPart{}, err
+	}
+	if number == o.failPart {
+		o.failPart = 0
+		return minio.ObjectPart{}, fmt.Errorf("part %d failed", number)
+	}
+	o.parts[number] = data
+	return minio.ObjectPart{PartNumber: number, ETag: fmt.Sprintf("etag-%d", number), Size: size}, nil
+}
+
+func (o *memoryObjects) CompleteMultipartUploadS3(_ context.Context, _, _ string, parts []minio.CompletePart) (minio.UploadInfo, error) {
+	o.completed = nil
+	for i, part := range parts {
+		if part.PartNumber != i+1 || part.ETag != fmt.Sprintf("etag-%d", part.PartNumber) {
+			return minio.UploadInfo{}, fmt.Errorf("invalid part %d", part.PartNumber)
+		}
+		o.completed = append(o.completed, o.parts[part.PartNumber]...)
+	}
+	return minio.UploadInfo{VersionID: "version-1", ETag: "etag"}, nil
+}
+
+func (o *memoryObjects) DeleteObjectS3(_ context.Context, object *minio.ObjectInfo) error {
+	o.deleted = append(o.deleted, object.VersionID)
+	return nil
+}
+
+// recordingIngester ingests every object as the tracks trackIDs, or fails
+// with err.
+type recordingIngester struct {
+	object   *minio.ObjectInfo
+	trackIDs []string
+	err      error
+}
+
+func (i *recordingIngester) IngestObject(_ context.Context, object *minio.ObjectInfo) ([]string, error) {
+	i.object = object
+	return i.trackIDs, i.err
+}
@@ -0,0 +1,249 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/artwork"
+	"s3MediaStreamer/app/services/cue"
+	"s3MediaStreamer/app/services/s3"
+	"s3MediaStreamer/app/services/tags"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// FormField is the multipart field the files are uploaded in.
+	FormField = "files"
+
+	bytesPerMB         = 1 << 20
+	defaultMaxFileSize = 512
+	defaultMaxFiles    = 20
+	// formOverhead is the room for the part headers and the boundaries in
+	// the limit of the whole request body.
+	formOverhead = 1 << 20
+)
+
+var errTooLarge = errors.New("file too large")
+
+type Repository interface {
+	GetTrackIDByIdentity(ctx context.Context, track *model.Track) (string, error)
+}
+
+// Ingester creates the tracks of an uploaded object version, the S3 event
+// consumer.
+type Ingester interface {
+	IngestObject(ctx context.Context, object *minio.ObjectInfo) ([]string, error)
+}
+
+type Service struct {
+	repository  Repository
+	s3          s3.Service
+	tags        tags.Service
+	ingester    Ingester
+	logger      *logs.Logger
+	maxFileSize int64
+	maxFiles    int
+}
+
+func NewUploadService(cfg *model.Config, repository Repository, s3 s3.Service, tags tags.Service, ingester Ingester, logger *logs.Logger) *Service {
+	s := &Service{
+		repository:  repository,
+		s3:          s3,
+		tags:        tags,
+		ingester:    ingester,
+		logger:      logger,
+		maxFileSize: int64(cfg.AppConfig.Upload.MaxFileSize) * bytesPerMB,
+		maxFiles:    cfg.AppConfig.Upload.MaxFiles,
+	}
+	if s.maxFileSize <= 0 {
+		s.maxFileSize = defaultMaxFileSize * bytesPerMB
+	}
+	if s.maxFiles <= 0 {
+		s.maxFiles = defaultMaxFiles
+	}
+	return s
+}
+
+// UploadService reads the files of a multipart upload as they stream in,
+// checks the format and the tags of each and uploads the valid ones to S3,
+// where they are ingested at once. Files above the size limit are cut off
+// while they are read. It returns the outcome of every file and whether any
+// of them was ingested.
+func (s *Service) UploadService(c *gin.Context) (*model.UploadResponse, bool, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "UploadService")
+	defer span.End()
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(s.maxFiles)*(s.maxFileSize+formOverhead))
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, false, &model.RestError{Code: http.StatusBadRequest, Err: "the request must be multipart/form-data"}
+	}
+
+	response := &model.UploadResponse{Files: []model.UploadResult{}}
+	ingested := false
+	for {
+		part, errPart := reader.NextPart()
+		if errors.Is(errPart, io.EOF) {
+			break
+		}
+		if errPart != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(errPart, &tooLarge) {
+				return nil, false, &model.RestError{Code: http.StatusRequestEntityTooLarge, Err: "the request is too large"}
+			}
+			return nil, false, &model.RestError{Code: http.StatusBadRequest, Err: "invalid multipart body: " + errPart.Error()}
+		}
+		if part.FormName() != FormField || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		result := model.UploadResult{File: part.FileName()}
+		if len(response.Files) == s.maxFiles {
+			result.Error = fmt.Sprintf("at most %d files can be uploaded at once", s.maxFiles)
+		} else {
+			result.TrackIDs, err = s.uploadFile(ctx, part)
+			if err != nil {
+				result.Error = err.Error()
+			}
+		}
+		part.Close()
+		ingested = ingested || len(result.TrackIDs) > 0
+		response.Files = append(response.Files, result)
+	}
+
+	if len(response.Files) == 0 {
+		return nil, false, &model.RestError{Code: http.StatusBadRequest, Err: "no files in the " + FormField + " field"}
+	}
+	return response, ingested, nil
+}
+
+// uploadFile checks the file of the part and uploads it, and returns the IDs
+// of the tracks it was ingested as. The errors are the reasons the file was
+// rejected.
+func (s *Service) uploadFile(ctx context.Context, part *multipart.Part) ([]string, error) {
+	key, err := ObjectKey(part.FileName())
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "upload-*"+filepath.Ext(key))
+	if err != nil {
+		s.logger.Errorf("Error creating a temporary file: %v", err)
+		return nil, errors.New("internal server error")
+	}
+	defer os.Remove(file.Name())
+	err = s.receive(file, part)
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	switch {
+	case errors.Is(err, errTooLarge):
+		return nil, fmt.Errorf("the file is larger than %d MB", s.maxFileSize/bytesPerMB)
+	case err != nil:
+		return nil, fmt.Errorf("error receiving the file: %w", err)
+	}
+
+	track, err := s.tags.ReadTags(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	if track.ContentHash, err = s.tags.HashAudio(file.Name()); err != nil {
+		s.logger.Errorf("Error hashing the audio of %s: %v", key, err)
+		return nil, errors.New("internal server error")
+	}
+	if err = s.checkDuplicate(ctx, key, track); err != nil {
+		return nil, err
+	}
+
+	// The ingestion of an uploaded file is not cut short by the client
+	ctx = context.WithoutCancel(ctx)
+	info, err := s.s3.UploadFilesS3(ctx, &model.UploadS3{ObjectName: key, FilePath: file.Name(), ContentType: track.MimeType})
+	if err != nil {
+		s.logger.Errorf("Error uploading %s: %v", key, err)
+		return nil, errors.New("the file could not be uploaded to S3")
+	}
+	object := &minio.ObjectInfo{
+		Key: key, VersionID: info.VersionID, ETag: info.ETag, Size: info.Size, LastModified: info.LastModified,
+	}
+	trackIDs, err := s.ingester.IngestObject(ctx, object)
+	if err != nil {
+		s.logger.Errorf("Error ingesting %s version %s: %v", key, info.VersionID, err)
+		s.deleteRejected(ctx, object)
+		return nil, fmt.Errorf("the file was uploaded but not ingested: %w", err)
+	}
+	if len(trackIDs) == 0 {
+		s.deleteRejected(ctx, object)
+		return nil, fmt.Errorf("the file was uploaded but the track %q exists already", track.Title)
+	}
+	s.logger.Infof("Uploaded %s version %s as tracks %s", key, info.VersionID, strings.Join(trackIDs, ", "))
+	return trackIDs, nil
+}
+
+// ObjectKey returns the key of the object an uploaded file of the name is
+// stored as, an error when the name is not one of an audio file.
+func ObjectKey(name string) (string, error) {
+	key := filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	if key == "." || key == "/" || strings.HasPrefix(key, ".") {
+		return "", errors.New("invalid file name")
+	}
+	if artwork.IsArtworkObject(key) || cue.IsSheet(key) {
+		return "", errors.New("only audio files can be uploaded")
+	}
+	return key, nil
+}
+
+// receive copies the part to the file, errTooLarge when it is larger than
+// the limit.
+func (s *Service) receive(file *os.File, part *multipart.Part) error {
+	n, err := io.Copy(file, io.LimitReader(part, s.maxFileSize+1))
+	if err != nil {
+		return err
+	}
+	if n > s.maxFileSize {
+		return errTooLarge
+	}
+	return nil
+}
+
+// checkDuplicate rejects a file that would not become a track because the
+// same track exists, one with the same audio payload or with the same artist,
+// album, title and duration. A new version of an object of existing tracks
+// becomes their current version, it is only rejected when it is the same as
+// another track.
+func (s *Service) checkDuplicate(ctx context.Context, key string, track *model.Track) error {
+	trackIDs, err := s.s3.GetTrackIDsByObjectKey(ctx, key)
+	if err != nil {
+		s.logger.Errorf("Error getting the tracks of %s: %v", key, err)
+		return errors.New("internal server error")
+	}
+	existing, err := s.repository.GetTrackIDByIdentity(ctx, track)
+	if err != nil {
+		s.logger.Errorf("Error looking up the tracks like %s: %v", key, err)
+		return errors.New("internal server error")
+	}
+	if existing != "" && !slices.Contains(trackIDs, existing) {
+		return fmt.Errorf("the track %q exists already as track %s", track.Title, existing)
+	}
+	return nil
+}
+
+// deleteRejected deletes the uploaded object version that was not ingested,
+// so that the bucket does not keep a version no track streams.
+func (s *Service) deleteRejected(ctx context.Context, object *minio.ObjectInfo) {
+	if err := s.s3.DeleteObjectS3(ctx, object); err != nil {
+		s.logger.Errorf("Error deleting the rejected version %s of %s: %v", object.VersionID, object.Key, err)
+	}
+}
@@ -0,0 +1,148 @@
+package upload_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/s3"
+	"s3MediaStreamer/app/services/tags"
+	"s3MediaStreamer/app/services/upload"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadRejectsCorruptFLAC(t *testing.T) {
+	env := newTestEnv([]string{"track-1"})
+
+	response, ingested, restErr := env.upload(t, map[string][]byte{
+		"corrupt.flac": flacFile(0, "Broken", "Artist"),
+		"valid.flac":   flacFile(44100, "Song", "Artist"),
+	})
+	require.Nil(t, restErr)
+	assert.True(t, ingested)
+	require.Len(t, response.Files, 2)
+	for _, result := range response.Files {
+		switch result.File {
+		case "corrupt.flac":
+			assert.Contains(t, result.Error, "invalid FLAC stream")
+			assert.Empty(t, result.TrackIDs)
+		case "valid.flac":
+			assert.Empty(t, result.Error)
+			assert.Equal(t, []string{"track-1"}, result.TrackIDs)
+		}
+	}
+	assert.Equal(t, []string{"valid.flac"}, env.objects.uploaded)
+}
+
+func TestUploadDeletesRejectedVersion(t *testing.T) {
+	env := newTestEnv(nil)
+
+	response, ingested, restErr := env.upload(t, map[string][]byte{"valid.flac": flacFile(44100, "Song", "Artist")})
+	require.Nil(t, restErr)
+	assert.False(t, ingested)
+	require.Len(t, response.Files, 1)
+	assert.Contains(t, response.Files[0].Error, "exists already")
+	assert.Equal(t, []string{"version-1"}, env.objects.deleted)
+}
+
+type testEnv struct {
+	service *upload.Service
+	objects *memoryObjects
+}
+
+func newTestEnv(trackIDs []string) *testEnv {
+	gin.SetMode(gin.TestMode)
+	objects := &memoryObjects{}
+	logger := &logs.Logger{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	service := upload.NewUploadService(&model.Config{}, noTracks{}, *s3.NewS3Service(objects, noVersions{}),
+		*tags.NewTagsService(), ingester(trackIDs), logger)
+	return &testEnv{service: service, objects: objects}
+}
+
+func (env *testEnv) upload(t *testing.T, files map[string][]byte) (*model.UploadResponse, bool, *model.RestError) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, data := range files {
+		part, err := writer.CreateFormFile(upload.FormField, name)
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/upload", &body)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return env.service.UploadService(c)
+}
+
+// flacFile returns a FLAC file of one second of 16 bit stereo at the sample
+// rate, without frames, with the title and artist.
+func flacFile(sampleRate uint64, title, artist string) []byte {
+	info := make([]byte, 34)
+	copy(info, []byte{0x10, 0x00, 0x10, 0x00})
+	packed := sampleRate<<44 | uint64(1)<<41 | uint64(15)<<36 | 44100
+	for i := 0; i < 8; i++ {
+		info[10+i] = byte(packed >> (56 - 8*i))
+	}
+	comments := []string{"TITLE=" + title, "ARTIST=" + artist}
+	comment := []byte{6, 0, 0, 0, 'v', 'e', 'n', 'd', 'o', 'r', byte(len(comments)), 0, 0, 0}
+	for _, c := range comments {
+		comment = append(comment, byte(len(c)), 0, 0, 0)
+		comment = append(comment, c...)
+	}
+	file := append([]byte("fLaC\x00\x00\x00\x22"), info...)
+	file = append(file, 0x84, 0, 0, byte(len(comment)))
+	return append(file, comment...)
+}
+
+// noTracks finds no track like the uploaded ones.
+type noTracks struct{}
+
+func (noTracks) GetTrackIDByIdentity(context.Context, *model.Track) (string, error) {
+	return "", nil
+}
+
+// noVersions knows no object versions.
+type noVersions struct {
+	s3.DBRepository
+}
+
+func (noVersions) GetTrackIDsByObjectKey(context.Context, string) ([]string, error) {
+	return nil, nil
+}
+
+// memoryObjects records the objects uploaded and the versions deleted.
+type memoryObjects struct {
+	s3.Repository
+	uploaded []string
+	deleted  []string
+}
+
+func (o *memoryObjects) UploadFilesS3(_ context.Context, upload *model.UploadS3) (minio.UploadInfo, error) {
+	o.uploaded = append(o.uploaded, upload.ObjectName)
+	return minio.UploadInfo{Key: upload.ObjectName, VersionID: "version-1"}, nil
+}
+
+func (o *memoryObjects) DeleteObjectS3(_ context.Context, object *minio.ObjectInfo) error {
+	o.deleted = append(o.deleted, object.VersionID)
+	return nil
+}
+
+// ingester ingests every object as the tracks.
+type ingester []string
+
+func (i ingester) IngestObject(context.Context, *minio.ObjectInfo) ([]string, error) {
+	return i, nil
+}
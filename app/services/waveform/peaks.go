@@ -0,0 +1,111 @@
+package waveform
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"s3MediaStreamer/app/services/pcm"
+)
+
+const (
+	formatVersion = 1
+	headerSize    = 5
+	peakScale     = 127
+)
+
+var errInvalidPeaks = errors.New("invalid waveform data")
+
+// Encode packs peaks as a format version byte, the big endian number of
+// min/max pairs and the pairs as signed bytes.
+func Encode(peaks []int8) []byte {
+	data := make([]byte, headerSize+len(peaks))
+	data[0] = formatVersion
+	binary.BigEndian.PutUint32(data[1:headerSize], uint32(len(peaks)/2))
+	for i, p := range peaks {
+		data[headerSize+i] = byte(p)
+	}
+	return data
+}
+
+// Decode unpacks peaks written by Encode.
+func Decode(data []byte) ([]int8, error) {
+	if len(data) < headerSize || data[0] != formatVersion {
+		return nil, errInvalidPeaks
+	}
+	pairs := int(binary.BigEndian.Uint32(data[1:headerSize]))
+	if len(data) != headerSize+2*pairs {
+		return nil, errInvalidPeaks
+	}
+	peaks := make([]int8, 2*pairs)
+	for i := range peaks {
+		peaks[i] = int8(data[headerSize+i])
+	}
+	return peaks, nil
+}
+
+// Downsample merges neighbouring min/max pairs down to points pairs. Peaks
+// with no more pairs than points are returned as they are.
+func Downsample(peaks []int8, points int) []int8 {
+	pairs := len(peaks) / 2
+	if points <= 0 || points >= pairs {
+		return peaks
+	}
+	out := make([]int8, 2*points)
+	for i := 0; i < points; i++ {
+		from, to := i*pairs/points, (i+1)*pairs/points
+		lo, hi := peaks[2*from], peaks[2*from+1]
+		for j := from + 1; j < to; j++ {
+			lo = min(lo, peaks[2*j])
+			hi = max(hi, peaks[2*j+1])
+		}
+		out[2*i], out[2*i+1] = lo, hi
+	}
+	return out
+}
+
+// peakBuilder spreads the frames of a stream of known length over a fixed
+// number of min/max pairs. The channels are mixed by taking their extremes.
+type peakBuilder struct {
+	total int64
+	pos   int64
+	mins  []float64
+	maxs  []float64
+}
+
+func newPeakBuilder(points int) *peakBuilder {
+	return &peakBuilder{mins: make([]float64, points), maxs: make([]float64, points)}
+}
+
+func (b *peakBuilder) Start(info pcm.Info) error {
+	if info.Frames <= 0 {
+		return errors.New("unknown number of samples")
+	}
+	b.total = info.Frames
+	return nil
+}
+
+func (b *peakBuilder) Frame(samples []float64) {
+	i := int(b.pos * int64(len(b.mins)) / b.total)
+	if i >= len(b.mins) {
+		// The length of MP3 streams is estimated from their frames.
+		i = len(b.mins) - 1
+	}
+	for _, value := range samples {
+		b.mins[i] = math.Min(b.mins[i], value)
+		b.maxs[i] = math.Max(b.maxs[i], value)
+	}
+	b.pos++
+}
+
+func (b *peakBuilder) peaks() []int8 {
+	out := make([]int8, 2*len(b.mins))
+	for i := range b.mins {
+		out[2*i] = scalePeak(b.mins[i])
+		out[2*i+1] = scalePeak(b.maxs[i])
+	}
+	return out
+}
+
+func scalePeak(v float64) int8 {
+	return int8(math.Round(math.Max(-1, math.Min(1, v)) * peakScale))
+}
@@ -0,0 +1,36 @@
+package waveform_test
+
+import (
+	"path/filepath"
+	"s3MediaStreamer/app/services/waveform"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	peaks := []int8{-127, 127, -3, 5, 0, 0}
+	data := waveform.Encode(peaks)
+	assert.Len(t, data, 5+len(peaks))
+
+	decoded, err := waveform.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, peaks, decoded)
+
+	_, err = waveform.Decode(data[:len(data)-1])
+	assert.Error(t, err)
+}
+
+func TestDownsample(t *testing.T) {
+	peaks := []int8{-1, 1, -5, 2, -2, 9, -3, 3}
+	assert.Equal(t, []int8{-5, 2, -3, 9}, waveform.Downsample(peaks, 2))
+	assert.Equal(t, peaks, waveform.Downsample(peaks, 10))
+}
+
+func TestComputeFileFLAC(t *testing.T) {
+	// 512 samples at half scale followed by 512 at full negative scale.
+	peaks, err := waveform.ComputeFile(filepath.Join("testdata", "steps.flac"), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int8{0, 64, -127, 0}, peaks)
+}
@@ -0,0 +1,174 @@
+package waveform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"s3MediaStreamer/app/internal/logs"
+	"s3MediaStreamer/app/model"
+	"s3MediaStreamer/app/services/diskcache"
+	"s3MediaStreamer/app/services/pcm"
+	"s3MediaStreamer/app/services/s3"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/singleflight"
+)
+
+// Resolution is the number of min/max pairs stored per track, requests for
+// fewer points are downsampled from them.
+const Resolution = 2048
+
+var errNoAudio = errors.New("track has no audio object")
+
+type Repository interface {
+	GetWaveform(ctx context.Context, trackID string) (string, []byte, error)
+	SaveWaveform(ctx context.Context, trackID, version string, peaks []byte) error
+	GetTracksByColumns(ctx context.Context, code, columns string) (*model.Track, error)
+	GetTracksWithoutWaveform(ctx context.Context, after string, mimeTypes []string, limit int) ([]model.Track, error)
+}
+
+type Service struct {
+	repository Repository
+	s3         s3.Service
+	cache      *diskcache.Service
+	logger     *logs.Logger
+	group      singleflight.Group
+}
+
+func NewWaveformService(repository Repository, s3 s3.Service, cache *diskcache.Service, logger *logs.Logger) *Service {
+	return &Service{
+		repository: repository,
+		s3:         s3,
+		cache:      cache,
+		logger:     logger,
+	}
+}
+
+// ComputeFile decodes the audio file and returns points min/max pairs.
+func ComputeFile(fileName string, points int) ([]int8, error) {
+	b := newPeakBuilder(points)
+	if err := pcm.DecodeFile(fileName, b); err != nil {
+		return nil, err
+	}
+	return b.peaks(), nil
+}
+
+// ComputeCueFile computes the peaks of the samples from start up to end of
+// the audio file, end <= 0 meaning the end of the file.
+func ComputeCueFile(fileName string, points int, start, end int64) ([]int8, error) {
+	b := newPeakBuilder(points)
+	if err := pcm.DecodeFile(fileName, pcm.Range(start, end, b)); err != nil {
+		return nil, err
+	}
+	return b.peaks(), nil
+}
+
+// UpdateService computes the peaks of the current S3 version of the track
+// unless they are stored already, and returns them.
+func (s *Service) UpdateService(ctx context.Context, trackID string) ([]int8, error) {
+	return s.update(ctx, trackID, s.cache.Fetch)
+}
+
+// BackfillService computes the peaks of the track like UpdateService, reading
+// its audio past the disk cache so that a backfill does not evict the files
+// being played.
+func (s *Service) BackfillService(ctx context.Context, trackID string) error {
+	_, err := s.update(ctx, trackID, s.cache.FetchUncached)
+	return err
+}
+
+// TracksWithoutWaveformService returns up to limit tracks without peaks in a
+// format they can be computed for, whose ID sorts after the given one.
+func (s *Service) TracksWithoutWaveformService(ctx context.Context, after string, limit int) ([]model.Track, error) {
+	return s.repository.GetTracksWithoutWaveform(ctx, after, pcm.MimeTypes, limit)
+}
+
+type fetchFunc func(ctx context.Context, object *minio.ObjectInfo, fn func(path string) error) error
+
+func (s *Service) update(ctx context.Context, trackID string, fetch fetchFunc) ([]int8, error) {
+	version, err := s.s3.GetS3VersionByTrackID(ctx, trackID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errNoAudio, err)
+	}
+	if storedVersion, data, errGet := s.repository.GetWaveform(ctx, trackID); errGet == nil && storedVersion == version {
+		return Decode(data)
+	}
+
+	peaks, err, _ := s.group.Do(trackID+"\x00"+version, func() (interface{}, error) {
+		return s.compute(context.WithoutCancel(ctx), trackID, version, fetch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, _ := peaks.([]int8)
+	return result, nil
+}
+
+func (s *Service) compute(ctx context.Context, trackID, version string, fetch fetchFunc) ([]int8, error) {
+	_, span := otel.Tracer("").Start(ctx, "ComputeWaveform")
+	defer span.End()
+
+	object, err := s.s3.FindObjectFromVersion(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errNoAudio, err)
+	}
+	track, err := s.repository.GetTracksByColumns(ctx, trackID, "_id")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errNoAudio, err)
+	}
+	var peaks []int8
+	err = fetch(ctx, &object, func(path string) error {
+		var errCompute error
+		if track.CueStart == nil {
+			peaks, errCompute = ComputeFile(path, Resolution)
+			return errCompute
+		}
+		// A track cut out of a single-file album by a CUE sheet.
+		end := int64(0)
+		if track.CueEnd != nil {
+			end = *track.CueEnd
+		}
+		peaks, errCompute = ComputeCueFile(path, Resolution, *track.CueStart, end)
+		return errCompute
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repository.SaveWaveform(ctx, trackID, version, Encode(peaks)); err != nil {
+		return nil, err
+	}
+	s.logger.Infof("Waveform of track %s version %s computed", trackID, version)
+	return peaks, nil
+}
+
+// WaveformService returns the peaks of the track downsampled to points pairs,
+// recomputing them when the S3 version of the track changed.
+func (s *Service) WaveformService(c *gin.Context, trackID, points string) (*model.Waveform, *model.RestError) {
+	ctx, span := otel.Tracer("").Start(c.Request.Context(), "WaveformService")
+	defer span.End()
+
+	count := Resolution
+	if points != "" {
+		var err error
+		if count, err = strconv.Atoi(points); err != nil || count < 1 || count > Resolution {
+			return nil, &model.RestError{Code: http.StatusBadRequest, Err: "points must be between 1 and " + strconv.Itoa(Resolution)}
+		}
+	}
+
+	peaks, err := s.UpdateService(ctx, trackID)
+	switch {
+	case errors.Is(err, pcm.ErrUnsupported):
+		return nil, &model.RestError{Code: http.StatusUnprocessableEntity, Err: err.Error()}
+	case errors.Is(err, errNoAudio):
+		return nil, &model.RestError{Code: http.StatusNotFound, Err: "Waveform not found"}
+	case err != nil:
+		s.logger.Errorf("Error computing waveform of track %s: %v", trackID, err)
+		return nil, &model.RestError{Code: http.StatusInternalServerError, Err: "Error computing waveform"}
+	}
+	peaks = Downsample(peaks, count)
+	return &model.Waveform{TrackID: trackID, Points: len(peaks) / 2, Data: peaks}, nil
+}
@@ -0,0 +1,2 @@
+-- Drop the table
+DROP TABLE IF EXISTS hls_keys;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS hls_keys (
+                                        track_id   UUID NOT NULL REFERENCES tracks(_id) ON DELETE CASCADE,
+                                        version    TEXT NOT NULL,
+                                        key        BYTEA NOT NULL,
+                                        created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                        PRIMARY KEY (track_id, version)
+);
+
+-- Alter table owner
+ALTER TABLE hls_keys OWNER TO root;
+
+COMMENT ON TABLE hls_keys IS 'AES-128 keys of encrypted HLS segments, one per track S3 version.';
+COMMENT ON COLUMN hls_keys.track_id IS 'Reference to the _id column in the tracks table';
+COMMENT ON COLUMN hls_keys.version IS 'S3 version the key encrypts';
+COMMENT ON COLUMN hls_keys.key IS 'Raw 16 byte AES-128 key';
+COMMENT ON COLUMN hls_keys.created_at IS 'Timestamp when the key was generated';
@@ -0,0 +1,3 @@
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS mime_type TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN tracks.mime_type IS 'Audio MIME type detected from the magic bytes of the file, empty for tracks scanned before detection';
@@ -0,0 +1,2 @@
+-- Drop the column
+ALTER TABLE tracks DROP COLUMN IF EXISTS artwork;
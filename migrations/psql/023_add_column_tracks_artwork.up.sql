@@ -0,0 +1,3 @@
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS artwork TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN tracks.artwork IS 'S3 key of the embedded cover art under artwork/, empty when the file has none';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS track_waveforms (
+                                        track_id   UUID PRIMARY KEY REFERENCES tracks(_id) ON DELETE CASCADE,
+                                        version    TEXT NOT NULL,
+                                        peaks      BYTEA NOT NULL,
+                                        created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Alter table owner
+ALTER TABLE track_waveforms OWNER TO root;
+
+COMMENT ON TABLE track_waveforms IS 'Waveform min/max peaks of the current S3 version of a track.';
+COMMENT ON COLUMN track_waveforms.track_id IS 'Reference to the _id column in the tracks table';
+COMMENT ON COLUMN track_waveforms.version IS 'S3 version the peaks were computed from';
+COMMENT ON COLUMN track_waveforms.peaks IS 'Encoded peaks: format version byte, big endian uint32 pair count, int8 min/max pairs';
+COMMENT ON COLUMN track_waveforms.created_at IS 'Timestamp when the peaks were computed';
@@ -0,0 +1,4 @@
+-- Drop the columns
+ALTER TABLE tracks DROP COLUMN IF EXISTS track_gain;
+ALTER TABLE tracks DROP COLUMN IF EXISTS true_peak;
+ALTER TABLE tracks DROP COLUMN IF EXISTS loudness;
@@ -0,0 +1,7 @@
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS loudness DOUBLE PRECISION;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS true_peak DOUBLE PRECISION;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS track_gain DOUBLE PRECISION;
+
+COMMENT ON COLUMN tracks.loudness IS 'EBU R128 integrated loudness in LUFS, NULL when the audio could not be measured';
+COMMENT ON COLUMN tracks.true_peak IS 'True peak in dBTP';
+COMMENT ON COLUMN tracks.track_gain IS 'ReplayGain 2.0 track gain in dB relative to -18 LUFS';
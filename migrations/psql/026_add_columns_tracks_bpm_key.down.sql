@@ -0,0 +1,7 @@
+-- Drop the indexes and columns
+DROP INDEX IF EXISTS idx_tracks_musical_key;
+DROP INDEX IF EXISTS idx_tracks_bpm;
+ALTER TABLE tracks DROP COLUMN IF EXISTS key_confidence;
+ALTER TABLE tracks DROP COLUMN IF EXISTS musical_key;
+ALTER TABLE tracks DROP COLUMN IF EXISTS bpm_confidence;
+ALTER TABLE tracks DROP COLUMN IF EXISTS bpm;
@@ -0,0 +1,12 @@
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS bpm DOUBLE PRECISION;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS bpm_confidence DOUBLE PRECISION;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS musical_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS key_confidence DOUBLE PRECISION;
+
+CREATE INDEX IF NOT EXISTS idx_tracks_bpm ON tracks (bpm);
+CREATE INDEX IF NOT EXISTS idx_tracks_musical_key ON tracks (musical_key);
+
+COMMENT ON COLUMN tracks.bpm IS 'Estimated tempo, NULL until analysed and 0 when no beat was found';
+COMMENT ON COLUMN tracks.bpm_confidence IS 'Confidence of the tempo in 0..1';
+COMMENT ON COLUMN tracks.musical_key IS 'Estimated key such as ''A minor'', empty when unknown';
+COMMENT ON COLUMN tracks.key_confidence IS 'Confidence of the key in 0..1, NULL until analysed';
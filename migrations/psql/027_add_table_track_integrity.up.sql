@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS track_integrity (
+                                        object_key TEXT NOT NULL,
+                                        version_id TEXT NOT NULL DEFAULT '',
+                                        etag       TEXT NOT NULL,
+                                        size       BIGINT NOT NULL,
+                                        status     TEXT NOT NULL,
+                                        detail     TEXT NOT NULL DEFAULT '',
+                                        checked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                        PRIMARY KEY (object_key, version_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_track_integrity_status ON track_integrity (status);
+
+-- Alter table owner
+ALTER TABLE track_integrity OWNER TO root;
+
+COMMENT ON TABLE track_integrity IS 'Result of fully decoding each S3 object version.';
+COMMENT ON COLUMN track_integrity.object_key IS 'S3 object key';
+COMMENT ON COLUMN track_integrity.version_id IS 'S3 version of the object, empty without bucket versioning';
+COMMENT ON COLUMN track_integrity.etag IS 'ETag of the checked content, the object is checked again when it changes';
+COMMENT ON COLUMN track_integrity.size IS 'Object size in bytes';
+COMMENT ON COLUMN track_integrity.status IS 'ok, corrupt, truncated or unsupported';
+COMMENT ON COLUMN track_integrity.detail IS 'What the check found wrong';
+COMMENT ON COLUMN track_integrity.checked_at IS 'Timestamp of the check';
@@ -0,0 +1,3 @@
+-- Drop the columns
+ALTER TABLE tracks DROP COLUMN IF EXISTS cue_end;
+ALTER TABLE tracks DROP COLUMN IF EXISTS cue_start;
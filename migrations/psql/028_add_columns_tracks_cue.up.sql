@@ -0,0 +1,5 @@
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS cue_start BIGINT;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS cue_end BIGINT;
+
+COMMENT ON COLUMN tracks.cue_start IS 'First sample of a track cut out of a single-file album by a CUE sheet, NULL for whole objects';
+COMMENT ON COLUMN tracks.cue_end IS 'Sample the CUE track ends before, NULL when it runs to the end of the object';
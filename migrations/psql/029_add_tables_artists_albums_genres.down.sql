@@ -0,0 +1,9 @@
+-- Drop the columns
+ALTER TABLE tracks DROP COLUMN IF EXISTS genre_id;
+ALTER TABLE tracks DROP COLUMN IF EXISTS album_id;
+ALTER TABLE tracks DROP COLUMN IF EXISTS artist_id;
+
+-- Drop the tables
+DROP TABLE IF EXISTS albums;
+DROP TABLE IF EXISTS genres;
+DROP TABLE IF EXISTS artists;
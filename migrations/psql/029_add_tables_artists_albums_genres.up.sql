@@ -0,0 +1,102 @@
+CREATE TABLE IF NOT EXISTS artists (
+                                       _id             UUID NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY,
+                                       created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                       name            TEXT NOT NULL,
+                                       normalized_name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS genres (
+                                      _id             UUID NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY,
+                                      created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                      name            TEXT NOT NULL,
+                                      normalized_name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS albums (
+                                      _id              UUID NOT NULL DEFAULT gen_random_uuid() PRIMARY KEY,
+                                      created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                      title            TEXT NOT NULL,
+                                      normalized_title TEXT NOT NULL,
+                                      artist_id        UUID NOT NULL REFERENCES artists (_id) ON DELETE CASCADE,
+                                      year             SMALLINT NOT NULL DEFAULT 0,
+                                      artwork          TEXT NOT NULL DEFAULT '',
+                                      UNIQUE (artist_id, normalized_title)
+);
+
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS artist_id UUID REFERENCES artists (_id) ON DELETE SET NULL;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS album_id UUID REFERENCES albums (_id) ON DELETE SET NULL;
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS genre_id UUID REFERENCES genres (_id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tracks_artist_id ON tracks (artist_id);
+CREATE INDEX IF NOT EXISTS idx_tracks_album_id ON tracks (album_id);
+CREATE INDEX IF NOT EXISTS idx_tracks_genre_id ON tracks (genre_id);
+
+-- Alter table owner
+ALTER TABLE artists OWNER TO root;
+ALTER TABLE genres OWNER TO root;
+ALTER TABLE albums OWNER TO root;
+
+COMMENT ON TABLE artists IS 'Artists of the tracks and album artists of the albums';
+COMMENT ON COLUMN artists.name IS 'Name as first ingested';
+COMMENT ON COLUMN artists.normalized_name IS 'Lower case name with runs of white space collapsed, names are matched on it';
+COMMENT ON TABLE genres IS 'Genres of the tracks';
+COMMENT ON COLUMN genres.name IS 'Name as first ingested';
+COMMENT ON COLUMN genres.normalized_name IS 'Lower case name with runs of white space collapsed, names are matched on it';
+COMMENT ON TABLE albums IS 'Albums, one per album artist and title';
+COMMENT ON COLUMN albums.title IS 'Title as first ingested';
+COMMENT ON COLUMN albums.normalized_title IS 'Lower case title with runs of white space collapsed, titles are matched on it';
+COMMENT ON COLUMN albums.artist_id IS 'Album artist, the artist of the track when the album artist tag is empty';
+COMMENT ON COLUMN albums.year IS 'Year of the first track that has one';
+COMMENT ON COLUMN albums.artwork IS 'Artwork of the first track that has one';
+COMMENT ON COLUMN tracks.artist_id IS 'Artist entity of the artist column';
+COMMENT ON COLUMN tracks.album_id IS 'Album entity of the album column, NULL when the track has no album or no artist';
+COMMENT ON COLUMN tracks.genre_id IS 'Genre entity of the genre column';
+
+-- Link the tracks ingested before the tables existed, normalizing the same
+-- way as ingestion does.
+CREATE OR REPLACE FUNCTION pg_temp.normalize_name(value TEXT) RETURNS TEXT AS $$
+SELECT lower(regexp_replace(btrim(value), '\s+', ' ', 'g'))
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION pg_temp.display_name(value TEXT) RETURNS TEXT AS $$
+SELECT regexp_replace(btrim(value), '\s+', ' ', 'g')
+$$ LANGUAGE SQL IMMUTABLE;
+
+INSERT INTO artists (name, normalized_name)
+SELECT DISTINCT ON (pg_temp.normalize_name(name)) pg_temp.display_name(name), pg_temp.normalize_name(name)
+FROM (SELECT artist AS name, created_at FROM tracks
+      UNION ALL
+      SELECT album_artist, created_at FROM tracks) names
+WHERE pg_temp.normalize_name(name) <> ''
+ORDER BY pg_temp.normalize_name(name), created_at
+ON CONFLICT (normalized_name) DO NOTHING;
+
+INSERT INTO genres (name, normalized_name)
+SELECT DISTINCT ON (pg_temp.normalize_name(genre)) pg_temp.display_name(genre), pg_temp.normalize_name(genre)
+FROM tracks
+WHERE pg_temp.normalize_name(genre) <> ''
+ORDER BY pg_temp.normalize_name(genre), created_at
+ON CONFLICT (normalized_name) DO NOTHING;
+
+UPDATE tracks t SET artist_id = a._id
+FROM artists a
+WHERE a.normalized_name = pg_temp.normalize_name(t.artist);
+
+UPDATE tracks t SET genre_id = g._id
+FROM genres g
+WHERE g.normalized_name = pg_temp.normalize_name(t.genre);
+
+INSERT INTO albums (title, normalized_title, artist_id, year, artwork)
+SELECT DISTINCT ON (a._id, pg_temp.normalize_name(t.album))
+    pg_temp.display_name(t.album), pg_temp.normalize_name(t.album), a._id, COALESCE(t.year, 0), COALESCE(t.artwork, '')
+FROM tracks t
+         JOIN artists a ON a.normalized_name = pg_temp.normalize_name(COALESCE(NULLIF(btrim(t.album_artist), ''), t.artist))
+WHERE pg_temp.normalize_name(t.album) <> ''
+ORDER BY a._id, pg_temp.normalize_name(t.album), t.year = 0, t.artwork = '', t.created_at
+ON CONFLICT (artist_id, normalized_title) DO NOTHING;
+
+UPDATE tracks t SET album_id = al._id
+FROM albums al
+         JOIN artists a ON a._id = al.artist_id
+WHERE al.normalized_title = pg_temp.normalize_name(t.album)
+  AND a.normalized_name = pg_temp.normalize_name(COALESCE(NULLIF(btrim(t.album_artist), ''), t.artist));
@@ -0,0 +1,13 @@
+-- Drop the indexes
+DROP INDEX IF EXISTS idx_playlists_title_trgm;
+DROP INDEX IF EXISTS idx_artists_name_trgm;
+DROP INDEX IF EXISTS idx_albums_title_trgm;
+DROP INDEX IF EXISTS idx_tracks_album_trgm;
+DROP INDEX IF EXISTS idx_tracks_artist_trgm;
+DROP INDEX IF EXISTS idx_tracks_title_trgm;
+DROP INDEX IF EXISTS idx_playlists_search;
+DROP INDEX IF EXISTS idx_artists_search;
+DROP INDEX IF EXISTS idx_tracks_search;
+
+-- Drop the function
+DROP FUNCTION IF EXISTS tracks_search_document(TEXT, TEXT, TEXT, TEXT, TEXT);
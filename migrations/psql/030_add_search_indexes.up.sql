@@ -0,0 +1,28 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Weighted document of a track: the title ranks first, the artist and album
+-- next, the composer and the lyrics last. The 'simple' configuration does not
+-- stem, the library is in many languages.
+CREATE OR REPLACE FUNCTION tracks_search_document(title TEXT, artist TEXT, album TEXT, composer TEXT, lyrics TEXT)
+    RETURNS tsvector AS $$
+SELECT setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+       setweight(to_tsvector('simple', coalesce(artist, '')), 'B') ||
+       setweight(to_tsvector('simple', coalesce(album, '')), 'B') ||
+       setweight(to_tsvector('simple', coalesce(composer, '')), 'C') ||
+       setweight(to_tsvector('simple', coalesce(lyrics, '')), 'D')
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+COMMENT ON FUNCTION tracks_search_document(TEXT, TEXT, TEXT, TEXT, TEXT) IS 'Weighted full-text document of a track, the search queries match it';
+
+-- Full-text indexes
+CREATE INDEX IF NOT EXISTS idx_tracks_search ON tracks USING GIN (tracks_search_document(title, artist, album, composer, lyrics));
+CREATE INDEX IF NOT EXISTS idx_artists_search ON artists USING GIN (to_tsvector('simple', name));
+CREATE INDEX IF NOT EXISTS idx_playlists_search ON playlists USING GIN (to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(description, '')));
+
+-- Trigram indexes for the fuzzy matches
+CREATE INDEX IF NOT EXISTS idx_tracks_title_trgm ON tracks USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_tracks_artist_trgm ON tracks USING GIN (artist gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_tracks_album_trgm ON tracks USING GIN (album gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_albums_title_trgm ON albums USING GIN (title gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_artists_name_trgm ON artists USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_playlists_title_trgm ON playlists USING GIN (title gin_trgm_ops);
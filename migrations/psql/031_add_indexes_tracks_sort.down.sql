@@ -0,0 +1,11 @@
+-- Drop the indexes
+DROP INDEX IF EXISTS idx_tracks_loudness;
+DROP INDEX IF EXISTS idx_tracks_duration;
+DROP INDEX IF EXISTS idx_tracks_year;
+DROP INDEX IF EXISTS idx_tracks_genre;
+DROP INDEX IF EXISTS idx_tracks_album_artist;
+DROP INDEX IF EXISTS idx_tracks_album;
+DROP INDEX IF EXISTS idx_tracks_artist;
+DROP INDEX IF EXISTS idx_tracks_title;
+DROP INDEX IF EXISTS idx_tracks_updated_at;
+DROP INDEX IF EXISTS idx_tracks_created_at;
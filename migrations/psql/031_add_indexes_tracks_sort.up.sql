@@ -0,0 +1,11 @@
+-- Indexes of the columns the track list can be sorted by
+CREATE INDEX IF NOT EXISTS idx_tracks_created_at ON tracks (created_at);
+CREATE INDEX IF NOT EXISTS idx_tracks_updated_at ON tracks (updated_at);
+CREATE INDEX IF NOT EXISTS idx_tracks_title ON tracks (title);
+CREATE INDEX IF NOT EXISTS idx_tracks_artist ON tracks (artist);
+CREATE INDEX IF NOT EXISTS idx_tracks_album ON tracks (album);
+CREATE INDEX IF NOT EXISTS idx_tracks_album_artist ON tracks (album_artist);
+CREATE INDEX IF NOT EXISTS idx_tracks_genre ON tracks (genre);
+CREATE INDEX IF NOT EXISTS idx_tracks_year ON tracks (year);
+CREATE INDEX IF NOT EXISTS idx_tracks_duration ON tracks (duration);
+CREATE INDEX IF NOT EXISTS idx_tracks_loudness ON tracks (loudness);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_playlists_creator_created_at_id;
+DROP INDEX IF EXISTS idx_playlists_created_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_tracks_created_at ON tracks (created_at);
+DROP INDEX IF EXISTS idx_tracks_created_at_id;
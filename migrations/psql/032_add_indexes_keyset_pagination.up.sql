@@ -0,0 +1,7 @@
+-- Cursors continue after the sort key and the _id of the last row, these
+-- indexes serve the default orders of the track and playlist listings.
+CREATE INDEX IF NOT EXISTS idx_tracks_created_at_id ON tracks (created_at, _id);
+DROP INDEX IF EXISTS idx_tracks_created_at;
+
+CREATE INDEX IF NOT EXISTS idx_playlists_created_at_id ON playlists (created_at, _id);
+CREATE INDEX IF NOT EXISTS idx_playlists_creator_created_at_id ON playlists (_creator_user, created_at, _id);
@@ -0,0 +1,16 @@
+-- Keep only the current version of every track
+DELETE FROM s3version WHERE NOT is_current;
+
+DROP INDEX IF EXISTS idx_s3version_object_key;
+DROP INDEX IF EXISTS idx_s3version_version;
+DROP INDEX IF EXISTS idx_s3version_current;
+
+ALTER TABLE s3version DROP CONSTRAINT IF EXISTS s3version_pkey;
+ALTER TABLE s3version ADD PRIMARY KEY (track_id);
+
+-- Drop the columns
+ALTER TABLE s3version DROP COLUMN IF EXISTS is_current;
+ALTER TABLE s3version DROP COLUMN IF EXISTS uploaded_at;
+ALTER TABLE s3version DROP COLUMN IF EXISTS size;
+ALTER TABLE s3version DROP COLUMN IF EXISTS etag;
+ALTER TABLE s3version DROP COLUMN IF EXISTS object_key;
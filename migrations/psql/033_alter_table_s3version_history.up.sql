@@ -0,0 +1,21 @@
+-- Every object version a track has had is kept, one of them is current. The
+-- versions recorded before have no object key, the s3VersionKeys job fills
+-- it in from the bucket.
+ALTER TABLE s3version ADD COLUMN IF NOT EXISTS object_key TEXT NOT NULL DEFAULT '';
+ALTER TABLE s3version ADD COLUMN IF NOT EXISTS etag TEXT NOT NULL DEFAULT '';
+ALTER TABLE s3version ADD COLUMN IF NOT EXISTS size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE s3version ADD COLUMN IF NOT EXISTS uploaded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now();
+ALTER TABLE s3version ADD COLUMN IF NOT EXISTS is_current BOOLEAN NOT NULL DEFAULT TRUE;
+
+ALTER TABLE s3version DROP CONSTRAINT IF EXISTS s3version_pkey;
+ALTER TABLE s3version ADD PRIMARY KEY (track_id, version);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_s3version_current ON s3version (track_id) WHERE is_current;
+CREATE INDEX IF NOT EXISTS idx_s3version_version ON s3version (version);
+CREATE INDEX IF NOT EXISTS idx_s3version_object_key ON s3version (object_key) WHERE is_current;
+
+COMMENT ON COLUMN s3version.object_key IS 'Key of the S3 object the version belongs to';
+COMMENT ON COLUMN s3version.etag IS 'ETag of the object version';
+COMMENT ON COLUMN s3version.size IS 'Size of the object version in bytes';
+COMMENT ON COLUMN s3version.uploaded_at IS 'Time the object version was uploaded';
+COMMENT ON COLUMN s3version.is_current IS 'Whether the track streams this version, one per track';
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS tus_uploads (
+                                        _id           UUID PRIMARY KEY,
+                                        object_key    TEXT NOT NULL,
+                                        upload_id     TEXT NOT NULL,
+                                        length        BIGINT NOT NULL,
+                                        upload_offset BIGINT NOT NULL DEFAULT 0,
+                                        metadata      TEXT NOT NULL DEFAULT '',
+                                        parts         JSONB NOT NULL DEFAULT '[]',
+                                        pending       BYTEA,
+                                        track_ids     TEXT[],
+                                        ingest_error  TEXT NOT NULL DEFAULT '',
+                                        created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                        expires_at    TIMESTAMPTZ NOT NULL,
+                                        completed_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_tus_uploads_expires_at ON tus_uploads (expires_at);
+
+-- Alter table owner
+ALTER TABLE tus_uploads OWNER TO root;
+
+COMMENT ON TABLE tus_uploads IS 'Resumable tus uploads, each one an S3 multipart upload.';
+COMMENT ON COLUMN tus_uploads._id IS 'ID of the upload in its tus URL';
+COMMENT ON COLUMN tus_uploads.object_key IS 'S3 object key the upload completes to';
+COMMENT ON COLUMN tus_uploads.upload_id IS 'ID of the S3 multipart upload';
+COMMENT ON COLUMN tus_uploads.length IS 'Size of the whole file in bytes';
+COMMENT ON COLUMN tus_uploads.upload_offset IS 'Number of bytes received so far';
+COMMENT ON COLUMN tus_uploads.metadata IS 'Upload-Metadata header the upload was created with';
+COMMENT ON COLUMN tus_uploads.parts IS 'Uploaded S3 parts: number, etag and size';
+COMMENT ON COLUMN tus_uploads.pending IS 'Received bytes that do not fill an S3 part yet';
+COMMENT ON COLUMN tus_uploads.track_ids IS 'Tracks the completed upload was ingested as';
+COMMENT ON COLUMN tus_uploads.ingest_error IS 'Why the completed upload did not become a track';
+COMMENT ON COLUMN tus_uploads.created_at IS 'Timestamp when the upload was created';
+COMMENT ON COLUMN tus_uploads.expires_at IS 'Timestamp after which the upload is removed';
+COMMENT ON COLUMN tus_uploads.completed_at IS 'Timestamp when the last byte was received and the object completed';
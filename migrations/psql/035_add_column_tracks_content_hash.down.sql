@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tracks_identity;
+DROP INDEX IF EXISTS idx_tracks_content_hash;
+
+ALTER TABLE tracks DROP COLUMN IF EXISTS content_hash;
@@ -0,0 +1,9 @@
+-- The identity of a track is the hash of its audio payload, and its artist,
+-- album, title and duration for the tracks without one. The tracks ingested
+-- before are hashed by the hashTracks job.
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_tracks_content_hash ON tracks (content_hash) WHERE content_hash <> '';
+CREATE INDEX IF NOT EXISTS idx_tracks_identity ON tracks (lower(artist), lower(album), lower(title));
+
+COMMENT ON COLUMN tracks.content_hash IS 'Hex SHA-256 of the audio payload without tags, empty when not hashed';
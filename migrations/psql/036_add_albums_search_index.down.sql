@@ -0,0 +1,8 @@
+-- Drop the index
+DROP INDEX IF EXISTS idx_albums_search;
+
+-- Drop the function
+DROP FUNCTION IF EXISTS albums_search_document(TEXT, TEXT);
+
+-- Drop the column
+ALTER TABLE albums DROP COLUMN IF EXISTS artist_name;
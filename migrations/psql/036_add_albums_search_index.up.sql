@@ -0,0 +1,21 @@
+-- The album document holds the name of the album artist, which lives in
+-- another table. The name is kept on the album so that the document can be
+-- indexed.
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS artist_name TEXT NOT NULL DEFAULT '';
+
+UPDATE albums al SET artist_name = a.name
+FROM artists a
+WHERE a._id = al.artist_id;
+
+COMMENT ON COLUMN albums.artist_name IS 'Name of the album artist, copied for the full-text index';
+
+-- Weighted document of an album: the title ranks first, the artist next.
+CREATE OR REPLACE FUNCTION albums_search_document(title TEXT, artist_name TEXT)
+    RETURNS tsvector AS $$
+SELECT setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+       setweight(to_tsvector('simple', coalesce(artist_name, '')), 'B')
+$$ LANGUAGE SQL IMMUTABLE PARALLEL SAFE;
+
+COMMENT ON FUNCTION albums_search_document(TEXT, TEXT) IS 'Weighted full-text document of an album, the search queries match it';
+
+CREATE INDEX IF NOT EXISTS idx_albums_search ON albums USING GIN (albums_search_document(title, artist_name));
@@ -0,0 +1 @@
+ALTER TABLE s3version ALTER COLUMN version TYPE UUID USING version::uuid;
@@ -0,0 +1,2 @@
+-- S3 version IDs are opaque strings, only MinIO makes them UUIDs.
+ALTER TABLE s3version ALTER COLUMN version TYPE TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE tus_uploads DROP COLUMN IF EXISTS leased_until;
+ALTER TABLE tus_uploads DROP COLUMN IF EXISTS lease_token;
@@ -0,0 +1,7 @@
+-- One request at a time writes to an upload, the one that holds its lease.
+-- A lease runs out when the request that took it dies without releasing it.
+ALTER TABLE tus_uploads ADD COLUMN IF NOT EXISTS lease_token UUID;
+ALTER TABLE tus_uploads ADD COLUMN IF NOT EXISTS leased_until TIMESTAMPTZ;
+
+COMMENT ON COLUMN tus_uploads.lease_token IS 'Token of the request that writes to the upload';
+COMMENT ON COLUMN tus_uploads.leased_until IS 'Timestamp until which the lease holds unless it is renewed';
@@ -0,0 +1,5 @@
+-- The hashTracks job gives up on a track that failed to hash a few times, so
+-- that the tracks it cannot hash do not hold the backfill up.
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS content_hash_attempts SMALLINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN tracks.content_hash_attempts IS 'Failed attempts of the hashTracks job at hashing the audio payload';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tracks_unhashed;
+
+ALTER TABLE tracks DROP COLUMN IF EXISTS ingested_at;
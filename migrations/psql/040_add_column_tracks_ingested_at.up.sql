@@ -0,0 +1,12 @@
+-- created_at is the release date read from the tags, tracks are ordered by
+-- when they were ingested. The tracks ingested before get the upload time of
+-- their first recorded version.
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS ingested_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+UPDATE tracks t SET ingested_at = v.uploaded_at
+FROM (SELECT track_id, min(uploaded_at) AS uploaded_at FROM s3version GROUP BY track_id) v
+WHERE t._id = v.track_id;
+
+CREATE INDEX IF NOT EXISTS idx_tracks_unhashed ON tracks (ingested_at) WHERE content_hash = '' AND cue_start IS NULL;
+
+COMMENT ON COLUMN tracks.ingested_at IS 'Timestamp when the track was ingested';